@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -8,12 +9,18 @@ import (
 	"time"
 
 	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/maintenance"
 	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/scheduler"
+	"calendar-assistant/pkg/storage"
 	"calendar-assistant/pkg/telegram"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// storeFlushInterval is how often changes kept in memory, like processed updates, are saved
+const storeFlushInterval = 5 * time.Second
+
 func main() {
 	// Configure logging
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
@@ -26,19 +33,137 @@ func main() {
 	}
 	log.Println("Configuration loaded successfully")
 
-	// Create OpenAI client
-	log.Println("Creating OpenAI client...")
-	openaiClient := openai.NewClient(cfg)
-	log.Println("OpenAI client created successfully")
-
-	// Create Telegram bot
-	log.Println("Creating Telegram bot...")
-	bot, err := telegram.NewBot(cfg.TelegramBotToken, openaiClient)
+	// Open storage
+	log.Printf("Opening storage at %s...", cfg.StoragePath)
+	store, err := storage.Open(cfg.StoragePath)
 	if err != nil {
-		log.Fatalf("Failed to create Telegram bot: %v", err)
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	log.Println("Storage opened successfully")
+
+	// Import users from an older deployment; this is best-effort and never blocks startup
+	if cfg.ImportUsersPath != "" {
+		log.Printf("Importing users from %s...", cfg.ImportUsersPath)
+		imported, err := store.ImportUsersFile(cfg.ImportUsersPath)
+		if err != nil {
+			log.Printf("Warning: Failed to import users: %v", err)
+		} else {
+			log.Printf("Imported %d users", imported)
+		}
+	}
+
+	// Create the Telegram bots; additional ones share the storage. Each gets its own OpenAI
+	// client, which looks up its users' keys and records their usage through the bot.
+	configs := []*config.Config{cfg}
+	for _, extra := range cfg.Bots {
+		configs = append(configs, cfg.ForBot(extra))
 	}
-	log.Println("Telegram bot created successfully")
+	var bots []*telegram.Bot
+	var openaiClients []*openai.Client
+	for _, botCfg := range configs {
+		openaiClient, err := openai.NewClient(botCfg)
+		if err != nil {
+			log.Fatalf("Failed to create OpenAI client of bot %s: %v", botLabel(botCfg), err)
+		}
+		openaiClients = append(openaiClients, openaiClient)
+
+		log.Printf("Creating Telegram bot %s...", botLabel(botCfg))
+		bot, err := telegram.NewBot(botCfg, openaiClient, store)
+		if err != nil {
+			log.Fatalf("Failed to create Telegram bot %s: %v", botLabel(botCfg), err)
+		}
+		bots = append(bots, bot)
+	}
+	log.Printf("%d Telegram bots created successfully", len(bots))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i, bot := range bots {
+		botCfg, bot := configs[i], bot
+		if botCfg.UpdateMode == config.UpdateModeWebhook {
+			// Start the webhook server in a goroutine
+			go func() {
+				log.Printf("Starting Telegram bot %s in webhook mode...", botLabel(botCfg))
+				if err := bot.StartWebhook(ctx); err != nil {
+					log.Fatalf("Failed to start webhook server of bot %s: %v", botLabel(botCfg), err)
+				}
+			}()
+		} else {
+			startPolling(botCfg, bot)
+		}
+	}
+
+	// Register the background jobs and start running them as they become due
+	sched := scheduler.New(store, cfg.SchedulerInterval)
+	if err := maintenance.NewJob(cfg, store, openaiClients).Register(sched); err != nil {
+		log.Fatalf("Failed to schedule maintenance: %v", err)
+	}
+	for i, bot := range bots {
+		if err := bot.RegisterJobs(sched); err != nil {
+			log.Fatalf("Failed to schedule the jobs of bot %s: %v", botLabel(configs[i]), err)
+		}
+	}
+	go sched.Run(ctx)
+	go flushStore(ctx, store)
+
+	// Announce a new release to the users who opted in. Users are shared, so the main bot
+	// announces it for all bots.
+	go bots[0].AnnounceReleaseNotes(ctx)
+
+	log.Println("Bot is now running. Press CTRL-C to exit.")
+
+	// Wait for interrupt signal to gracefully shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
 
+	log.Println("Shutting down...")
+
+	// Stop the webhook server and background jobs, then let running handlers finish
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	for i, bot := range bots {
+		if err := bot.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: bot %s: %v", botLabel(configs[i]), err)
+		}
+	}
+
+	if err := store.Flush(); err != nil {
+		log.Printf("Warning: failed to save storage: %v", err)
+	}
+
+	log.Println("Shutdown complete")
+}
+
+// flushStore saves the changes the store keeps in memory regularly, until ctx is cancelled
+func flushStore(ctx context.Context, store *storage.Store) {
+	ticker := time.NewTicker(storeFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Flush(); err != nil {
+				log.Printf("Error saving storage: %v", err)
+			}
+		}
+	}
+}
+
+// botLabel names a bot in the logs
+func botLabel(cfg *config.Config) string {
+	if cfg.BotName == "" {
+		return "main"
+	}
+	return cfg.BotName
+}
+
+// startPolling removes any webhook, which would block polling, and starts polling for updates
+func startPolling(cfg *config.Config, bot *telegram.Bot) {
 	// Delete webhook using the underlying BotAPI instance
 	log.Println("Deleting any existing webhook...")
 	botAPI, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
@@ -46,8 +171,7 @@ func main() {
 		log.Fatalf("Failed to create BotAPI: %v", err)
 	}
 
-	// Enable debug mode
-	botAPI.Debug = true
+	botAPI.Debug = cfg.TelegramDebug
 
 	// Delete webhook
 	_, err = botAPI.Request(tgbotapi.DeleteWebhookConfig{
@@ -68,13 +192,4 @@ func main() {
 			log.Fatalf("Failed to start Telegram bot: %v", err)
 		}
 	}()
-
-	log.Println("Bot is now running. Press CTRL-C to exit.")
-
-	// Wait for interrupt signal to gracefully shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down...")
 }
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// entry is a cached value and when it expires
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// call is an in-flight load that concurrent callers for the same key wait on
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// TTL is a concurrency-safe in-memory cache whose entries expire after a fixed duration
+type TTL[V any] struct {
+	ttl      time.Duration
+	entries  map[string]entry[V]
+	inflight map[string]*call[V]
+	mu       sync.Mutex // Mutex to protect the entries and in-flight loads
+}
+
+// NewTTL creates a cache whose entries live for ttl
+func NewTTL[V any](ttl time.Duration) *TTL[V] {
+	return &TTL[V]{
+		ttl:      ttl,
+		entries:  make(map[string]entry[V]),
+		inflight: make(map[string]*call[V]),
+	}
+}
+
+// Get returns the value for key if it exists and hasn't expired
+func (c *TTL[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores a value for key, replacing any existing one
+func (c *TTL[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries as we go so the cache doesn't grow without bound
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Delete removes the value for key
+func (c *TTL[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Take removes the value for key and returns it if it hadn't expired. Of concurrent callers,
+// only one gets the value, so it can be used to act on an entry exactly once.
+func (c *TTL[V]) Take(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	delete(c.entries, key)
+	if !ok || time.Now().After(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// GetOrLoad returns the cached value for key, or calls load to produce it. Concurrent
+// callers for the same key share a single load. Errors are returned but not cached.
+func (c *TTL[V]) GetOrLoad(key string, load func() (V, error)) (V, bool, error) {
+	if value, ok := c.Get(key); ok {
+		return value, true, nil
+	}
+
+	c.mu.Lock()
+	if inflight, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-inflight.done
+		return inflight.value, true, inflight.err
+	}
+	current := &call[V]{done: make(chan struct{})}
+	c.inflight[key] = current
+	c.mu.Unlock()
+
+	current.value, current.err = load()
+	if current.err == nil {
+		c.Set(key, current.value)
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(current.done)
+
+	return current.value, false, current.err
+}
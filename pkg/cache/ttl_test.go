@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	c := NewTTL[int](time.Minute)
+	c.Set("preview", 42)
+
+	// Of many callers taking the same entry at once, only one gets it
+	var wg sync.WaitGroup
+	var taken atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, ok := c.Take("preview"); ok && value == 42 {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := taken.Load(); got != 1 {
+		t.Errorf("the entry was taken %d times, want once", got)
+	}
+	if _, ok := c.Get("preview"); ok {
+		t.Errorf("Get() found the taken entry")
+	}
+}
+
+func TestTakeExpired(t *testing.T) {
+	c := NewTTL[int](time.Millisecond)
+	c.Set("preview", 42)
+	time.Sleep(5 * time.Millisecond)
+
+	if value, ok := c.Take("preview"); ok {
+		t.Errorf("Take() = %d, true for an expired entry, want false", value)
+	}
+	if _, ok := c.Take("missing"); ok {
+		t.Errorf("Take() of a missing entry = _, true, want false")
+	}
+}
@@ -0,0 +1,128 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// allDayAlarmHour is the hour of the day all-day events are reminded of, like the bot's own reminders
+const allDayAlarmHour = 9
+
+// addAlarms adds a VALARM for each reminder of an event, or for the default reminder if the
+// event has none of its own
+func addAlarms(e *ics.VEvent, event *openai.Event, opts ICSOptions) {
+	reminders := event.Reminders
+	if len(reminders) == 0 && opts.ReminderMinutes > 0 {
+		reminders = []int{opts.ReminderMinutes}
+	}
+
+	for _, minutes := range reminders {
+		if minutes < 0 {
+			continue
+		}
+		before := time.Duration(minutes) * time.Minute
+		trigger := -before
+		if IsAllDay(event) {
+			// All-day events start at midnight, so remind in the morning of the day itself or
+			// of the days before
+			days := minutes / (24 * 60)
+			trigger = -time.Duration(days)*24*time.Hour + allDayAlarmHour*time.Hour
+		}
+
+		alarm := e.AddAlarm()
+		alarm.SetAction(ics.ActionDisplay)
+		alarm.SetTrigger(icsDuration(trigger))
+		alarm.SetProperty(ics.ComponentPropertyDescription, event.Title)
+	}
+}
+
+// icsDuration formats a duration as an ICS DURATION value, e.g. -PT30M or -P1DT2H
+func icsDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	value := sign + "P"
+	if days > 0 {
+		value += fmt.Sprintf("%dD", days)
+	}
+	if hours > 0 || minutes > 0 || days == 0 {
+		value += "T"
+		if hours > 0 {
+			value += fmt.Sprintf("%dH", hours)
+		}
+		if minutes > 0 || hours == 0 {
+			value += fmt.Sprintf("%dM", minutes)
+		}
+	}
+	return value
+}
+
+// durationPattern matches an ICS DURATION value, e.g. -PT30M, P1DT2H or -P1W
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration parses an ICS DURATION value
+func parseICSDuration(value string) (time.Duration, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || strings.HasSuffix(value, "P") || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// importReminders returns the minutes before the start of the alarms of an event in a
+// calendar file. Alarms at a fixed time or relative to the end can't be expressed as
+// reminders and are left out.
+func importReminders(e *ics.VEvent) []int {
+	var minutes []int
+	for _, alarm := range e.Alarms() {
+		trigger := alarm.GetProperty(ics.ComponentPropertyTrigger)
+		if trigger == nil {
+			continue
+		}
+		if related := trigger.ICalParameters[string(ics.ParameterRelated)]; len(related) > 0 && strings.EqualFold(related[0], "END") {
+			continue
+		}
+		if value := trigger.ICalParameters[string(ics.ParameterValue)]; len(value) > 0 && strings.EqualFold(value[0], "DATE-TIME") {
+			continue
+		}
+
+		d, err := parseICSDuration(trigger.Value)
+		if err != nil || d > 0 {
+			continue
+		}
+		minutes = append(minutes, int(-d/time.Minute))
+	}
+	return openai.ValidReminders(minutes)
+}
@@ -0,0 +1,56 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestICSDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{"zero", 0, "PT0M"},
+		{"minutes before", -30 * time.Minute, "-PT30M"},
+		{"hours and minutes", 90 * time.Minute, "PT1H30M"},
+		{"whole day", 24 * time.Hour, "P1D"},
+		{"days and hours before", -26 * time.Hour, "-P1DT2H"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := icsDuration(tt.d); got != tt.want {
+				t.Errorf("icsDuration(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "-PT30M", want: -30 * time.Minute},
+		{value: "PT1H30M", want: 90 * time.Minute},
+		{value: "-P1DT2H", want: -26 * time.Hour},
+		{value: "P1W", want: 7 * 24 * time.Hour},
+		{value: " pt15s ", want: 15 * time.Second},
+		{value: "P", wantErr: true},
+		{value: "PT", wantErr: true},
+		{value: "30M", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseICSDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseICSDuration(%q) error = %v, want error %t", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseICSDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
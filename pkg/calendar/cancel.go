@@ -0,0 +1,30 @@
+package calendar
+
+import (
+	"time"
+
+	"calendar-assistant/pkg/openai"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// GenerateCancellation generates an ICS file that removes events sent before from the calendar
+// they were added to. Each keeps its UID and gets a later SEQUENCE and no reminders, so
+// calendar apps cancel the events instead of adding others.
+func GenerateCancellation(events []*openai.Event, loc *time.Location, opts ICSOptions) ([]byte, error) {
+	cancelled := make([]*openai.Event, 0, len(events))
+	for _, event := range events {
+		c := *event
+		if c.UID == "" {
+			c.UID = EventUID(opts.UserID, event)
+		}
+		c.Status = openai.StatusCancelled
+		c.Sequence++
+		c.Reminders = nil
+		cancelled = append(cancelled, &c)
+	}
+
+	opts.Method = string(ics.MethodCancel)
+	opts.ReminderMinutes = 0
+	return GenerateICS(cancelled, loc, opts)
+}
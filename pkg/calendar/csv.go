@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// googleCSVHeader is the header of Google Calendar's CSV import
+var googleCSVHeader = []string{"Subject", "Start Date", "Start Time", "End Date", "End Time", "All Day Event", "Description", "Location"}
+
+// csvHeader is the header of the generic CSV export, for spreadsheets and other tools
+var csvHeader = []string{"Title", "Start", "End", "All Day", "Timezone", "Location", "Description", "URL", "Categories", "Status", "Recurrence", "UID"}
+
+// GenerateGoogleCSV generates a CSV file in the format of Google Calendar's import. It has no
+// timezone, so the times are taken in the timezone of the calendar imported into, and no
+// recurrence, so recurring events only have their first occurrence.
+func GenerateGoogleCSV(events []*openai.Event) ([]byte, error) {
+	rows := [][]string{googleCSVHeader}
+	for _, event := range events {
+		if IsAllDay(event) {
+			rows = append(rows, []string{
+				event.Title,
+				event.StartTime.Format("01/02/2006"), "",
+				LastDay(event).Format("01/02/2006"), "",
+				"True", eventDetails(event), event.Location,
+			})
+			continue
+		}
+		rows = append(rows, []string{
+			event.Title,
+			event.StartTime.Format("01/02/2006"), event.StartTime.Format("03:04 PM"),
+			event.EndTime.Format("01/02/2006"), event.EndTime.Format("03:04 PM"),
+			"False", eventDetails(event), event.Location,
+		})
+	}
+	return writeCSV(rows)
+}
+
+// GenerateCSV generates a CSV file with a row for each event, its times being wall-clock times
+// in loc. All-day events have their first and last day as dates, and recurring events their
+// RRULE.
+func GenerateCSV(events []*openai.Event, loc *time.Location, userID string) ([]byte, error) {
+	rows := [][]string{csvHeader}
+	for _, event := range events {
+		start, end := event.StartTime.Format("2006-01-02 15:04"), event.EndTime.Format("2006-01-02 15:04")
+		if IsAllDay(event) {
+			start, end = event.StartTime.Format("2006-01-02"), LastDay(event).Format("2006-01-02")
+		}
+		uid := event.UID
+		if uid == "" {
+			uid = EventUID(userID, event)
+		}
+		rows = append(rows, []string{
+			event.Title, start, end, strconv.FormatBool(IsAllDay(event)), loc.String(),
+			event.Location, event.Description, event.URL, strings.Join(event.Categories, ", "),
+			event.Status, RRule(event, loc), uid,
+		})
+	}
+	return writeCSV(rows)
+}
+
+// formulaPrefixes start the cells spreadsheet apps evaluate as formulas
+const formulaPrefixes = "=+-@\t\r"
+
+// neutralizeFormula keeps a spreadsheet app from evaluating a cell as a formula, which an
+// event's title or description could use to run commands or leak data when the file is opened
+func neutralizeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// writeCSV writes rows as CSV with the CRLF line breaks RFC 4180 and spreadsheet apps expect
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = neutralizeFormula(cell)
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
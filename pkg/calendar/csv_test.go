@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// readCSV parses a generated CSV file, failing the test if it isn't valid
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		t.Errorf("CSV doesn't end with CRLF: %q", data)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	return rows
+}
+
+func TestGenerateCSV(t *testing.T) {
+	start := time.Date(2025, time.May, 20, 18, 30, 0, 0, time.UTC)
+	day := time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event *openai.Event
+		want  map[string]string // Column of csvHeader -> value
+	}{
+		{
+			"timed",
+			&openai.Event{Title: "Dinner", Location: "Marktplatz 1, Bonn", StartTime: start, EndTime: start.Add(2 * time.Hour), UID: "dinner@test"},
+			map[string]string{"Title": "Dinner", "Start": "2025-05-20 18:30", "End": "2025-05-20 20:30", "All Day": "false", "Location": "Marktplatz 1, Bonn", "UID": "dinner@test"},
+		},
+		{
+			"all day",
+			&openai.Event{Title: "Holiday", AllDay: true, StartTime: day, EndTime: day},
+			map[string]string{"Start": "2025-05-20", "End": "2025-05-20", "All Day": "true"},
+		},
+		{
+			"several days",
+			&openai.Event{Title: "Conference", AllDay: true, StartTime: day, EndTime: day.AddDate(0, 0, 2)},
+			map[string]string{"Start": "2025-05-20", "End": "2025-05-22", "All Day": "true"},
+		},
+		{
+			"recurring",
+			&openai.Event{Title: "Standup", StartTime: start, EndTime: start.Add(15 * time.Minute),
+				Recurrence: &openai.Recurrence{Frequency: openai.FrequencyWeekly, ByDay: []string{"MO", "WE"}, Count: 10}},
+			map[string]string{"Recurrence": "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"},
+		},
+		{
+			"quoting",
+			&openai.Event{Title: `Review "Q2", part 1`, Description: "First line\nsecond line", StartTime: start, EndTime: start,
+				Categories: []string{"work", "meeting"}},
+			map[string]string{"Title": `Review "Q2", part 1`, "Description": "First line\nsecond line", "Categories": "work, meeting"},
+		},
+		{
+			"formulas",
+			&openai.Event{Title: "=HYPERLINK(\"http://evil.example\")", Location: "+1 555 0100", Description: "-2+3",
+				URL: "@SUM(A1)", StartTime: start, EndTime: start},
+			map[string]string{"Title": "'=HYPERLINK(\"http://evil.example\")", "Location": "'+1 555 0100", "Description": "'-2+3", "URL": "'@SUM(A1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateCSV([]*openai.Event{tt.event}, time.UTC, "1")
+			if err != nil {
+				t.Fatalf("GenerateCSV() error = %v", err)
+			}
+			rows := readCSV(t, data)
+			if len(rows) != 2 {
+				t.Fatalf("GenerateCSV() has %d rows, want a header and one event", len(rows))
+			}
+			for i, column := range csvHeader {
+				want, ok := tt.want[column]
+				if ok && rows[1][i] != want {
+					t.Errorf("%s = %q, want %q", column, rows[1][i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateGoogleCSV(t *testing.T) {
+	start := time.Date(2025, time.May, 20, 18, 30, 0, 0, time.UTC)
+	day := time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event *openai.Event
+		want  []string
+	}{
+		{
+			"timed",
+			&openai.Event{Title: "Dinner", Location: "Bonn", StartTime: start, EndTime: start.Add(2 * time.Hour)},
+			[]string{"Dinner", "05/20/2025", "06:30 PM", "05/20/2025", "08:30 PM", "False", "", "Bonn"},
+		},
+		{
+			"several days",
+			&openai.Event{Title: "Conference", AllDay: true, StartTime: day, EndTime: day.AddDate(0, 0, 2)},
+			[]string{"Conference", "05/20/2025", "", "05/22/2025", "", "True", "", ""},
+		},
+		{
+			"link in the description",
+			&openai.Event{Title: "Webinar", Description: "Bring questions", URL: "https://example.com/join", StartTime: start, EndTime: start},
+			[]string{"Webinar", "05/20/2025", "06:30 PM", "05/20/2025", "06:30 PM", "False", "https://example.com/join\n\nBring questions", ""},
+		},
+		{
+			"formulas",
+			&openai.Event{Title: "@cmd", Description: "=1+1", Location: "-Bonn", StartTime: start, EndTime: start},
+			[]string{"'@cmd", "05/20/2025", "06:30 PM", "05/20/2025", "06:30 PM", "False", "'=1+1", "'-Bonn"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateGoogleCSV([]*openai.Event{tt.event})
+			if err != nil {
+				t.Fatalf("GenerateGoogleCSV() error = %v", err)
+			}
+			rows := readCSV(t, data)
+			if len(rows) != 2 || strings.Join(rows[0], ",") != strings.Join(googleCSVHeader, ",") {
+				t.Fatalf("GenerateGoogleCSV() = %q, want the header and one event", rows)
+			}
+			if got := strings.Join(rows[1], "|"); got != strings.Join(tt.want, "|") {
+				t.Errorf("GenerateGoogleCSV() row = %q, want %q", rows[1], tt.want)
+			}
+		})
+	}
+}
+
+func TestNeutralizeFormula(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"", ""},
+		{"Dinner", "Dinner"},
+		{"=1+1", "'=1+1"},
+		{"+49 228 123", "'+49 228 123"},
+		{"-", "'-"},
+		{"@user", "'@user"},
+		{"\tindented", "'\tindented"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := neutralizeFormula(tt.cell); got != tt.want {
+			t.Errorf("neutralizeFormula(%q) = %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package calendar
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,88 +12,143 @@ import (
 	ics "github.com/arran4/golang-ical"
 )
 
-// GenerateICS generates an ICS file from an event
-func GenerateICS(event *openai.Event, timezone string) ([]byte, error) {
-	cal := ics.NewCalendar()
-	cal.SetMethod(ics.MethodRequest)
-	cal.SetProductId("-//Calendar Assistant//EN")
-
-	// Validate the timezone
-	loc, err := time.LoadLocation(timezone)
-	if err != nil {
-		// Fall back to UTC if the timezone is invalid
-		fmt.Printf("Invalid timezone %s, falling back to UTC\n", timezone)
-		timezone = "UTC"
-		loc = time.UTC
-	}
-
-	fmt.Printf("Generating ICS with timezone: %s\n", timezone)
-	fmt.Printf("Original event start time (UTC): %s\n", event.StartTime.Format(time.RFC3339))
-	fmt.Printf("Original event end time (UTC): %s\n", event.EndTime.Format(time.RFC3339))
-
-	// Calculate the timezone offset
-	_, offset := time.Now().In(loc).Zone()
-	offsetHours := offset / 3600 // Convert seconds to hours
-
-	fmt.Printf("Timezone offset: %d hours\n", offsetHours)
+// ICSOptions customizes generated ICS files
+type ICSOptions struct {
+	UserID          string // The user the file is made for, which the UIDs of its events depend on
+	ReminderMinutes int    // Reminder before events that don't ask for their own, 0 for none
 
-	// Adjust the times to compensate for the timezone offset
-	// If GPT returns 16:00 GMT+0 and user is in GMT+3, we need to set 13:00 GMT+0
-	// so that when the calendar app applies GMT+3, it will show as 16:00 GMT+3
-	adjustedStartTime := event.StartTime.Add(time.Duration(-offsetHours) * time.Hour)
-	adjustedEndTime := event.EndTime.Add(time.Duration(-offsetHours) * time.Hour)
+	Method       string // METHOD of the file, REQUEST if empty
+	ProductID    string // PRODID of the file, the default one if empty
+	CalendarName string // X-WR-CALNAME shown when the file is imported, none if empty
+}
 
-	fmt.Printf("Adjusted start time (UTC): %s\n", adjustedStartTime.Format(time.RFC3339))
-	fmt.Printf("Adjusted end time (UTC): %s\n", adjustedEndTime.Format(time.RFC3339))
+// defaultProductID is the PRODID of files that don't set their own
+const defaultProductID = "-//Calendar Assistant//EN"
 
-	// Create the event
-	e := cal.AddEvent(fmt.Sprintf("%d", time.Now().Unix()))
-	e.SetCreatedTime(time.Now())
-	e.SetDtStampTime(time.Now())
-	e.SetModifiedAt(time.Now())
+// GenerateICS generates a single ICS file with a VEVENT for each event, in the user's timezone,
+// so a set of events such as a conference schedule is imported in one go
+func GenerateICS(events []*openai.Event, loc *time.Location, opts ICSOptions) ([]byte, error) {
+	if len(events) == 0 {
+		return nil, ErrNoEvents
+	}
 
-	// Use the adjusted times for the ICS file
-	e.SetStartAt(adjustedStartTime)
-	e.SetEndAt(adjustedEndTime)
-	e.SetSummary(event.Title)
-	e.SetDescription(event.Description)
-	e.SetLocation(event.Location)
+	cal := ics.NewCalendar()
+	method, productID := ics.MethodRequest, defaultProductID
+	if opts.Method != "" {
+		method = ics.Method(opts.Method)
+	}
+	if opts.ProductID != "" {
+		productID = opts.ProductID
+	}
+	cal.SetMethod(method)
+	cal.SetProductId(productID)
+	if opts.CalendarName != "" {
+		cal.SetXWRCalName(opts.CalendarName)
+	}
 
-	// Add a custom property to indicate the user's display timezone
-	e.AddProperty("X-DISPLAY-TIMEZONE", timezone)
+	// Times are given in the user's zone, which the calendar describes
+	first, last := events[0].StartTime, recurrenceEnd(events[0])
+	for _, event := range events[1:] {
+		if event.StartTime.Before(first) {
+			first = event.StartTime
+		}
+		if end := recurrenceEnd(event); end.After(last) {
+			last = end
+		}
+	}
+	addTimezone(cal, loc, first, last)
+
+	// Events of the same file need distinct UIDs, even if they look the same
+	seen := make(map[string]int)
+	for _, event := range events {
+		uid := event.UID
+		if uid == "" {
+			uid = EventUID(opts.UserID, event)
+		}
+		n := seen[uid]
+		seen[uid]++
+		if n > 0 {
+			uid = fmt.Sprintf("%d-%s", n, uid)
+		}
+		addEvent(cal, uid, event, loc, opts)
+	}
 
-	// Serialize to buffer
+	// Serialize to buffer, with the CRLF line breaks RFC 5545 requires
 	var buf bytes.Buffer
-	if err := cal.SerializeTo(&buf); err != nil {
+	if err := cal.SerializeTo(&buf, ics.WithNewLine("\r\n")); err != nil {
 		return nil, fmt.Errorf("failed to serialize ICS: %w", err)
 	}
 
 	// Get the ICS content as string
 	icsContent := buf.String()
 
-	// For all-day events (events with time at 00:00:00), modify the format to be DATE instead of DATE-TIME
-	if event.StartTime.Hour() == 0 && event.StartTime.Minute() == 0 && event.StartTime.Second() == 0 {
-		fmt.Println("Detected all-day event, converting to DATE format")
+	fmt.Println("Final ICS content:")
+	fmt.Println(icsContent)
+
+	// A broken file is better not sent at all, as calendar apps reject it or import it wrong
+	if err := Validate(buf.Bytes()); err != nil {
+		fmt.Printf("Generated ICS is invalid: %v\n", err)
+		return nil, fmt.Errorf("generated ICS is invalid: %w", err)
+	}
 
-		// Replace DTSTART with DATE format
-		startBefore := fmt.Sprintf("DTSTART:%s", adjustedStartTime.Format("20060102T150405Z"))
-		startAfter := fmt.Sprintf("DTSTART;VALUE=DATE:%s", adjustedStartTime.Format("20060102"))
-		icsContent = strings.Replace(icsContent, startBefore, startAfter, -1)
+	return []byte(icsContent), nil
+}
 
-		fmt.Printf("Replaced '%s' with '%s'\n", startBefore, startAfter)
+// addEvent adds an event to a calendar
+func addEvent(cal *ics.Calendar, uid string, event *openai.Event, loc *time.Location, opts ICSOptions) {
+	timezone := loc.String()
+	fmt.Printf("Generating ICS with timezone: %s\n", timezone)
 
-		// If end time is also at midnight, replace it too
-		if event.EndTime.Hour() == 0 && event.EndTime.Minute() == 0 && event.EndTime.Second() == 0 {
-			endBefore := fmt.Sprintf("DTEND:%s", adjustedEndTime.Format("20060102T150405Z"))
-			endAfter := fmt.Sprintf("DTEND;VALUE=DATE:%s", adjustedEndTime.Format("20060102"))
-			icsContent = strings.Replace(icsContent, endBefore, endAfter, -1)
+	// Create the event
+	e := cal.AddEvent(uid)
+	e.SetCreatedTime(time.Now())
+	e.SetDtStampTime(time.Now())
+	e.SetModifiedAt(time.Now())
+	if event.Sequence > 0 {
+		// Calendar apps only apply an update of a UID they know with a higher SEQUENCE
+		e.SetSequence(event.Sequence)
+	}
 
-			fmt.Printf("Replaced '%s' with '%s'\n", endBefore, endAfter)
-		}
+	// All-day events use the DATE format instead of DATE-TIME, and timed events are local
+	// times of the user's zone
+	if IsAllDay(event) {
+		e.SetAllDayStartAt(event.StartTime)
+		e.SetAllDayEndAt(allDayEnd(event))
+	} else {
+		setLocalTime(e, ics.ComponentPropertyDtStart, event.StartTime, loc)
+		setLocalTime(e, ics.ComponentPropertyDtEnd, event.EndTime, loc)
 	}
 
-	fmt.Println("Final ICS content:")
-	fmt.Println(icsContent)
+	if rule := RRule(event, loc); rule != "" {
+		e.AddRrule(rule)
+	}
 
-	return []byte(icsContent), nil
+	e.SetSummary(event.Title)
+	e.SetDescription(descriptionWithMaps(event))
+	e.SetLocation(event.Location)
+	if event.Geo != nil {
+		// Fixed decimals, as %v would write small values with an exponent
+		e.SetGeo(strconv.FormatFloat(event.Geo.Lat, 'f', 6, 64), strconv.FormatFloat(event.Geo.Lon, 'f', 6, 64))
+	}
+	if event.URL != "" {
+		e.SetProperty(ics.ComponentPropertyUrl, event.URL)
+	}
+	if event.Status != "" {
+		e.SetProperty(ics.ComponentPropertyStatus, event.Status)
+	}
+	if event.Transparency != "" {
+		e.SetTimeTransparency(ics.TimeTransparency(event.Transparency))
+	}
+	if event.Priority > 0 {
+		e.SetPriority(event.Priority)
+	}
+	// One property per category, as the library would escape the commas of a list
+	for _, category := range event.Categories {
+		e.AddProperty(ics.ComponentPropertyCategories, strings.ToUpper(category))
+	}
+
+	// Add a custom property to indicate the user's display timezone
+	e.AddProperty("X-DISPLAY-TIMEZONE", timezone)
+
+	addAlarms(e, event, opts)
 }
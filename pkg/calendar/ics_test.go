@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+func TestGenerateICSRoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	start := time.Date(2025, time.May, 20, 18, 30, 0, 0, time.UTC)
+	day := time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		loc   *time.Location
+		event *openai.Event
+	}{
+		{"timed", berlin, &openai.Event{Title: "Dinner", Location: "Marktplatz 1, Bonn", StartTime: start, EndTime: start.Add(2 * time.Hour)}},
+		{"timed in UTC", time.UTC, &openai.Event{Title: "Call", StartTime: start, EndTime: start.Add(30 * time.Minute)}},
+		{"all day", berlin, &openai.Event{Title: "Holiday", AllDay: true, StartTime: day, EndTime: day}},
+		{"several days", berlin, &openai.Event{Title: "Conference", AllDay: true, StartTime: day, EndTime: day.AddDate(0, 0, 2)}},
+		{
+			"special characters and a long description",
+			berlin,
+			&openai.Event{
+				Title:       "Review; notes, and \"quotes\"",
+				Description: strings.TrimSpace(strings.Repeat("A long line with commas, semicolons; and backslashes \\ ", 5)),
+				StartTime:   start,
+				EndTime:     start.Add(time.Hour),
+			},
+		},
+		{
+			"recurring",
+			berlin,
+			&openai.Event{Title: "Standup", StartTime: start, EndTime: start.Add(15 * time.Minute),
+				Recurrence: &openai.Recurrence{Frequency: openai.FrequencyWeekly, ByDay: []string{"MO", "WE"}, Count: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateICS([]*openai.Event{tt.event}, tt.loc, ICSOptions{UserID: "1"})
+			if err != nil {
+				t.Fatalf("GenerateICS() error = %v", err)
+			}
+			if err := Validate(data); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+
+			got, err := EventFromICS(data, tt.loc)
+			if err != nil {
+				t.Fatalf("EventFromICS() error = %v", err)
+			}
+			if got.Title != tt.event.Title || got.AllDay != tt.event.AllDay {
+				t.Errorf("EventFromICS() = %q, all day %t, want %q, all day %t", got.Title, got.AllDay, tt.event.Title, tt.event.AllDay)
+			}
+			if !got.StartTime.Equal(tt.event.StartTime) || !got.EndTime.Equal(tt.event.EndTime) {
+				t.Errorf("EventFromICS() from %v to %v, want from %v to %v", got.StartTime, got.EndTime, tt.event.StartTime, tt.event.EndTime)
+			}
+			if got.Description != descriptionWithMaps(tt.event) {
+				t.Errorf("EventFromICS() description = %q, want %q", got.Description, descriptionWithMaps(tt.event))
+			}
+			if RRule(got, tt.loc) != RRule(tt.event, tt.loc) {
+				t.Errorf("EventFromICS() RRULE = %q, want %q", RRule(got, tt.loc), RRule(tt.event, tt.loc))
+			}
+		})
+	}
+}
+
+func TestGenerateICSNoEvents(t *testing.T) {
+	if _, err := GenerateICS(nil, time.UTC, ICSOptions{}); err != ErrNoEvents {
+		t.Errorf("GenerateICS(nil) error = %v, want %v", err, ErrNoEvents)
+	}
+}
@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// ErrNoEvents is returned when a calendar file contains no events
+var ErrNoEvents = errors.New("the calendar file contains no events")
+
+// EventFromICS reads the first event of an existing calendar file, such as an email invitation.
+// Times are converted to loc and returned as wall-clock times in the same form the assistant
+// extracts them, so the event goes through the same pipeline as an extracted one.
+func EventFromICS(data []byte, loc *time.Location) (*openai.Event, error) {
+	events, err := EventsFromICS(data, loc)
+	if err != nil {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// EventsFromICS reads the events of an existing calendar file, like EventFromICS. Changed
+// occurrences of a recurring event (RECURRENCE-ID) are left out, as events can't express them.
+func EventsFromICS(data []byte, loc *time.Location) ([]*openai.Event, error) {
+	cal, err := ics.ParseCalendar(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar file: %w", err)
+	}
+
+	var events []*openai.Event
+	for _, vevent := range cal.Events() {
+		if vevent.GetProperty(ics.ComponentPropertyRecurrenceId) != nil {
+			continue
+		}
+		event, err := importEvent(vevent, loc)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, ErrNoEvents
+	}
+	return events, nil
+}
+
+// importEvent converts an event of a calendar file
+func importEvent(vevent *ics.VEvent, loc *time.Location) (*openai.Event, error) {
+	event := &openai.Event{
+		Title:        propertyValue(vevent, ics.ComponentPropertySummary),
+		Description:  propertyValue(vevent, ics.ComponentPropertyDescription),
+		Location:     propertyValue(vevent, ics.ComponentPropertyLocation),
+		URL:          propertyValue(vevent, ics.ComponentPropertyUrl),
+		Status:       openai.ValidStatus(propertyValue(vevent, ics.ComponentPropertyStatus)),
+		Transparency: openai.ValidTransparency(propertyValue(vevent, ics.ComponentPropertyTransp)),
+		Priority:     openai.ParsePriority(propertyValue(vevent, ics.ComponentPropertyPriority)),
+		Reminders:    importReminders(vevent),
+	}
+
+	if rule := propertyValue(vevent, ics.ComponentPropertyRrule); rule != "" {
+		recurrence, err := recurrenceFromRRule(rule, loc)
+		if err != nil {
+			fmt.Printf("Warning: Importing a single event instead of %q: %v\n", rule, err)
+		}
+		event.Recurrence = recurrence
+	}
+
+	if isAllDayProperty(vevent.GetProperty(ics.ComponentPropertyDtStart)) {
+		start, err := vevent.GetAllDayStartAt()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event start: %w", err)
+		}
+		event.AllDay = true
+		event.StartTime = wallClock(start)
+		event.EndTime = event.StartTime
+		if end, err := vevent.GetAllDayEndAt(); err == nil && end.After(start) {
+			// DTEND of an all-day event is exclusive
+			event.EndTime = wallClock(end).AddDate(0, 0, -1)
+		}
+		return event, nil
+	}
+
+	start, err := vevent.GetStartAt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event start: %w", err)
+	}
+	end, err := vevent.GetEndAt()
+	if err != nil || !end.After(start) {
+		end = start.Add(time.Hour)
+	}
+	event.StartTime = wallClock(start.In(loc))
+	event.EndTime = wallClock(end.In(loc))
+
+	fmt.Printf("Imported event from calendar file: %+v\n", event)
+	return event, nil
+}
+
+// propertyValue returns the value of a property, or "" if the event doesn't have it
+func propertyValue(event *ics.VEvent, property ics.ComponentProperty) string {
+	if prop := event.GetProperty(property); prop != nil {
+		return strings.TrimSpace(prop.Value)
+	}
+	return ""
+}
+
+// isAllDayProperty reports whether a date-time property holds a date without a time
+func isAllDayProperty(prop *ics.IANAProperty) bool {
+	if prop == nil {
+		return false
+	}
+	if values, ok := prop.ICalParameters[string(ics.ParameterValue)]; ok && len(values) > 0 && strings.EqualFold(values[0], "DATE") {
+		return true
+	}
+	return len(prop.Value) == len("20060102")
+}
+
+// wallClock keeps the date and time of t but drops its timezone, matching the assistant's output
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// maxLinkDetailsLength limits the description put into add-to-calendar links, which must stay
+// short enough for a Telegram button
+const maxLinkDetailsLength = 500
+
+// GoogleCalendarURL returns a link that opens Google Calendar with the event filled in. The
+// event's times are wall-clock times in loc, like in the ICS file.
+func GoogleCalendarURL(event *openai.Event, loc *time.Location) string {
+	var dates string
+	if IsAllDay(event) {
+		dates = event.StartTime.Format("20060102") + "/" + allDayEnd(event).Format("20060102")
+	} else {
+		dates = event.StartTime.Format("20060102T150405") + "/" + event.EndTime.Format("20060102T150405")
+	}
+
+	query := url.Values{}
+	query.Set("action", "TEMPLATE")
+	query.Set("text", event.Title)
+	query.Set("dates", dates)
+	query.Set("ctz", loc.String())
+	if rule := RRule(event, loc); rule != "" {
+		query.Set("recur", "RRULE:"+rule)
+	}
+	if event.Location != "" {
+		query.Set("location", event.Location)
+	}
+	if details := linkDetails(eventDetails(event)); details != "" {
+		query.Set("details", details)
+	}
+	return "https://calendar.google.com/calendar/render?" + query.Encode()
+}
+
+// Outlook hosts: Outlook.com for personal accounts, Office 365 for work and school accounts
+const (
+	OutlookPersonal = "outlook.live.com"
+	OutlookOffice   = "outlook.office.com"
+)
+
+// OutlookCalendarURL returns a link that opens Outlook on the web at host with the event filled
+// in. The event's times are wall-clock times in loc, like in the ICS file.
+func OutlookCalendarURL(event *openai.Event, loc *time.Location, host string) string {
+	query := url.Values{}
+	query.Set("path", "/calendar/action/compose")
+	query.Set("rru", "addevent")
+	query.Set("subject", event.Title)
+	if IsAllDay(event) {
+		query.Set("allday", "true")
+		query.Set("startdt", event.StartTime.Format("2006-01-02"))
+		query.Set("enddt", allDayEnd(event).Format("2006-01-02"))
+	} else {
+		query.Set("startdt", inLocation(event.StartTime, loc).Format(time.RFC3339))
+		query.Set("enddt", inLocation(event.EndTime, loc).Format(time.RFC3339))
+	}
+	if event.Location != "" {
+		query.Set("location", event.Location)
+	}
+	if details := linkDetails(eventDetails(event)); details != "" {
+		query.Set("body", details)
+	}
+	return "https://" + host + "/calendar/0/deeplink/compose?" + query.Encode()
+}
+
+// inLocation returns the time with the same wall clock as t in loc
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// IsAllDay reports whether an event takes whole days
+func IsAllDay(event *openai.Event) bool {
+	return event.AllDay
+}
+
+// LastDay returns the last day of an all-day event: the day of its end, and at least the day
+// it starts
+func LastDay(event *openai.Event) time.Time {
+	start := event.StartTime
+	last := time.Date(event.EndTime.Year(), event.EndTime.Month(), event.EndTime.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(start) {
+		last = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return last
+}
+
+// IsMultiDay reports whether an all-day event takes more than one day
+func IsMultiDay(event *openai.Event) bool {
+	return IsAllDay(event) && LastDay(event).After(event.StartTime)
+}
+
+// allDayEnd returns the exclusive end date of an all-day event, the day after its last day
+func allDayEnd(event *openai.Event) time.Time {
+	return LastDay(event).AddDate(0, 0, 1)
+}
+
+// EventEnd returns when an event is over: the end of its last day for all-day events
+func EventEnd(event *openai.Event) time.Time {
+	if IsAllDay(event) {
+		return allDayEnd(event)
+	}
+	return event.EndTime
+}
+
+// eventDetails returns the description of an event for an add-to-calendar link, which has no
+// field for the event's link, followed by the link
+func eventDetails(event *openai.Event) string {
+	if event.URL == "" || strings.Contains(event.Description, event.URL) {
+		return event.Description
+	}
+	if event.Description == "" {
+		return event.URL
+	}
+	return event.URL + "\n\n" + event.Description
+}
+
+// linkDetails shortens a description for an add-to-calendar link
+func linkDetails(description string) string {
+	if utf8.RuneCountInString(description) <= maxLinkDetailsLength {
+		return description
+	}
+	return string([]rune(description)[:maxLinkDetailsLength-1]) + "…"
+}
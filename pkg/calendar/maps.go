@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// HasPlace reports whether an event takes place somewhere that can be found on a map,
+// rather than having no location or the meeting link of an online event
+func HasPlace(event *openai.Event) bool {
+	location := strings.TrimSpace(event.Location)
+	if location == "" {
+		return false
+	}
+	u, err := url.Parse(location)
+	return err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == ""
+}
+
+// GoogleMapsURL returns a link to the location of an event on Google Maps, at its coordinates
+// if it was geocoded, or an empty string if the event has no place
+func GoogleMapsURL(event *openai.Event) string {
+	if !HasPlace(event) {
+		return ""
+	}
+	query := event.Location
+	if event.Geo != nil {
+		query = geoQuery(event.Geo)
+	}
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(query)
+}
+
+// AppleMapsURL returns a link to the location of an event on Apple Maps, at its coordinates
+// if it was geocoded, or an empty string if the event has no place
+func AppleMapsURL(event *openai.Event) string {
+	if !HasPlace(event) {
+		return ""
+	}
+	params := url.Values{}
+	params.Set("q", event.Location)
+	if event.Geo != nil {
+		params.Set("ll", geoQuery(event.Geo))
+	}
+	return "https://maps.apple.com/?" + params.Encode()
+}
+
+// geoQuery formats coordinates as the latitude,longitude a map search understands
+func geoQuery(geo *openai.Geo) string {
+	return fmt.Sprintf("%.6f,%.6f", geo.Lat, geo.Lon)
+}
+
+// descriptionWithMaps returns the description of an event followed by links to its location,
+// for calendar apps that don't show a map of the location or GEO
+func descriptionWithMaps(event *openai.Event) string {
+	if !HasPlace(event) {
+		return event.Description
+	}
+	links := "Google Maps: " + GoogleMapsURL(event) + "\nApple Maps: " + AppleMapsURL(event)
+	if event.Description == "" {
+		return links
+	}
+	return event.Description + "\n\n" + links
+}
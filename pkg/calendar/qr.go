@@ -0,0 +1,62 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrCodeSize is the width and height of QR code images in pixels
+const qrCodeSize = 512
+
+// maxQRDescriptionLength limits the description in QR codes, which get hard to scan when dense
+const maxQRDescriptionLength = 200
+
+// QRCode returns a PNG image of a QR code with the event as a VEVENT, which phone cameras offer
+// to add to the calendar when scanned
+func QRCode(event *openai.Event, loc *time.Location) ([]byte, error) {
+	png, err := qrcode.Encode(veventText(event, loc), qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return png, nil
+}
+
+// veventText formats an event as a compact VEVENT. Timed events are converted to UTC, so the
+// scanner doesn't need to know the timezone.
+func veventText(event *openai.Event, loc *time.Location) string {
+	lines := []string{"BEGIN:VEVENT", "SUMMARY:" + escapeText(event.Title)}
+	if IsAllDay(event) {
+		lines = append(lines,
+			"DTSTART;VALUE=DATE:"+event.StartTime.Format("20060102"),
+			"DTEND;VALUE=DATE:"+allDayEnd(event).Format("20060102"))
+	} else {
+		lines = append(lines,
+			"DTSTART:"+inLocation(event.StartTime, loc).UTC().Format("20060102T150405Z"),
+			"DTEND:"+inLocation(event.EndTime, loc).UTC().Format("20060102T150405Z"))
+	}
+	if event.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeText(event.Location))
+	}
+	if event.Description != "" {
+		description := []rune(event.Description)
+		if len(description) > maxQRDescriptionLength {
+			description = append(description[:maxQRDescriptionLength-1], '…')
+		}
+		lines = append(lines, "DESCRIPTION:"+escapeText(string(description)))
+	}
+	lines = append(lines, "END:VEVENT")
+	return strings.Join(lines, "\r\n")
+}
+
+// textEscaper escapes the characters with a special meaning in iCalendar text values
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeText escapes an iCalendar text value
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,203 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// weekdayNames are the names of the weekdays of an RRULE BYDAY
+var weekdayNames = map[string]string{
+	"MO": "Monday", "TU": "Tuesday", "WE": "Wednesday", "TH": "Thursday",
+	"FR": "Friday", "SA": "Saturday", "SU": "Sunday",
+}
+
+// RRule returns the RRULE value of a recurring event, or "" if it happens once. UNTIL includes
+// the whole last day in loc, and is given in UTC for timed events as RFC 5545 requires.
+func RRule(event *openai.Event, loc *time.Location) string {
+	rec := event.Recurrence
+	if rec == nil {
+		return ""
+	}
+
+	parts := []string{"FREQ=" + rec.Frequency}
+	if rec.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", rec.Interval))
+	}
+	if rec.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", rec.Count))
+	} else if !rec.Until.IsZero() {
+		if IsAllDay(event) {
+			parts = append(parts, "UNTIL="+rec.Until.Format("20060102"))
+		} else {
+			until := time.Date(rec.Until.Year(), rec.Until.Month(), rec.Until.Day(), 23, 59, 59, 0, loc)
+			parts = append(parts, "UNTIL="+until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(rec.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(rec.ByDay, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// recurrenceHorizon is how far ahead the timezone of a recurring event is described when its
+// rule doesn't say when it ends, or ends later than that
+const recurrenceHorizon = 10 // years
+
+// recurrenceEnd returns when the last occurrence of an event ends at the latest, so the
+// calendar's timezone covers every occurrence: the end of its UNTIL day, the end of COUNT
+// periods, or recurrenceHorizon years after it starts
+func recurrenceEnd(event *openai.Event) time.Time {
+	rec := event.Recurrence
+	if rec == nil {
+		return event.EndTime
+	}
+
+	horizon := event.StartTime.AddDate(recurrenceHorizon, 0, 0)
+	end := horizon
+	switch {
+	case rec.Count > 0:
+		// Each period holds at least one occurrence, so COUNT periods hold them all
+		periods := rec.Count * max(rec.Interval, 1)
+		switch rec.Frequency {
+		case openai.FrequencyDaily:
+			end = event.EndTime.AddDate(0, 0, periods)
+		case openai.FrequencyWeekly:
+			end = event.EndTime.AddDate(0, 0, 7*periods)
+		case openai.FrequencyMonthly:
+			end = event.EndTime.AddDate(0, periods, 0)
+		case openai.FrequencyYearly:
+			end = event.EndTime.AddDate(periods, 0, 0)
+		}
+	case !rec.Until.IsZero():
+		end = rec.Until.AddDate(0, 0, 1).Add(event.EndTime.Sub(event.StartTime))
+	}
+
+	if end.After(horizon) {
+		end = horizon
+	}
+	if end.Before(event.EndTime) {
+		return event.EndTime
+	}
+	return end
+}
+
+// DescribeRecurrence returns how an event repeats in words, e.g. "every 2 weeks on Tuesday"
+func DescribeRecurrence(rec *openai.Recurrence) string {
+	if rec == nil {
+		return ""
+	}
+
+	units := map[string]string{
+		openai.FrequencyDaily:   "day",
+		openai.FrequencyWeekly:  "week",
+		openai.FrequencyMonthly: "month",
+		openai.FrequencyYearly:  "year",
+	}
+	text := "every " + units[rec.Frequency]
+	if rec.Interval > 1 {
+		text = fmt.Sprintf("every %d %ss", rec.Interval, units[rec.Frequency])
+	}
+
+	if len(rec.ByDay) > 0 {
+		days := make([]string, len(rec.ByDay))
+		for i, day := range rec.ByDay {
+			days[i] = describeWeekday(day)
+		}
+		text += " on " + strings.Join(days, ", ")
+	}
+
+	if rec.Count > 0 {
+		text += fmt.Sprintf(", %d times", rec.Count)
+	} else if !rec.Until.IsZero() {
+		text += " until " + rec.Until.Format("2 Jan 2006")
+	}
+	return text
+}
+
+// describeWeekday names a weekday of an RRULE BYDAY, e.g. "the first Monday" for 1MO
+func describeWeekday(day string) string {
+	name := weekdayNames[day[len(day)-2:]]
+	ordinals := map[string]string{
+		"1": "the first", "+1": "the first", "2": "the second", "+2": "the second",
+		"3": "the third", "+3": "the third", "4": "the fourth", "+4": "the fourth",
+		"5": "the fifth", "+5": "the fifth", "-1": "the last", "-2": "the second to last",
+	}
+	if week := day[:len(day)-2]; week != "" {
+		if ordinal, ok := ordinals[week]; ok {
+			return ordinal + " " + name
+		}
+		return week + " " + name
+	}
+	return name
+}
+
+// rruleByDayPattern matches a weekday of an RRULE BYDAY, optionally with the week of the month
+var rruleByDayPattern = regexp.MustCompile(`^([+-]?[1-5])?(MO|TU|WE|TH|FR|SA|SU)$`)
+
+// recurrenceFromRRule reads an RRULE of another calendar app. Rules with parts a Recurrence
+// can't express are an error, rather than repeating the event on the wrong days.
+func recurrenceFromRRule(rule string, loc *time.Location) (*openai.Recurrence, error) {
+	rec := &openai.Recurrence{}
+	for _, part := range strings.Split(strings.ToUpper(rule), ";") {
+		name, value, _ := strings.Cut(part, "=")
+		var err error
+		switch name {
+		case "FREQ":
+			rec.Frequency = value
+		case "INTERVAL":
+			rec.Interval, err = strconv.Atoi(value)
+		case "COUNT":
+			rec.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			rec.Until, err = rruleUntil(value, loc)
+		case "BYDAY":
+			rec.ByDay = strings.Split(value, ",")
+			for _, day := range rec.ByDay {
+				if !rruleByDayPattern.MatchString(day) {
+					err = fmt.Errorf("invalid weekday %q", day)
+				}
+			}
+		case "WKST", "":
+			// The start of the week only matters for rules with several weeks and weekdays
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch rec.Frequency {
+	case openai.FrequencyDaily, openai.FrequencyWeekly, openai.FrequencyMonthly, openai.FrequencyYearly:
+	default:
+		return nil, fmt.Errorf("unsupported frequency %q", rec.Frequency)
+	}
+	if rec.Interval <= 1 {
+		rec.Interval = 0
+	}
+	return rec, nil
+}
+
+// rruleUntil returns the last day of an RRULE UNTIL, a date or a time in UTC or local time
+func rruleUntil(value string, loc *time.Location) (time.Time, error) {
+	var t time.Time
+	var err error
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+		t = t.In(loc)
+	case strings.Contains(value, "T"):
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	default:
+		t, err = time.Parse("20060102", value)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid end of recurrence %q", value)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+func TestRRule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	until := time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event *openai.Event
+		want  string
+	}{
+		{"once", &openai.Event{}, ""},
+		{"daily count", &openai.Event{Recurrence: &openai.Recurrence{Frequency: openai.FrequencyDaily, Count: 5}}, "FREQ=DAILY;COUNT=5"},
+		{
+			"weekly interval and days",
+			&openai.Event{Recurrence: &openai.Recurrence{Frequency: openai.FrequencyWeekly, Interval: 2, ByDay: []string{"TU", "TH"}}},
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+		},
+		{
+			"count wins over until",
+			&openai.Event{Recurrence: &openai.Recurrence{Frequency: openai.FrequencyMonthly, Count: 3, Until: until}},
+			"FREQ=MONTHLY;COUNT=3",
+		},
+		{
+			"timed until is the end of the day in UTC",
+			&openai.Event{Recurrence: &openai.Recurrence{Frequency: openai.FrequencyDaily, Until: until}},
+			"FREQ=DAILY;UNTIL=20250331T215959Z",
+		},
+		{
+			"all-day until is a date",
+			&openai.Event{AllDay: true, Recurrence: &openai.Recurrence{Frequency: openai.FrequencyYearly, Until: until}},
+			"FREQ=YEARLY;UNTIL=20250331",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RRule(tt.event, berlin); got != tt.want {
+				t.Errorf("RRule() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceFromRRule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	tests := []struct {
+		rule    string
+		want    *openai.Recurrence
+		wantErr bool
+	}{
+		{
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;WKST=MO",
+			want: &openai.Recurrence{Frequency: openai.FrequencyWeekly, Interval: 2, ByDay: []string{"MO", "WE"}},
+		},
+		{
+			rule: "freq=monthly;byday=-1fr;count=3",
+			want: &openai.Recurrence{Frequency: openai.FrequencyMonthly, Count: 3, ByDay: []string{"-1FR"}},
+		},
+		{
+			rule: "FREQ=DAILY;INTERVAL=1;UNTIL=20250110T225959Z",
+			want: &openai.Recurrence{Frequency: openai.FrequencyDaily, Until: time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			rule: "FREQ=YEARLY;UNTIL=20300101",
+			want: &openai.Recurrence{Frequency: openai.FrequencyYearly, Until: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;BYMONTH=1", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=many", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := recurrenceFromRRule(tt.rule, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("recurrenceFromRRule(%q) error = %v, want error %t", tt.rule, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recurrenceFromRRule(%q) = %+v, want %+v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceEnd(t *testing.T) {
+	start := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	event := func(rec *openai.Recurrence) *openai.Event {
+		return &openai.Event{StartTime: start, EndTime: end, Recurrence: rec}
+	}
+
+	tests := []struct {
+		name  string
+		event *openai.Event
+		want  time.Time
+	}{
+		{"once", event(nil), end},
+		{"daily count", event(&openai.Recurrence{Frequency: openai.FrequencyDaily, Count: 10}), end.AddDate(0, 0, 10)},
+		{"weekly count and interval", event(&openai.Recurrence{Frequency: openai.FrequencyWeekly, Interval: 2, Count: 3}), end.AddDate(0, 0, 42)},
+		{"monthly count", event(&openai.Recurrence{Frequency: openai.FrequencyMonthly, Count: 2}), end.AddDate(0, 2, 0)},
+		{
+			"until",
+			event(&openai.Recurrence{Frequency: openai.FrequencyDaily, Until: time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)}),
+			time.Date(2025, time.July, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			"until before the event",
+			event(&openai.Recurrence{Frequency: openai.FrequencyDaily, Until: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC)}),
+			end,
+		},
+		{"open-ended", event(&openai.Recurrence{Frequency: openai.FrequencyWeekly}), start.AddDate(recurrenceHorizon, 0, 0)},
+		{"count beyond the horizon", event(&openai.Recurrence{Frequency: openai.FrequencyYearly, Count: 50}), start.AddDate(recurrenceHorizon, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recurrenceEnd(tt.event); !got.Equal(tt.want) {
+				t.Errorf("recurrenceEnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeRecurrence(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  *openai.Recurrence
+		want string
+	}{
+		{"once", nil, ""},
+		{"daily", &openai.Recurrence{Frequency: openai.FrequencyDaily}, "every day"},
+		{"interval and days", &openai.Recurrence{Frequency: openai.FrequencyWeekly, Interval: 2, ByDay: []string{"TU", "TH"}}, "every 2 weeks on Tuesday, Thursday"},
+		{"week of the month", &openai.Recurrence{Frequency: openai.FrequencyMonthly, ByDay: []string{"-1FR"}}, "every month on the last Friday"},
+		{"count", &openai.Recurrence{Frequency: openai.FrequencyYearly, Count: 3}, "every year, 3 times"},
+		{"until", &openai.Recurrence{Frequency: openai.FrequencyDaily, Until: time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC)}, "every day until 2 May 2025"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeRecurrence(tt.rec); got != tt.want {
+				t.Errorf("DescribeRecurrence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// icsLocalFormat is the format of a local DATE-TIME value, which a TZID parameter qualifies
+const icsLocalFormat = "20060102T150405"
+
+// isUTC reports whether a location is UTC, whose times need no VTIMEZONE
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// setLocalTime sets a DATE-TIME property of an event to a wall-clock time in loc, which the
+// assistant stores as UTC
+func setLocalTime(e *ics.VEvent, property ics.ComponentProperty, t time.Time, loc *time.Location) {
+	if isUTC(loc) {
+		e.SetProperty(property, t.UTC().Format(icsLocalFormat)+"Z")
+		return
+	}
+	e.SetProperty(property, t.Format(icsLocalFormat), ics.WithTZID(loc.String()))
+}
+
+// addTimezone adds the VTIMEZONE of loc to a calendar, covering the years from start to end,
+// where end includes the occurrences of recurring events (see recurrenceEnd). Go doesn't expose
+// the rules of a zone, so its transitions are found by probing and each is written as an
+// observance of its own, which also gets historical offset changes right.
+func addTimezone(cal *ics.Calendar, loc *time.Location, start, end time.Time) {
+	if isUTC(loc) {
+		return
+	}
+
+	from := time.Date(start.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	to := time.Date(end.Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	tz := cal.AddTimezone(loc.String())
+
+	// The observance in effect at the beginning of the range
+	name, offset := from.In(loc).Zone()
+	addObservance(tz, from.In(loc).IsDST(), from, name, offset, offset)
+
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if !zoneChanged(day, next, loc) {
+			continue
+		}
+		at := transition(day, next, loc)
+		_, before := at.Add(-time.Second).In(loc).Zone()
+		name, after := at.In(loc).Zone()
+		addObservance(tz, at.In(loc).IsDST(), at, name, before, after)
+	}
+}
+
+// zoneChanged reports whether the offset or daylight saving time of loc differs between two instants
+func zoneChanged(a, b time.Time, loc *time.Location) bool {
+	_, offsetA := a.In(loc).Zone()
+	_, offsetB := b.In(loc).Zone()
+	return offsetA != offsetB || a.In(loc).IsDST() != b.In(loc).IsDST()
+}
+
+// transition finds the first second at which the zone of loc changes between a and b
+func transition(a, b time.Time, loc *time.Location) time.Time {
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2).Truncate(time.Second)
+		if zoneChanged(a, mid, loc) {
+			b = mid
+		} else {
+			a = mid
+		}
+	}
+	return b
+}
+
+// addObservance adds a STANDARD or DAYLIGHT observance that starts at the instant at. Its
+// DTSTART is the local time before the change, as RFC 5545 requires.
+func addObservance(tz *ics.VTimezone, daylight bool, at time.Time, name string, offsetFrom, offsetTo int) {
+	var observance *ics.ComponentBase
+	if daylight {
+		d := &ics.Daylight{}
+		tz.Components = append(tz.Components, d)
+		observance = &d.ComponentBase
+	} else {
+		observance = &tz.AddStandard().ComponentBase
+	}
+
+	observance.SetProperty(ics.ComponentPropertyDtStart, at.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(icsLocalFormat))
+	observance.SetProperty(ics.ComponentProperty("TZOFFSETFROM"), utcOffset(offsetFrom))
+	observance.SetProperty(ics.ComponentProperty("TZOFFSETTO"), utcOffset(offsetTo))
+	observance.SetProperty(ics.ComponentProperty("TZNAME"), name)
+}
+
+// utcOffset formats an offset in seconds east of UTC as an ICS UTC-OFFSET, e.g. +0530
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
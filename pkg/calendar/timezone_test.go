@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+func TestUTCOffset(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "+0000"},
+		{3600, "+0100"},
+		{19800, "+0530"},
+		{20700, "+0545"},
+		{-12600, "-0330"},
+		{3630, "+010030"},
+	}
+	for _, tt := range tests {
+		if got := utcOffset(tt.seconds); got != tt.want {
+			t.Errorf("utcOffset(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateICSTimezoneCoversRecurrence(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	start := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+
+	// Daylight saving time starts at 02:00 local time on the last Sunday of March
+	tests := []struct {
+		name    string
+		rec     *openai.Recurrence
+		covered []string
+		missing []string
+	}{
+		{"once", nil, []string{"DTSTART:20250330T020000"}, []string{"DTSTART:20260329T020000"}},
+		{"count", &openai.Recurrence{Frequency: openai.FrequencyWeekly, Count: 60}, []string{"DTSTART:20260329T020000"}, []string{"DTSTART:20270328T020000"}},
+		{"until", &openai.Recurrence{Frequency: openai.FrequencyDaily, Until: time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)}, []string{"DTSTART:20270328T020000"}, nil},
+		{"open-ended", &openai.Recurrence{Frequency: openai.FrequencyWeekly}, []string{"DTSTART:20340326T020000"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &openai.Event{Title: "Standup", StartTime: start, EndTime: start.Add(15 * time.Minute), Recurrence: tt.rec}
+			data, err := GenerateICS([]*openai.Event{event}, berlin, ICSOptions{UserID: "1"})
+			if err != nil {
+				t.Fatalf("GenerateICS() error = %v", err)
+			}
+			for _, line := range tt.covered {
+				if !strings.Contains(string(data), line+"\r\n") {
+					t.Errorf("VTIMEZONE has no observance %s", line)
+				}
+			}
+			for _, line := range tt.missing {
+				if strings.Contains(string(data), line+"\r\n") {
+					t.Errorf("VTIMEZONE has observance %s beyond the last occurrence", line)
+				}
+			}
+		})
+	}
+}
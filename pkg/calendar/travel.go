@@ -0,0 +1,63 @@
+package calendar
+
+import (
+	"math"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// Travel times are estimated from straight-line distances, as roads wind and city traffic is slow
+const (
+	travelDetour     = 1.3  // Road distance per straight-line distance
+	travelSpeed      = 30.0 // Average speed in km/h
+	travelRounding   = 5    // Estimates are rounded up to this many minutes
+	minTravelMinutes = 10   // Getting out of the door takes a while too
+	maxTravelMinutes = 4 * 60
+	nearbyKm         = 0.2 // No travel is needed to a place this close
+	earthRadiusKm    = 6371.0
+)
+
+// EstimateTravelMinutes estimates how long getting from one place to another takes, rounded
+// up to 5 minutes, and 0 if the places are next to each other. It reports false if they're so
+// far apart that a guess would be meaningless.
+func EstimateTravelMinutes(from, to *openai.Geo) (int, bool) {
+	km := distanceKm(from, to)
+	if km < nearbyKm {
+		return 0, true
+	}
+
+	minutes := int(math.Ceil(km*travelDetour/travelSpeed*60/travelRounding)) * travelRounding
+	if minutes > maxTravelMinutes {
+		return 0, false
+	}
+	return max(minutes, minTravelMinutes), true
+}
+
+// distanceKm returns the great-circle distance between two points
+func distanceKm(from, to *openai.Geo) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (to.Lon - from.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// TravelEvent returns an event blocking the time of getting to an event, ending when the
+// event starts and repeating with it
+func TravelEvent(event *openai.Event, title string, minutes int) *openai.Event {
+	travel := &openai.Event{
+		Title:      title,
+		StartTime:  event.StartTime.Add(-time.Duration(minutes) * time.Minute),
+		EndTime:    event.StartTime,
+		Recurrence: event.Recurrence,
+		Status:     event.Status,
+		Sequence:   event.Sequence,
+	}
+	if event.UID != "" {
+		// The travel time moves with its event when a correction updates it
+		travel.UID = "travel-" + event.UID
+	}
+	return travel
+}
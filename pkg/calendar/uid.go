@@ -0,0 +1,23 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// uidDomain is the domain part of the UIDs of generated events, which keeps them apart from
+// the UIDs of other calendar producers
+const uidDomain = "calendar-assistant"
+
+// EventUID returns the UID of an event created for a user. It depends only on the user, the
+// title and the start, so sending the same event again updates it in the calendar instead of
+// adding a duplicate.
+func EventUID(userID string, event *openai.Event) string {
+	title := strings.ToLower(strings.Join(strings.Fields(event.Title), " "))
+	sum := sha256.Sum256([]byte(userID + "\n" + title + "\n" + event.StartTime.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:16]) + "@" + uidDomain
+}
@@ -0,0 +1,36 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+func TestEventUID(t *testing.T) {
+	start := time.Date(2025, time.March, 14, 18, 0, 0, 0, time.UTC)
+	event := &openai.Event{Title: "Team dinner", StartTime: start}
+
+	tests := []struct {
+		name   string
+		userID string
+		event  *openai.Event
+		same   bool
+	}{
+		{"same event", "1", &openai.Event{Title: "Team dinner", StartTime: start}, true},
+		{"case and spacing of the title", "1", &openai.Event{Title: "  team   DINNER ", StartTime: start}, true},
+		{"other details", "1", &openai.Event{Title: "Team dinner", StartTime: start, Location: "Elsewhere"}, true},
+		{"other user", "2", &openai.Event{Title: "Team dinner", StartTime: start}, false},
+		{"other title", "1", &openai.Event{Title: "Team lunch", StartTime: start}, false},
+		{"other start", "1", &openai.Event{Title: "Team dinner", StartTime: start.Add(time.Hour)}, false},
+	}
+	want := EventUID("1", event)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EventUID(tt.userID, tt.event)
+			if (got == want) != tt.same {
+				t.Errorf("EventUID = %q, original %q, want same %t", got, want, tt.same)
+			}
+		})
+	}
+}
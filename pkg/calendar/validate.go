@@ -0,0 +1,186 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxLineOctets is the longest line RFC 5545 allows, without the line break
+const maxLineOctets = 75
+
+// textProperties are the properties whose values must have their special characters escaped
+var textProperties = map[string]bool{"SUMMARY": true, "DESCRIPTION": true, "LOCATION": true}
+
+// contentLine is an unfolded line of an ICS file
+type contentLine struct {
+	number int // Physical line the content line starts on, from 1
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Validate checks a serialized calendar for the RFC 5545 rules calendar apps are strict about:
+// CRLF line endings, lines folded at 75 octets, escaped text values, matching BEGIN and END
+// lines, and events that end after they start. It returns all problems found.
+func Validate(data []byte) error {
+	var problems []error
+	content := string(data)
+	if !strings.HasSuffix(content, "\r\n") {
+		problems = append(problems, errors.New("the file doesn't end with CRLF"))
+	}
+
+	physical := strings.Split(strings.TrimSuffix(content, "\r\n"), "\r\n")
+	var lines []contentLine
+	for i, line := range physical {
+		if strings.Contains(line, "\n") || strings.Contains(line, "\r") {
+			problems = append(problems, fmt.Errorf("line %d doesn't end with CRLF", i+1))
+		}
+		if len(line) > maxLineOctets {
+			problems = append(problems, fmt.Errorf("line %d is %d octets long, more than %d", i+1, len(line), maxLineOctets))
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].value += line[1:] // Folded lines continue the previous one
+			continue
+		}
+		lines = append(lines, contentLine{number: i + 1, value: line})
+	}
+
+	var stack []string
+	var start, end *contentLine
+	for i := range lines {
+		line := &lines[i]
+		if err := line.parse(); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		switch line.name {
+		case "BEGIN":
+			stack = append(stack, line.value)
+			if line.value == "VEVENT" {
+				start, end = nil, nil
+			}
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != line.value {
+				problems = append(problems, fmt.Errorf("line %d ends %s, which isn't open", line.number, line.value))
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if line.value == "VEVENT" && start != nil && end != nil {
+				if err := checkEventSpan(start, end); err != nil {
+					problems = append(problems, err)
+				}
+			}
+		case "DTSTART", "DTEND":
+			if len(stack) > 0 && stack[len(stack)-1] == "VEVENT" {
+				if line.name == "DTSTART" {
+					start = line
+				} else {
+					end = line
+				}
+			}
+		}
+
+		if textProperties[line.name] {
+			if err := checkEscaped(line.value); err != nil {
+				problems = append(problems, fmt.Errorf("line %d: %s %w", line.number, line.name, err))
+			}
+		}
+	}
+	if len(stack) > 0 {
+		problems = append(problems, fmt.Errorf("%s isn't ended", strings.Join(stack, ", ")))
+	}
+
+	return errors.Join(problems...)
+}
+
+// parse splits a content line into its name, parameters and value. The value starts at the
+// first colon outside of a quoted parameter value.
+func (l *contentLine) parse() error {
+	raw := l.value
+	quoted := false
+	colon := -1
+	for i, c := range raw {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return fmt.Errorf("line %d has no property name and value", l.number)
+	}
+
+	parts := strings.Split(raw[:colon], ";")
+	l.name = strings.ToUpper(parts[0])
+	l.params = make(map[string]string)
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		l.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	l.value = raw[colon+1:]
+	return nil
+}
+
+// checkEscaped checks that commas, semicolons, backslashes and line breaks of a text value are escaped
+func checkEscaped(value string) error {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 == len(value) || !strings.ContainsRune(`\;,nN`, rune(value[i+1])) {
+				return errors.New("has a backslash that doesn't escape anything")
+			}
+			i++
+		case ',', ';':
+			return fmt.Errorf("has an unescaped %q", value[i])
+		}
+	}
+	return nil
+}
+
+// checkEventSpan checks that an event's DTEND is of the same type as its DTSTART and after it
+func checkEventSpan(start, end *contentLine) error {
+	startTime, startDate, err := start.time()
+	if err != nil {
+		return err
+	}
+	endTime, endDate, err := end.time()
+	if err != nil {
+		return err
+	}
+	if startDate != endDate {
+		return fmt.Errorf("line %d: DTSTART and DTEND mix dates and times", end.number)
+	}
+	if !endTime.After(startTime) {
+		return fmt.Errorf("line %d: DTEND isn't after DTSTART", end.number)
+	}
+	return nil
+}
+
+// time parses a DATE or DATE-TIME value, reporting whether it's a date
+func (l *contentLine) time() (time.Time, bool, error) {
+	if l.params["VALUE"] == "DATE" {
+		t, err := time.Parse("20060102", l.value)
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("line %d: invalid date %q", l.number, l.value)
+		}
+		return t, true, nil
+	}
+
+	loc := time.UTC
+	if tzid := l.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("line %d: unknown timezone %q", l.number, tzid)
+		}
+	}
+	t, err := time.ParseInLocation(icsLocalFormat, strings.TrimSuffix(l.value, "Z"), loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("line %d: invalid date-time %q", l.number, l.value)
+	}
+	return t, false, nil
+}
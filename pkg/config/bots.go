@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// botNamePattern matches the names of additional bots, which are used in storage keys
+var botNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// BotConfig is an additional bot served by the same process, e.g. a team bot next to a
+// personal one. It shares the OpenAI account and storage with the main bot; settings it leaves
+// empty are the main bot's.
+type BotConfig struct {
+	Name              string  `json:"name"` // Identifies the bot in logs and storage, e.g. "team"
+	TelegramBotToken  string  `json:"telegram_bot_token"`
+	AllowedUserIDs    []int64 `json:"allowed_user_ids,omitempty"`
+	AdminUserIDs      []int64 `json:"admin_user_ids,omitempty"`
+	DefaultTimezone   string  `json:"default_timezone,omitempty"`
+	DefaultLanguage   string  `json:"default_language,omitempty"`
+	FeedbackChatID    int64   `json:"feedback_chat_id,omitempty"`
+	WebhookURL        string  `json:"webhook_url,omitempty"`         // Required in webhook mode
+	WebhookListenAddr string  `json:"webhook_listen_addr,omitempty"` // Required in webhook mode, as each bot has its own server
+}
+
+// botNames returns the names of the main bot and the additional bots
+func botNames(bots []BotConfig) []string {
+	names := []string{""}
+	for _, bot := range bots {
+		names = append(names, bot.Name)
+	}
+	return names
+}
+
+// ForBot returns the configuration of an additional bot: a copy of c with the bot's settings
+func (c *Config) ForBot(bot BotConfig) *Config {
+	cfg := *c
+	cfg.BotName = bot.Name
+	cfg.TelegramBotToken = bot.TelegramBotToken
+	cfg.Bots = nil
+	if bot.AllowedUserIDs != nil {
+		cfg.AllowedUserIDs = bot.AllowedUserIDs
+	}
+	if bot.AdminUserIDs != nil {
+		cfg.AdminUserIDs = bot.AdminUserIDs
+	}
+	if bot.DefaultTimezone != "" {
+		cfg.DefaultTimezone = bot.DefaultTimezone
+	}
+	if bot.DefaultLanguage != "" {
+		cfg.DefaultLanguage = bot.DefaultLanguage
+	}
+	if bot.FeedbackChatID != 0 {
+		cfg.FeedbackChatID = bot.FeedbackChatID
+	}
+	if bot.WebhookURL != "" {
+		cfg.WebhookURL = bot.WebhookURL
+	}
+	if bot.WebhookListenAddr != "" {
+		cfg.WebhookListenAddr = bot.WebhookListenAddr
+	}
+	return &cfg
+}
+
+// loadBots reads the additional bots from the file at path, returning nil if path is empty.
+// In webhook mode every bot needs its own webhook URL and listen address.
+func loadBots(path, updateMode, mainToken, mainListenAddr string) ([]BotConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBots, err)
+	}
+
+	var bots []BotConfig
+	if err := json.Unmarshal(data, &bots); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBots, err)
+	}
+
+	names := make(map[string]bool)
+	tokens := map[string]bool{mainToken: true}
+	listenAddrs := map[string]bool{mainListenAddr: true}
+	for i, bot := range bots {
+		bot.Name = strings.TrimSpace(bot.Name)
+		bot.TelegramBotToken = strings.TrimSpace(bot.TelegramBotToken)
+		switch {
+		case !botNamePattern.MatchString(bot.Name):
+			return nil, fmt.Errorf("%w: bot %d needs a name of 1-32 characters of a-z, 0-9, _ and -", ErrInvalidBots, i+1)
+		case names[bot.Name]:
+			return nil, fmt.Errorf("%w: bot name %q is used twice", ErrInvalidBots, bot.Name)
+		case bot.TelegramBotToken == "":
+			return nil, fmt.Errorf("%w: bot %q has no token", ErrInvalidBots, bot.Name)
+		case tokens[bot.TelegramBotToken]:
+			return nil, fmt.Errorf("%w: bot %q uses the token of another bot", ErrInvalidBots, bot.Name)
+		}
+		if bot.DefaultTimezone != "" {
+			if _, err := time.LoadLocation(bot.DefaultTimezone); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, bot.DefaultTimezone)
+			}
+		}
+		if updateMode == UpdateModeWebhook {
+			if bot.WebhookURL == "" || bot.WebhookListenAddr == "" {
+				return nil, fmt.Errorf("%w: bot %q needs a webhook URL and listen address in webhook mode", ErrInvalidBots, bot.Name)
+			}
+			if listenAddrs[bot.WebhookListenAddr] {
+				return nil, fmt.Errorf("%w: bot %q listens on the address of another bot", ErrInvalidBots, bot.Name)
+			}
+			listenAddrs[bot.WebhookListenAddr] = true
+		}
+		names[bot.Name] = true
+		tokens[bot.TelegramBotToken] = true
+		bots[i] = bot
+	}
+
+	log.Printf("Loaded %d additional bots from %s", len(bots), path)
+	return bots, nil
+}
@@ -1,17 +1,141 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"calendar-assistant/pkg/imaging"
+)
+
+// Update modes
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
 )
 
+// webhookSecretPattern matches the secret tokens Telegram accepts
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
 // Config holds all configuration for the application
 type Config struct {
+	BotName           string // Name of an additional bot, empty for the main bot
 	TelegramBotToken  string
 	OpenAIAPIKey      string
 	OpenAIAssistantID string
+	OpenAIStrongModel string // Model offered for re-extraction when the default gets it wrong
+	TelegramDebug     bool   // Log every Telegram request and response, which includes what users send
+
+	// How updates are received: "polling" or "webhook"
+	UpdateMode        string
+	WebhookURL        string // Public HTTPS URL Telegram sends updates to
+	WebhookListenAddr string // Address the webhook server listens on
+	WebhookSecret     string // Secret token Telegram sends with every update
+	WebhookTLSCert    string // Optional TLS certificate, when not running behind a TLS-terminating proxy
+	WebhookTLSKey     string // Optional TLS key for WebhookTLSCert
+
+	// How long shutdown waits for running handlers to finish
+	ShutdownTimeout time.Duration
+
+	// Additional bots served by the same process, with their own settings
+	Bots []BotConfig
+
+	// Names of all bots served by the process, the main bot's being empty; shared by every bot's
+	// copy of the configuration, unlike Bots
+	BotNames []string
+
+	// Telegram user IDs allowed to use the bot; everyone may use it if empty
+	AllowedUserIDs []int64
+
+	// Telegram user IDs of the operators, who may use the admin commands
+	AdminUserIDs []int64
+
+	// Abuse controls: users who exceed the rate limit too often are blocked temporarily
+	RateLimitPerMinute int           // Events a user may request per minute, 0 to disable rate limiting
+	RateLimitStrikes   int           // How often within an hour a user may hit the rate limit before being blocked
+	RateLimitBlock     time.Duration // How long a user who hit the rate limit too often is blocked
+
+	// Premium tier bought with Telegram Stars: a higher daily quota, priority processing and
+	// voice and PDF input. Disabled if the price is 0, and then everyone gets voice and PDF input.
+	PremiumPriceStars        int           // Price of the premium tier in Telegram Stars, 0 to disable it
+	PremiumDuration          time.Duration // How long a purchase of the premium tier lasts
+	FreeDailyQuota           int           // Events a free user may request per day, 0 for no limit
+	PremiumDailyQuota        int           // Events a premium user may request per day, 0 for no limit
+	MaxConcurrentExtractions int           // Extractions running at the same time, 0 for no limit; premium users go first
+
+	// Messages per second sent when announcing something to many users
+	BroadcastRate int
+
+	// Chat that /feedback is forwarded to, 0 to send it to the admins' private chats
+	FeedbackChatID int64
+
+	// "What's new" summary announced to the users who opted in, nil if there is none
+	ReleaseNotes *ReleaseNotes
+
+	// Defaults for new users
+	DefaultTimezone string // IANA timezone used until a user sets their own, empty to require /timezone
+	DefaultLanguage string // Language code assigned to new users
+
+	// Process events from users without a timezone (with a warning) instead of asking for it first
+	AllowEventsWithoutTimezone bool
+
+	// Storage and maintenance
+	StoragePath         string        // Path of the JSON storage file
+	ImportUsersPath     string        // Optional snapshot or log file to import user preferences from
+	MaintenanceInterval time.Duration // How often the maintenance job runs
+	HistoryRetention    time.Duration // How long raw inputs are kept in the history
+	ThreadMaxAge        time.Duration // How long an OpenAI thread is reused before rotation
+	SchedulerInterval   time.Duration // How often the scheduler checks for due jobs, such as scheduled messages
+	FollowUpDelay       time.Duration // How long after an event ends to ask whether it was accurate, 0 if disabled
+	InactivePurgeAfter  time.Duration // How long after a user blocks the bot their data is deleted, 0 to keep it
+
+	// Downloads of the files users send
+	DownloadTimeout time.Duration // Timeout of a single download attempt
+	DownloadMaxSize int           // Largest file that is downloaded, in bytes
+	DownloadRetries int           // How often a download is retried after a transient failure
+	StreamThreshold int           // Images of at least this many bytes are streamed into the upload unprocessed
+
+	// Image preprocessing before upload to the vision API
+	SupportedImageTypes []string      // MIME types of image files that are accepted
+	ImageMaxDimension   int           // Longest side of uploaded images in pixels
+	ImageJPEGQuality    int           // JPEG quality (1-100) used when re-encoding images
+	ImageCacheTTL       time.Duration // How long extraction results are reused for identical images
+	TextCacheTTL        time.Duration // How long extraction results are reused for identical texts
+
+	// Number of key frames taken from videos for extraction
+	VideoFrameCount int
+
+	// Deployment-specific vocabulary (venues, series titles, acronyms) added to every extraction
+	Glossary string
+
+	// Prompt templates
+	PromptTemplatesDir   string        // Optional directory with text.tmpl/image.tmpl/plan.tmpl overriding the built-in prompts
+	WorkingHours         string        // Working hours available to the prompt templates, e.g. "09:00-18:00"
+	DefaultEventDuration time.Duration // Event duration available to the prompt templates
+
+	// Metadata of the calendar files, for files with a single event and for feeds of several
+	ICSMethod           string // METHOD of single event files, PUBLISH or REQUEST
+	ICSFeedMethod       string // METHOD of files with several events
+	ICSProductID        string // PRODID of all files
+	ICSCalendarName     string // X-WR-CALNAME of single event files, none if empty
+	ICSFeedCalendarName string // X-WR-CALNAME of files with several events, none if empty
+
+	// Geocoding of event locations, disabled if Geocoder is empty
+	Geocoder       string // Provider, nominatim or google
+	GeocoderAPIKey string // API key of the provider, required by google
+	GeocoderURL    string // Optional endpoint overriding the provider's default, e.g. a self-hosted Nominatim
+
+	// Optional Open-Meteo forecast endpoint, e.g. a self-hosted instance; the public API if empty
+	WeatherURL string
+
+	// Passphrase used to encrypt users' own API keys; bring-your-own-key is disabled if empty
+	EncryptionKey string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -35,9 +159,450 @@ func LoadConfig() (*Config, error) {
 	// Assistant ID is optional
 	openAIAssistantID := os.Getenv("OPENAI_ASSISTANT_ID")
 
+	// Stronger model is optional; without it only a plain re-extract is offered
+	openAIStrongModel := os.Getenv("OPENAI_STRONG_MODEL")
+
+	updateMode := os.Getenv("UPDATE_MODE")
+	if updateMode == "" {
+		updateMode = UpdateModePolling
+	}
+	if updateMode != UpdateModePolling && updateMode != UpdateModeWebhook {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateMode, updateMode)
+	}
+
+	// Webhook settings are only required in webhook mode
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	if updateMode == UpdateModeWebhook {
+		if webhookURL == "" {
+			return nil, ErrMissingWebhookURL
+		}
+		if !webhookSecretPattern.MatchString(webhookSecret) {
+			return nil, ErrInvalidWebhookSecret
+		}
+	}
+
+	// Platforms like Cloud Run and Heroku tell the app which port to listen on
+	webhookListenAddr := os.Getenv("WEBHOOK_LISTEN_ADDR")
+	if webhookListenAddr == "" {
+		webhookListenAddr = ":8080"
+		if port := os.Getenv("PORT"); port != "" {
+			webhookListenAddr = ":" + port
+		}
+	}
+
+	shutdownTimeout, err := getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedUserIDs, err := getUserIDsEnv("ALLOWED_USER_IDS")
+	if err != nil {
+		return nil, err
+	}
+
+	adminUserIDs, err := getUserIDsEnv("ADMIN_USER_IDS")
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitPerMinute, err := getIntEnv("RATE_LIMIT_PER_MINUTE", 10, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitStrikes, err := getIntEnv("RATE_LIMIT_STRIKES", 3, 1, 100)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitBlock, err := getDurationEnv("RATE_LIMIT_BLOCK_DURATION", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	premiumPriceStars, err := getIntEnv("PREMIUM_PRICE_STARS", 0, 0, 10000)
+	if err != nil {
+		return nil, err
+	}
+	premiumDuration, err := getDurationEnv("PREMIUM_DURATION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	freeDailyQuota, err := getIntEnv("FREE_DAILY_QUOTA", 0, 0, 100000)
+	if err != nil {
+		return nil, err
+	}
+	premiumDailyQuota, err := getIntEnv("PREMIUM_DAILY_QUOTA", 0, 0, 100000)
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrentExtractions, err := getIntEnv("MAX_CONCURRENT_EXTRACTIONS", 0, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	// Telegram allows about 30 messages per second to different users
+	broadcastRate, err := getIntEnv("BROADCAST_RATE", 20, 1, 30)
+	if err != nil {
+		return nil, err
+	}
+
+	// Feedback chat is optional; group chat IDs are negative
+	var feedbackChatID int64
+	if value := os.Getenv("FEEDBACK_CHAT_ID"); value != "" {
+		feedbackChatID, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || feedbackChatID == 0 {
+			return nil, fmt.Errorf("%w: FEEDBACK_CHAT_ID is %q", ErrInvalidChatID, value)
+		}
+	}
+
+	// Release notes are optional, but the file must be valid if set
+	releaseNotes, err := loadReleaseNotes(os.Getenv("RELEASE_NOTES_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Additional bots are optional, but the file must be valid if set
+	bots, err := loadBots(os.Getenv("BOTS_PATH"), updateMode, telegramBotToken, webhookListenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Default timezone is optional, but must be valid if set
+	defaultTimezone := os.Getenv("DEFAULT_TIMEZONE")
+	if defaultTimezone != "" {
+		if _, err := time.LoadLocation(defaultTimezone); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, defaultTimezone)
+		}
+	}
+
+	defaultLanguage := os.Getenv("DEFAULT_LANGUAGE")
+	if defaultLanguage == "" {
+		defaultLanguage = "en"
+	}
+
+	allowEventsWithoutTimezone, err := getBoolEnv("ALLOW_EVENTS_WITHOUT_TIMEZONE", false)
+	if err != nil {
+		return nil, err
+	}
+
+	telegramDebug, err := getBoolEnv("TELEGRAM_DEBUG", false)
+	if err != nil {
+		return nil, err
+	}
+
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "tmp/calendar-assistant.json"
+	}
+
+	// Import path is optional
+	importUsersPath := os.Getenv("IMPORT_USERS_PATH")
+
+	maintenanceInterval, err := getDurationEnv("MAINTENANCE_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	historyRetention, err := getDurationEnv("HISTORY_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	threadMaxAge, err := getDurationEnv("THREAD_MAX_AGE", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	schedulerInterval, err := getDurationEnv("SCHEDULER_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	followUpDelay, err := getDurationEnv("FOLLOW_UP_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	inactivePurgeAfter, err := getDurationEnv("INACTIVE_PURGE_AFTER", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadTimeout, err := getDurationEnv("DOWNLOAD_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	// Telegram bots can't download files larger than 20 MB anyway
+	downloadMaxSize, err := getIntEnv("DOWNLOAD_MAX_SIZE", 20<<20, 1, 2<<30-1)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadRetries, err := getIntEnv("DOWNLOAD_RETRIES", 2, 0, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	streamThreshold, err := getIntEnv("STREAM_THRESHOLD", 5<<20, 1, 2<<30-1)
+	if err != nil {
+		return nil, err
+	}
+
+	supportedImageTypes, err := getImageTypesEnv("SUPPORTED_IMAGE_TYPES", []string{"image/jpeg", "image/png", "image/gif", "image/webp"})
+	if err != nil {
+		return nil, err
+	}
+
+	imageMaxDimension, err := getIntEnv("IMAGE_MAX_DIMENSION", 2048, 1, 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	imageJPEGQuality, err := getIntEnv("IMAGE_JPEG_QUALITY", 85, 1, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	imageCacheTTL, err := getDurationEnv("IMAGE_CACHE_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	textCacheTTL, err := getDurationEnv("TEXT_CACHE_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	videoFrameCount, err := getIntEnv("VIDEO_FRAME_COUNT", 4, 1, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	// Glossary is optional, but the file must be readable if set
+	glossary, err := loadGlossary(os.Getenv("GLOSSARY_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Prompt templates directory is optional; missing templates use the built-in ones
+	promptTemplatesDir := os.Getenv("PROMPT_TEMPLATES_DIR")
+
+	workingHours := os.Getenv("WORKING_HOURS")
+	if workingHours == "" {
+		workingHours = "09:00-18:00"
+	}
+
+	defaultEventDuration, err := getDurationEnv("DEFAULT_EVENT_DURATION", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	// REQUEST makes some calendar apps treat a file as an invitation to reply to
+	icsMethod, err := getICSMethodEnv("ICS_METHOD", "REQUEST")
+	if err != nil {
+		return nil, err
+	}
+	icsFeedMethod, err := getICSMethodEnv("ICS_FEED_METHOD", "PUBLISH")
+	if err != nil {
+		return nil, err
+	}
+	icsProductID := os.Getenv("ICS_PRODID")
+	if icsProductID == "" {
+		icsProductID = "-//Calendar Assistant//EN"
+	}
+
+	// Geocoding is optional; Google needs a key, Nominatim asks for no more than a request per second
+	geocoder := strings.ToLower(strings.TrimSpace(os.Getenv("GEOCODER")))
+	geocoderAPIKey := os.Getenv("GEOCODER_API_KEY")
+	switch geocoder {
+	case "", "nominatim":
+	case "google":
+		if geocoderAPIKey == "" {
+			return nil, ErrMissingGeocoderKey
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidGeocoder, geocoder)
+	}
+
+	// Encryption key is optional
+	encryptionKey := os.Getenv("ENCRYPTION_KEY")
+
 	return &Config{
-		TelegramBotToken:  telegramBotToken,
-		OpenAIAPIKey:      openAIAPIKey,
-		OpenAIAssistantID: openAIAssistantID,
+		TelegramBotToken:           telegramBotToken,
+		OpenAIAPIKey:               openAIAPIKey,
+		OpenAIAssistantID:          openAIAssistantID,
+		OpenAIStrongModel:          openAIStrongModel,
+		TelegramDebug:              telegramDebug,
+		UpdateMode:                 updateMode,
+		WebhookURL:                 webhookURL,
+		WebhookListenAddr:          webhookListenAddr,
+		WebhookSecret:              webhookSecret,
+		WebhookTLSCert:             os.Getenv("WEBHOOK_TLS_CERT"),
+		WebhookTLSKey:              os.Getenv("WEBHOOK_TLS_KEY"),
+		ShutdownTimeout:            shutdownTimeout,
+		Bots:                       bots,
+		BotNames:                   botNames(bots),
+		AllowedUserIDs:             allowedUserIDs,
+		AdminUserIDs:               adminUserIDs,
+		RateLimitPerMinute:         rateLimitPerMinute,
+		RateLimitStrikes:           rateLimitStrikes,
+		RateLimitBlock:             rateLimitBlock,
+		PremiumPriceStars:          premiumPriceStars,
+		PremiumDuration:            premiumDuration,
+		FreeDailyQuota:             freeDailyQuota,
+		PremiumDailyQuota:          premiumDailyQuota,
+		MaxConcurrentExtractions:   maxConcurrentExtractions,
+		BroadcastRate:              broadcastRate,
+		FeedbackChatID:             feedbackChatID,
+		ReleaseNotes:               releaseNotes,
+		DefaultTimezone:            defaultTimezone,
+		DefaultLanguage:            defaultLanguage,
+		AllowEventsWithoutTimezone: allowEventsWithoutTimezone,
+		StoragePath:                storagePath,
+		ImportUsersPath:            importUsersPath,
+		MaintenanceInterval:        maintenanceInterval,
+		HistoryRetention:           historyRetention,
+		ThreadMaxAge:               threadMaxAge,
+		SchedulerInterval:          schedulerInterval,
+		FollowUpDelay:              followUpDelay,
+		InactivePurgeAfter:         inactivePurgeAfter,
+		DownloadTimeout:            downloadTimeout,
+		DownloadMaxSize:            downloadMaxSize,
+		DownloadRetries:            downloadRetries,
+		StreamThreshold:            streamThreshold,
+		SupportedImageTypes:        supportedImageTypes,
+		ImageMaxDimension:          imageMaxDimension,
+		ImageJPEGQuality:           imageJPEGQuality,
+		ImageCacheTTL:              imageCacheTTL,
+		TextCacheTTL:               textCacheTTL,
+		VideoFrameCount:            videoFrameCount,
+		Glossary:                   glossary,
+		PromptTemplatesDir:         promptTemplatesDir,
+		WorkingHours:               workingHours,
+		DefaultEventDuration:       defaultEventDuration,
+		ICSMethod:                  icsMethod,
+		ICSFeedMethod:              icsFeedMethod,
+		ICSProductID:               icsProductID,
+		ICSCalendarName:            os.Getenv("ICS_CALENDAR_NAME"),
+		ICSFeedCalendarName:        os.Getenv("ICS_FEED_CALENDAR_NAME"),
+		Geocoder:                   geocoder,
+		GeocoderAPIKey:             geocoderAPIKey,
+		GeocoderURL:                os.Getenv("GEOCODER_URL"),
+		WeatherURL:                 os.Getenv("WEATHER_URL"),
+		EncryptionKey:              encryptionKey,
 	}, nil
 }
+
+// loadGlossary reads the glossary file at path, returning an empty glossary if path is empty
+func loadGlossary(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidGlossary, err)
+	}
+
+	glossary := strings.TrimSpace(string(data))
+	log.Printf("Loaded glossary from %s (%d bytes)", path, len(glossary))
+	return glossary, nil
+}
+
+// getImageTypesEnv reads a comma-separated list of image MIME types from the environment,
+// falling back to a default. Only formats the vision API accepts can be enabled.
+func getImageTypesEnv(key string, fallback []string) ([]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	var types []string
+	for _, mimeType := range strings.Split(value, ",") {
+		mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+		if mimeType == "" {
+			continue
+		}
+		if !imaging.IsSupported(mimeType) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImageType, mimeType)
+		}
+		types = append(types, mimeType)
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%w: %s is empty", ErrInvalidImageType, key)
+	}
+	return types, nil
+}
+
+// getUserIDsEnv reads a comma-separated list of Telegram user IDs from the environment
+func getUserIDsEnv(key string) ([]int64, error) {
+	var ids []int64
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: %s contains %q", ErrInvalidUserID, key, value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// getDurationEnv reads a duration (e.g. "24h", "90m") from the environment, falling back to a default
+func getDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w: %s=%q", ErrInvalidDuration, key, value)
+	}
+	return d, nil
+}
+
+// getIntEnv reads an integer within [min, max] from the environment, falling back to a default
+func getIntEnv(key string, fallback, min, max int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%w: %s=%q (expected %d-%d)", ErrInvalidNumber, key, value, min, max)
+	}
+	return n, nil
+}
+
+// getBoolEnv reads a boolean (true/false, 1/0) from the environment, falling back to a default
+func getBoolEnv(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s=%q", ErrInvalidBool, key, value)
+	}
+	return b, nil
+}
+
+// getICSMethodEnv reads the METHOD of calendar files from the environment, falling back to a default
+func getICSMethodEnv(key, fallback string) (string, error) {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv(key)))
+	if value == "" {
+		return fallback, nil
+	}
+
+	if value != "PUBLISH" && value != "REQUEST" {
+		return "", fmt.Errorf("%w: %s=%q", ErrInvalidICSMethod, key, value)
+	}
+	return value, nil
+}
@@ -6,4 +6,20 @@ import "errors"
 var (
 	ErrMissingTelegramToken = errors.New("missing Telegram bot token")
 	ErrMissingOpenAIKey     = errors.New("missing OpenAI API key")
+	ErrInvalidDuration      = errors.New("invalid duration")
+	ErrInvalidNumber        = errors.New("invalid number")
+	ErrInvalidTimezone      = errors.New("invalid timezone")
+	ErrInvalidBool          = errors.New("invalid boolean")
+	ErrInvalidGlossary      = errors.New("invalid glossary file")
+	ErrInvalidUpdateMode    = errors.New("invalid update mode, use polling or webhook")
+	ErrMissingWebhookURL    = errors.New("missing webhook URL")
+	ErrInvalidImageType     = errors.New("unsupported image type, use image/jpeg, image/png, image/gif or image/webp")
+	ErrInvalidWebhookSecret = errors.New("webhook secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	ErrInvalidUserID        = errors.New("invalid Telegram user ID")
+	ErrInvalidChatID        = errors.New("invalid Telegram chat ID")
+	ErrInvalidReleaseNotes  = errors.New("invalid release notes file")
+	ErrInvalidBots          = errors.New("invalid bots file")
+	ErrInvalidICSMethod     = errors.New("invalid ICS method, use PUBLISH or REQUEST")
+	ErrInvalidGeocoder      = errors.New("invalid geocoder, use nominatim or google")
+	ErrMissingGeocoderKey   = errors.New("missing API key of the google geocoder")
 )
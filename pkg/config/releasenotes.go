@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ReleaseNotes is the "what's new" summary of the current release, announced once to the
+// users who opted in
+type ReleaseNotes struct {
+	Version string            `json:"version"` // Announced again whenever it changes
+	Notes   map[string]string `json:"notes"`   // Summary per language code, e.g. "en" and "ru"
+}
+
+// loadReleaseNotes reads the release notes file at path, returning nil if path is empty
+func loadReleaseNotes(path string) (*ReleaseNotes, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReleaseNotes, err)
+	}
+
+	var notes ReleaseNotes
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReleaseNotes, err)
+	}
+	notes.Version = strings.TrimSpace(notes.Version)
+	if notes.Version == "" {
+		return nil, fmt.Errorf("%w: missing version", ErrInvalidReleaseNotes)
+	}
+	for lang, text := range notes.Notes {
+		if strings.TrimSpace(text) == "" {
+			delete(notes.Notes, lang)
+		}
+	}
+	if len(notes.Notes) == 0 {
+		return nil, fmt.Errorf("%w: no notes", ErrInvalidReleaseNotes)
+	}
+
+	log.Printf("Loaded release notes for version %s in %d languages from %s", notes.Version, len(notes.Notes), path)
+	return &notes, nil
+}
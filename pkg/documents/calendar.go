@@ -0,0 +1,15 @@
+package documents
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MIMECalendar is the MIME type of an iCalendar (.ics) file
+const MIMECalendar = "text/calendar"
+
+// IsCalendar reports whether a document with the given MIME type or filename is a calendar file
+func IsCalendar(mimeType, filename string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	return mimeType == MIMECalendar || strings.EqualFold(filepath.Ext(filename), ".ics")
+}
@@ -0,0 +1,193 @@
+package documents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MIMEEmail is the MIME type of a saved email message
+const MIMEEmail = "message/rfc822"
+
+// Email is the content of a forwarded .eml file that matters for event extraction
+type Email struct {
+	From      string
+	To        string
+	Subject   string
+	Date      string
+	Body      string   // Plain-text body, converted from HTML if there's no plain-text part
+	Calendars [][]byte // Embedded invitations (text/calendar parts and .ics attachments)
+}
+
+var (
+	htmlBlockPattern   = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h[1-6])[^>]*>`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]+>`)
+	blankLinesPattern  = regexp.MustCompile(`\n\s*\n\s*\n+`)
+	headerWordsDecoder = new(mime.WordDecoder)
+)
+
+// IsEmail reports whether a document with the given MIME type or filename is an email message
+func IsEmail(mimeType, filename string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	return mimeType == MIMEEmail || strings.EqualFold(filepath.Ext(filename), ".eml")
+}
+
+// ParseEmail parses the headers and body of an email message, collecting any embedded invitations
+func ParseEmail(data []byte) (*Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a valid email: %v", ErrUnsupportedDocument, err)
+	}
+
+	email := &Email{
+		From:    decodeHeader(msg.Header.Get("From")),
+		To:      decodeHeader(msg.Header.Get("To")),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+		Date:    msg.Header.Get("Date"),
+	}
+
+	var plain, htmlBody string
+	err = walkPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body,
+		func(mediaType, filename string, content []byte) {
+			switch {
+			case IsCalendar(mediaType, filename):
+				email.Calendars = append(email.Calendars, content)
+			case filename != "":
+				// Other attachments aren't used
+			case mediaType == "text/plain" && plain == "":
+				plain = string(content)
+			case mediaType == "text/html" && htmlBody == "":
+				htmlBody = string(content)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	email.Body = plain
+	if strings.TrimSpace(email.Body) == "" {
+		email.Body = htmlToText(htmlBody)
+	}
+	email.Body = strings.TrimSpace(email.Body)
+
+	return email, nil
+}
+
+// Text returns the email as plain text for extraction, with the headers that help
+// place the event (the sender, the date it was sent) followed by the body
+func (e *Email) Text() string {
+	var sb strings.Builder
+	if e.Subject != "" {
+		sb.WriteString("Subject: " + e.Subject + "\n")
+	}
+	if e.From != "" {
+		sb.WriteString("From: " + e.From + "\n")
+	}
+	if e.To != "" {
+		sb.WriteString("To: " + e.To + "\n")
+	}
+	if e.Date != "" {
+		sb.WriteString("Date: " + e.Date + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(e.Body)
+
+	return truncateText(strings.TrimSpace(sb.String()))
+}
+
+// walkPart decodes a MIME part, recursing into multipart containers and calling visit for each leaf
+func walkPart(contentType, encoding, disposition string, body io.Reader, visit func(mediaType, filename string, content []byte)) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Messages without a (valid) Content-Type are plain text by definition
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read email part: %w", err)
+			}
+			err = walkPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"), part, visit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(body, encoding))
+	if err != nil {
+		return fmt.Errorf("failed to decode email part: %w", err)
+	}
+
+	filename := params["name"]
+	if _, dispParams, err := mime.ParseMediaType(disposition); err == nil && dispParams["filename"] != "" {
+		filename = dispParams["filename"]
+	}
+
+	visit(mediaType, decodeHeader(filename), content)
+	return nil
+}
+
+// decodeTransfer undoes the Content-Transfer-Encoding of a part
+func decodeTransfer(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// newlineStripper drops line breaks, which base64 bodies are wrapped with
+type newlineStripper struct {
+	r io.Reader
+}
+
+// Read reads from the underlying reader, skipping CR and LF bytes
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, c := range p[:n] {
+		if c != '\r' && c != '\n' {
+			p[kept] = c
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// decodeHeader decodes RFC 2047 encoded words (e.g. =?UTF-8?B?...?=) in a header value
+func decodeHeader(value string) string {
+	decoded, err := headerWordsDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// htmlToText turns an HTML body into readable plain text
+func htmlToText(body string) string {
+	body = htmlBlockPattern.ReplaceAllString(body, "")
+	body = htmlBreakPattern.ReplaceAllString(body, "\n")
+	body = htmlTagPattern.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+	return blankLinesPattern.ReplaceAllString(body, "\n\n")
+}
@@ -0,0 +1,36 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfText extracts the text of all pages of a PDF file. Scanned PDFs have no text layer, so
+// their text is empty.
+func pdfText(data []byte) (text string, err error) {
+	// The parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: not a valid PDF file: %v", ErrUnsupportedDocument, r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: not a valid PDF file: %v", ErrUnsupportedDocument, err)
+	}
+	content, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF text: %w", err)
+	}
+
+	// Only read as much as is used, long PDFs can have a lot of text
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(content, 4*MaxTextLength)); err != nil {
+		return "", fmt.Errorf("failed to read PDF text: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,174 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedDocument is returned for documents that aren't plain text, DOCX or PDF
+var ErrUnsupportedDocument = errors.New("unsupported document")
+
+// MaxTextLength limits how much text is taken from a document, which keeps long files
+// within the model's context
+const MaxTextLength = 20000
+
+// Document MIME types with extractable text
+const (
+	MIMEText = "text/plain"
+	MIMEDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MIMEPDF  = "application/pdf"
+)
+
+// IsTextDocument reports whether a document with the given MIME type or filename has
+// text that ExtractText can read
+func IsTextDocument(mimeType, filename string) bool {
+	return kind(mimeType, filename) != ""
+}
+
+// IsPDF reports whether a document with the given MIME type or filename is a PDF
+func IsPDF(mimeType, filename string) bool {
+	return kind(mimeType, filename) == MIMEPDF
+}
+
+// ExtractText returns the text content of a plain-text, DOCX or PDF document
+func ExtractText(data []byte, mimeType, filename string) (string, error) {
+	var text string
+	switch kind(mimeType, filename) {
+	case MIMEText:
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("%w: text file isn't valid UTF-8", ErrUnsupportedDocument)
+		}
+		text = string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))) // Drop the BOM some editors add
+	case MIMEDOCX:
+		var err error
+		text, err = docxText(data)
+		if err != nil {
+			return "", err
+		}
+	case MIMEPDF:
+		var err error
+		text, err = pdfText(data)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", ErrUnsupportedDocument
+	}
+
+	return truncateText(strings.TrimSpace(text)), nil
+}
+
+// truncateText cuts text to MaxTextLength bytes at a rune boundary so it stays valid UTF-8
+func truncateText(text string) string {
+	if len(text) <= MaxTextLength {
+		return text
+	}
+	cut := MaxTextLength
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// kind returns the canonical MIME type of a supported document, or "" if it isn't supported.
+// Clients don't always send a MIME type, so the file extension is checked as well.
+func kind(mimeType, filename string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	switch mimeType {
+	case MIMEText, MIMEDOCX, MIMEPDF:
+		return mimeType
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt":
+		return MIMEText
+	case ".docx":
+		return MIMEDOCX
+	case ".pdf":
+		return MIMEPDF
+	}
+	return ""
+}
+
+// maxDOCXContentSize limits how much of the main document part of a DOCX file is decompressed.
+// The markup around the text takes far more room than the text, but a small archive can
+// decompress to gigabytes.
+const maxDOCXContentSize = 64 * MaxTextLength
+
+// docxText extracts the paragraphs of the main document part of a DOCX file
+func docxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: not a valid DOCX file: %v", ErrUnsupportedDocument, err)
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open DOCX content: %w", err)
+		}
+		defer rc.Close()
+
+		return wordprocessingText(&io.LimitedReader{R: rc, N: maxDOCXContentSize})
+	}
+
+	return "", fmt.Errorf("%w: DOCX file has no document content", ErrUnsupportedDocument)
+}
+
+// wordprocessingText walks a WordprocessingML document, keeping the text runs and turning
+// paragraphs, line breaks and tabs into their plain-text equivalents. It stops once it has
+// MaxTextLength bytes of text, or where the limit of r cuts the document off.
+func wordprocessingText(r *io.LimitedReader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	var sb strings.Builder
+	inText := false
+
+	for sb.Len() < MaxTextLength {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && r.N <= 0 {
+			break // Cut off at the limit, so the text so far is all there is
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse DOCX content: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,169 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Errors returned for downloads that can't succeed by retrying
+var (
+	ErrTooLarge          = errors.New("file is too large")
+	ErrUnexpectedContent = errors.New("server returned an error page instead of the file")
+)
+
+// errRetryableStatus marks responses whose status means the server may succeed later
+var errRetryableStatus = errors.New("retryable status")
+
+// Backoff between attempts, doubling after every retry
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = 10 * time.Second
+)
+
+// retryableStatusCodes are the statuses of transient server failures
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// Client downloads files with a timeout, a size limit and retries on transient failures
+type Client struct {
+	http    *http.Client
+	maxSize int64
+	retries int
+}
+
+// NewClient creates a download client. Each attempt is limited to timeout, files larger than
+// maxSize bytes are rejected, and transient failures are retried up to retries times.
+func NewClient(timeout time.Duration, maxSize int64, retries int) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &Client{
+		http:    &http.Client{Timeout: timeout, Transport: transport},
+		maxSize: maxSize,
+		retries: retries,
+	}
+}
+
+// Get downloads a file, giving up when ctx is cancelled
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	body, err := c.Open(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// Open starts downloading a file and returns its body for streaming. Failures to connect are
+// retried; reading the body fails with ErrTooLarge once it exceeds the size limit.
+func (c *Client) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	backoff := retryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt < 1+c.retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("Retrying download in %s after error: %v", backoff, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxRetryBackoff)
+		}
+
+		body, err := c.open(ctx, url)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if !retryable(ctx, err) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("download failed after %d attempts: %w", 1+c.retries, lastErr)
+}
+
+// open makes a single attempt to start a download
+func (c *Client) open(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if retryableStatusCodes[resp.StatusCode] {
+			return nil, fmt.Errorf("%w: %s", errRetryableStatus, resp.Status)
+		}
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	// File servers never send HTML, so that's a proxy or error page
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "text/html" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (%s)", ErrUnexpectedContent, mediaType)
+	}
+
+	if resp.ContentLength > c.maxSize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrTooLarge, resp.ContentLength, c.maxSize)
+	}
+
+	return &limitedBody{body: resp.Body, remaining: c.maxSize}, nil
+}
+
+// limitedBody fails with ErrTooLarge when a body without a (truthful) Content-Length turns out
+// to be larger than the limit
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+// Read reads from the body, failing once more than the limit has been read
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// Close closes the body
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+// retryable reports whether a failed attempt may succeed when tried again
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, errRetryableStatus) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,74 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/cache"
+)
+
+// Providers that can be configured
+const (
+	ProviderNominatim = "nominatim"
+	ProviderGoogle    = "google"
+)
+
+// ErrNotFound is returned when a provider knows no place for a query
+var ErrNotFound = errors.New("place not found")
+
+// requestTimeout limits a single request to a provider
+const requestTimeout = 10 * time.Second
+
+// cacheTTL is how long results are reused; places rarely move, and events repeat venues
+const cacheTTL = 30 * 24 * time.Hour
+
+// Place is a geocoded location
+type Place struct {
+	Lat     float64
+	Lon     float64
+	Address string // Normalized address of the place
+}
+
+// Geocoder looks up the coordinates of a location
+type Geocoder interface {
+	Geocode(ctx context.Context, query string) (Place, error)
+}
+
+// New creates the geocoder of a provider, with results cached. baseURL overrides the
+// provider's default endpoint, e.g. for a self-hosted Nominatim; Google needs an API key.
+func New(provider, apiKey, baseURL string) (Geocoder, error) {
+	client := &http.Client{Timeout: requestTimeout}
+
+	var g Geocoder
+	switch provider {
+	case ProviderNominatim:
+		g = newNominatim(client, baseURL)
+	case ProviderGoogle:
+		if apiKey == "" {
+			return nil, errors.New("the Google geocoder needs an API key")
+		}
+		g = newGoogle(client, apiKey, baseURL)
+	default:
+		return nil, fmt.Errorf("unknown geocoding provider %q", provider)
+	}
+	return &cached{next: g, results: cache.NewTTL[Place](cacheTTL)}, nil
+}
+
+// cached reuses the results of a geocoder, so a venue is looked up once
+type cached struct {
+	next    Geocoder
+	results *cache.TTL[Place]
+}
+
+// Geocode looks up a location, reusing an earlier result for the same query
+func (c *cached) Geocode(ctx context.Context, query string) (Place, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	place, _, err := c.results.GetOrLoad(key, func() (Place, error) {
+		return c.next.Geocode(ctx, query)
+	})
+	return place, err
+}
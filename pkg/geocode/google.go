@@ -0,0 +1,79 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// googleURL is the endpoint of the Google Geocoding API
+const googleURL = "https://maps.googleapis.com/maps/api/geocode/json"
+
+// google geocodes with the Google Geocoding API
+type google struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+// newGoogle creates a Google geocoder, using the public endpoint if baseURL is empty
+func newGoogle(client *http.Client, apiKey, baseURL string) *google {
+	if baseURL == "" {
+		baseURL = googleURL
+	}
+	return &google{client: client, apiKey: apiKey, baseURL: baseURL}
+}
+
+// Geocode looks up the best match of a query
+func (g *google) Geocode(ctx context.Context, query string) (Place, error) {
+	params := url.Values{}
+	params.Set("address", query)
+	params.Set("key", g.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return Place{}, err
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return Place{}, fmt.Errorf("failed to query Google geocoding: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Results      []struct {
+			FormattedAddress string `json:"formatted_address"`
+			Geometry         struct {
+				Location struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"location"`
+			} `json:"geometry"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Place{}, fmt.Errorf("failed to parse Google geocoding response: %w", err)
+	}
+
+	switch result.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return Place{}, ErrNotFound
+	default:
+		return Place{}, fmt.Errorf("Google geocoding failed: %s %s", result.Status, result.ErrorMessage)
+	}
+	if len(result.Results) == 0 {
+		return Place{}, ErrNotFound
+	}
+
+	best := result.Results[0]
+	return Place{
+		Lat:     best.Geometry.Location.Lat,
+		Lon:     best.Geometry.Location.Lng,
+		Address: best.FormattedAddress,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Nominatim defaults: the public instance allows one request per second from applications
+// that name themselves in the User-Agent
+const (
+	nominatimURL       = "https://nominatim.openstreetmap.org"
+	nominatimUserAgent = "calendar-assistant (Telegram bot)"
+	nominatimInterval  = time.Second
+)
+
+// nominatim geocodes with the OpenStreetMap Nominatim API
+type nominatim struct {
+	client  *http.Client
+	baseURL string
+	mu      sync.Mutex // Mutex to space out requests
+	last    time.Time  // When the last request was sent
+}
+
+// newNominatim creates a Nominatim geocoder, using the public instance if baseURL is empty
+func newNominatim(client *http.Client, baseURL string) *nominatim {
+	if baseURL == "" {
+		baseURL = nominatimURL
+	}
+	return &nominatim{client: client, baseURL: baseURL}
+}
+
+// Geocode looks up the best match of a query
+func (n *nominatim) Geocode(ctx context.Context, query string) (Place, error) {
+	if err := n.wait(ctx); err != nil {
+		return Place{}, err
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "jsonv2")
+	params.Set("limit", "1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return Place{}, err
+	}
+	req.Header.Set("User-Agent", nominatimUserAgent)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return Place{}, fmt.Errorf("failed to query Nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Place{}, fmt.Errorf("Nominatim returned status %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return Place{}, fmt.Errorf("failed to parse Nominatim response: %w", err)
+	}
+	if len(results) == 0 {
+		return Place{}, ErrNotFound
+	}
+
+	lat, latErr := strconv.ParseFloat(results[0].Lat, 64)
+	lon, lonErr := strconv.ParseFloat(results[0].Lon, 64)
+	if latErr != nil || lonErr != nil {
+		return Place{}, fmt.Errorf("invalid coordinates %q, %q from Nominatim", results[0].Lat, results[0].Lon)
+	}
+	return Place{Lat: lat, Lon: lon, Address: results[0].DisplayName}, nil
+}
+
+// wait holds a request until the interval since the previous one has passed
+func (n *nominatim) wait(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if d := time.Until(n.last.Add(nominatimInterval)); d > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	n.last = time.Now()
+	return nil
+}
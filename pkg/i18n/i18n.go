@@ -0,0 +1,94 @@
+// Package i18n translates the bot's messages using a catalog of messages per language,
+// embedded from locales/<language code>.json
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the complete catalog, used for messages missing in others
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog holds the messages of each supported language by key
+type Catalog struct {
+	messages map[string]map[string]string // Map of language code -> key -> message
+}
+
+// Load parses the embedded catalogs
+func Load() (*Catalog, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locales: %w", err)
+	}
+
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	for _, file := range files {
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale %s: %w", file.Name(), err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse locale %s: %w", file.Name(), err)
+		}
+		c.messages[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+
+	if _, ok := c.messages[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("locale %s is missing", DefaultLanguage)
+	}
+	return c, nil
+}
+
+// Has reports whether there's a catalog for a language
+func (c *Catalog) Has(lang string) bool {
+	_, ok := c.messages[lang]
+	return ok
+}
+
+// Translates reports whether a language has its own message for a key, rather than falling
+// back to the default language
+func (c *Catalog) Translates(lang, key string) bool {
+	_, ok := c.messages[lang][key]
+	return ok
+}
+
+// Languages returns the codes of the supported languages, in order
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T returns the message with a key in a language, formatted with args like fmt.Sprintf. Messages
+// missing in the language fall back to the default language, and missing keys to the key itself.
+func (c *Catalog) T(lang, key string, args ...any) string {
+	message, ok := c.messages[lang][key]
+	if !ok {
+		message, ok = c.messages[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Base returns the language of a code that may include a region, e.g. "pt" for "pt-BR"
+func Base(code string) string {
+	code, _, _ = strings.Cut(strings.ToLower(code), "-")
+	return code
+}
@@ -0,0 +1,101 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// verbPattern matches the fmt verbs of a message, with their argument index and flags
+var verbPattern = regexp.MustCompile(`%(?:\[\d+\])?[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+// verbs returns the fmt verbs of a message in order, without escaped percent signs
+func verbs(message string) []string {
+	var found []string
+	for _, verb := range verbPattern.FindAllString(message, -1) {
+		if verb != "%%" {
+			found = append(found, verb)
+		}
+	}
+	slices.Sort(found)
+	return found
+}
+
+func TestCatalogParity(t *testing.T) {
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	base := c.messages[DefaultLanguage]
+	for _, lang := range c.Languages() {
+		if lang == DefaultLanguage {
+			continue
+		}
+		t.Run(lang, func(t *testing.T) {
+			messages := c.messages[lang]
+			for key, message := range base {
+				translated, ok := messages[key]
+				if !ok {
+					t.Errorf("%s is missing", key)
+					continue
+				}
+				// Translations may reorder the arguments, but need the same ones
+				if want, got := verbs(message), verbs(translated); !slices.Equal(got, want) {
+					t.Errorf("%s has the verbs %q, want %q", key, got, want)
+				}
+			}
+			for key := range messages {
+				if _, ok := base[key]; !ok {
+					t.Errorf("%s isn't in the %s catalog", key, DefaultLanguage)
+				}
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	c := &Catalog{messages: map[string]map[string]string{
+		"en": {"greeting": "Hello, %s!", "plain": "Plain", "only_en": "English only"},
+		"ru": {"greeting": "Привет, %s!", "plain": "Просто"},
+	}}
+
+	tests := []struct {
+		name string
+		lang string
+		key  string
+		args []any
+		want string
+	}{
+		{"translated with args", "ru", "greeting", []any{"Анна"}, "Привет, Анна!"},
+		{"default language", "en", "greeting", []any{"Anna"}, "Hello, Anna!"},
+		{"without args", "ru", "plain", nil, "Просто"},
+		{"falls back to the default language", "ru", "only_en", nil, "English only"},
+		{"unknown language", "de", "plain", nil, "Plain"},
+		{"unknown key", "ru", "missing.key", nil, "missing.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.T(tt.lang, tt.key, tt.args...); got != tt.want {
+				t.Errorf("T(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"en", "en"},
+		{"pt-BR", "pt"},
+		{"RU", "ru"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Base(tt.code); got != tt.want {
+			t.Errorf("Base(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
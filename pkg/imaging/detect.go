@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrUnsupportedImage is returned when data isn't an image format the vision API accepts
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// Format describes a detected image format
+type Format struct {
+	MIME      string
+	Extension string
+}
+
+// supportedFormats are the image formats accepted by the vision API, keyed by MIME type
+var supportedFormats = map[string]Format{
+	"image/jpeg": {MIME: "image/jpeg", Extension: ".jpg"},
+	"image/png":  {MIME: "image/png", Extension: ".png"},
+	"image/gif":  {MIME: "image/gif", Extension: ".gif"},
+	"image/webp": {MIME: "image/webp", Extension: ".webp"},
+}
+
+// isoMediaBrands maps the brands of ISO media files (HEIC, AVIF, ...) to their MIME types
+var isoMediaBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"avif": "image/avif",
+}
+
+// IsSupported reports whether a MIME type is an image format the vision API accepts
+func IsSupported(mimeType string) bool {
+	_, ok := supportedFormats[mimeType]
+	return ok
+}
+
+// Detect identifies the image format from the data's magic bytes
+func Detect(data []byte) (Format, error) {
+	mimeType := Sniff(data)
+	if format, ok := supportedFormats[mimeType]; ok {
+		return format, nil
+	}
+	return Format{}, fmt.Errorf("%w: detected %s", ErrUnsupportedImage, mimeType)
+}
+
+// Sniff returns the MIME type of data from its magic bytes. Unlike Detect it also recognizes
+// formats that aren't supported, such as HEIC and TIFF, so users can be told what to do.
+func Sniff(data []byte) string {
+	// HEIC/HEIF/AVIF: an ISO media "ftyp" box with the brand at offset 8
+	if len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) {
+		if mimeType, ok := isoMediaBrands[string(data[8:12])]; ok {
+			return mimeType
+		}
+	}
+
+	// TIFF, in little- and big-endian byte order
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+
+	mimeType := http.DetectContentType(data)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i] // Drop the charset of text types
+	}
+	return mimeType
+}
@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	// Register decoders for the formats Telegram users commonly send
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Prepare downscales an image so neither side exceeds maxDimension and re-encodes it
+// as JPEG with the given quality. Images that are already small JPEGs are returned as is.
+func Prepare(data []byte, maxDimension, quality int) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	needsResize := width > maxDimension || height > maxDimension
+
+	if !needsResize && format == "jpeg" {
+		return data, nil
+	}
+
+	img := toRGBA(src)
+	if needsResize {
+		newWidth, newHeight := fitWithin(width, height, maxDimension)
+		img = downscale(img, newWidth, newHeight)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+
+	// Re-encoding a small image can make it bigger, in which case keep the original
+	if !needsResize && buf.Len() >= len(data) {
+		return data, nil
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitWithin scales width and height down proportionally so neither exceeds maxDimension
+func fitWithin(width, height, maxDimension int) (int, int) {
+	if width >= height {
+		newHeight := height * maxDimension / width
+		if newHeight < 1 {
+			newHeight = 1
+		}
+		return maxDimension, newHeight
+	}
+
+	newWidth := width * maxDimension / height
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	return newWidth, maxDimension
+}
+
+// toRGBA converts an image to RGBA on a white background, since JPEG has no transparency
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// downscale shrinks an image with a box filter, averaging the source pixels covered
+// by each destination pixel
+func downscale(src *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := (y + 1) * srcHeight / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := (x + 1) * srcWidth / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			d[0] = uint8(r / n)
+			d[1] = uint8(g / n)
+			d[2] = uint8(b / n)
+			d[3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
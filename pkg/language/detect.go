@@ -0,0 +1,143 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// names maps the language codes Detect returns to English names, for use in prompts
+var names = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hy": "Armenian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ka": "Georgian",
+	"ko": "Korean",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// latinLanguages are the Latin-script languages Detect can tell apart, in order of preference on ties
+var latinLanguages = []string{"en", "de", "fr", "es", "it", "pt"}
+
+// stopwords are frequent short words that tell apart languages written in the Latin script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "at", "on", "for", "with", "is", "will", "from", "join", "us"},
+	"de": {"der", "die", "das", "und", "mit", "für", "im", "am", "um", "uhr", "von", "zum", "ist", "ein"},
+	"fr": {"le", "la", "les", "et", "des", "du", "au", "pour", "avec", "est", "une", "sur", "à"},
+	"es": {"el", "los", "las", "y", "del", "en", "para", "con", "es", "una", "por", "al"},
+	"it": {"il", "gli", "e", "di", "della", "per", "con", "è", "una", "alle", "dal"},
+	"pt": {"o", "os", "as", "e", "do", "da", "em", "para", "com", "uma", "às", "no", "na"},
+}
+
+// Name returns the English name of a language code, or "" if it's unknown
+func Name(code string) string {
+	return names[code]
+}
+
+// Detect guesses the language of a text from its script and, for the Latin script, common
+// words. It returns "" if the text is too short or ambiguous to tell.
+func Detect(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case strings.ContainsRune("іїєґІЇЄҐ", r):
+			counts["uk"] += 10 // Letters only Ukrainian uses among Cyrillic languages
+		case strings.ContainsRune("ыэъЫЭЪё", r):
+			counts["ru"] += 10 // Letters Ukrainian doesn't use
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["ja"] += 10 // Kana means Japanese even when mixed with Han characters
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Georgian, r):
+			counts["ka"]++
+		case unicode.Is(unicode.Armenian, r):
+			counts["hy"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+	if letters < 3 {
+		return ""
+	}
+
+	// Cyrillic text is Russian unless it has letters only Ukrainian uses
+	if counts["cyrillic"] > 0 || counts["uk"] > 0 || counts["ru"] > 0 {
+		cyrillic := counts["cyrillic"] + counts["uk"] + counts["ru"]
+		if cyrillic*2 >= letters {
+			if counts["uk"] > counts["ru"] {
+				return "uk"
+			}
+			return "ru"
+		}
+	}
+
+	best, bestCount := "", 0
+	for code, count := range counts {
+		if code == "latin" || code == "cyrillic" || code == "uk" || code == "ru" {
+			continue
+		}
+		if count > bestCount {
+			best, bestCount = code, count
+		}
+	}
+	if best != "" && bestCount*2 >= letters {
+		return best
+	}
+
+	if counts["latin"]*2 >= letters {
+		return detectLatin(text)
+	}
+	return ""
+}
+
+// detectLatin picks the Latin-script language whose common words appear most often
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	best, bestCount := "", 0
+	for _, code := range latinLanguages {
+		list := stopwords[code]
+		count := 0
+		for _, word := range words {
+			for _, stopword := range list {
+				if word == stopword {
+					count++
+					break
+				}
+			}
+		}
+		if count > bestCount {
+			best, bestCount = code, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,98 @@
+package maintenance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/scheduler"
+	"calendar-assistant/pkg/storage"
+)
+
+// usageRetention is how long daily usage stats are kept, a bit longer than /stats shows
+const usageRetention = 35 * 24 * time.Hour
+
+// sharedEventRetention is how long the links to events created through inline mode keep working
+const sharedEventRetention = 30 * 24 * time.Hour
+
+// Job periodically compacts old history, purges expired bans, old usage stats and shared events
+// and rotates OpenAI threads. The store is a JSON file rewritten on every change, so what's
+// purged is gone from disk right away and there's nothing to vacuum.
+type Job struct {
+	store         *storage.Store
+	openaiClients []*openai.Client // Clients of all bots, each with the threads of its users
+	interval      time.Duration
+	retention     time.Duration
+	threadMaxAge  time.Duration
+}
+
+// NewJob creates a new maintenance job
+func NewJob(cfg *config.Config, store *storage.Store, openaiClients []*openai.Client) *Job {
+	return &Job{
+		store:         store,
+		openaiClients: openaiClients,
+		interval:      cfg.MaintenanceInterval,
+		retention:     cfg.HistoryRetention,
+		threadMaxAge:  cfg.ThreadMaxAge,
+	}
+}
+
+// jobKind is the kind of the maintenance job in the scheduler
+const jobKind = "maintenance"
+
+// Register schedules the job with s, to run once right away and then on every interval
+func (j *Job) Register(s *scheduler.Scheduler) error {
+	return s.Every(jobKind, j.interval, func(ctx context.Context) error {
+		j.RunOnce(ctx)
+		return nil
+	})
+}
+
+// RunOnce performs a single maintenance pass
+func (j *Job) RunOnce(ctx context.Context) {
+	log.Println("Running maintenance...")
+	sizeBefore := j.store.Size()
+
+	// Drop raw inputs from old history entries, keeping the structured events
+	compacted, err := j.store.CompactHistory(time.Now().Add(-j.retention))
+	if err != nil {
+		log.Printf("Error compacting history: %v", err)
+	} else {
+		log.Printf("Compacted %d history entries", compacted)
+	}
+
+	// Forget temporary blocks that have run out
+	purged, err := j.store.PurgeExpiredBans(time.Now())
+	if err != nil {
+		log.Printf("Error purging expired bans: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d expired bans", purged)
+	}
+
+	// Usage stats only cover the last month, so older days aren't needed
+	purgedDays, err := j.store.PurgeUsage(time.Now().Add(-usageRetention))
+	if err != nil {
+		log.Printf("Error purging usage stats: %v", err)
+	} else if purgedDays > 0 {
+		log.Printf("Purged usage stats of %d days", purgedDays)
+	}
+
+	// Links to events shared through inline mode expire
+	purgedShared, err := j.store.PurgeSharedEvents(time.Now().Add(-sharedEventRetention))
+	if err != nil {
+		log.Printf("Error purging shared events: %v", err)
+	} else if purgedShared > 0 {
+		log.Printf("Purged %d shared events", purgedShared)
+	}
+
+	// Rotate long-lived threads so their context doesn't grow without bound
+	rotated := 0
+	for _, client := range j.openaiClients {
+		rotated += client.RotateThreads(ctx, j.threadMaxAge)
+	}
+	log.Printf("Rotated %d OpenAI threads", rotated)
+
+	log.Printf("Maintenance finished, storage size %d -> %d bytes", sizeBefore, j.store.Size())
+}
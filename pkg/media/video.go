@@ -0,0 +1,118 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// ErrFFmpegNotFound is returned when ffmpeg isn't installed on the server
+var ErrFFmpegNotFound = errors.New("video support requires ffmpeg, which isn't installed on this server")
+
+// ErrNoAudio is returned when a video has no audio track to extract
+var ErrNoAudio = errors.New("video has no audio track")
+
+// ExtractFrames returns up to count JPEG frames spread evenly over a video of the given
+// duration in seconds
+func ExtractFrames(ctx context.Context, videoData []byte, durationSeconds, count int) ([][]byte, error) {
+	dir, input, err := writeTempVideo(videoData)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if durationSeconds < 1 {
+		durationSeconds = 1
+	}
+
+	// Sample count frames per duration, i.e. one every duration/count seconds
+	rate := fmt.Sprintf("fps=%d/%d", count, durationSeconds)
+	output := filepath.Join(dir, "frame-%02d.jpg")
+	if err := runFFmpeg(ctx, "-i", input, "-vf", rate, "-frames:v", fmt.Sprintf("%d", count), "-q:v", "3", output); err != nil {
+		return nil, fmt.Errorf("failed to extract frames: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "frame-*.jpg"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frames: %w", err)
+	}
+	sort.Strings(paths)
+
+	var frames [][]byte
+	for _, path := range paths {
+		frame, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read frame: %w", err)
+		}
+		frames = append(frames, frame)
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames could be extracted from the video")
+	}
+
+	fmt.Printf("Extracted %d frames from video\n", len(frames))
+	return frames, nil
+}
+
+// ExtractAudio returns the audio track of a video as 16 kHz mono WAV, which is all speech
+// recognition needs
+func ExtractAudio(ctx context.Context, videoData []byte) ([]byte, error) {
+	dir, input, err := writeTempVideo(videoData)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "audio.wav")
+	if err := runFFmpeg(ctx, "-i", input, "-vn", "-ac", "1", "-ar", "16000", output); err != nil {
+		return nil, fmt.Errorf("failed to extract audio: %w", err)
+	}
+
+	audio, err := os.ReadFile(output)
+	if err != nil {
+		return nil, ErrNoAudio
+	}
+
+	fmt.Printf("Extracted %d bytes of audio from video\n", len(audio))
+	return audio, nil
+}
+
+// writeTempVideo writes video data to a new temporary directory
+func writeTempVideo(videoData []byte) (string, string, error) {
+	dir, err := os.MkdirTemp("", "event-video-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	input := filepath.Join(dir, "input.mp4")
+	if err := os.WriteFile(input, videoData, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("failed to write video to temporary file: %w", err)
+	}
+
+	return dir, input, nil
+}
+
+// runFFmpeg runs ffmpeg quietly with the given arguments
+func runFFmpeg(ctx context.Context, args ...string) error {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return ErrFFmpegNotFound
+	}
+
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)
+	cmd := exec.CommandContext(ctx, path, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// assistantInstructions are used when the assistant has to be created in a user's own account
+const assistantInstructions = `You extract calendar events from text and images.
+Reply with a single JSON object and nothing else, using these fields:
+- "title": short event title
+- "description": event details
+- "location": venue or address, empty if unknown
+- "start_time": start in RFC3339 format
+- "end_time": end in RFC3339 format, empty if unknown
+` + eventFields + `
+Write the times as they appear in the source, using the Z suffix without converting timezones.
+For all-day events use midnight (00:00:00) as the time.
+Write the title, description and location in the language of the source, don't translate them.`
+
+// sameLanguageInstructions keep the event fields in the language of the source, also for
+// assistants created before this was part of their instructions
+const sameLanguageInstructions = `Write the title, description and location in the same language as the source, don't translate them.`
+
+// eventFields describes the event fields added after the first assistants were created
+const eventFields = `- "all_day": true if the event takes whole days without a time of day, otherwise false.
+  All-day events start at midnight of their first day and end at midnight of their last day,
+  e.g. a retreat from June 3 to June 6 ends on June 6 at 00:00:00
+- "recurrence": null if the event happens once, otherwise an object with "frequency" (daily, weekly, monthly or yearly),
+  "interval" (e.g. 2 for every other week), "until" (last date as YYYY-MM-DD, empty if open-ended),
+  "count" (number of occurrences, 0 if open-ended) and "by_day" (weekdays as MO, TU, WE, TH, FR, SA, SU,
+  prefixed with the week of the month for monthly events, e.g. 1MO or -1FR)
+- "reminders": minutes before the start for each reminder the source asks for, e.g. [30] for "remind me 30 minutes before", empty if none
+- "url": the registration, ticket or online meeting link of the event, empty if there is none
+- "categories": one or two kinds of event from this list, most fitting first: ` + categoryList + `
+- "status": "tentative" if the source says the event isn't certain yet (e.g. "tentatively next Friday"),
+  "cancelled" if it says the event was called off, otherwise "confirmed"
+- "busy": "busy" if the source asks to block the time (e.g. "block this as busy"), "free" if the event
+  shouldn't block it (e.g. "I'm still available then"), otherwise empty
+- "priority": "high", "medium" or "low" if the source says how important the event is
+  (e.g. "urgent", "optional"), otherwise empty
+- "outdoor": true if the event takes place outdoors, e.g. a hike, picnic, open-air concert or match on a field, otherwise false
+- "flexible": true if the source names a period but no time for the event, e.g. "let's meet sometime next week",
+  otherwise false. start_time and end_time then span the whole period (e.g. Monday 00:00:00 to Sunday 23:59:00),
+  and "duration_minutes" is how long the event takes, 0 if the source doesn't say
+For a recurring event, start_time and end_time are those of the first occurrence.
+For an online event without a venue, also use the meeting link as the location.`
+
+// eventFieldsInstructions ask existing assistants for the fields they weren't created with
+const eventFieldsInstructions = "Also add these fields to the JSON object:\n" + eventFields
+
+// glossaryInstructions introduce the deployment's glossary in the instructions of a run
+const glossaryInstructions = `The following glossary lists names, places and abbreviations used by this community.
+Use these exact spellings in the title and description, and expand known venues into their full location:
+`
+
+// describeInstructions ask for a description of the image content for users who can't see it
+const describeInstructions = `Also add a "content_description" field describing in plain language everything the image shows and says,
+including text that isn't part of the event, so that someone who can't see the image knows its full content.
+Write it in the language of the source, as a few short sentences without formatting.`
+
+// userAccount is a bring-your-own-key OpenAI account and the assistant used in it
+type userAccount struct {
+	client      *openai.Client
+	assistantID string
+	mu          sync.Mutex // Mutex to protect the assistant lookup
+}
+
+// SetKeyProvider sets the function used to look up a user's own API key
+func (c *Client) SetKeyProvider(provider func(userID string) string) {
+	c.keyProvider = provider
+}
+
+// SetUsageRecorder sets the function that receives the token usage of each run billed to the operator
+func (c *Client) SetUsageRecorder(recorder func(userID string, promptTokens, completionTokens int64)) {
+	c.usageRecorder = recorder
+}
+
+// recordUsage reports the token usage of a run. Runs on users' own keys are billed to them,
+// so they're left out.
+func (c *Client) recordUsage(api *openai.Client, userID string, usage openai.RunUsage) {
+	if c.usageRecorder == nil || api != c.client {
+		return
+	}
+	c.usageRecorder(userID, usage.PromptTokens, usage.CompletionTokens)
+}
+
+// ValidateAPIKey checks that an API key is accepted by OpenAI
+func ValidateAPIKey(ctx context.Context, apiKey string) error {
+	client := openai.NewClient(option.WithAPIKey(apiKey))
+	if _, err := client.Models.List(ctx); err != nil {
+		return fmt.Errorf("API key was rejected: %w", err)
+	}
+	return nil
+}
+
+// accountFor returns the API client and assistant ID to use for a user's requests
+func (c *Client) accountFor(ctx context.Context, userID string) (*openai.Client, string, error) {
+	var apiKey string
+	if c.keyProvider != nil {
+		apiKey = c.keyProvider(userID)
+	}
+
+	// Users without their own key are billed to the operator
+	if apiKey == "" {
+		if err := c.InitializeAssistant(ctx); err != nil {
+			return nil, "", err
+		}
+		return c.client, c.assistantID, nil
+	}
+
+	c.accountMutex.Lock()
+	account, exists := c.userAccounts[apiKey]
+	if !exists {
+		betaOption := option.WithHeader("OpenAI-Beta", "assistants=v2")
+		apiKeyOption := option.WithAPIKey(apiKey)
+		account = &userAccount{client: openai.NewClient(betaOption, apiKeyOption)}
+		c.userAccounts[apiKey] = account
+	}
+	c.accountMutex.Unlock()
+
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	if account.assistantID == "" {
+		assistantID, err := c.findOrCreateAssistant(ctx, account.client)
+		if err != nil {
+			return nil, "", err
+		}
+		account.assistantID = assistantID
+	}
+
+	fmt.Printf("Using user's own API key for user %s\n", userID)
+	return account.client, account.assistantID, nil
+}
+
+// findOrCreateAssistant finds the assistant by name in an account, creating it if needed
+func (c *Client) findOrCreateAssistant(ctx context.Context, api *openai.Client) (string, error) {
+	assistants, err := api.Beta.Assistants.List(ctx, openai.BetaAssistantListParams{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list assistants: %w", err)
+	}
+
+	for _, assistant := range assistants.Data {
+		if assistant.Name == c.assistantName {
+			return assistant.ID, nil
+		}
+	}
+
+	fmt.Printf("Creating assistant %q in user's account\n", c.assistantName)
+	assistant, err := api.Beta.Assistants.New(ctx, openai.BetaAssistantNewParams{
+		Model:        openai.F(openai.ChatModelGPT4o),
+		Name:         openai.F(c.assistantName),
+		Instructions: openai.F(assistantInstructions),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create assistant: %w", err)
+	}
+
+	return assistant.ID, nil
+}
@@ -0,0 +1,31 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// TranscribeAudio converts speech to text with Whisper. The filename's extension tells the
+// API which audio format the data is in.
+func (c *Client) TranscribeAudio(ctx context.Context, userID string, audioData []byte, filename, contentType string) (string, error) {
+	// Use the same account as the extraction that follows
+	api, _, err := c.accountFor(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Transcribing %s (%d bytes) for user %s\n", filename, len(audioData), userID)
+	transcription, err := api.Audio.Transcriptions.New(ctx, openai.AudioTranscriptionNewParams{
+		File:  openai.FileParam(bytes.NewReader(audioData), filename, contentType),
+		Model: openai.F(openai.AudioModelWhisper1),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	fmt.Printf("Transcribed %d characters for user %s\n", len(transcription.Text), userID)
+	return transcription.Text, nil
+}
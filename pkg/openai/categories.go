@@ -0,0 +1,27 @@
+package openai
+
+import (
+	"slices"
+	"strings"
+)
+
+// categoryList lists the kinds of events the assistant classifies events into, as it's told
+const categoryList = "work, meeting, conference, education, sports, concert, theatre, movie, " +
+	"exhibition, festival, party, family, medical, travel, holiday, other"
+
+// Categories are the kinds of events the assistant classifies events into
+var Categories = strings.Split(categoryList, ", ")
+
+// validCategories keeps the known categories the assistant chose, lower case and without
+// repetitions
+func validCategories(categories []string) []string {
+	var valid []string
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if !slices.Contains(Categories, category) || slices.Contains(valid, category) {
+			continue
+		}
+		valid = append(valid, category)
+	}
+	return valid
+}
@@ -1,30 +1,61 @@
 package openai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"golang.org/x/sync/errgroup"
 
 	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/imaging"
+	"calendar-assistant/pkg/language"
 )
 
 // Client represents an OpenAI API client
 type Client struct {
-	client        *openai.Client
-	assistantID   string
-	assistantName string
-	threadCache   map[string]string // Map of userID -> threadID
-	cacheMutex    sync.RWMutex      // Mutex to protect the thread cache
+	client         *openai.Client
+	assistantID    string
+	assistantName  string
+	glossary       string                  // Deployment-specific vocabulary added to every run
+	prompts        *template.Template      // Templates of the messages sent to the assistant
+	promptDefaults promptDefaults          // Deployment-wide values available to the templates
+	threadCache    map[string]cachedThread // Map of userID -> thread
+	threadHolds    map[string]int          // Map of thread ID -> requests using it, which keep it from being rotated
+	cacheMutex     sync.RWMutex            // Mutex to protect the thread cache and holds
+
+	// Bring-your-own-key support
+	keyProvider  func(userID string) string // Returns the user's own API key, or "" to use the operator's
+	userAccounts map[string]*userAccount    // Map of API key -> account
+	accountMutex sync.Mutex                 // Mutex to protect the user accounts
+
+	usageRecorder func(userID string, promptTokens, completionTokens int64) // Receives the token usage of runs billed to the operator
 }
 
+// cachedThread is a user's OpenAI thread and when it was created
+type cachedThread struct {
+	ID         string
+	CreatedAt  time.Time
+	VerifiedAt time.Time      // When the thread was last confirmed to still exist
+	client     *openai.Client // Client of the account that owns the thread
+}
+
+// threadVerifyInterval is how long a thread is trusted to exist without asking the API again,
+// so preparing a thread ahead of an extraction doesn't cost a second round trip
+const threadVerifyInterval = time.Minute
+
 // Event represents a calendar event
 type Event struct {
 	Title       string    `json:"title"`
@@ -32,10 +63,45 @@ type Event struct {
 	Location    string    `json:"location"`
 	StartTime   time.Time `json:"start_time"`
 	EndTime     time.Time `json:"end_time"`
+	AllDay      bool      `json:"all_day,omitempty"` // The event takes whole days; the times are then at midnight
+
+	Recurrence   *Recurrence `json:"recurrence,omitempty"`       // How the event repeats, nil if it happens once
+	Reminders    []int       `json:"reminders,omitempty"`        // Minutes before the start the source asks to be reminded
+	URL          string      `json:"url,omitempty"`              // Registration, ticket or online meeting link
+	Categories   []string    `json:"categories,omitempty"`       // Kinds of event, from Categories
+	Status       string      `json:"status,omitempty"`           // One of the Status constants, empty if not known
+	Transparency string      `json:"transparency,omitempty"`     // One of the Transparency constants, empty if not known
+	Priority     int         `json:"priority,omitempty"`         // One of the Priority constants or another ICS priority, 0 if not known
+	Outdoor      bool        `json:"outdoor,omitempty"`          // The event takes place outdoors, where the weather matters
+	Flexible     bool        `json:"flexible,omitempty"`         // The source gives a period but no time; StartTime and EndTime span the period
+	Duration     int         `json:"duration_minutes,omitempty"` // Minutes a flexible event takes, 0 if not known
+	Geo          *Geo        `json:"geo,omitempty"`              // Coordinates of the location, set by geocoding rather than extracted
+	UID          string      `json:"uid,omitempty"`              // UID of the event's ICS files, set when it's sent rather than extracted
+	Sequence     int         `json:"sequence,omitempty"`         // Number of times the event was corrected after its first ICS file
+
+	ContentDescription string `json:"content_description,omitempty"` // Plain-language description of an image, if requested
+}
+
+// Geo is a point on the map, in degrees
+type Geo struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ExtractOptions customizes a single extraction
+type ExtractOptions struct {
+	Model    string // Overrides the assistant's model for this run, if set
+	Context  string // Additional text sent along with the input, e.g. a transcript
+	Timezone string // The user's timezone, used for today's date and in prompt templates
+	Language string // The user's language code, available to prompt templates
+
+	InputLanguage string // Detected language code of the input, if known
+	Describe      bool   // Also describe the content of images in plain language, for accessibility
+	Summarize     bool   // Summarize the details of long input instead of copying them
 }
 
 // NewClient creates a new OpenAI client
-func NewClient(cfg *config.Config) *Client {
+func NewClient(cfg *config.Config) (*Client, error) {
 	// Set the beta header for assistants API v2
 	betaOption := option.WithHeader("OpenAI-Beta", "assistants=v2")
 	apiKeyOption := option.WithAPIKey(cfg.OpenAIAPIKey)
@@ -44,47 +110,102 @@ func NewClient(cfg *config.Config) *Client {
 	// Default assistant name - can be configured if needed
 	assistantName := "Calendar Assistant"
 
-	return &Client{
-		client:        client,
-		assistantID:   cfg.OpenAIAssistantID,
-		assistantName: assistantName,
-		threadCache:   make(map[string]string),
+	prompts, err := loadPrompts(cfg.PromptTemplatesDir)
+	if err != nil {
+		return nil, err
 	}
+
+	return &Client{
+		client:         client,
+		assistantID:    cfg.OpenAIAssistantID,
+		assistantName:  assistantName,
+		glossary:       cfg.Glossary,
+		prompts:        prompts,
+		promptDefaults: newPromptDefaults(cfg),
+		threadCache:    make(map[string]cachedThread),
+		threadHolds:    make(map[string]int),
+		userAccounts:   make(map[string]*userAccount),
+	}, nil
 }
 
-// getOrCreateThread gets an existing thread for a user or creates a new one
-func (c *Client) getOrCreateThread(ctx context.Context, userID string) (string, error) {
-	// Check if we have a cached thread for this user
-	c.cacheMutex.RLock()
-	threadID, exists := c.threadCache[userID]
-	c.cacheMutex.RUnlock()
+// getOrCreateThread gets an existing thread for a user or creates a new one. The thread is held
+// until release is called, so RotateThreads doesn't delete it while a run uses it.
+func (c *Client) getOrCreateThread(ctx context.Context, api *openai.Client, userID string) (threadID string, release func(), err error) {
+	// Check if we have a cached thread for this user. Threads can't be shared between accounts,
+	// so a key change means a new thread.
+	c.cacheMutex.Lock()
+	cached, exists := c.threadCache[userID]
+	exists = exists && cached.client == api
+	if exists {
+		c.threadHolds[cached.ID]++
+	}
+	c.cacheMutex.Unlock()
 
 	if exists {
-		fmt.Printf("Using cached thread %s for user %s\n", threadID, userID)
+		fmt.Printf("Using cached thread %s for user %s\n", cached.ID, userID)
+		if time.Since(cached.VerifiedAt) < threadVerifyInterval {
+			return cached.ID, c.threadRelease(cached.ID), nil
+		}
+
 		// Verify that the thread still exists
-		_, err := c.client.Beta.Threads.Get(ctx, threadID)
+		_, err := api.Beta.Threads.Get(ctx, cached.ID)
 		if err == nil {
 			// Thread exists, we can use it
-			return threadID, nil
+			c.cacheMutex.Lock()
+			if current, ok := c.threadCache[userID]; ok && current.ID == cached.ID {
+				current.VerifiedAt = time.Now()
+				c.threadCache[userID] = current
+			}
+			c.cacheMutex.Unlock()
+			return cached.ID, c.threadRelease(cached.ID), nil
 		}
-		fmt.Printf("Cached thread %s for user %s no longer exists: %v\n", threadID, userID, err)
+		c.threadRelease(cached.ID)()
+		fmt.Printf("Cached thread %s for user %s no longer exists: %v\n", cached.ID, userID, err)
 		// If there's an error, the thread might not exist, so we'll create a new one
 	}
 
 	// Create a new thread
 	fmt.Printf("Creating a new thread for user %s\n", userID)
-	thread, err := c.client.Beta.Threads.New(ctx, openai.BetaThreadNewParams{})
+	thread, err := api.Beta.Threads.New(ctx, openai.BetaThreadNewParams{})
 	if err != nil {
-		return "", fmt.Errorf("failed to create thread: %w", err)
+		return "", nil, fmt.Errorf("failed to create thread: %w", err)
 	}
 
 	// Cache the thread ID
 	c.cacheMutex.Lock()
-	c.threadCache[userID] = thread.ID
+	c.threadCache[userID] = cachedThread{ID: thread.ID, CreatedAt: time.Now(), VerifiedAt: time.Now(), client: api}
+	c.threadHolds[thread.ID]++
 	c.cacheMutex.Unlock()
 
 	fmt.Printf("Created and cached thread %s for user %s\n", thread.ID, userID)
-	return thread.ID, nil
+	return thread.ID, c.threadRelease(thread.ID), nil
+}
+
+// threadRelease returns a function that releases a hold on a thread taken by getOrCreateThread
+func (c *Client) threadRelease(threadID string) func() {
+	return func() {
+		c.cacheMutex.Lock()
+		defer c.cacheMutex.Unlock()
+
+		if c.threadHolds[threadID]--; c.threadHolds[threadID] <= 0 {
+			delete(c.threadHolds, threadID)
+		}
+	}
+}
+
+// PrepareThread makes sure the user's account and thread are ready, so it can run while the
+// input is still being downloaded
+func (c *Client) PrepareThread(ctx context.Context, userID string) error {
+	api, _, err := c.accountFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	_, release, err := c.getOrCreateThread(ctx, api, userID)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
 }
 
 // InitializeAssistant creates or retrieves the assistant
@@ -120,9 +241,8 @@ func (c *Client) InitializeAssistant(ctx context.Context) error {
 	return nil
 }
 
-// formatCurrentDate returns the current date in a user-friendly format
-func formatCurrentDate() string {
-	now := time.Now()
+// formatDate returns a date in a user-friendly format
+func formatDate(now time.Time) string {
 	return fmt.Sprintf("%s, %s %d, %d",
 		now.Weekday().String(),
 		now.Month().String(),
@@ -131,27 +251,43 @@ func formatCurrentDate() string {
 }
 
 // ExtractEventFromText extracts event information from text
-func (c *Client) ExtractEventFromText(ctx context.Context, userID string, text string) (*Event, error) {
-	// Initialize assistant if needed
-	if err := c.InitializeAssistant(ctx); err != nil {
+func (c *Client) ExtractEventFromText(ctx context.Context, userID string, text string, opts ExtractOptions) (*Event, error) {
+	// The user already has the text, so there's nothing to describe
+	opts.Describe = false
+
+	// Long text such as a pasted brochure is condensed, and its details summarized
+	text = condenseText(text)
+	if utf8.RuneCountInString(text)+utf8.RuneCountInString(opts.Context) > longInputLength {
+		fmt.Printf("Input is %d characters long, asking for a summary\n", utf8.RuneCountInString(text))
+		opts.Summarize = true
+	}
+
+	// Resolve the account (operator's or the user's own key) and its assistant
+	api, assistantID, err := c.accountFor(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
 
 	// Get or create a thread for this user
-	threadID, err := c.getOrCreateThread(ctx, userID)
+	threadID, release, err := c.getOrCreateThread(ctx, api, userID)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	// Add current date information to the message
-	currentDate := formatCurrentDate()
-	messageText := fmt.Sprintf("Today is %s. Please extract event information from the following text:\n\n%s", currentDate, text)
+	// Render the prompt with the current date and the input
+	data := c.promptData(opts)
+	data.Text = text
+	messageText, err := c.renderPrompt(promptText, data)
+	if err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("Sending message with current date: %s\n", currentDate)
+	fmt.Printf("Sending message with current date: %s\n", data.Date)
 
 	// Add a message to the thread
 	role := openai.BetaThreadMessageNewParamsRoleUser
-	_, err = c.client.Beta.Threads.Messages.New(ctx, threadID, openai.BetaThreadMessageNewParams{
+	_, err = api.Beta.Threads.Messages.New(ctx, threadID, openai.BetaThreadMessageNewParams{
 		Role: openai.F(role),
 		Content: openai.F([]openai.MessageContentPartParamUnion{
 			openai.TextContentBlockParam{
@@ -165,15 +301,13 @@ func (c *Client) ExtractEventFromText(ctx context.Context, userID string, text s
 	}
 
 	// Run the assistant
-	run, err := c.client.Beta.Threads.Runs.New(ctx, threadID, openai.BetaThreadRunNewParams{
-		AssistantID: openai.F(c.assistantID),
-	})
+	run, err := api.Beta.Threads.Runs.New(ctx, threadID, c.runParams(assistantID, opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create run: %w", err)
 	}
 
 	// Poll for completion
-	event, err := c.pollForCompletion(ctx, threadID, run.ID)
+	event, err := c.pollForCompletion(ctx, api, userID, threadID, run.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -182,79 +316,121 @@ func (c *Client) ExtractEventFromText(ctx context.Context, userID string, text s
 }
 
 // ExtractEventFromImage extracts event information from an image
-func (c *Client) ExtractEventFromImage(ctx context.Context, userID string, imageData []byte) (*Event, error) {
-	// Initialize assistant if needed
-	if err := c.InitializeAssistant(ctx); err != nil {
-		return nil, err
+func (c *Client) ExtractEventFromImage(ctx context.Context, userID string, imageData []byte, opts ExtractOptions) (*Event, error) {
+	return c.ExtractEventFromImages(ctx, userID, [][]byte{imageData}, opts)
+}
+
+// ExtractEventFromImages extracts a single event from one or more images, such as frames of a video
+func (c *Client) ExtractEventFromImages(ctx context.Context, userID string, images [][]byte, opts ExtractOptions) (*Event, error) {
+	// Detect the actual image formats so the uploads get the right extensions
+	uploads := make([]imageUpload, len(images))
+	for i, imageData := range images {
+		format, err := imaging.Detect(imageData)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Detected image format: %s\n", format.MIME)
+		uploads[i] = imageUpload{reader: bytes.NewReader(imageData), format: format}
 	}
 
-	// Get or create a thread for this user
-	threadID, err := c.getOrCreateThread(ctx, userID)
+	return c.extractEventFromUploads(ctx, userID, uploads, opts)
+}
+
+// ExtractEventFromImageReader extracts an event from an image that is streamed from r, so a large
+// download can go straight into the upload without being buffered first
+func (c *Client) ExtractEventFromImageReader(ctx context.Context, userID string, r io.Reader, opts ExtractOptions) (*Event, error) {
+	// The format can be detected from the first bytes without reading the whole image
+	buffered := bufio.NewReaderSize(r, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	format, err := imaging.Detect(head)
 	if err != nil {
 		return nil, err
 	}
+	fmt.Printf("Detected streamed image format: %s\n", format.MIME)
 
-	// Create a temporary file with a proper extension
-	tempFile, err := os.CreateTemp("", "event-image-*.png")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temporary file: %w", err)
-	}
-	defer os.Remove(tempFile.Name()) // Clean up the file when we're done
+	return c.extractEventFromUploads(ctx, userID, []imageUpload{{reader: buffered, format: format}}, opts)
+}
+
+// sniffLength is how many bytes content type detection looks at
+const sniffLength = 512
 
-	// Write the image data to the temporary file
-	if _, err := tempFile.Write(imageData); err != nil {
-		tempFile.Close()
-		return nil, fmt.Errorf("failed to write image data to temporary file: %w", err)
+// imageUpload is an image to upload and its format
+type imageUpload struct {
+	reader io.Reader
+	format imaging.Format
+}
+
+// extractEventFromUploads uploads images and runs the assistant on them
+func (c *Client) extractEventFromUploads(ctx context.Context, userID string, uploads []imageUpload, opts ExtractOptions) (*Event, error) {
+	// Resolve the account (operator's or the user's own key) and its assistant
+	api, assistantID, err := c.accountFor(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	tempFile.Close()
 
-	// Reopen the file for reading
-	file, err := os.Open(tempFile.Name())
+	// Render the prompt with the current date
+	data := c.promptData(opts)
+	data.ImageCount = len(uploads)
+	messageText, err := c.renderPrompt(promptImage, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open temporary file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	// Upload the image as a file
-	fmt.Printf("Uploading image file: %s with purpose: %s\n", tempFile.Name(), openai.FilePurposeVision)
-	fileObj, err := c.client.Files.New(ctx, openai.FileNewParams{
-		File:    openai.F[io.Reader](file),
-		Purpose: openai.F(openai.FilePurposeVision),
+	// Set up the thread and upload the images concurrently
+	var threadID string
+	var release func()
+	fileIDs := make([]string, len(uploads))
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		threadID, release, err = c.getOrCreateThread(gctx, api, userID)
+		return err
 	})
+	for i, upload := range uploads {
+		i, upload := i, upload
+		g.Go(func() error {
+			var err error
+			fileIDs[i], err = uploadImage(gctx, api, upload)
+			return err
+		})
+	}
+	err = g.Wait()
+	if release != nil {
+		defer release()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload image: %w", err)
+		return nil, err
 	}
 
-	// Print file information for debugging
-	fmt.Printf("Uploaded file with ID: %s, Filename: %s, Purpose: %s\n",
-		fileObj.ID, tempFile.Name(), fileObj.Purpose)
+	fmt.Printf("Sending message with current date: %s\n", data.Date)
 
-	// Add a message with the image to the thread
-	role := openai.BetaThreadMessageNewParamsRoleUser
-	fmt.Println("Creating message with image content...")
-
-	// Add current date information to the message
-	currentDate := formatCurrentDate()
-	messageText := fmt.Sprintf("Today is %s. Please extract event information from this image.", currentDate)
+	content := []openai.MessageContentPartParamUnion{
+		openai.TextContentBlockParam{
+			Type: openai.F(openai.TextContentBlockParamTypeText),
+			Text: openai.F(messageText),
+		},
+	}
 
-	fmt.Printf("Sending message with current date: %s\n", currentDate)
+	// Reference the uploaded images in the message
+	for _, fileID := range fileIDs {
+		content = append(content, openai.ImageFileContentBlockParam{
+			Type: openai.F(openai.ImageFileContentBlockTypeImageFile),
+			ImageFile: openai.F(openai.ImageFileParam{
+				FileID: openai.F(fileID),
+				Detail: openai.F(openai.ImageFileDetailHigh),
+			}),
+		})
+	}
 
-	// Create the message with image content
-	message, err := c.client.Beta.Threads.Messages.New(ctx, threadID, openai.BetaThreadMessageNewParams{
-		Role: openai.F(role),
-		Content: openai.F([]openai.MessageContentPartParamUnion{
-			openai.TextContentBlockParam{
-				Type: openai.F(openai.TextContentBlockParamTypeText),
-				Text: openai.F(messageText),
-			},
-			openai.ImageFileContentBlockParam{
-				Type: openai.F(openai.ImageFileContentBlockTypeImageFile),
-				ImageFile: openai.F(openai.ImageFileParam{
-					FileID: openai.F(fileObj.ID),
-					Detail: openai.F(openai.ImageFileDetailHigh),
-				}),
-			},
-		}),
+	// Add a message with the images to the thread
+	role := openai.BetaThreadMessageNewParamsRoleUser
+	fmt.Println("Creating message with image content...")
+	message, err := api.Beta.Threads.Messages.New(ctx, threadID, openai.BetaThreadMessageNewParams{
+		Role:    openai.F(role),
+		Content: openai.F(content),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message with image: %w", err)
@@ -262,17 +438,15 @@ func (c *Client) ExtractEventFromImage(ctx context.Context, userID string, image
 	fmt.Printf("Created message with ID: %s\n", message.ID)
 
 	// Run the assistant
-	fmt.Printf("Running assistant with ID: %s on thread: %s\n", c.assistantID, threadID)
-	run, err := c.client.Beta.Threads.Runs.New(ctx, threadID, openai.BetaThreadRunNewParams{
-		AssistantID: openai.F(c.assistantID),
-	})
+	fmt.Printf("Running assistant with ID: %s on thread: %s\n", assistantID, threadID)
+	run, err := api.Beta.Threads.Runs.New(ctx, threadID, c.runParams(assistantID, opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create run: %w", err)
 	}
 	fmt.Printf("Created run with ID: %s\n", run.ID)
 
 	// Poll for completion
-	event, err := c.pollForCompletion(ctx, threadID, run.ID)
+	event, err := c.pollForCompletion(ctx, api, userID, threadID, run.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -280,10 +454,61 @@ func (c *Client) ExtractEventFromImage(ctx context.Context, userID string, image
 	return event, nil
 }
 
+// uploadImage uploads an image for use with vision and returns its file ID
+func uploadImage(ctx context.Context, api *openai.Client, upload imageUpload) (string, error) {
+	// The filename's extension tells the API the image format
+	filename := "event-image" + upload.format.Extension
+
+	fmt.Printf("Uploading image file: %s with purpose: %s\n", filename, openai.FilePurposeVision)
+	fileObj, err := api.Files.New(ctx, openai.FileNewParams{
+		File:    openai.FileParam(upload.reader, filename, upload.format.MIME),
+		Purpose: openai.F(openai.FilePurposeVision),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload image: %w", err)
+	}
+
+	// Print file information for debugging
+	fmt.Printf("Uploaded file with ID: %s, Filename: %s, Purpose: %s\n",
+		fileObj.ID, filename, fileObj.Purpose)
+
+	return fileObj.ID, nil
+}
+
+// runParams builds the parameters for a run of the assistant
+func (c *Client) runParams(assistantID string, opts ExtractOptions) openai.BetaThreadRunNewParams {
+	params := openai.BetaThreadRunNewParams{
+		AssistantID: openai.F(assistantID),
+	}
+
+	// Sent with every run so they also apply to existing assistants and users' own accounts
+	instructions := sameLanguageInstructions
+	if name := language.Name(opts.InputLanguage); name != "" {
+		instructions += fmt.Sprintf(" The source is in %s.", name)
+	}
+	instructions += "\n\n" + eventFieldsInstructions
+	if c.glossary != "" {
+		instructions += "\n\n" + glossaryInstructions + c.glossary
+	}
+	if opts.Describe {
+		instructions += "\n\n" + describeInstructions
+	}
+	if opts.Summarize {
+		instructions += "\n\n" + summarizeInstructions
+	}
+	params.AdditionalInstructions = openai.F(instructions)
+
+	if opts.Model != "" {
+		fmt.Printf("Overriding model for this run: %s\n", opts.Model)
+		params.Model = openai.F(openai.ChatModel(opts.Model))
+	}
+	return params
+}
+
 // ClearThreadForUser clears the thread for a specific user
 func (c *Client) ClearThreadForUser(ctx context.Context, userID string) error {
 	c.cacheMutex.RLock()
-	threadID, exists := c.threadCache[userID]
+	cached, exists := c.threadCache[userID]
 	c.cacheMutex.RUnlock()
 
 	if !exists {
@@ -295,12 +520,50 @@ func (c *Client) ClearThreadForUser(ctx context.Context, userID string) error {
 	delete(c.threadCache, userID)
 	c.cacheMutex.Unlock()
 
-	fmt.Printf("Cleared thread %s for user %s from cache\n", threadID, userID)
+	fmt.Printf("Cleared thread %s for user %s from cache\n", cached.ID, userID)
 	return nil
 }
 
+// RotateThreads deletes threads older than maxAge so the next request starts a fresh one.
+// Threads a request still uses are left for the next rotation. It returns the number of
+// threads rotated.
+func (c *Client) RotateThreads(ctx context.Context, maxAge time.Duration) int {
+	cutoff := time.Now().Add(-maxAge)
+
+	// Collect the expired threads and drop them from the cache
+	c.cacheMutex.Lock()
+	var expired []cachedThread
+	for userID, cached := range c.threadCache {
+		if cached.CreatedAt.Before(cutoff) && c.threadHolds[cached.ID] == 0 {
+			expired = append(expired, cached)
+			delete(c.threadCache, userID)
+		}
+	}
+	c.cacheMutex.Unlock()
+
+	// Delete them on the OpenAI side too so they don't accumulate in the account
+	for _, cached := range expired {
+		if _, err := cached.client.Beta.Threads.Delete(ctx, cached.ID); err != nil {
+			fmt.Printf("Failed to delete rotated thread %s: %v\n", cached.ID, err)
+			continue
+		}
+		fmt.Printf("Deleted rotated thread %s\n", cached.ID)
+	}
+
+	return len(expired)
+}
+
 // pollForCompletion polls for the completion of a run and extracts the event information
-func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string) (*Event, error) {
+func (c *Client) pollForCompletion(ctx context.Context, api *openai.Client, userID, threadID, runID string) (*Event, error) {
+	reply, err := c.waitForReply(ctx, api, userID, threadID, runID)
+	if err != nil {
+		return nil, err
+	}
+	return parseEvent(reply)
+}
+
+// waitForReply polls for the completion of a run and returns the text of the assistant's reply
+func (c *Client) waitForReply(ctx context.Context, api *openai.Client, userID, threadID, runID string) (string, error) {
 	fmt.Printf("Starting to poll for completion of run %s on thread %s\n", runID, threadID)
 	pollCount := 0
 
@@ -309,9 +572,9 @@ func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string)
 		pollCount++
 		fmt.Printf("Poll attempt #%d for run %s\n", pollCount, runID)
 
-		run, err := c.client.Beta.Threads.Runs.Get(ctx, threadID, runID)
+		run, err := api.Beta.Threads.Runs.Get(ctx, threadID, runID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve run: %w", err)
+			return "", fmt.Errorf("failed to retrieve run: %w", err)
 		}
 
 		fmt.Printf("Run status: %s\n", run.Status)
@@ -319,26 +582,27 @@ func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string)
 		switch run.Status {
 		case openai.RunStatusCompleted:
 			fmt.Println("Run completed successfully, retrieving messages...")
+			c.recordUsage(api, userID, run.Usage)
 			// Get the messages
 			order := openai.BetaThreadMessageListParamsOrderDesc
-			messages, err := c.client.Beta.Threads.Messages.List(ctx, threadID, openai.BetaThreadMessageListParams{
+			messages, err := api.Beta.Threads.Messages.List(ctx, threadID, openai.BetaThreadMessageListParams{
 				Order: openai.F(order),
 				Limit: openai.F(int64(1)),
 			})
 			if err != nil {
-				return nil, fmt.Errorf("failed to list messages: %w", err)
+				return "", fmt.Errorf("failed to list messages: %w", err)
 			}
 
 			fmt.Printf("Retrieved %d messages\n", len(messages.Data))
 
 			if len(messages.Data) == 0 {
-				return nil, fmt.Errorf("no messages found")
+				return "", fmt.Errorf("no messages found")
 			}
 
 			// Extract the event information from the assistant's response
 			assistantMessage := messages.Data[0]
 			if assistantMessage.Role != openai.MessageRoleAssistant {
-				return nil, fmt.Errorf("unexpected message role: %s", assistantMessage.Role)
+				return "", fmt.Errorf("unexpected message role: %s", assistantMessage.Role)
 			}
 
 			// Extract JSON from the message content
@@ -353,7 +617,7 @@ func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string)
 			}
 
 			if jsonContent == "" {
-				return nil, fmt.Errorf("no text content found in assistant message")
+				return "", fmt.Errorf("no text content found in assistant message")
 			}
 
 			// Log the full response from the assistant
@@ -361,106 +625,15 @@ func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string)
 			fmt.Println(jsonContent)
 			fmt.Println("=========================")
 
-			// Parse the JSON
-			var eventData struct {
-				Title       string `json:"title"`
-				Description string `json:"description"`
-				Location    string `json:"location"`
-				StartTime   string `json:"start_time"`
-				EndTime     string `json:"end_time"`
-			}
-
-			// Try to extract JSON from the text
-			// Look for JSON object markers
-			startIdx := bytes.IndexByte([]byte(jsonContent), '{')
-			endIdx := bytes.LastIndexByte([]byte(jsonContent), '}')
-
-			if startIdx >= 0 && endIdx > startIdx {
-				fmt.Printf("Found JSON object from index %d to %d\n", startIdx, endIdx)
-				jsonContent = jsonContent[startIdx : endIdx+1]
-				fmt.Printf("Extracted JSON: %s\n", jsonContent)
-			} else {
-				fmt.Println("Warning: Could not find JSON object markers in the response")
-			}
-
-			if err := json.Unmarshal([]byte(jsonContent), &eventData); err != nil {
-				fmt.Printf("JSON unmarshal error: %v\n", err)
-				return nil, fmt.Errorf("failed to parse event data: %w", err)
-			}
-
-			// Print the extracted data for debugging
-			fmt.Printf("Extracted event data: %+v\n", eventData)
-
-			// Parse the times with fallback to current time if empty or invalid
-			var startTime, endTime time.Time
-			now := time.Now()
-
-			if eventData.StartTime == "" {
-				startTime = now
-				fmt.Println("Warning: Start time was empty, using current time")
-			} else {
-				var err error
-				startTime, err = time.Parse(time.RFC3339, eventData.StartTime)
-				if err != nil {
-					fmt.Printf("Warning: Failed to parse start time '%s': %v, using current time\n",
-						eventData.StartTime, err)
-					startTime = now
-				} else {
-					// Check if this might be an all-day event (time at midnight)
-					if startTime.Hour() == 0 && startTime.Minute() == 0 && startTime.Second() == 0 {
-						fmt.Println("Detected possible all-day event (start time at midnight)")
-					}
-				}
-			}
-
-			if eventData.EndTime == "" {
-				// Default to start time + 1 hour if end time is empty
-				endTime = startTime.Add(1 * time.Hour)
-				fmt.Println("Warning: End time was empty, using start time + 1 hour")
-
-				// For all-day events, set end time to midnight of the next day
-				if startTime.Hour() == 0 && startTime.Minute() == 0 && startTime.Second() == 0 {
-					// Set to midnight of the next day
-					endTime = time.Date(
-						startTime.Year(), startTime.Month(), startTime.Day()+1,
-						0, 0, 0, 0, startTime.Location(),
-					)
-					fmt.Println("All-day event detected, setting end time to midnight of the next day")
-				}
-			} else {
-				var err error
-				endTime, err = time.Parse(time.RFC3339, eventData.EndTime)
-				if err != nil {
-					fmt.Printf("Warning: Failed to parse end time '%s': %v, using start time + 1 hour\n",
-						eventData.EndTime, err)
-					endTime = startTime.Add(1 * time.Hour)
-
-					// For all-day events, set end time to midnight of the next day
-					if startTime.Hour() == 0 && startTime.Minute() == 0 && startTime.Second() == 0 {
-						// Set to midnight of the next day
-						endTime = time.Date(
-							startTime.Year(), startTime.Month(), startTime.Day()+1,
-							0, 0, 0, 0, startTime.Location(),
-						)
-						fmt.Println("All-day event detected, setting end time to midnight of the next day")
-					}
-				}
-			}
-
-			return &Event{
-				Title:       eventData.Title,
-				Description: eventData.Description,
-				Location:    eventData.Location,
-				StartTime:   startTime,
-				EndTime:     endTime,
-			}, nil
+			return jsonContent, nil
 
 		case openai.RunStatusFailed, openai.RunStatusCancelled, openai.RunStatusExpired:
-			return nil, fmt.Errorf("run failed with status: %s", run.Status)
+			c.recordUsage(api, userID, run.Usage)
+			return "", fmt.Errorf("run failed with status: %s", run.Status)
 
 		case openai.RunStatusRequiresAction:
 			// Handle required actions if needed
-			return nil, fmt.Errorf("run requires action, not implemented")
+			return "", fmt.Errorf("run requires action, not implemented")
 
 		default:
 			// Wait and check again
@@ -468,3 +641,227 @@ func (c *Client) pollForCompletion(ctx context.Context, threadID, runID string)
 		}
 	}
 }
+
+// maxFlexibleMinutes is the longest a flexible event can take, as free time is found within a day
+const maxFlexibleMinutes = 12 * 60
+
+// parseEvent parses the event in the JSON reply of the assistant
+func parseEvent(jsonContent string) (*Event, error) {
+	// Parse the JSON
+	var eventData struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Location    string `json:"location"`
+		StartTime   string `json:"start_time"`
+		EndTime     string `json:"end_time"`
+		AllDay      *bool  `json:"all_day"`
+
+		Recurrence *recurrenceData `json:"recurrence"`
+		Reminders  []int           `json:"reminders"`
+		URL        string          `json:"url"`
+		Categories []string        `json:"categories"`
+		Status     string          `json:"status"`
+		Busy       string          `json:"busy"`
+		Priority   json.RawMessage `json:"priority"`
+		Outdoor    bool            `json:"outdoor"`
+		Flexible   bool            `json:"flexible"`
+		Duration   int             `json:"duration_minutes"`
+
+		ContentDescription string `json:"content_description"`
+	}
+
+	// Try to extract JSON from the text
+	// Look for JSON object markers
+	startIdx := bytes.IndexByte([]byte(jsonContent), '{')
+	endIdx := bytes.LastIndexByte([]byte(jsonContent), '}')
+
+	if startIdx >= 0 && endIdx > startIdx {
+		fmt.Printf("Found JSON object from index %d to %d\n", startIdx, endIdx)
+		jsonContent = jsonContent[startIdx : endIdx+1]
+		fmt.Printf("Extracted JSON: %s\n", jsonContent)
+	} else {
+		fmt.Println("Warning: Could not find JSON object markers in the response")
+	}
+
+	if err := json.Unmarshal([]byte(jsonContent), &eventData); err != nil {
+		fmt.Printf("JSON unmarshal error: %v\n", err)
+		return nil, fmt.Errorf("failed to parse event data: %w", err)
+	}
+
+	// Print the extracted data for debugging
+	fmt.Printf("Extracted event data: %+v\n", eventData)
+
+	// Parse the times with fallback to current time if empty or invalid
+	var startTime, endTime time.Time
+	now := time.Now()
+
+	if eventData.StartTime == "" {
+		startTime = now
+		fmt.Println("Warning: Start time was empty, using current time")
+	} else {
+		var err error
+		startTime, err = time.Parse(time.RFC3339, eventData.StartTime)
+		if err != nil {
+			fmt.Printf("Warning: Failed to parse start time '%s': %v, using current time\n",
+				eventData.StartTime, err)
+			startTime = now
+		}
+	}
+
+	// Assistants that don't set all_day yet mark all-day events with a midnight start
+	allDay := startTime.Hour() == 0 && startTime.Minute() == 0 && startTime.Second() == 0
+	if eventData.AllDay != nil {
+		allDay = *eventData.AllDay
+	}
+	if eventData.Flexible {
+		allDay = false // The period is where a time is still to be found
+	}
+	if allDay {
+		fmt.Println("All-day event, using midnight as the start time")
+		startTime = time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, startTime.Location())
+	}
+
+	if eventData.EndTime == "" {
+		// Default to start time + 1 hour if end time is empty
+		endTime = startTime.Add(1 * time.Hour)
+		fmt.Println("Warning: End time was empty, using start time + 1 hour")
+	} else {
+		var err error
+		endTime, err = time.Parse(time.RFC3339, eventData.EndTime)
+		if err != nil {
+			fmt.Printf("Warning: Failed to parse end time '%s': %v, using start time + 1 hour\n",
+				eventData.EndTime, err)
+			endTime = startTime.Add(1 * time.Hour)
+		}
+	}
+
+	switch {
+	case allDay:
+		// The end of an all-day event is its last day, the day it starts if the end is unknown
+		endTime = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, endTime.Location())
+		if eventData.EndTime == "" || endTime.Before(startTime) {
+			endTime = startTime
+		}
+	case endTime.Before(startTime) && startTime.Sub(endTime) < 24*time.Hour:
+		// An end before the start on the same date runs past midnight, e.g. 22:00 to 02:00
+		fmt.Println("End time is before the start time, assuming the event ends the next day")
+		endTime = endTime.Add(24 * time.Hour)
+	case eventData.Flexible && !endTime.After(startTime):
+		// A period without an end is the day it starts
+		endTime = startTime.AddDate(0, 0, 1)
+	case !endTime.After(startTime):
+		endTime = startTime.Add(1 * time.Hour)
+	}
+
+	duration := eventData.Duration
+	if !eventData.Flexible || duration < 0 || duration > maxFlexibleMinutes {
+		duration = 0
+	}
+
+	// A broken recurrence leaves a single event rather than failing the extraction
+	recurrence, err := parseRecurrence(eventData.Recurrence)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring recurrence: %v\n", err)
+	}
+
+	return &Event{
+		Title:        truncateRunes(eventData.Title, maxTitleLength),
+		Description:  truncateRunes(eventData.Description, maxDescriptionLength),
+		Location:     truncateRunes(eventData.Location, maxLocationLength),
+		StartTime:    startTime,
+		EndTime:      endTime,
+		AllDay:       allDay,
+		Recurrence:   recurrence,
+		Reminders:    ValidReminders(eventData.Reminders),
+		URL:          eventURL(eventData.URL),
+		Categories:   validCategories(eventData.Categories),
+		Status:       ValidStatus(eventData.Status),
+		Transparency: ValidTransparency(eventData.Busy),
+		Priority:     ParsePriority(strings.Trim(string(eventData.Priority), `"`)),
+		Outdoor:      eventData.Outdoor,
+		Flexible:     eventData.Flexible,
+		Duration:     duration,
+
+		ContentDescription: eventData.ContentDescription,
+	}, nil
+}
+
+// EventJSON writes an event in the JSON format of the assistant's replies, so it can be sent
+// back in full, e.g. to apply a correction without losing the fields the correction doesn't
+// mention
+func EventJSON(event *Event) string {
+	var recurrence *recurrenceData
+	if r := event.Recurrence; r != nil {
+		recurrence = &recurrenceData{Frequency: strings.ToLower(r.Frequency), Interval: r.Interval, Count: r.Count, ByDay: r.ByDay}
+		if !r.Until.IsZero() {
+			recurrence.Until = r.Until.Format("2006-01-02")
+		}
+	}
+	busy := ""
+	switch event.Transparency {
+	case TransparencyBusy:
+		busy = "busy"
+	case TransparencyFree:
+		busy = "free"
+	}
+	priority := ""
+	switch event.Priority {
+	case 0:
+	case PriorityHigh:
+		priority = "high"
+	case PriorityMedium:
+		priority = "medium"
+	case PriorityLow:
+		priority = "low"
+	default:
+		priority = strconv.Itoa(event.Priority)
+	}
+
+	data, _ := json.Marshal(struct {
+		Title       string          `json:"title"`
+		Description string          `json:"description"`
+		Location    string          `json:"location"`
+		StartTime   string          `json:"start_time"`
+		EndTime     string          `json:"end_time"`
+		AllDay      bool            `json:"all_day"`
+		Recurrence  *recurrenceData `json:"recurrence"`
+		Reminders   []int           `json:"reminders"`
+		URL         string          `json:"url"`
+		Categories  []string        `json:"categories"`
+		Status      string          `json:"status"`
+		Busy        string          `json:"busy"`
+		Priority    string          `json:"priority"`
+		Outdoor     bool            `json:"outdoor"`
+		Flexible    bool            `json:"flexible"`
+		Duration    int             `json:"duration_minutes"`
+	}{
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		StartTime:   event.StartTime.Format(time.RFC3339),
+		EndTime:     event.EndTime.Format(time.RFC3339),
+		AllDay:      event.AllDay,
+		Recurrence:  recurrence,
+		Reminders:   event.Reminders,
+		URL:         event.URL,
+		Categories:  event.Categories,
+		Status:      strings.ToLower(event.Status),
+		Busy:        busy,
+		Priority:    priority,
+		Outdoor:     event.Outdoor,
+		Flexible:    event.Flexible,
+		Duration:    event.Duration,
+	})
+	return string(data)
+}
+
+// eventURL returns the link of an event if it's a web link, dropping anything else the
+// assistant put there
+func eventURL(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return link
+}
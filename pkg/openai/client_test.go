@@ -0,0 +1,44 @@
+package openai
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	start := time.Date(2025, time.May, 20, 18, 30, 0, 0, time.UTC)
+	day := time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event *Event
+	}{
+		{"timed", &Event{Title: "Dinner", Description: "With Anna", Location: "Bonn", StartTime: start, EndTime: start.Add(2 * time.Hour)}},
+		{"all day", &Event{Title: "Holiday", AllDay: true, StartTime: day, EndTime: day.AddDate(0, 0, 2)}},
+		{
+			"every field",
+			&Event{
+				Title: "Standup", StartTime: start, EndTime: start.Add(15 * time.Minute),
+				Recurrence: &Recurrence{Frequency: FrequencyWeekly, Interval: 2, Until: day.AddDate(0, 3, 0), ByDay: []string{"MO", "WE"}},
+				Reminders:  []int{10, 60},
+				URL:        "https://example.com/join",
+				Categories: []string{"work"},
+				Status:     StatusTentative, Transparency: TransparencyFree, Priority: PriorityHigh, Outdoor: true,
+			},
+		},
+		{"other priority", &Event{Title: "Chores", StartTime: start, EndTime: start.Add(time.Hour), Priority: 3, Transparency: TransparencyBusy, Status: StatusCancelled}},
+		{"flexible", &Event{Title: "Coffee", StartTime: day, EndTime: day.AddDate(0, 0, 7), Flexible: true, Duration: 60}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEvent(EventJSON(tt.event))
+			if err != nil {
+				t.Fatalf("parseEvent() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.event) {
+				t.Errorf("parseEvent(EventJSON()) = %+v, want %+v", got, tt.event)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the text sent for extraction, in characters
+const (
+	longInputLength = 3000  // Inputs longer than this have their details summarized rather than copied
+	maxInputLength  = 12000 // Inputs longer than this are cut down to their beginning and end
+)
+
+// Limits of the extracted fields, in characters. They keep the description within what calendar
+// apps import, and the title and location short enough for the 1024-character file caption.
+const (
+	maxTitleLength       = 200
+	maxLocationLength    = 300
+	maxDescriptionLength = 2000
+)
+
+// summarizeInstructions ask for a summary of long input, so the reply isn't cut off while
+// copying a whole brochure into the description
+const summarizeInstructions = `The source is long, like a full brochure or programme. Don't copy it into the description:
+summarize the details that matter to someone attending in a few short paragraphs.`
+
+// condenseText drops the blank lines and spacing of pasted text, and cuts text that's still
+// longer than maxInputLength down to its beginning and end, where event details usually are
+func condenseText(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	text = strings.Join(kept, "\n")
+
+	runes := []rune(text)
+	if len(runes) <= maxInputLength {
+		return text
+	}
+	head := maxInputLength * 2 / 3
+	tail := maxInputLength - head
+	return string(runes[:head]) + "\n[…]\n" + string(runes[len(runes)-tail:])
+}
+
+// truncateRunes cuts s to at most limit characters, marking the cut with an ellipsis
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:limit-1])) + "…"
+}
@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// planInstructions replace the single-event reply format for planning runs
+const planInstructions = `This time you are planning rather than extracting. Reply with a single JSON object
+of the form {"events": [...]} and nothing else, where each event uses the usual fields.
+Make each event a timeboxed focus block for one task, with the task as the title and any notes as the description.`
+
+// PlanDay proposes timeboxed focus events for the tasks of a to-do list within the available hours,
+// e.g. "09:00-12:00, 13:00-17:00", or the deployment's working hours if empty
+func (c *Client) PlanDay(ctx context.Context, userID, todo, hours string, opts ExtractOptions) ([]*Event, error) {
+	api, assistantID, err := c.accountFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadID, release, err := c.getOrCreateThread(ctx, api, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	data := c.promptData(opts)
+	data.Text = todo
+	data.AvailableHours = hours
+	messageText, err := c.renderPrompt(promptPlan, data)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = api.Beta.Threads.Messages.New(ctx, threadID, openai.BetaThreadMessageNewParams{
+		Role: openai.F(openai.BetaThreadMessageNewParamsRoleUser),
+		Content: openai.F([]openai.MessageContentPartParamUnion{
+			openai.TextContentBlockParam{
+				Type: openai.F(openai.TextContentBlockParamTypeText),
+				Text: openai.String(messageText),
+			},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	opts.Describe = false
+	params := c.runParams(assistantID, opts)
+	params.AdditionalInstructions = openai.F(params.AdditionalInstructions.Value + "\n\n" + planInstructions)
+	run, err := api.Beta.Threads.Runs.New(ctx, threadID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run: %w", err)
+	}
+
+	reply, err := c.waitForReply(ctx, api, userID, threadID, run.ID)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlan(reply)
+}
+
+// parsePlan parses the events of a planning reply
+func parsePlan(reply string) ([]*Event, error) {
+	startIdx := bytes.IndexByte([]byte(reply), '{')
+	endIdx := bytes.LastIndexByte([]byte(reply), '}')
+	if startIdx < 0 || endIdx <= startIdx {
+		return nil, fmt.Errorf("no plan found in the reply")
+	}
+
+	var plan struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	if err := json.Unmarshal([]byte(reply[startIdx:endIdx+1]), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	var events []*Event
+	for _, raw := range plan.Events {
+		event, err := parseEvent(string(raw))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("none of the tasks fit into the available hours")
+	}
+	return events, nil
+}
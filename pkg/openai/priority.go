@@ -0,0 +1,31 @@
+package openai
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Priorities of an event, as in an ICS PRIORITY where 1 is the highest and 9 the lowest
+const (
+	PriorityHigh   = 1
+	PriorityMedium = 5
+	PriorityLow    = 9
+)
+
+// ParsePriority returns the priority the assistant or a calendar file gave, as a name or an ICS
+// value, or 0 if it didn't give one
+func ParsePriority(value string) int {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "high":
+		return PriorityHigh
+	case "medium", "normal":
+		return PriorityMedium
+	case "low":
+		return PriorityLow
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= PriorityHigh && n <= PriorityLow {
+		return n
+	}
+	return 0
+}
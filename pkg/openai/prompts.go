@@ -0,0 +1,116 @@
+package openai
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"calendar-assistant/pkg/config"
+)
+
+// Prompt template names, which are also the file names looked up in the templates directory
+const (
+	promptText  = "text.tmpl"
+	promptImage = "image.tmpl"
+	promptPlan  = "plan.tmpl"
+)
+
+// defaultPrompts are used for any template the deployment doesn't override
+var defaultPrompts = map[string]string{
+	promptText: `Today is {{.Date}}. Please extract event information from the following text:
+
+{{.Text}}{{with .Context}}
+
+Additional context:
+{{.}}{{end}}`,
+	promptImage: `Today is {{.Date}}. {{if gt .ImageCount 1}}These images are frames from the same video. Please extract event information from them.{{else}}Please extract event information from this image.{{end}}{{with .Context}}
+
+Additional context:
+{{.}}{{end}}`,
+	promptPlan: `Today is {{.Date}}. Please plan my focus time today for the following to-do list, within {{with .AvailableHours}}these available hours: {{.}}{{else}}my working hours ({{.WorkingHours}}){{end}}.
+Give each task a realistic timebox, put the most important tasks first, leave short breaks between blocks, and leave out tasks that don't fit.
+
+{{.Text}}`,
+}
+
+// PromptData holds the variables available to prompt templates
+type PromptData struct {
+	Date            string // Today's date in the user's timezone, e.g. "Monday, June 2, 2025"
+	Timezone        string // The user's IANA timezone
+	Language        string // The user's language code
+	WorkingHours    string // Working hours of the deployment, e.g. "09:00-18:00"
+	DefaultDuration string // Duration to assume when the input has no end time, e.g. "1h0m0s"
+	Text            string // The input text (text prompts only)
+	ImageCount      int    // The number of images sent (image prompts only)
+	AvailableHours  string // The hours available for planning, empty for the working hours (plan prompts only)
+	Context         string // Additional context such as a transcript, may be empty
+}
+
+// loadPrompts parses the prompt templates, preferring files in dir over the defaults
+func loadPrompts(dir string) (*template.Template, error) {
+	prompts := template.New("prompts").Option("missingkey=error")
+
+	for name, text := range defaultPrompts {
+		if dir != "" {
+			content, err := os.ReadFile(filepath.Join(dir, name))
+			switch {
+			case err == nil:
+				fmt.Printf("Using prompt template %s from %s\n", name, dir)
+				text = string(content)
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, fmt.Errorf("failed to read prompt template %s: %w", name, err)
+			}
+		}
+
+		if _, err := prompts.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+		}
+	}
+
+	return prompts, nil
+}
+
+// promptData fills in the template variables for an extraction
+func (c *Client) promptData(opts ExtractOptions) PromptData {
+	now := time.Now()
+	if loc, err := time.LoadLocation(opts.Timezone); err == nil && opts.Timezone != "" {
+		now = now.In(loc)
+	}
+
+	return PromptData{
+		Date:            formatDate(now),
+		Timezone:        opts.Timezone,
+		Language:        opts.Language,
+		WorkingHours:    c.promptDefaults.WorkingHours,
+		DefaultDuration: c.promptDefaults.DefaultEventDuration.String(),
+		Context:         opts.Context,
+	}
+}
+
+// renderPrompt executes a prompt template
+func (c *Client) renderPrompt(name string, data PromptData) (string, error) {
+	var buf bytes.Buffer
+	if err := c.prompts.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// promptDefaults are the deployment-wide values available to prompt templates
+type promptDefaults struct {
+	WorkingHours         string
+	DefaultEventDuration time.Duration
+}
+
+// newPromptDefaults takes the prompt template values from the configuration
+func newPromptDefaults(cfg *config.Config) promptDefaults {
+	return promptDefaults{
+		WorkingHours:         cfg.WorkingHours,
+		DefaultEventDuration: cfg.DefaultEventDuration,
+	}
+}
@@ -0,0 +1,103 @@
+package openai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies, as in an ICS RRULE
+const (
+	FrequencyDaily   = "DAILY"
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY"
+	FrequencyYearly  = "YEARLY"
+)
+
+// Recurrence describes how an event repeats
+type Recurrence struct {
+	Frequency string    `json:"frequency"`          // One of the Frequency constants
+	Interval  int       `json:"interval,omitempty"` // Repeat every this many periods, 1 if 0
+	Until     time.Time `json:"until,omitempty"`    // Last day the event may occur on, zero if open-ended
+	Count     int       `json:"count,omitempty"`    // Number of occurrences, 0 if open-ended
+	ByDay     []string  `json:"by_day,omitempty"`   // Weekdays such as TU, or 1MO and -1FR for monthly rules
+}
+
+// byDayPattern matches a weekday of an RRULE BYDAY, optionally with the week of the month
+var byDayPattern = regexp.MustCompile(`^([+-]?[1-5])?(MO|TU|WE|TH|FR|SA|SU)$`)
+
+// recurrenceData is the recurrence as the assistant writes it
+type recurrenceData struct {
+	Frequency string   `json:"frequency"`
+	Interval  int      `json:"interval"`
+	Until     string   `json:"until"`
+	Count     int      `json:"count"`
+	ByDay     []string `json:"by_day"`
+}
+
+// parseRecurrence checks the recurrence written by the assistant, returning nil if the event
+// doesn't repeat
+func parseRecurrence(data *recurrenceData) (*Recurrence, error) {
+	if data == nil || data.Frequency == "" {
+		return nil, nil
+	}
+
+	rec := &Recurrence{
+		Frequency: strings.ToUpper(strings.TrimSpace(data.Frequency)),
+		Interval:  data.Interval,
+		Count:     data.Count,
+	}
+	switch rec.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+	default:
+		return nil, fmt.Errorf("unknown frequency %q", data.Frequency)
+	}
+	if rec.Interval <= 1 {
+		rec.Interval = 0
+	}
+	if rec.Count < 0 {
+		rec.Count = 0
+	}
+
+	if data.Until != "" && rec.Count == 0 {
+		until, err := time.Parse("2006-01-02", data.Until)
+		if err != nil {
+			until, err = time.Parse(time.RFC3339, data.Until)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid end of recurrence %q: %w", data.Until, err)
+		}
+		rec.Until = time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, day := range data.ByDay {
+		day = strings.ToUpper(strings.TrimSpace(day))
+		if !byDayPattern.MatchString(day) {
+			return nil, fmt.Errorf("invalid weekday %q", day)
+		}
+		rec.ByDay = append(rec.ByDay, day)
+	}
+
+	return rec, nil
+}
+
+// Limits of the reminders an event asks for
+const (
+	maxReminders       = 5
+	maxReminderMinutes = 4 * 7 * 24 * 60
+)
+
+// ValidReminders drops reminders the assistant or another calendar app got wrong and repeated ones
+func ValidReminders(minutes []int) []int {
+	var valid []int
+	seen := make(map[int]bool)
+	for _, m := range minutes {
+		if m <= 0 || m > maxReminderMinutes || seen[m] || len(valid) == maxReminders {
+			continue
+		}
+		seen[m] = true
+		valid = append(valid, m)
+	}
+	return valid
+}
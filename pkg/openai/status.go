@@ -0,0 +1,23 @@
+package openai
+
+import "strings"
+
+// Statuses of an event, as in an ICS STATUS
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// ValidStatus returns the status the assistant or a calendar file gave, or "" if it isn't one of
+// the statuses
+func ValidStatus(status string) string {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	switch status {
+	case StatusConfirmed, StatusTentative, StatusCancelled:
+		return status
+	case "CANCELED":
+		return StatusCancelled
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package openai
+
+import "strings"
+
+// Whether an event blocks its time, as in an ICS TRANSP
+const (
+	TransparencyBusy = "OPAQUE"
+	TransparencyFree = "TRANSPARENT"
+)
+
+// ValidTransparency returns whether the assistant or a calendar file said an event blocks its
+// time, or "" if it didn't say
+func ValidTransparency(value string) string {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "BUSY", TransparencyBusy:
+		return TransparencyBusy
+	case "FREE", TransparencyFree:
+		return TransparencyFree
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+)
+
+// maxAttempts is how often a one-off job is tried before it's dropped
+const maxAttempts = 10
+
+// maxRetryDelay caps the growing delay between attempts of a failing job
+const maxRetryDelay = time.Hour
+
+// Handler runs a job. A job whose handler returns an error is tried again later.
+type Handler func(ctx context.Context, job storage.Job) error
+
+// Scheduler runs persistent jobs when they're due: one-off jobs such as a reminder, and
+// recurring jobs such as the nightly cleanup. Jobs are only removed or moved on once their
+// handler succeeded, so every job runs at least once, even across restarts.
+type Scheduler struct {
+	store *storage.Store
+	tick  time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New creates a scheduler that checks for due jobs every tick
+func New(store *storage.Store, tick time.Duration) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		tick:     tick,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of a kind of job. Jobs of kinds without a handler wait until one
+// is registered, e.g. by a newer version of the bot.
+func (s *Scheduler) Handle(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// Schedule stores a one-off job that runs at runAt with payload encoded as JSON. A job of the
+// same kind and key is replaced, unless key is empty.
+func (s *Scheduler) Schedule(kind, key string, payload any, runAt time.Time) (storage.Job, error) {
+	return s.put(storage.Job{Kind: kind, Key: key, RunAt: runAt}, payload)
+}
+
+// Every registers a recurring job that runs fn every interval. It runs right away the first
+// time, and after a restart as soon as its next run is due.
+func (s *Scheduler) Every(kind string, interval time.Duration, fn func(ctx context.Context) error) error {
+	s.Handle(kind, func(ctx context.Context, job storage.Job) error {
+		return fn(ctx)
+	})
+
+	if job, ok := s.store.JobByKey(kind, kind); ok {
+		if job.Interval == interval {
+			return nil
+		}
+		// The interval was reconfigured, so the next run moves with it
+		job.RunAt = job.RunAt.Add(interval - job.Interval)
+		job.Interval = interval
+		_, err := s.store.UpdateJob(job)
+		return err
+	}
+	_, err := s.put(storage.Job{Kind: kind, Key: kind, RunAt: time.Now(), Interval: interval}, nil)
+	return err
+}
+
+// Cancel removes the job of a kind with a key, reporting whether there was one
+func (s *Scheduler) Cancel(kind, key string) (bool, error) {
+	job, ok := s.store.JobByKey(kind, key)
+	if !ok {
+		return false, nil
+	}
+	return s.store.DeleteJob(job.ID)
+}
+
+// put stores a job, replacing the job of the same kind and key
+func (s *Scheduler) put(job storage.Job, payload any) (storage.Job, error) {
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return storage.Job{}, fmt.Errorf("failed to encode payload of %s job: %w", job.Kind, err)
+		}
+		job.Payload = data
+	}
+
+	// The replacement gets a new ID, so a run of the old job that's in progress can't remove it
+	if job.Key != "" {
+		if existing, ok := s.store.JobByKey(job.Kind, job.Key); ok {
+			if _, err := s.store.DeleteJob(existing.ID); err != nil {
+				return storage.Job{}, err
+			}
+		}
+	}
+	return s.store.AddJob(job)
+}
+
+// Run runs due jobs every tick until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs the jobs that are due, one after another
+func (s *Scheduler) RunDue(ctx context.Context) {
+	for _, job := range s.store.DueJobs(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mu.RLock()
+		handler, ok := s.handlers[job.Kind]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		s.finish(job, s.run(ctx, handler, job))
+	}
+}
+
+// run calls a handler, turning a panic into an error so one bad job can't stop the others
+func (s *Scheduler) run(ctx context.Context, handler Handler, job storage.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// finish moves a job on after it ran: a recurring job to its next run, a one-off job out of the
+// store, and a failed job to its next attempt
+func (s *Scheduler) finish(job storage.Job, err error) {
+	now := time.Now()
+	if err != nil {
+		job.Attempts++
+		job.LastError = err.Error()
+		log.Printf("Error running %s job %d (attempt %d): %v", job.Kind, job.ID, job.Attempts, err)
+
+		if job.Interval == 0 && job.Attempts >= maxAttempts {
+			log.Printf("Giving up on %s job %d", job.Kind, job.ID)
+			if _, err := s.store.DeleteJob(job.ID); err != nil {
+				log.Printf("Error deleting %s job %d: %v", job.Kind, job.ID, err)
+			}
+			return
+		}
+		job.RunAt = now.Add(retryDelay(s.tick, job.Attempts))
+		if job.Interval > 0 && job.Interval < job.RunAt.Sub(now) {
+			job.RunAt = now.Add(job.Interval)
+		}
+	} else if job.Interval > 0 {
+		job.Attempts, job.LastError = 0, ""
+		job.RunAt = now.Add(job.Interval)
+	} else {
+		if _, err := s.store.DeleteJob(job.ID); err != nil {
+			log.Printf("Error deleting %s job %d: %v", job.Kind, job.ID, err)
+		}
+		return
+	}
+
+	if _, err := s.store.UpdateJob(job); err != nil {
+		log.Printf("Error updating %s job %d: %v", job.Kind, job.ID, err)
+	}
+}
+
+// retryDelay doubles the delay with every failed attempt, up to maxRetryDelay
+func retryDelay(tick time.Duration, attempts int) time.Duration {
+	delay := tick
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
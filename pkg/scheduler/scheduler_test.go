@@ -0,0 +1,216 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+)
+
+// newTestScheduler returns a scheduler with an empty store in a temporary directory
+func newTestScheduler(t *testing.T) (*Scheduler, *storage.Store) {
+	t.Helper()
+	store, err := storage.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return New(store, time.Minute), store
+}
+
+func TestRunDueOneOff(t *testing.T) {
+	s, store := newTestScheduler(t)
+
+	type payload struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	var got []payload
+	s.Handle("remind", func(ctx context.Context, job storage.Job) error {
+		var p payload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return err
+		}
+		got = append(got, p)
+		return nil
+	})
+
+	if _, err := s.Schedule("remind", "due", payload{ChatID: 1}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if _, err := s.Schedule("remind", "later", payload{ChatID: 2}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	s.RunDue(context.Background())
+	s.RunDue(context.Background())
+
+	if len(got) != 1 || got[0].ChatID != 1 {
+		t.Errorf("ran %+v, want only the due job once", got)
+	}
+	if _, ok := store.JobByKey("remind", "due"); ok {
+		t.Errorf("the job that ran is still stored")
+	}
+	if _, ok := store.JobByKey("remind", "later"); !ok {
+		t.Errorf("the job that isn't due was removed")
+	}
+}
+
+func TestScheduleReplaces(t *testing.T) {
+	s, store := newTestScheduler(t)
+
+	first, err := s.Schedule("digest", "1", nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	second, err := s.Schedule("digest", "1", nil, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("the replacement kept ID %d", first.ID)
+	}
+
+	job, ok := store.JobByKey("digest", "1")
+	if !ok || job.ID != second.ID {
+		t.Errorf("JobByKey() = %+v, %v, want the replacement", job, ok)
+	}
+
+	if cancelled, err := s.Cancel("digest", "1"); err != nil || !cancelled {
+		t.Errorf("Cancel() = %v, %v, want true, nil", cancelled, err)
+	}
+	if cancelled, err := s.Cancel("digest", "1"); err != nil || cancelled {
+		t.Errorf("Cancel() of a cancelled job = %v, %v, want false, nil", cancelled, err)
+	}
+}
+
+func TestRunDueFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		wantErr string
+	}{
+		{"error", func(ctx context.Context, job storage.Job) error { return errors.New("telegram is down") }, "telegram is down"},
+		{"panic", func(ctx context.Context, job storage.Job) error { panic("bad payload") }, "panic: bad payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestScheduler(t)
+			s.Handle("send", tt.handler)
+			if _, err := s.Schedule("send", "1", nil, time.Now().Add(-time.Second)); err != nil {
+				t.Fatalf("Schedule() error = %v", err)
+			}
+
+			before := time.Now()
+			s.RunDue(context.Background())
+
+			job, ok := store.JobByKey("send", "1")
+			if !ok {
+				t.Fatalf("the failed job was removed")
+			}
+			if job.Attempts != 1 || job.LastError != tt.wantErr {
+				t.Errorf("job has %d attempts and error %q, want 1 and %q", job.Attempts, job.LastError, tt.wantErr)
+			}
+			if job.RunAt.Before(before.Add(time.Minute)) {
+				t.Errorf("job runs again at %v, want a tick later", job.RunAt)
+			}
+		})
+	}
+}
+
+func TestRunDueGivesUp(t *testing.T) {
+	s, store := newTestScheduler(t)
+	s.Handle("send", func(ctx context.Context, job storage.Job) error { return errors.New("failed") })
+
+	job, err := s.Schedule("send", "1", nil, time.Now())
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	job.Attempts = maxAttempts - 1
+	job.RunAt = time.Now().Add(-time.Second)
+	if _, err := store.UpdateJob(job); err != nil {
+		t.Fatalf("UpdateJob() error = %v", err)
+	}
+
+	s.RunDue(context.Background())
+	if _, ok := store.JobByKey("send", "1"); ok {
+		t.Errorf("the job is still stored after %d attempts", maxAttempts)
+	}
+}
+
+func TestRunDueWithoutHandler(t *testing.T) {
+	s, store := newTestScheduler(t)
+	if _, err := s.Schedule("unknown", "1", nil, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	// Jobs wait for a newer version of the bot to handle them
+	s.RunDue(context.Background())
+	if job, ok := store.JobByKey("unknown", "1"); !ok || job.Attempts != 0 {
+		t.Errorf("JobByKey() = %+v, %v, want the job untouched", job, ok)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s, store := newTestScheduler(t)
+
+	runs := 0
+	fn := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+	if err := s.Every("cleanup", time.Hour, fn); err != nil {
+		t.Fatalf("Every() error = %v", err)
+	}
+
+	// The first run is right away, and the next one an interval later
+	before := time.Now()
+	s.RunDue(context.Background())
+	s.RunDue(context.Background())
+	if runs != 1 {
+		t.Errorf("ran %d times, want 1", runs)
+	}
+	job, ok := store.JobByKey("cleanup", "cleanup")
+	if !ok {
+		t.Fatalf("the recurring job was removed")
+	}
+	if next := job.RunAt.Sub(before); next < time.Hour || next > time.Hour+time.Minute {
+		t.Errorf("next run in %v, want an hour", next)
+	}
+
+	// Registering it again after a restart keeps its schedule, and a new interval moves it
+	if err := s.Every("cleanup", time.Hour, fn); err != nil {
+		t.Fatalf("Every() error = %v", err)
+	}
+	if again, _ := store.JobByKey("cleanup", "cleanup"); !again.RunAt.Equal(job.RunAt) {
+		t.Errorf("next run moved to %v, want %v", again.RunAt, job.RunAt)
+	}
+	if err := s.Every("cleanup", 2*time.Hour, fn); err != nil {
+		t.Fatalf("Every() error = %v", err)
+	}
+	moved, _ := store.JobByKey("cleanup", "cleanup")
+	if want := job.RunAt.Add(time.Hour); !moved.RunAt.Equal(want) || moved.Interval != 2*time.Hour {
+		t.Errorf("job runs at %v every %v, want %v every 2h", moved.RunAt, moved.Interval, want)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(1m, %d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrCiphertextTooShort is returned when decrypting data that can't contain a nonce
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// Cipher encrypts and decrypts secrets with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a passphrase; the passphrase is hashed to a 256-bit key
+func NewCipher(passphrase string) (*Cipher, error) {
+	key := sha256.Sum256([]byte(passphrase))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create block cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts plaintext and returns it base64-encoded with the nonce prepended
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", ErrCiphertextTooShort
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher("passphrase")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"empty", ""},
+		{"api key", "sk-proj-abcdefghijklmnopqrstuvwxyz0123456789"},
+		{"unicode", "ключ 🔑"},
+		{"long", strings.Repeat("x", 4096)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := c.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if tt.plaintext != "" && strings.Contains(encrypted, tt.plaintext) {
+				t.Errorf("Encrypt() = %q contains the plaintext", encrypted)
+			}
+
+			got, err := c.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != tt.plaintext {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestCipherNonce(t *testing.T) {
+	c, err := NewCipher("passphrase")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	// Each encryption uses a new nonce, so equal keys aren't recognisable in the store
+	first, err := c.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	second, err := c.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if first == second {
+		t.Errorf("Encrypt() returned %q twice, want different ciphertexts", first)
+	}
+}
+
+func TestCipherDecryptErrors(t *testing.T) {
+	c, err := NewCipher("passphrase")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	other, err := NewCipher("another passphrase")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	encrypted, err := c.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	sealed, _ := base64.StdEncoding.DecodeString(encrypted)
+	sealed[len(sealed)-1] ^= 1
+	tampered := base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		cipher     *Cipher
+		ciphertext string
+		wantErr    error // Specific error expected, nil if any error will do
+	}{
+		{"wrong passphrase", other, encrypted, nil},
+		{"tampered", c, tampered, nil},
+		{"not base64", c, "not base64!", nil},
+		{"too short", c, base64.StdEncoding.EncodeToString([]byte("short")), ErrCiphertextTooShort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.ciphertext)
+			if err == nil {
+				t.Fatalf("Decrypt() = %q, want an error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package storage
+
+// SetAPIKey stores a user's encrypted OpenAI API key
+func (s *Store) SetAPIKey(userID, encryptedKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.APIKeys == nil {
+		s.data.APIKeys = make(map[string]string)
+	}
+	s.data.APIKeys[userID] = encryptedKey
+
+	return s.saveLocked()
+}
+
+// APIKey returns a user's encrypted OpenAI API key, or "" if none is stored
+func (s *Store) APIKey(userID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.APIKeys[userID]
+}
+
+// DeleteAPIKey removes a user's OpenAI API key
+func (s *Store) DeleteAPIKey(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.APIKeys[userID]; !exists {
+		return nil
+	}
+	delete(s.data.APIKeys, userID)
+
+	return s.saveLocked()
+}
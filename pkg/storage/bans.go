@@ -0,0 +1,77 @@
+package storage
+
+import "time"
+
+// Ban blocks a user from the bot, permanently or until ExpiresAt
+type Ban struct {
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason,omitempty"`
+	BannedBy  string    `json:"banned_by,omitempty"` // Admin user ID, empty for automatic blocks
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Zero for a permanent ban
+}
+
+// Active reports whether the ban is in effect at now
+func (b Ban) Active(now time.Time) bool {
+	return b.ExpiresAt.IsZero() || now.Before(b.ExpiresAt)
+}
+
+// SetBan stores a ban, replacing any existing ban of the user
+func (s *Store) SetBan(ban Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Bans == nil {
+		s.data.Bans = make(map[string]Ban)
+	}
+	if ban.CreatedAt.IsZero() {
+		ban.CreatedAt = time.Now()
+	}
+	s.data.Bans[ban.UserID] = ban
+
+	return s.saveLocked()
+}
+
+// ActiveBan returns the ban of a user if one is in effect
+func (s *Store) ActiveBan(userID string) (Ban, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ban, exists := s.data.Bans[userID]
+	if !exists || !ban.Active(time.Now()) {
+		return Ban{}, false
+	}
+	return ban, true
+}
+
+// DeleteBan lifts the ban of a user, reporting whether they had one
+func (s *Store) DeleteBan(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Bans[userID]; !exists {
+		return false, nil
+	}
+	delete(s.data.Bans, userID)
+
+	return true, s.saveLocked()
+}
+
+// PurgeExpiredBans removes bans that are no longer in effect, returning how many were removed
+func (s *Store) PurgeExpiredBans(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for userID, ban := range s.data.Bans {
+		if !ban.Active(now) {
+			delete(s.data.Bans, userID)
+			purged++
+		}
+	}
+
+	if purged == 0 {
+		return 0, nil
+	}
+	return purged, s.saveLocked()
+}
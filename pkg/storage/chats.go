@@ -0,0 +1,75 @@
+package storage
+
+import "slices"
+
+// ChatSettings are the defaults of a group chat, used for members who haven't set their own
+type ChatSettings struct {
+	Timezone string `json:"timezone,omitempty"` // IANA timezone name, empty if not set
+	Language string `json:"language,omitempty"` // Language code, empty if not set
+}
+
+// ChatSettings returns the settings of a chat, reporting whether it has any
+func (s *Store) ChatSettings(chatID int64) (ChatSettings, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.data.Chats[chatID]
+	return settings, ok
+}
+
+// SaveChatSettings stores the settings of a chat, removing them when they're empty
+func (s *Store) SaveChatSettings(chatID int64, settings ChatSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings == (ChatSettings{}) {
+		delete(s.data.Chats, chatID)
+		return s.saveLocked()
+	}
+
+	if s.data.Chats == nil {
+		s.data.Chats = make(map[int64]ChatSettings)
+	}
+	s.data.Chats[chatID] = settings
+
+	return s.saveLocked()
+}
+
+// GroupPermissions are the roles and allowlist of a group chat, which limit who may work with
+// its events
+type GroupPermissions struct {
+	Roles     map[string]string `json:"roles,omitempty"`     // Map of action -> who may perform it, anyone if unset
+	Allowlist []int64           `json:"allowlist,omitempty"` // User IDs allowed when a role is the allowlist
+}
+
+// GroupPermissions returns a copy of the permissions of a group chat, which are empty if it
+// has none
+func (s *Store) GroupPermissions(chatID int64) GroupPermissions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	perms := s.data.Groups[chatID]
+	roles := make(map[string]string, len(perms.Roles))
+	for action, role := range perms.Roles {
+		roles[action] = role
+	}
+	return GroupPermissions{Roles: roles, Allowlist: slices.Clone(perms.Allowlist)}
+}
+
+// SaveGroupPermissions stores the permissions of a group chat, removing them when they're empty
+func (s *Store) SaveGroupPermissions(chatID int64, perms GroupPermissions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(perms.Roles) == 0 && len(perms.Allowlist) == 0 {
+		delete(s.data.Groups, chatID)
+		return s.saveLocked()
+	}
+
+	if s.data.Groups == nil {
+		s.data.Groups = make(map[int64]GroupPermissions)
+	}
+	s.data.Groups[chatID] = perms
+
+	return s.saveLocked()
+}
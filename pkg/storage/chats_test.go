@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupPermissions(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms GroupPermissions
+		want  GroupPermissions
+	}{
+		{"none", GroupPermissions{}, GroupPermissions{Roles: map[string]string{}}},
+		{
+			"roles and allowlist",
+			GroupPermissions{Roles: map[string]string{"edit": "admins"}, Allowlist: []int64{1, 2}},
+			GroupPermissions{Roles: map[string]string{"edit": "admins"}, Allowlist: []int64{1, 2}},
+		},
+		{
+			"allowlist only",
+			GroupPermissions{Allowlist: []int64{3}},
+			GroupPermissions{Roles: map[string]string{}, Allowlist: []int64{3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			if err := s.SaveGroupPermissions(-100, tt.perms); err != nil {
+				t.Fatalf("SaveGroupPermissions() error = %v", err)
+			}
+
+			// The permissions survive a restart
+			s = reopen(t, s)
+			if got := s.GroupPermissions(-100); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupPermissions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupPermissionsCopy(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.SaveGroupPermissions(-100, GroupPermissions{Roles: map[string]string{"edit": "admins"}, Allowlist: []int64{1}}); err != nil {
+		t.Fatalf("SaveGroupPermissions() error = %v", err)
+	}
+
+	perms := s.GroupPermissions(-100)
+	perms.Roles["edit"] = "anyone"
+	perms.Allowlist[0] = 2
+
+	got := s.GroupPermissions(-100)
+	if got.Roles["edit"] != "admins" || got.Allowlist[0] != 1 {
+		t.Errorf("changing the returned permissions changed the stored ones to %+v", got)
+	}
+
+	// Saving empty permissions removes them
+	if err := s.SaveGroupPermissions(-100, GroupPermissions{}); err != nil {
+		t.Fatalf("SaveGroupPermissions() error = %v", err)
+	}
+	if _, ok := s.data.Groups[-100]; ok {
+		t.Error("empty permissions are still stored")
+	}
+}
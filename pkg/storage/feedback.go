@@ -0,0 +1,37 @@
+package storage
+
+import "time"
+
+// Feedback is a message a user sent with /feedback, e.g. about an extraction mistake
+type Feedback struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	ChatID    int64     `json:"chat_id"`
+	Text      string    `json:"text"`
+	Context   string    `json:"context,omitempty"`    // The bot message the feedback replies to, if any
+	HistoryID int64     `json:"history_id,omitempty"` // The extraction the feedback is about, if known
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddFeedback stores feedback and returns it with its assigned ID
+func (s *Store) AddFeedback(feedback Feedback) (Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextFeedbackID++
+	feedback.ID = s.data.NextFeedbackID
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+	s.data.Feedback = append(s.data.Feedback, feedback)
+
+	return feedback, s.saveLocked()
+}
+
+// AllFeedback returns all stored feedback, oldest first
+func (s *Store) AllFeedback() []Feedback {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Feedback(nil), s.data.Feedback...)
+}
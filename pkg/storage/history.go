@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"time"
+
+	"calendar-assistant/pkg/language"
+	"calendar-assistant/pkg/openai"
+)
+
+// Input types recorded in the history
+const (
+	InputText     = "text"
+	InputPhoto    = "photo"
+	InputDocument = "document"
+	InputVoice    = "voice"
+	InputVideo    = "video"
+	InputPlan     = "plan" // Focus events planned from a to-do list rather than extracted
+)
+
+// Answers to the follow-up asking whether an event was extracted accurately
+const (
+	AccuracyCorrect       = "accurate"
+	AccuracyWrongTime     = "wrong_time"
+	AccuracyWrongLocation = "wrong_location"
+	AccuracyWrongDetails  = "wrong_details"
+)
+
+// HistoryEntry records a single extraction
+type HistoryEntry struct {
+	ID        int64         `json:"id,omitempty"`
+	Bot       string        `json:"bot,omitempty"` // Name of the bot the event came through, empty for the main bot
+	UserID    string        `json:"user_id"`
+	ChatID    int64         `json:"chat_id"`
+	InputType string        `json:"input_type"`
+	RawInput  string        `json:"raw_input,omitempty"` // Message text or Telegram file ID, dropped on compaction
+	Event     *openai.Event `json:"event"`
+	CreatedAt time.Time     `json:"created_at"`
+	Compacted bool          `json:"compacted,omitempty"`
+	Accuracy  string        `json:"accuracy,omitempty"`   // The user's answer to the follow-up, if any
+	Language  string        `json:"language,omitempty"`   // Detected language code of the event, if known
+	Retry     bool          `json:"retry,omitempty"`      // Whether the user asked to extract the event again
+	MessageID int           `json:"message_id,omitempty"` // The bot's message with the event's ICS file, if it was sent
+}
+
+// AddHistory appends an entry to the history and returns it with its assigned ID
+func (s *Store) AddHistory(entry HistoryEntry) (HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextHistoryID++
+	entry.ID = s.data.NextHistoryID
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.Language == "" && entry.Event != nil {
+		entry.Language = language.Detect(entry.Event.Title + "\n" + entry.Event.Description)
+	}
+	s.data.History = append(s.data.History, entry)
+
+	return entry, s.saveLocked()
+}
+
+// SetHistoryAccuracy records a user's answer to the follow-up about one of their entries,
+// reporting whether the entry was found
+func (s *Store) SetHistoryAccuracy(id int64, userID, accuracy string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.History {
+		entry := &s.data.History[i]
+		if entry.ID == id && entry.UserID == userID {
+			entry.Accuracy = accuracy
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// SetHistoryMessage records the bot's message that sent the ICS file of an entry, reporting
+// whether the entry was found
+func (s *Store) SetHistoryMessage(id int64, messageID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.History {
+		entry := &s.data.History[i]
+		if entry.ID == id {
+			entry.MessageID = messageID
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// HistoryByMessage returns the entries whose ICS file a bot sent as a message of a chat,
+// several for a file with several events
+func (s *Store) HistoryByMessage(bot string, chatID int64, messageID int) []HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []HistoryEntry
+	for _, entry := range s.data.History {
+		if entry.Bot == bot && entry.ChatID == chatID && entry.MessageID == messageID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// EventSequence returns the highest sequence a user's events with a UID were sent with,
+// reporting false if none was sent yet
+func (s *Store) EventSequence(userID, uid string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sequence, found := 0, false
+	for _, entry := range s.data.History {
+		if entry.UserID != userID || entry.Event == nil || entry.Event.UID != uid {
+			continue
+		}
+		sequence, found = max(sequence, entry.Event.Sequence), true
+	}
+	return sequence, found
+}
+
+// CancelEvent marks every version of a user's event with a UID as cancelled, one sequence
+// later than the latest, reporting whether the event was found
+func (s *Store) CancelEvent(userID, uid string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sequence, found := 0, false
+	for _, entry := range s.data.History {
+		if entry.UserID == userID && entry.Event != nil && entry.Event.UID == uid {
+			sequence, found = max(sequence, entry.Event.Sequence), true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	for i := range s.data.History {
+		entry := &s.data.History[i]
+		if entry.UserID != userID || entry.Event == nil || entry.Event.UID != uid {
+			continue
+		}
+		// Entries handed out before share the event, so it's replaced rather than changed
+		event := *entry.Event
+		event.Status = openai.StatusCancelled
+		event.Sequence = sequence + 1
+		entry.Event = &event
+	}
+	return true, s.saveLocked()
+}
+
+// History returns the history entries for a user, oldest first
+func (s *Store) History(userID string) []HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []HistoryEntry
+	for _, entry := range s.data.History {
+		if entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// CompactHistory drops the raw inputs of entries created before cutoff, keeping
+// only the structured events. It returns the number of entries compacted.
+func (s *Store) CompactHistory(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	compacted := 0
+	for i := range s.data.History {
+		entry := &s.data.History[i]
+		if entry.Compacted || !entry.CreatedAt.Before(cutoff) {
+			continue
+		}
+		entry.RawInput = ""
+		entry.Compacted = true
+		compacted++
+	}
+
+	if compacted == 0 {
+		return 0, nil
+	}
+	return compacted, s.saveLocked()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"testing"
+
+	"calendar-assistant/pkg/openai"
+)
+
+func TestHistoryByMessage(t *testing.T) {
+	s := openTestStore(t)
+	for _, entry := range []HistoryEntry{
+		{UserID: "1", ChatID: 10, MessageID: 5, Event: &openai.Event{Title: "A"}},
+		{UserID: "1", ChatID: 10, MessageID: 5, Event: &openai.Event{Title: "B"}},
+		{UserID: "2", ChatID: 20, MessageID: 5, Event: &openai.Event{Title: "C"}},
+		{Bot: "other", UserID: "1", ChatID: 10, MessageID: 5, Event: &openai.Event{Title: "D"}},
+		{UserID: "1", ChatID: 10, Event: &openai.Event{Title: "Not sent"}},
+	} {
+		if _, err := s.AddHistory(entry); err != nil {
+			t.Fatalf("AddHistory() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		bot       string
+		chatID    int64
+		messageID int
+		want      []string
+	}{
+		{"file with several events", "", 10, 5, []string{"A", "B"}},
+		{"same message ID in another chat", "", 20, 5, []string{"C"}},
+		{"same message of another bot", "other", 10, 5, []string{"D"}},
+		{"unknown message", "", 10, 6, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range s.HistoryByMessage(tt.bot, tt.chatID, tt.messageID) {
+				got = append(got, entry.Event.Title)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("HistoryByMessage() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("HistoryByMessage() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEventSequence(t *testing.T) {
+	s := openTestStore(t)
+	for _, entry := range []HistoryEntry{
+		{UserID: "1", Event: &openai.Event{UID: "a"}},
+		{UserID: "1", Event: &openai.Event{UID: "a", Sequence: 2}},
+		{UserID: "1", Event: &openai.Event{UID: "a", Sequence: 1}},
+		{UserID: "2", Event: &openai.Event{UID: "a", Sequence: 5}},
+		{UserID: "1", Event: &openai.Event{UID: "b"}},
+	} {
+		if _, err := s.AddHistory(entry); err != nil {
+			t.Fatalf("AddHistory() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		userID, uid string
+		want        int
+		wantFound   bool
+	}{
+		{"1", "a", 2, true},
+		{"2", "a", 5, true},
+		{"1", "b", 0, true},
+		{"1", "c", 0, false},
+		{"3", "a", 0, false},
+	}
+	for _, tt := range tests {
+		got, found := s.EventSequence(tt.userID, tt.uid)
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("EventSequence(%q, %q) = %d, %t, want %d, %t", tt.userID, tt.uid, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
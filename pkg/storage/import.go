@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+)
+
+// UserSnapshot is the format of exported and imported user preferences
+type UserSnapshot struct {
+	Users map[string]UserPreferences `json:"users"`
+}
+
+// legacyTimezonePattern matches the log line written whenever a user sets their timezone
+var legacyTimezonePattern = regexp.MustCompile(`Set timezone for user (\d+) to (\S+)`)
+
+// ImportUsersFile imports user preferences from a JSON snapshot or, failing that, from a
+// log file of an older deployment. Users that already exist in the store are left alone.
+// It returns the number of users imported.
+func (s *Store) ImportUsersFile(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	var snapshot UserSnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		log.Printf("Import file %s is not a JSON snapshot, reading it as a log file", path)
+		snapshot.Users = parseLegacyLog(content)
+	}
+
+	return s.importUsers(snapshot.Users)
+}
+
+// ExportUsers returns a JSON snapshot of all user preferences
+func (s *Store) ExportUsers() ([]byte, error) {
+	snapshot := UserSnapshot{Users: s.Users()}
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user snapshot: %w", err)
+	}
+	return content, nil
+}
+
+// parseLegacyLog collects the last timezone each user set according to the log
+func parseLegacyLog(content []byte) map[string]UserPreferences {
+	users := make(map[string]UserPreferences)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		matches := legacyTimezonePattern.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		// Later lines win, so a user who changed their timezone keeps the latest one
+		users[matches[1]] = UserPreferences{Timezone: matches[2]}
+	}
+
+	return users
+}
+
+// importUsers adds the users that aren't in the store yet, skipping invalid timezones
+func (s *Store) importUsers(users map[string]UserPreferences) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Users == nil {
+		s.data.Users = make(map[string]UserPreferences)
+	}
+
+	imported := 0
+	for userID, prefs := range users {
+		if _, exists := s.data.Users[userID]; exists {
+			continue
+		}
+		if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+			log.Printf("Skipping user %s with invalid timezone %q", userID, prefs.Timezone)
+			continue
+		}
+		s.data.Users[userID] = prefs
+		imported++
+	}
+
+	if imported == 0 {
+		return 0, nil
+	}
+	return imported, s.saveLocked()
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// InactiveChat is a chat that blocked a bot or removed it from the group
+type InactiveChat struct {
+	Bot     string    `json:"bot,omitempty"`     // Name of the bot, empty for the main bot
+	ChatID  int64     `json:"chat_id"`           // Private chats have the ID of the user
+	Private bool      `json:"private,omitempty"` // Whether the chat is a user's private chat rather than a group
+	Since   time.Time `json:"since"`
+}
+
+// inactiveKey returns the key of a chat in the inactive chats of the store
+func inactiveKey(bot string, chatID int64) string {
+	return fmt.Sprintf("%s:%d", bot, chatID)
+}
+
+// SetInactive records that a chat blocked a bot or removed it
+func (s *Store) SetInactive(chat InactiveChat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if chat.Since.IsZero() {
+		chat.Since = time.Now()
+	}
+	if s.data.Inactive == nil {
+		s.data.Inactive = make(map[string]InactiveChat)
+	}
+	s.data.Inactive[inactiveKey(chat.Bot, chat.ChatID)] = chat
+
+	return s.saveLocked()
+}
+
+// SetActive removes a chat from the inactive chats of a bot, reporting whether it was inactive
+func (s *Store) SetActive(bot string, chatID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := inactiveKey(bot, chatID)
+	if _, ok := s.data.Inactive[key]; !ok {
+		return false, nil
+	}
+	delete(s.data.Inactive, key)
+
+	return true, s.saveLocked()
+}
+
+// Inactive returns the record of a chat that blocked a bot or removed it, reporting whether there is one
+func (s *Store) Inactive(bot string, chatID int64) (InactiveChat, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	chat, ok := s.data.Inactive[inactiveKey(bot, chatID)]
+	return chat, ok
+}
+
+// PurgeUser deletes everything stored about a user except their payments and bans, which are
+// kept for bookkeeping and abuse prevention
+func (s *Store) PurgeUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.Users, userID)
+	delete(s.data.APIKeys, userID)
+
+	history := s.data.History[:0]
+	for _, entry := range s.data.History {
+		if entry.UserID != userID {
+			history = append(history, entry)
+		}
+	}
+	s.data.History = history
+
+	scheduled := s.data.Scheduled[:0]
+	for _, msg := range s.data.Scheduled {
+		if msg.UserID != userID {
+			scheduled = append(scheduled, msg)
+		}
+	}
+	s.data.Scheduled = scheduled
+
+	return s.saveLocked()
+}
+
+// PurgeBotUser deletes the scheduled messages a bot has for a user, the only data stored per
+// bot, for a user who still uses other bots
+func (s *Store) PurgeBotUser(bot, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled := s.data.Scheduled[:0]
+	for _, msg := range s.data.Scheduled {
+		if msg.UserID != userID || msg.Bot != bot {
+			scheduled = append(scheduled, msg)
+		}
+	}
+	s.data.Scheduled = scheduled
+
+	return s.saveLocked()
+}
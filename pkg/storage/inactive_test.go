@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"testing"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// seedUsers stores the same data for users 1 and 2, with scheduled messages of two bots
+func seedUsers(t *testing.T, s *Store) {
+	t.Helper()
+	for _, userID := range []string{"1", "2"} {
+		if err := s.SaveUser(userID, UserPreferences{Timezone: "Europe/Berlin"}); err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+		if err := s.SetAPIKey(userID, "encrypted"); err != nil {
+			t.Fatalf("SetAPIKey() error = %v", err)
+		}
+		if _, err := s.AddHistory(HistoryEntry{UserID: userID, Event: &openai.Event{Title: "Event"}}); err != nil {
+			t.Fatalf("AddHistory() error = %v", err)
+		}
+		for _, bot := range []string{"", "other"} {
+			if _, err := s.AddScheduled(ScheduledMessage{Bot: bot, UserID: userID, Kind: ScheduledFile}); err != nil {
+				t.Fatalf("AddScheduled() error = %v", err)
+			}
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name          string
+		purge         func(s *Store) error
+		wantUser      bool
+		wantAPIKey    bool
+		wantHistory   int
+		wantScheduled int
+	}{
+		{"whole user", func(s *Store) error { return s.PurgeUser("1") }, false, false, 0, 0},
+		{"main bot only", func(s *Store) error { return s.PurgeBotUser("", "1") }, true, true, 1, 1},
+		{"other bot only", func(s *Store) error { return s.PurgeBotUser("other", "1") }, true, true, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			seedUsers(t, s)
+			if err := tt.purge(s); err != nil {
+				t.Fatalf("purge error = %v", err)
+			}
+			s = reopen(t, s)
+
+			if _, ok := s.Users()["1"]; ok != tt.wantUser {
+				t.Errorf("user stored = %t, want %t", ok, tt.wantUser)
+			}
+			if got := s.APIKey("1") != ""; got != tt.wantAPIKey {
+				t.Errorf("API key stored = %t, want %t", got, tt.wantAPIKey)
+			}
+			if got := len(s.History("1")); got != tt.wantHistory {
+				t.Errorf("%d history entries left, want %d", got, tt.wantHistory)
+			}
+			if got := len(s.Scheduled("1")); got != tt.wantScheduled {
+				t.Errorf("%d scheduled messages left, want %d", got, tt.wantScheduled)
+			}
+
+			// Other users keep everything
+			if len(s.History("2")) != 1 || len(s.Scheduled("2")) != 2 || s.APIKey("2") == "" {
+				t.Error("purging user 1 changed the data of user 2")
+			}
+		})
+	}
+}
+
+func TestInactive(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.SetInactive(InactiveChat{Bot: "other", ChatID: 1, Private: true}); err != nil {
+		t.Fatalf("SetInactive() error = %v", err)
+	}
+
+	tests := []struct {
+		bot    string
+		chatID int64
+		want   bool
+	}{
+		{"other", 1, true},
+		{"", 1, false}, // Inactive per bot
+		{"other", 2, false},
+	}
+	for _, tt := range tests {
+		if _, got := s.Inactive(tt.bot, tt.chatID); got != tt.want {
+			t.Errorf("Inactive(%q, %d) = %t, want %t", tt.bot, tt.chatID, got, tt.want)
+		}
+	}
+
+	if active, err := s.SetActive("other", 1); err != nil || !active {
+		t.Errorf("SetActive() = %t, %v, want true, nil", active, err)
+	}
+	if _, inactive := s.Inactive("other", 1); inactive {
+		t.Error("chat is still inactive after SetActive()")
+	}
+}
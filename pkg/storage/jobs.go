@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Job is a task the scheduler runs at RunAt, once or again every Interval. It stays stored until
+// it has run, so a job that was due while the bot was down still runs after a restart.
+type Job struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`                 // Name of the handler that runs the job
+	Key       string          `json:"key,omitempty"`        // Identifies the job within its kind, e.g. a user ID
+	Payload   json.RawMessage `json:"payload,omitempty"`    // Data the handler needs, if any
+	RunAt     time.Time       `json:"run_at"`               // When the job is due
+	Interval  time.Duration   `json:"interval,omitempty"`   // How often a recurring job runs, 0 for a one-off job
+	Attempts  int             `json:"attempts,omitempty"`   // Failed attempts since the last successful run
+	LastError string          `json:"last_error,omitempty"` // Error of the last failed attempt
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AddJob stores a new job and returns it with its assigned ID
+func (s *Store) AddJob(job Job) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextJobID++
+	job.ID = s.data.NextJobID
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	s.data.Jobs = append(s.data.Jobs, job)
+
+	return job, s.saveLocked()
+}
+
+// JobByKey returns the job of a kind with a key, reporting whether there is one
+func (s *Store) JobByKey(kind, key string) (Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, job := range s.data.Jobs {
+		if job.Kind == kind && job.Key == key {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+// DueJobs returns the jobs that are due at now, earliest first
+func (s *Store) DueJobs(now time.Time) []Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []Job
+	for _, job := range s.data.Jobs {
+		if !job.RunAt.After(now) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].RunAt.Before(jobs[j].RunAt)
+	})
+	return jobs
+}
+
+// UpdateJob replaces a stored job with the same ID, reporting whether it still exists
+func (s *Store) UpdateJob(job Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Jobs {
+		if s.data.Jobs[i].ID == job.ID {
+			s.data.Jobs[i] = job
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// DeleteJob removes a job, reporting whether it existed
+func (s *Store) DeleteJob(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.data.Jobs {
+		if job.ID == id {
+			s.data.Jobs = append(s.data.Jobs[:i], s.data.Jobs[i+1:]...)
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+// AccuracyStats summarizes how well extractions went for a group of history entries
+type AccuracyStats struct {
+	Extractions int // Events created
+	Retries     int // Extractions the user asked to redo
+	Rated       int // Events the user answered the follow-up for
+	Accurate    int // Events rated as accurate
+	Wrong       int // Events rated as wrong in some way
+}
+
+// RetryRate returns the share of extractions the user asked to redo
+func (a AccuracyStats) RetryRate() float64 {
+	return ratio(a.Retries, a.Extractions)
+}
+
+// AccurateRate returns the share of rated events that were accurate
+func (a AccuracyStats) AccurateRate() float64 {
+	return ratio(a.Accurate, a.Rated)
+}
+
+// WrongRate returns the share of rated events that were reported as wrong
+func (a AccuracyStats) WrongRate() float64 {
+	return ratio(a.Wrong, a.Rated)
+}
+
+// ratio returns n/total, or 0 if total is 0
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// AccuracyByInputType breaks the accuracy of extractions down by input type
+func (s *Store) AccuracyByInputType() map[string]AccuracyStats {
+	return s.accuracyBy(func(entry HistoryEntry) string { return entry.InputType })
+}
+
+// AccuracyByLanguage breaks the accuracy of extractions down by the language of the events,
+// with "" for events whose language wasn't detected
+func (s *Store) AccuracyByLanguage() map[string]AccuracyStats {
+	return s.accuracyBy(func(entry HistoryEntry) string { return entry.Language })
+}
+
+// accuracyBy sums up the accuracy of the history entries grouped by key
+func (s *Store) accuracyBy(key func(HistoryEntry) string) map[string]AccuracyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make(map[string]AccuracyStats)
+	for _, entry := range s.data.History {
+		// Planned focus events aren't extractions
+		if entry.InputType == InputPlan {
+			continue
+		}
+
+		group := stats[key(entry)]
+		group.Extractions++
+		if entry.Retry {
+			group.Retries++
+		}
+		switch entry.Accuracy {
+		case "":
+		case AccuracyCorrect:
+			group.Rated++
+			group.Accurate++
+		default:
+			group.Rated++
+			group.Wrong++
+		}
+		stats[key(entry)] = group
+	}
+	return stats
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "time"
+
+// Payment is a purchase of the premium tier with Telegram Stars
+type Payment struct {
+	UserID    string    `json:"user_id"`
+	ChargeID  string    `json:"charge_id"` // Telegram's ID of the payment, needed to refund it
+	Stars     int       `json:"stars"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Entitlement is a user's premium tier, which lasts until ExpiresAt
+type Entitlement struct {
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Active reports whether the premium tier is in effect at now
+func (e Entitlement) Active(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// AddPremium records a payment and extends the user's premium tier by duration, from now or
+// from the end of the tier they already have. A payment that was already recorded, e.g.
+// because Telegram delivered it twice, doesn't extend it again.
+func (s *Store) AddPremium(payment Payment, duration time.Duration) (Entitlement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entitlement := s.data.Premium[payment.UserID]
+	for _, recorded := range s.data.Payments {
+		if recorded.ChargeID == payment.ChargeID {
+			return entitlement, nil
+		}
+	}
+
+	if payment.CreatedAt.IsZero() {
+		payment.CreatedAt = time.Now()
+	}
+	s.data.Payments = append(s.data.Payments, payment)
+
+	if s.data.Premium == nil {
+		s.data.Premium = make(map[string]Entitlement)
+	}
+	start := payment.CreatedAt
+	if entitlement.Active(start) {
+		start = entitlement.ExpiresAt
+	}
+	entitlement = Entitlement{UserID: payment.UserID, ExpiresAt: start.Add(duration)}
+	s.data.Premium[payment.UserID] = entitlement
+
+	return entitlement, s.saveLocked()
+}
+
+// ActivePremium returns the premium tier of a user if it's in effect
+func (s *Store) ActivePremium(userID string) (Entitlement, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entitlement, exists := s.data.Premium[userID]
+	if !exists || !entitlement.Active(time.Now()) {
+		return Entitlement{}, false
+	}
+	return entitlement, true
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// Kinds of scheduled messages
+const (
+	ScheduledFile     = "file"      // Re-sends an event file
+	ScheduledReminder = "reminder"  // Sends a text reminder about an event
+	ScheduledFollowUp = "follow_up" // Asks whether an event that has ended was extracted accurately
+)
+
+// ScheduledMessage is a message the bot delivers at a later time
+type ScheduledMessage struct {
+	ID        int64     `json:"id"`
+	Bot       string    `json:"bot,omitempty"` // Name of the bot that delivers the message, empty for the main bot
+	UserID    string    `json:"user_id"`
+	ChatID    int64     `json:"chat_id"`
+	Kind      string    `json:"kind"`
+	FileID    string    `json:"file_id,omitempty"` // Telegram file ID of the event file
+	Text      string    `json:"text"`              // Summary of the event shown with the message
+	HistoryID int64     `json:"history_id,omitempty"`
+	Automatic bool      `json:"automatic,omitempty"` // Scheduled by the bot rather than with /schedule, e.g. a reminder from the user's settings
+	SendAt    time.Time `json:"send_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserCreated reports whether the user scheduled the message themselves; follow-ups are always
+// the bot's, also those stored before the Automatic flag
+func (m ScheduledMessage) UserCreated() bool {
+	return !m.Automatic && m.Kind != ScheduledFollowUp
+}
+
+// AddScheduled stores a new scheduled message and returns it with its assigned ID
+func (s *Store) AddScheduled(msg ScheduledMessage) (ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextScheduledID++
+	msg.ID = s.data.NextScheduledID
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	s.data.Scheduled = append(s.data.Scheduled, msg)
+
+	return msg, s.saveLocked()
+}
+
+// Scheduled returns the pending scheduled messages of a user, soonest first
+func (s *Store) Scheduled(userID string) []ScheduledMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []ScheduledMessage
+	for _, msg := range s.data.Scheduled {
+		if msg.UserID == userID {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].SendAt.Before(messages[j].SendAt)
+	})
+	return messages
+}
+
+// DueScheduled returns the scheduled messages of all users that a bot delivers and that are
+// due at now
+func (s *Store) DueScheduled(bot string, now time.Time) []ScheduledMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []ScheduledMessage
+	for _, msg := range s.data.Scheduled {
+		if msg.Bot == bot && !msg.SendAt.After(now) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// DeleteScheduled removes a scheduled message, reporting whether it existed
+func (s *Store) DeleteScheduled(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.data.Scheduled {
+		if msg.ID == id {
+			s.data.Scheduled = append(s.data.Scheduled[:i], s.data.Scheduled[i+1:]...)
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// DeleteScheduledInChat removes the pending messages a bot would send into a chat and returns
+// how many there were
+func (s *Store) DeleteScheduledInChat(bot string, chatID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.data.Scheduled[:0]
+	for _, msg := range s.data.Scheduled {
+		if msg.Bot != bot || msg.ChatID != chatID {
+			kept = append(kept, msg)
+		}
+	}
+	deleted := len(s.data.Scheduled) - len(kept)
+	s.data.Scheduled = kept
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	return deleted, s.saveLocked()
+}
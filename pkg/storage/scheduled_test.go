@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduledMessageUserCreated(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  ScheduledMessage
+		want bool
+	}{
+		{"file", ScheduledMessage{Kind: ScheduledFile}, true},
+		{"reminder", ScheduledMessage{Kind: ScheduledReminder}, true},
+		{"reminder from the settings", ScheduledMessage{Kind: ScheduledReminder, Automatic: true}, false},
+		{"follow-up", ScheduledMessage{Kind: ScheduledFollowUp, Automatic: true}, false},
+		{"follow-up stored before the flag", ScheduledMessage{Kind: ScheduledFollowUp}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.UserCreated(); got != tt.want {
+				t.Errorf("UserCreated() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueScheduled(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Date(2025, time.May, 20, 12, 0, 0, 0, time.UTC)
+	for _, msg := range []ScheduledMessage{
+		{UserID: "1", Kind: ScheduledFile, SendAt: now.Add(-time.Minute)},
+		{UserID: "1", Kind: ScheduledReminder, SendAt: now},
+		{UserID: "1", Kind: ScheduledReminder, SendAt: now.Add(time.Minute)},
+		{Bot: "other", UserID: "1", Kind: ScheduledFile, SendAt: now.Add(-time.Hour)},
+	} {
+		if _, err := s.AddScheduled(msg); err != nil {
+			t.Fatalf("AddScheduled() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		bot  string
+		at   time.Time
+		want int
+	}{
+		{"", now.Add(-time.Hour), 0},
+		{"", now, 2},
+		{"", now.Add(time.Hour), 3},
+		{"other", now, 1},
+		{"unknown", now, 0},
+	}
+	for _, tt := range tests {
+		if got := s.DueScheduled(tt.bot, tt.at); len(got) != tt.want {
+			t.Errorf("DueScheduled(%q, %v) returned %d messages, want %d", tt.bot, tt.at, len(got), tt.want)
+		}
+	}
+}
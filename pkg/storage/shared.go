@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"time"
+
+	"calendar-assistant/pkg/openai"
+)
+
+// SharedEvent is an event created through inline mode, which anyone in the chat it was sent
+// to can add to their calendar through a link to the bot
+type SharedEvent struct {
+	Token     string        `json:"token"`    // Random identifier used in the link
+	UserID    string        `json:"user_id"`  // The user who created the event
+	Timezone  string        `json:"timezone"` // The creator's timezone, which the event times are in
+	Event     *openai.Event `json:"event"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// AddSharedEvent stores an event created through inline mode
+func (s *Store) AddSharedEvent(shared SharedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if shared.CreatedAt.IsZero() {
+		shared.CreatedAt = time.Now()
+	}
+	if s.data.Shared == nil {
+		s.data.Shared = make(map[string]SharedEvent)
+	}
+	s.data.Shared[shared.Token] = shared
+
+	return s.saveLocked()
+}
+
+// SharedEvent returns the shared event with a token, reporting whether it exists
+func (s *Store) SharedEvent(token string) (SharedEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shared, ok := s.data.Shared[token]
+	return shared, ok
+}
+
+// PurgeSharedEvents removes the shared events created before cutoff and returns how many were removed
+func (s *Store) PurgeSharedEvents(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for token, shared := range s.data.Shared {
+		if shared.CreatedAt.Before(cutoff) {
+			delete(s.data.Shared, token)
+			purged++
+		}
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+
+	return purged, s.saveLocked()
+}
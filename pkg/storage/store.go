@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists bot data in a single JSON file
+type Store struct {
+	path    string
+	data    storeData
+	unsaved bool         // Whether data has changes that aren't written yet, such as processed updates
+	mu      sync.RWMutex // Mutex to protect the data and the file
+}
+
+// storeData is the on-disk representation of the store
+type storeData struct {
+	History       []HistoryEntry `json:"history"`
+	NextHistoryID int64          `json:"next_history_id,omitempty"`
+
+	Users   map[string]UserPreferences `json:"users,omitempty"`    // Map of userID -> preferences
+	APIKeys map[string]string          `json:"api_keys,omitempty"` // Map of userID -> encrypted OpenAI API key
+	Bans    map[string]Ban             `json:"bans,omitempty"`     // Map of userID -> ban
+	Chats   map[int64]ChatSettings     `json:"chats,omitempty"`    // Map of chatID -> group chat defaults
+	Groups  map[int64]GroupPermissions `json:"groups,omitempty"`   // Map of chatID -> who may work with a group's events
+	Premium map[string]Entitlement     `json:"premium,omitempty"`  // Map of userID -> premium tier
+
+	Payments []Payment `json:"payments,omitempty"`
+
+	Inactive map[string]InactiveChat `json:"inactive,omitempty"` // Map of bot:chatID -> chat that blocked or removed the bot
+
+	Scheduled       []ScheduledMessage `json:"scheduled,omitempty"`
+	NextScheduledID int64              `json:"next_scheduled_id,omitempty"`
+
+	Usage  map[string]*DailyUsage `json:"usage,omitempty"`  // Map of UTC date -> usage of that day
+	Shared map[string]SharedEvent `json:"shared,omitempty"` // Map of token -> event created through inline mode
+
+	Updates    UpdateLog             `json:"updates"`               // Updates of the main bot
+	BotUpdates map[string]*UpdateLog `json:"bot_updates,omitempty"` // Map of bot name -> updates of an additional bot
+
+	Jobs      []Job `json:"jobs,omitempty"`
+	NextJobID int64 `json:"next_job_id,omitempty"`
+
+	Feedback       []Feedback `json:"feedback,omitempty"`
+	NextFeedbackID int64      `json:"next_feedback_id,omitempty"`
+}
+
+// Open loads the store from path, creating an empty one if the file doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("Storage file %s not found, starting with an empty store", path)
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse storage file: %w", err)
+	}
+
+	log.Printf("Loaded storage from %s", path)
+	return s, nil
+}
+
+// Size returns the size of the storage file in bytes
+func (s *Store) Size() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// saveLocked writes the data to disk atomically; the caller must hold the write lock
+func (s *Store) saveLocked() error {
+	content, err := json.Marshal(&s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode storage: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated store
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write storage file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace storage file: %w", err)
+	}
+
+	s.unsaved = false
+	return nil
+}
+
+// Flush writes the changes that are only kept in memory until then, if there are any
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.unsaved {
+		return nil
+	}
+	return s.saveLocked()
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestStore opens an empty store in a temporary directory
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s
+}
+
+// reopen loads a store again from its file
+func reopen(t *testing.T, s *Store) *Store {
+	t.Helper()
+	reopened, err := Open(s.path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return reopened
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// maxRecentUpdates limits how many processed update IDs are remembered for de-duplication
+const maxRecentUpdates = 1000
+
+// UpdateLog records the Telegram updates the bot has processed, so updates delivered again
+// after a restart aren't processed twice
+type UpdateLog struct {
+	LastID     int       `json:"last_id"`          // Highest processed update ID
+	Recent     []int     `json:"recent,omitempty"` // Most recently processed update IDs, oldest first
+	ReceivedAt time.Time `json:"received_at"`      // When the last update was processed
+}
+
+// UpdateProcessed reports whether an update of a bot was processed already. Each bot numbers
+// its updates on its own; the main bot's name is empty.
+func (s *Store) UpdateProcessed(bot string, id int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	updates := &s.data.Updates
+	if bot != "" {
+		if updates = s.data.BotUpdates[bot]; updates == nil {
+			return false
+		}
+	}
+	return slices.Contains(updates.Recent, id)
+}
+
+// MarkUpdate records an update of a bot as processed, reporting false if it already was.
+// Updates come in too often to rewrite the file for each, so the record stays in memory until
+// the next change that's saved or Flush.
+func (s *Store) MarkUpdate(bot string, id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updates := &s.data.Updates
+	if bot != "" {
+		if s.data.BotUpdates == nil {
+			s.data.BotUpdates = make(map[string]*UpdateLog)
+		}
+		if s.data.BotUpdates[bot] == nil {
+			s.data.BotUpdates[bot] = &UpdateLog{}
+		}
+		updates = s.data.BotUpdates[bot]
+	}
+	if slices.Contains(updates.Recent, id) {
+		return false
+	}
+
+	updates.Recent = append(updates.Recent, id)
+	if len(updates.Recent) > maxRecentUpdates {
+		updates.Recent = updates.Recent[len(updates.Recent)-maxRecentUpdates:]
+	}
+	updates.LastID = max(updates.LastID, id)
+	updates.ReceivedAt = time.Now()
+	s.unsaved = true
+
+	return true
+}
+
+// UpdateOffset returns the offset a bot resumes polling from, or 0 if it processed no update
+// within maxAge. Telegram picks update IDs randomly again after a week without updates, so an
+// old offset could skip new updates.
+func (s *Store) UpdateOffset(bot string, maxAge time.Duration) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	updates := &s.data.Updates
+	if bot != "" {
+		if updates = s.data.BotUpdates[bot]; updates == nil {
+			return 0
+		}
+	}
+	if updates.LastID == 0 || time.Since(updates.ReceivedAt) > maxAge {
+		return 0
+	}
+	return updates.LastID + 1
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkUpdate(t *testing.T) {
+	s := openTestStore(t)
+
+	// Each step depends on the ones before it
+	steps := []struct {
+		bot  string
+		id   int
+		want bool
+	}{
+		{"", 100, true},
+		{"", 100, false},
+		{"", 101, true},
+		{"other", 100, true}, // Each bot numbers its updates on its own
+		{"other", 100, false},
+		{"", 99, true}, // Out of order, but not processed yet
+		{"", 101, false},
+	}
+	for i, step := range steps {
+		if processed := s.UpdateProcessed(step.bot, step.id); processed == step.want {
+			t.Errorf("step %d: UpdateProcessed(%q, %d) = %t, want %t", i, step.bot, step.id, processed, !step.want)
+		}
+		if got := s.MarkUpdate(step.bot, step.id); got != step.want {
+			t.Errorf("step %d: MarkUpdate(%q, %d) = %t, want %t", i, step.bot, step.id, got, step.want)
+		}
+	}
+
+	offsets := []struct {
+		bot  string
+		want int
+	}{
+		{"", 102},
+		{"other", 101},
+		{"unknown", 0},
+	}
+	// Processed updates are only written out by the next save
+	if got := reopen(t, s).UpdateOffset("", time.Hour); got != 0 {
+		t.Errorf("UpdateOffset() before Flush() = %d, want 0", got)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	s = reopen(t, s)
+	for _, tt := range offsets {
+		if got := s.UpdateOffset(tt.bot, time.Hour); got != tt.want {
+			t.Errorf("UpdateOffset(%q) = %d, want %d", tt.bot, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package storage
+
+import "time"
+
+// usageDayFormat is the format of the dates the daily usage is kept under, in UTC
+const usageDayFormat = "2006-01-02"
+
+// DailyUsage sums up the extractions and OpenAI usage of a day
+type DailyUsage struct {
+	Extractions      int             `json:"extractions,omitempty"`       // Extractions that reached the OpenAI API
+	Errors           int             `json:"errors,omitempty"`            // Extractions that failed
+	LatencyMillis    int64           `json:"latency_ms,omitempty"`        // Total latency of the extractions
+	PromptTokens     int64           `json:"prompt_tokens,omitempty"`     // Prompt tokens billed to the operator
+	CompletionTokens int64           `json:"completion_tokens,omitempty"` // Completion tokens billed to the operator
+	Users            map[string]bool `json:"users,omitempty"`             // Users who made requests
+	UserExtractions  map[string]int  `json:"user_extractions,omitempty"`  // Map of userID -> extractions, for the daily quota
+}
+
+// UsageStats sums up the daily usage of a period
+type UsageStats struct {
+	ActiveUsers      int
+	Extractions      int
+	Errors           int
+	Latency          time.Duration // Total latency of the extractions
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// ErrorRate returns the share of extractions that failed
+func (u UsageStats) ErrorRate() float64 {
+	return ratio(u.Errors, u.Extractions)
+}
+
+// AverageLatency returns the average latency of an extraction
+func (u UsageStats) AverageLatency() time.Duration {
+	if u.Extractions == 0 {
+		return 0
+	}
+	return u.Latency / time.Duration(u.Extractions)
+}
+
+// RecordExtraction counts an extraction of a user towards today's usage
+func (s *Store) RecordExtraction(userID string, latency time.Duration, failed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := s.usageDayLocked(time.Now(), userID)
+	day.Extractions++
+	if day.UserExtractions == nil {
+		day.UserExtractions = make(map[string]int)
+	}
+	day.UserExtractions[userID]++
+	if failed {
+		day.Errors++
+	}
+	day.LatencyMillis += latency.Milliseconds()
+
+	return s.saveLocked()
+}
+
+// ExtractionsToday returns how many extractions a user made today (in UTC)
+func (s *Store) ExtractionsToday(userID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day, ok := s.data.Usage[time.Now().UTC().Format(usageDayFormat)]
+	if !ok {
+		return 0
+	}
+	return day.UserExtractions[userID]
+}
+
+// RecordTokens counts the tokens of an OpenAI run towards today's usage
+func (s *Store) RecordTokens(userID string, promptTokens, completionTokens int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := s.usageDayLocked(time.Now(), userID)
+	day.PromptTokens += promptTokens
+	day.CompletionTokens += completionTokens
+
+	return s.saveLocked()
+}
+
+// usageDayLocked returns the usage of the day of now, marking the user as active on it; the
+// caller must hold the write lock
+func (s *Store) usageDayLocked(now time.Time, userID string) *DailyUsage {
+	if s.data.Usage == nil {
+		s.data.Usage = make(map[string]*DailyUsage)
+	}
+
+	key := now.UTC().Format(usageDayFormat)
+	day, ok := s.data.Usage[key]
+	if !ok {
+		day = &DailyUsage{}
+		s.data.Usage[key] = day
+	}
+	if day.Users == nil {
+		day.Users = make(map[string]bool)
+	}
+	day.Users[userID] = true
+	return day
+}
+
+// UsageSince sums up the usage of the days from the day of since (in UTC) up to today
+func (s *Store) UsageSince(since time.Time) UsageStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	first := since.UTC().Format(usageDayFormat)
+	users := make(map[string]bool)
+	var stats UsageStats
+	for key, day := range s.data.Usage {
+		// Dates in this format sort chronologically
+		if key < first {
+			continue
+		}
+		stats.Extractions += day.Extractions
+		stats.Errors += day.Errors
+		stats.Latency += time.Duration(day.LatencyMillis) * time.Millisecond
+		stats.PromptTokens += day.PromptTokens
+		stats.CompletionTokens += day.CompletionTokens
+		for userID := range day.Users {
+			users[userID] = true
+		}
+	}
+	stats.ActiveUsers = len(users)
+	return stats
+}
+
+// PurgeUsage removes the usage of the days before cutoff and returns how many were removed
+func (s *Store) PurgeUsage(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := cutoff.UTC().Format(usageDayFormat)
+	purged := 0
+	for key := range s.data.Usage {
+		if key < first {
+			delete(s.data.Usage, key)
+			purged++
+		}
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+
+	return purged, s.saveLocked()
+}
@@ -0,0 +1,81 @@
+package storage
+
+import "calendar-assistant/pkg/openai"
+
+// UserPreferences stores user-specific settings
+type UserPreferences struct {
+	Timezone       string `json:"timezone"`                  // IANA timezone name (e.g., "Europe/London", "America/New_York"), empty if not set
+	Language       string `json:"language,omitempty"`        // Preferred language code (e.g., "en", "ru")
+	LanguageChosen bool   `json:"language_chosen,omitempty"` // The user picked Language, rather than getting the deployment's default
+
+	Accessibility bool `json:"accessibility,omitempty"` // Also describe the content of images in plain language
+	ReadBack      bool `json:"read_back,omitempty"`     // Confirm each event before its file is created
+	SkipPreview   bool `json:"skip_preview,omitempty"`  // Send event files right away instead of a preview to confirm
+
+	ReminderMinutes int    `json:"reminder_minutes,omitempty"` // Default reminder in minutes before an event, 0 for none
+	QRCode          bool   `json:"qr_code,omitempty"`          // Also send a QR code of each event
+	DigestTime      string `json:"digest_time,omitempty"`      // Local time (HH:MM) of the daily digest of the user's events, empty if off
+	FollowUp        bool   `json:"follow_up,omitempty"`        // Get asked whether each event was accurate once it's over
+
+	TravelMinutes int         `json:"travel_minutes,omitempty"` // Travel time blocked before events with a place, 0 for none
+	HomeLocation  string      `json:"home_location,omitempty"`  // Address travel times are estimated from, empty if not set
+	Home          *openai.Geo `json:"home,omitempty"`           // Coordinates of HomeLocation
+	Weather       bool        `json:"weather,omitempty"`        // Add the forecast to the description of outdoor events
+
+	WhatsNew         bool   `json:"whats_new,omitempty"`          // Get a summary of each new release
+	SeenReleaseNotes string `json:"seen_release_notes,omitempty"` // Version of the last release notes the user got
+}
+
+// Users returns a copy of all stored user preferences
+func (s *Store) Users() map[string]UserPreferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[string]UserPreferences, len(s.data.Users))
+	for userID, prefs := range s.data.Users {
+		users[userID] = prefs
+	}
+	return users
+}
+
+// SaveUser stores the preferences of a user
+func (s *Store) SaveUser(userID string, prefs UserPreferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Users == nil {
+		s.data.Users = make(map[string]UserPreferences)
+	}
+	s.data.Users[userID] = prefs
+
+	return s.saveLocked()
+}
+
+// User returns the preferences of a user, and whether they're stored
+func (s *Store) User(userID string) (UserPreferences, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs, ok := s.data.Users[userID]
+	return prefs, ok
+}
+
+// UpdateUser changes the preferences of a user, starting from defaults if none are stored yet,
+// and stores them. All bots share the store, so changes are made under its lock rather than
+// to a copy one bot read earlier.
+func (s *Store) UpdateUser(userID string, defaults UserPreferences, update func(prefs *UserPreferences)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Users == nil {
+		s.data.Users = make(map[string]UserPreferences)
+	}
+	prefs, ok := s.data.Users[userID]
+	if !ok {
+		prefs = defaults
+	}
+	update(&prefs)
+	s.data.Users[userID] = prefs
+
+	return s.saveLocked()
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestUpdateUser(t *testing.T) {
+	s := openTestStore(t)
+	defaults := UserPreferences{Language: "en"}
+
+	if err := s.UpdateUser("1", defaults, func(prefs *UserPreferences) { prefs.Timezone = "Europe/Berlin" }); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	// Defaults only apply to users that aren't stored yet
+	if err := s.UpdateUser("1", UserPreferences{Language: "ru"}, func(prefs *UserPreferences) { prefs.QRCode = true }); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	want := UserPreferences{Language: "en", Timezone: "Europe/Berlin", QRCode: true}
+	for name, store := range map[string]*Store{"open": s, "reopened": reopen(t, s)} {
+		got, ok := store.User("1")
+		if !ok || got != want {
+			t.Errorf("%s: User() = %+v, %v, want %+v, true", name, got, ok, want)
+		}
+	}
+
+	if _, ok := s.User("2"); ok {
+		t.Errorf("User() of an unknown user = _, true, want false")
+	}
+}
+
+func TestUpdateUserConcurrent(t *testing.T) {
+	s := openTestStore(t)
+
+	// Two bots changing different settings of the same user keep each other's changes
+	updates := []func(prefs *UserPreferences){
+		func(prefs *UserPreferences) { prefs.Timezone = "Europe/Berlin" },
+		func(prefs *UserPreferences) { prefs.ReminderMinutes = 15 },
+		func(prefs *UserPreferences) { prefs.Weather = true },
+		func(prefs *UserPreferences) { prefs.DigestTime = "08:00" },
+	}
+	var wg sync.WaitGroup
+	for _, update := range updates {
+		wg.Add(1)
+		go func(update func(prefs *UserPreferences)) {
+			defer wg.Done()
+			if err := s.UpdateUser("1", UserPreferences{}, update); err != nil {
+				t.Errorf("UpdateUser() error = %v", err)
+			}
+		}(update)
+	}
+	wg.Wait()
+
+	want := UserPreferences{Timezone: "Europe/Berlin", ReminderMinutes: 15, Weather: true, DigestTime: "08:00"}
+	if got, _ := s.User("1"); got != want {
+		t.Errorf("User() = %+v, want %+v", got, want)
+	}
+}
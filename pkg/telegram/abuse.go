@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// strikeWindow is how long a hit rate limit counts towards a temporary block
+const strikeWindow = time.Hour
+
+// rateLimiter limits how many events each user can request per minute and counts how often
+// they hit the limit
+type rateLimiter struct {
+	limit    int // Requests per minute, 0 if unlimited
+	mu       sync.Mutex
+	requests map[string][]time.Time // Requests of the last minute per user
+	strikes  map[string][]time.Time // Hit limits of the last strikeWindow per user
+}
+
+// newRateLimiter creates a rate limiter allowing limit requests per minute
+func newRateLimiter(limit int) *rateLimiter {
+	return &rateLimiter{
+		limit:    limit,
+		requests: make(map[string][]time.Time),
+		strikes:  make(map[string][]time.Time),
+	}
+}
+
+// allow records a request and reports whether it's within the limit. When it isn't, it also
+// returns how often the user hit the limit recently; a burst counts as a single strike.
+func (l *rateLimiter) allow(userID string, now time.Time) (bool, int) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	requests := keepSince(l.requests[userID], now.Add(-time.Minute))
+	strikes := keepSince(l.strikes[userID], now.Add(-strikeWindow))
+	defer func() {
+		l.requests[userID] = requests
+		l.strikes[userID] = strikes
+	}()
+
+	if len(requests) < l.limit {
+		requests = append(requests, now)
+		return true, len(strikes)
+	}
+
+	if len(strikes) == 0 || now.Sub(strikes[len(strikes)-1]) >= time.Minute {
+		strikes = append(strikes, now)
+	}
+	return false, len(strikes)
+}
+
+// reset forgets a user's requests and strikes, e.g. after they were blocked
+func (l *rateLimiter) reset(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.requests, userID)
+	delete(l.strikes, userID)
+}
+
+// keepSince returns the times at or after cutoff, which are in ascending order
+func keepSince(times []time.Time, cutoff time.Time) []time.Time {
+	for i, t := range times {
+		if !t.Before(cutoff) {
+			return times[i:]
+		}
+	}
+	return nil
+}
+
+// allowRequest checks the rate limit and daily quota before a request that costs API budget,
+// telling the user when they have to wait. Users who hit the rate limit too often are blocked
+// temporarily.
+func (b *Bot) allowRequest(message *tgbotapi.Message) bool {
+	return b.allowUserRequest(message.From, func(text string) {
+		b.sendText(message.Chat.ID, text, message.MessageID)
+	})
+}
+
+// allowUserRequest is allowRequest for requests that don't come with a message to reply to,
+// such as inline queries, telling the user why they have to wait through notify
+func (b *Bot) allowUserRequest(user *tgbotapi.User, notify func(text string)) bool {
+	userID := fmt.Sprintf("%d", user.ID)
+	if b.isAdmin(userID) {
+		return true
+	}
+
+	allowed, strikes := b.limiter.allow(userID, time.Now())
+	if allowed {
+		return b.allowUserQuota(user, notify)
+	}
+
+	if strikes < b.cfg.RateLimitStrikes {
+		log.Printf("User %s hit the rate limit (%d/%d strikes)", userID, strikes, b.cfg.RateLimitStrikes)
+		notify(b.t(user, "abuse.rate_limited", b.cfg.RateLimitPerMinute))
+		return false
+	}
+
+	ban := storage.Ban{
+		UserID:    userID,
+		Reason:    "hit the rate limit repeatedly",
+		ExpiresAt: time.Now().Add(b.cfg.RateLimitBlock),
+	}
+	if err := b.store.SetBan(ban); err != nil {
+		log.Printf("Error blocking user %s: %v", userID, err)
+	}
+	b.limiter.reset(userID)
+	log.Printf("Blocked user %s until %s for hitting the rate limit repeatedly", userID, ban.ExpiresAt.Format(time.RFC3339))
+
+	notify(b.t(user, "abuse.blocked", b.cfg.RateLimitBlock))
+	return false
+}
+
+// banMiddleware ignores messages from banned users. They're told about the ban in private
+// chats, so the bot stays quiet in groups.
+func (b *Bot) banMiddleware(r route, next commandHandler) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message) {
+		if message.From == nil {
+			next(ctx, message)
+			return
+		}
+
+		ban, banned := b.store.ActiveBan(fmt.Sprintf("%d", message.From.ID))
+		if !banned {
+			next(ctx, message)
+			return
+		}
+		log.Printf("Ignored %s from banned user %d", r.name, message.From.ID)
+
+		if message.Chat.IsPrivate() {
+			b.sendText(message.Chat.ID, b.banNotice(message.From, ban), message.MessageID)
+		}
+	}
+}
+
+// banNotice tells a user about their ban
+func (b *Bot) banNotice(user *tgbotapi.User, ban storage.Ban) string {
+	if ban.ExpiresAt.IsZero() {
+		return b.t(user, "abuse.banned")
+	}
+	return b.t(user, "abuse.banned_until", ban.ExpiresAt.UTC().Format("2006-01-02 15:04"))
+}
+
+// handleBan bans a user, permanently or for a while. The user is given by ID or by replying
+// to one of their messages: /ban <user ID> [duration] [reason]
+func (b *Bot) handleBan(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	adminID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+	usage := b.t(message.From, "ban.usage")
+
+	args := strings.Fields(message.CommandArguments())
+	var userID string
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && !reply.From.IsBot {
+		userID = fmt.Sprintf("%d", reply.From.ID)
+	} else if len(args) > 0 {
+		if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+			b.sendText(chatID, usage, messageID)
+			return
+		}
+		userID, args = args[0], args[1:]
+	} else {
+		b.sendText(chatID, usage, messageID)
+		return
+	}
+
+	if b.isAdmin(userID) {
+		b.sendError(message, "ban.admin", nil)
+		return
+	}
+
+	ban := storage.Ban{UserID: userID, BannedBy: adminID}
+	if len(args) > 0 {
+		if duration, err := parseBanDuration(args[0]); err == nil {
+			ban.ExpiresAt = time.Now().Add(duration)
+			args = args[1:]
+		}
+	}
+	ban.Reason = strings.Join(args, " ")
+
+	if err := b.store.SetBan(ban); err != nil {
+		log.Printf("Error banning user %s: %v", userID, err)
+		b.sendError(message, "ban.failed", err)
+		return
+	}
+	log.Printf("Admin %s banned user %s until %v: %s", adminID, userID, ban.ExpiresAt, ban.Reason)
+
+	if ban.ExpiresAt.IsZero() {
+		b.sendText(chatID, b.t(message.From, "ban.banned", userID), messageID)
+	} else {
+		b.sendText(chatID, b.t(message.From, "ban.banned_until", userID, ban.ExpiresAt.UTC().Format("2006-01-02 15:04")), messageID)
+	}
+}
+
+// handleUnban lifts the ban of a user
+func (b *Bot) handleUnban(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	userID := strings.TrimSpace(message.CommandArguments())
+	if reply := message.ReplyToMessage; userID == "" && reply != nil && reply.From != nil {
+		userID = fmt.Sprintf("%d", reply.From.ID)
+	}
+	if _, err := strconv.ParseInt(userID, 10, 64); err != nil {
+		b.sendText(chatID, b.t(message.From, "unban.usage"), messageID)
+		return
+	}
+
+	found, err := b.store.DeleteBan(userID)
+	if err != nil {
+		log.Printf("Error unbanning user %s: %v", userID, err)
+		b.sendError(message, "unban.failed", err)
+		return
+	}
+	if !found {
+		b.sendText(chatID, b.t(message.From, "unban.not_banned", userID), messageID)
+		return
+	}
+	b.limiter.reset(userID)
+	log.Printf("Unbanned user %s", userID)
+
+	b.sendText(chatID, b.t(message.From, "unban.done", userID), messageID)
+}
+
+// parseBanDuration parses a ban duration, which may also be given in days, e.g. "7d"
+func parseBanDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
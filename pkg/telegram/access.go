@@ -0,0 +1,36 @@
+package telegram
+
+import (
+	"context"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isAllowedUser reports whether a user may use the bot, which everyone may unless the
+// operator restricted it to an allowlist. Admins are always allowed.
+func (b *Bot) isAllowedUser(userID int64) bool {
+	return len(b.allowedUsers) == 0 || b.allowedUsers[userID] || b.admins[userID]
+}
+
+// accessMiddleware turns away users who aren't allowed to use a private bot before any of
+// their messages cost API budget. Unknown users only get an answer in private chats, so the
+// bot stays quiet in groups.
+func (b *Bot) accessMiddleware(r route, next commandHandler) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message) {
+		if message.From != nil && b.isAllowedUser(message.From.ID) {
+			next(ctx, message)
+			return
+		}
+
+		if message.From == nil {
+			log.Printf("Ignored %s without a sender in private mode in chat %d", r.name, message.Chat.ID)
+			return
+		}
+		log.Printf("Denied %s to user %d, who isn't on the allowlist", r.name, message.From.ID)
+
+		if message.Chat.IsPrivate() {
+			b.sendText(message.Chat.ID, b.t(message.From, "access.private", message.From.ID), message.MessageID)
+		}
+	}
+}
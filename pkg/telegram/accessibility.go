@@ -0,0 +1,26 @@
+package telegram
+
+import (
+	"context"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAccessibility shows or changes whether a user gets plain-language descriptions of
+// the images they send, for screen reader users
+func (b *Bot) handleAccessibility(ctx context.Context, message *tgbotapi.Message) {
+	b.handleToggle(message, toggleSetting{
+		key:  "accessibility",
+		name: "accessibility mode",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.Accessibility },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.Accessibility = enabled },
+	})
+}
+
+// sendContentDescription sends the plain-language description of an image as its own
+// message without formatting, so screen readers read it out as is
+func (b *Bot) sendContentDescription(message *tgbotapi.Message, description string) {
+	b.sendText(message.Chat.ID, b.t(message.From, "accessibility.description", description), message.MessageID)
+}
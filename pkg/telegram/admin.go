@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// adminHelpText lists the admin commands
+const adminHelpText = `Admin commands:
+/admin - Show this list
+/refresh_commands - Refresh the bot's command list
+/export_users - Export all user preferences as JSON
+/ban - Ban a user, permanently or for a while (e.g. /ban 123456789 7d spam)
+/unban - Lift a user's ban
+/accuracy - Show extraction accuracy by input type and language
+/stats - Show active users, extractions, error rate, latency and OpenAI token usage
+/broadcast - Preview an announcement to all users, then send it (e.g. /broadcast We'll be down for maintenance at 22:00 UTC)`
+
+// adminCommands are added to the command autocompletions in the private chats of admins
+var adminCommands = []tgbotapi.BotCommand{
+	{Command: "admin", Description: "List the admin commands"},
+	{Command: "refresh_commands", Description: "Refresh the bot's command list"},
+	{Command: "export_users", Description: "Export all user preferences as JSON"},
+	{Command: "ban", Description: "Ban a user, permanently or for a while"},
+	{Command: "unban", Description: "Lift a user's ban"},
+	{Command: "accuracy", Description: "Show extraction accuracy by input type and language"},
+	{Command: "stats", Description: "Show usage, error rate, latency and token usage"},
+	{Command: "broadcast", Description: "Preview an announcement to all users, then send it"},
+}
+
+// setupAdminCommands shows the admin commands in the autocompletions of the admins' private
+// chats, on top of the regular ones, so other users don't see them. An admin who never
+// started the bot has no private chat yet, which doesn't stop the others from getting them.
+func (b *Bot) setupAdminCommands(commands []tgbotapi.BotCommand) {
+	all := append(append([]tgbotapi.BotCommand{}, commands...), adminCommands...)
+	for adminID := range b.admins {
+		scope := tgbotapi.NewBotCommandScopeChat(adminID)
+		if _, err := b.bot.Request(tgbotapi.NewSetMyCommandsWithScope(scope, all...)); err != nil {
+			log.Printf("Warning: Failed to set admin commands for user %d: %v", adminID, err)
+		}
+	}
+}
+
+// adminMiddleware restricts admin routes to the admins configured with ADMIN_USER_IDS
+func (b *Bot) adminMiddleware(r route, next commandHandler) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message) {
+		if !r.adminOnly || (message.From != nil && b.isAdmin(fmt.Sprintf("%d", message.From.ID))) {
+			next(ctx, message)
+			return
+		}
+
+		if message.From != nil {
+			log.Printf("Denied admin command %s to user %d", r.name, message.From.ID)
+		}
+		b.sendError(message, "admin.not_authorized", nil)
+	}
+}
+
+// handleAdminHelp lists the admin commands
+func (b *Bot) handleAdminHelp(ctx context.Context, message *tgbotapi.Message) {
+	b.sendText(message.Chat.ID, adminHelpText, message.MessageID)
+}
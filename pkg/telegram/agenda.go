@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleToday lists the user's events of today
+func (b *Bot) handleToday(ctx context.Context, message *tgbotapi.Message) {
+	b.sendAgenda(message, b.userNow(message.From).Format("2006-01-02"))
+}
+
+// handleAgenda lists the user's events of a day, e.g. /agenda tomorrow or /agenda 2025-06-01
+func (b *Bot) handleAgenda(ctx context.Context, message *tgbotapi.Message) {
+	day, ok := parseAgendaDay(message.CommandArguments(), b.userNow(message.From))
+	if !ok {
+		b.sendError(message, "agenda.usage", nil)
+		return
+	}
+	b.sendAgenda(message, day.Format("2006-01-02"))
+}
+
+// userNow returns the current time in a user's timezone
+func (b *Bot) userNow(user *tgbotapi.User) time.Time {
+	prefs := b.getUserPreferences(fmt.Sprintf("%d", user.ID))
+	name := b.userTimezone(prefs)
+
+	loc, _ := b.timezones.Resolve(name)
+	return time.Now().In(loc)
+}
+
+// parseAgendaDay parses the day of /agenda: empty for today, "today", "tomorrow", "yesterday",
+// a weekday for its next occurrence, or a date like 2025-06-01 or 01.06.2025
+func parseAgendaDay(text string, now time.Time) (time.Time, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	switch text {
+	case "", "today":
+		return now, true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true
+	}
+
+	for i := 0; i < 7; i++ {
+		day := now.AddDate(0, 0, i)
+		if name := strings.ToLower(day.Weekday().String()); text == name || text == name[:3] {
+			return day, true
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006"} {
+		if day, err := time.ParseInLocation(layout, text, now.Location()); err == nil {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sendAgenda sends the list of the user's events on a day, given as 2006-01-02
+func (b *Bot) sendAgenda(message *tgbotapi.Message, date string) {
+	events := b.eventsOn(fmt.Sprintf("%d", message.From.ID), date)
+	if len(events) == 0 {
+		b.sendText(message.Chat.ID, b.t(message.From, "agenda.empty", date), message.MessageID)
+		return
+	}
+
+	text := b.agendaText(message.From, b.t(message.From, "agenda.header", date), events)
+	b.sendMarkdown(message.Chat.ID, text, message.MessageID)
+}
+
+// agendaText lists events under a header as MarkdownV2, one per line with the title in bold and
+// the time monospaced, in the user's time format
+func (b *Bot) agendaText(user *tgbotapi.User, header string, events []*openai.Event) string {
+	clock := b.t(user, "format.time")
+
+	var sb strings.Builder
+	sb.WriteString(escapeMarkdown(header))
+	for _, event := range events {
+		sb.WriteString("\n")
+		if calendar.IsAllDay(event) {
+			sb.WriteString(markdownCode(b.t(user, "agenda.all_day")))
+		} else {
+			sb.WriteString(markdownCode(event.StartTime.Format(clock) + "–" + event.EndTime.Format(clock)))
+		}
+		sb.WriteString("  " + markdownBold(event.Title))
+		if event.Location != "" {
+			sb.WriteString(" · " + markdownLocation(event.Location))
+		}
+	}
+	return sb.String()
+}
+
+// eventsOn returns the events in a user's history that take place on a day, given as
+// 2006-01-02, in order of their start
+func (b *Bot) eventsOn(userID, date string) []*openai.Event {
+	// Event times are wall-clock times in the user's timezone, stored as UTC
+	dayStart, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil
+	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var events []*openai.Event
+	for _, event := range b.storedEvents(userID) {
+		if event.Status == openai.StatusCancelled {
+			continue // Called off or removed with /delete
+		}
+		if !event.StartTime.Before(dayEnd) {
+			continue
+		}
+		if end := calendar.EventEnd(event); end.After(event.StartTime) && !end.After(dayStart) ||
+			!end.After(event.StartTime) && event.StartTime.Before(dayStart) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// storedEvents returns the events in a user's history in order of their start. Events
+// extracted again count once, as their latest version.
+func (b *Bot) storedEvents(userID string) []*openai.Event {
+	history := b.store.History(userID)
+	seen := make(map[string]bool)
+	var events []*openai.Event
+	for i := len(history) - 1; i >= 0; i-- {
+		event := history[i].Event
+		if event == nil {
+			continue
+		}
+
+		key := eventKey(event)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events
+}
+
+// eventKey identifies an event across extractions by its UID, so a correction replaces it, or
+// by its normalized title and start if it was never sent
+func eventKey(event *openai.Event) string {
+	if event.UID != "" {
+		return event.UID
+	}
+	return strings.ToLower(strings.Join(strings.Fields(event.Title), " ")) + "|" + event.StartTime.Format(time.RFC3339)
+}
@@ -0,0 +1,145 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// userAPIKey returns the decrypted API key of a user, or "" if they don't have one
+func (b *Bot) userAPIKey(userID string) string {
+	encrypted := b.store.APIKey(userID)
+	if encrypted == "" {
+		return ""
+	}
+
+	apiKey, err := b.cipher.Decrypt(encrypted)
+	if err != nil {
+		// Fall back to the operator's key rather than failing the user's request
+		log.Printf("Error decrypting API key for user %s: %v", userID, err)
+		return ""
+	}
+	return apiKey
+}
+
+// handleAPIKey shows, sets or removes a user's own OpenAI API key
+func (b *Bot) handleAPIKey(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+
+	if b.cipher == nil {
+		b.sendError(message, "apikey.disabled", nil)
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	switch {
+	case len(args) == 0:
+		b.sendAPIKeyStatus(message)
+		return
+	case len(args) == 1 && strings.EqualFold(args[0], "remove"):
+		if err := b.store.DeleteAPIKey(userID); err != nil {
+			log.Printf("Error deleting API key for user %s: %v", userID, err)
+			b.sendError(message, "apikey.remove_failed", err)
+			return
+		}
+		b.sendText(chatID, b.t(message.From, "apikey.removed"), messageID)
+		return
+	}
+
+	// Never leave a key sitting in the chat history; replies can't quote the deleted message
+	b.deleteMessage(chatID, messageID)
+	if !message.Chat.IsPrivate() {
+		b.sendUserError(message.From, chatID, 0, "apikey.private_only", nil)
+		return
+	}
+
+	targetID := userID
+	apiKey := args[0]
+	if len(args) == 2 {
+		// Admins listed in ADMIN_USER_IDS can register a key on behalf of another user
+		if !b.isAdmin(userID) {
+			log.Printf("Denied setting an API key for user %s to non-admin %s", args[0], userID)
+			b.sendUserError(message.From, chatID, 0, "apikey.not_admin", nil)
+			return
+		}
+		if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+			b.sendUserError(message.From, chatID, 0, "apikey.invalid_user", nil, args[0])
+			return
+		}
+		// A user's own key is theirs alone, so it's never replaced by someone else's
+		if args[0] != userID && b.store.APIKey(args[0]) != "" {
+			b.sendUserError(message.From, chatID, 0, "apikey.user_has_key", nil, args[0])
+			return
+		}
+		targetID, apiKey = args[0], args[1]
+	} else if len(args) > 2 {
+		b.sendUserError(message.From, chatID, 0, "apikey.usage", nil)
+		return
+	}
+
+	if err := openai.ValidateAPIKey(ctx, apiKey); err != nil {
+		log.Printf("API key validation failed for user %s: %v", targetID, err)
+		b.sendUserError(message.From, chatID, 0, "apikey.rejected", nil)
+		return
+	}
+
+	encrypted, err := b.cipher.Encrypt(apiKey)
+	if err != nil {
+		log.Printf("Error encrypting API key for user %s: %v", targetID, err)
+		b.sendUserError(message.From, chatID, 0, "apikey.store_failed", nil)
+		return
+	}
+	if err := b.store.SetAPIKey(targetID, encrypted); err != nil {
+		log.Printf("Error saving API key for user %s: %v", targetID, err)
+		b.sendUserError(message.From, chatID, 0, "apikey.store_failed", nil)
+		return
+	}
+
+	log.Printf("Stored API key for user %s (set by %s)", targetID, userID)
+	if targetID == userID {
+		b.sendText(chatID, b.t(message.From, "apikey.saved", maskAPIKey(apiKey)), 0)
+	} else {
+		b.sendText(chatID, b.t(message.From, "apikey.saved_for", maskAPIKey(apiKey), targetID), 0)
+	}
+}
+
+// sendAPIKeyStatus tells a user whether they're using their own key
+func (b *Bot) sendAPIKeyStatus(message *tgbotapi.Message) {
+	text := b.t(message.From, "apikey.status_bot")
+	if apiKey := b.userAPIKey(fmt.Sprintf("%d", message.From.ID)); apiKey != "" {
+		text = b.t(message.From, "apikey.status_own", maskAPIKey(apiKey))
+	}
+	b.sendText(message.Chat.ID, text, message.MessageID)
+}
+
+// maskAPIKey hides all but the last characters of an API key
+func maskAPIKey(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
+
+// sendText sends a plain text message, replying to messageID if it's non-zero
+func (b *Bot) sendText(chatID int64, text string, messageID int) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
+
+// deleteMessage deletes a message, logging any failure
+func (b *Bot) deleteMessage(chatID int64, messageID int) {
+	if _, err := b.bot.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		log.Printf("Error deleting message %d: %v", messageID, err)
+	}
+}
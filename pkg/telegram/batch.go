@@ -0,0 +1,289 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/imaging"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// batchTTL is how long a batch session stays open without new messages
+const batchTTL = time.Hour
+
+// maxBatchSize limits how many messages a batch can collect
+const maxBatchSize = 30
+
+// batchSession collects the messages a user forwards between /batch and /done. A user's
+// handlers run one at a time, so a session is never changed concurrently.
+type batchSession struct {
+	chatID   int64
+	messages []*tgbotapi.Message
+}
+
+// batchResult is the outcome of the extraction of one message of a batch
+type batchResult struct {
+	message *tgbotapi.Message
+	event   *openai.Event
+	err     error
+}
+
+// handleBatch starts a batch session, or cancels one with /batch cancel
+func (b *Bot) handleBatch(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+
+	if strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "cancel") {
+		if _, ok := b.batches.Get(userID); !ok {
+			b.sendText(chatID, b.t(message.From, "batch.none"), message.MessageID)
+			return
+		}
+		b.batches.Delete(userID)
+		b.sendText(chatID, b.t(message.From, "batch.cancelled"), message.MessageID)
+		return
+	}
+
+	prefs := b.eventPreferences(userID, chatID)
+	if b.needsTimezone(prefs) && !b.cfg.AllowEventsWithoutTimezone {
+		b.sendText(chatID, b.t(message.From, "batch.timezone_required"), message.MessageID)
+		return
+	}
+
+	if session, ok := b.batches.Get(userID); ok {
+		b.sendText(chatID, b.t(message.From, "batch.in_progress", len(session.messages)), message.MessageID)
+		return
+	}
+
+	b.batches.Set(userID, &batchSession{chatID: chatID})
+	b.sendText(chatID, b.t(message.From, "batch.started", maxBatchSize), message.MessageID)
+}
+
+// addToBatch collects a message if the user has a batch in progress in this chat, reporting
+// whether it did
+func (b *Bot) addToBatch(message *tgbotapi.Message) bool {
+	if message.From == nil || message.IsCommand() {
+		return false
+	}
+
+	userID := fmt.Sprintf("%d", message.From.ID)
+	session, ok := b.batches.Get(userID)
+	if !ok || session.chatID != message.Chat.ID {
+		return false
+	}
+
+	if len(session.messages) >= maxBatchSize {
+		b.sendText(message.Chat.ID, b.t(message.From, "batch.full", maxBatchSize), message.MessageID)
+		return true
+	}
+
+	session.messages = append(session.messages, message)
+	b.batches.Set(userID, session) // Keeps the session open for another batchTTL
+	log.Printf("Added message %d to the batch of user %s (%d messages)", message.MessageID, userID, len(session.messages))
+	return true
+}
+
+// handleBatchDone processes the messages of a batch and sends back one ICS file with all
+// the distinct events, along with a summary
+func (b *Bot) handleBatchDone(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+
+	session, ok := b.batches.Get(userID)
+	if !ok {
+		b.sendText(chatID, b.t(message.From, "batch.none_start"), messageID)
+		return
+	}
+	if !b.allowRequest(message) {
+		return // The batch stays, so /done can be sent again later
+	}
+	b.batches.Delete(userID)
+
+	if len(session.messages) == 0 {
+		b.sendText(chatID, b.t(message.From, "batch.empty"), messageID)
+		return
+	}
+
+	stopTyping := b.startChatAction(chatID, tgbotapi.ChatTyping)
+	defer stopTyping()
+
+	prefs := b.eventPreferences(userID, chatID)
+	opts := eventOptions{}
+	opts.extract.Timezone = b.userTimezone(prefs)
+	opts.extract.Language = prefs.Language
+
+	var results []batchResult
+	for _, item := range session.messages {
+		event, inputType, rawInput, err := b.extractBatchEvent(ctx, userID, item, opts)
+		if err != nil {
+			log.Printf("Error extracting event from batch message %d: %v", item.MessageID, err)
+			results = append(results, batchResult{message: item, err: err})
+			continue
+		}
+		b.pinToFreeSlot(message.From, event) // There's no one to offer free times to in a batch
+		results = append(results, batchResult{message: item, event: event})
+
+		if _, err := b.store.AddHistory(storage.HistoryEntry{
+			Bot:       b.cfg.BotName,
+			UserID:    userID,
+			ChatID:    chatID,
+			InputType: inputType,
+			RawInput:  rawInput,
+			Event:     event,
+		}); err != nil {
+			log.Printf("Error saving history entry: %v", err)
+		}
+	}
+
+	events, duplicates := dedupeBatchEvents(results)
+	if len(events) == 0 {
+		b.sendError(message, "batch.no_events", nil, len(session.messages))
+		return
+	}
+
+	loc, err := b.timezones.Resolve(b.userTimezone(prefs))
+	if err != nil {
+		b.sendText(chatID, b.t(message.From, "batch.timezone_fallback", err, b.formatTimezoneForDisplay(loc.String())), messageID)
+	}
+
+	b.geocodeEvents(events...)
+	events = b.withWeather(message.From, prefs, events, loc)
+	icsData, err := calendar.GenerateICS(b.withTravel(message.From, prefs, events), loc, b.icsOptions(userID, prefs.ReminderMinutes, true))
+	if err != nil {
+		log.Printf("Error generating ICS file: %v", err)
+		b.sendError(message, "error.ics_generate", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("events_%d.ics", messageID),
+		Bytes: icsData,
+	})
+	doc.Caption = b.t(message.From, "batch.caption", len(events), len(session.messages))
+	doc.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending ICS file: %v", err)
+		b.sendError(message, "error.ics_send", err)
+		return
+	}
+
+	summary := tgbotapi.NewMessage(chatID, b.batchSummary(message.From, events, results, duplicates))
+	summary.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.bot.Send(summary); err != nil {
+		log.Printf("Error sending batch summary: %v", err)
+	}
+}
+
+// errBatchUnsupported is the error of a message in a batch that's neither text nor a photo
+var errBatchUnsupported = errors.New("only text and photos are supported in a batch")
+
+// extractBatchEvent extracts the event of one message of a batch, returning the input type and
+// raw input for the history. Forwarded posts are text or photos, so only those are supported.
+func (b *Bot) extractBatchEvent(ctx context.Context, userID string, message *tgbotapi.Message, opts eventOptions) (*openai.Event, string, string, error) {
+	opts.extract.Context = b.forwardContext(message, opts.extract.Timezone)
+
+	switch {
+	case message.Text != "":
+		event, err := b.extractEventFromText(ctx, userID, message.Text, opts)
+		return event, storage.InputText, message.Text, err
+
+	case len(message.Photo) > 0:
+		photo := message.Photo[len(message.Photo)-1]
+		fileURL, err := b.bot.GetFileDirectURL(photo.FileID)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to get photo URL: %w", err)
+		}
+		imageData, err := b.downloadFile(ctx, fileURL)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to download photo: %w", err)
+		}
+		if _, err := imaging.Detect(imageData); err != nil {
+			return nil, "", "", err
+		}
+
+		// The text of a forwarded post is the caption of its photo
+		opts.extract.Context = withContext(opts.extract.Context, captionContext(message.Caption))
+		event, err := b.extractEventFromImage(ctx, userID, imageData, opts)
+		return event, storage.InputPhoto, photo.FileID, err
+
+	default:
+		return nil, "", "", errBatchUnsupported
+	}
+}
+
+// dedupeBatchEvents returns the distinct events of a batch ordered by start time, and the number
+// of duplicates dropped. Events with the same title and start time are duplicates, e.g. the same
+// announcement forwarded from two channels.
+func dedupeBatchEvents(results []batchResult) ([]*openai.Event, int) {
+	seen := make(map[string]bool)
+	var events []*openai.Event
+	duplicates := 0
+	for _, result := range results {
+		if result.event == nil {
+			continue
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(result.event.Title), " ")) + "|" + result.event.StartTime.Format(time.RFC3339)
+		if seen[key] {
+			duplicates++
+			continue
+		}
+		seen[key] = true
+		events = append(events, result.event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events, duplicates
+}
+
+// batchSummary formats a table of the events of a batch and notes the messages that were
+// skipped, as HTML
+func (b *Bot) batchSummary(user *tgbotapi.User, events []*openai.Event, results []batchResult, duplicates int) string {
+	var table strings.Builder
+	for i, event := range events {
+		when := event.StartTime.Format("Mon 02 Jan 15:04")
+		if calendar.IsMultiDay(event) {
+			when = event.StartTime.Format("02 Jan") + "–" + calendar.LastDay(event).Format("02 Jan")
+		} else if calendar.IsAllDay(event) {
+			when = event.StartTime.Format("Mon 02 Jan") + " " + b.t(user, "batch.all_day")
+		}
+		title := []rune(event.Title)
+		if len(title) > 40 {
+			title = append(title[:39], '…')
+		}
+		fmt.Fprintf(&table, "%2d  %-22s  %s\n", i+1, when, string(title))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>%s</b>\n<pre>%s</pre>", html.EscapeString(b.t(user, "batch.summary")), html.EscapeString(table.String()))
+
+	if duplicates > 0 {
+		sb.WriteString("\n" + html.EscapeString(b.t(user, "batch.duplicates", duplicates)))
+	}
+
+	var failed []string
+	for i, result := range results {
+		switch {
+		case errors.Is(result.err, errBatchUnsupported):
+			failed = append(failed, b.t(user, "batch.failed_message", i+1, b.t(user, "batch.unsupported")))
+		case result.err != nil:
+			failed = append(failed, b.t(user, "batch.failed_message", i+1, result.err))
+		}
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\n" + html.EscapeString(b.t(user, "batch.failed", len(failed), strings.Join(failed, "\n"))))
+	}
+
+	return sb.String()
+}
@@ -2,6 +2,9 @@ package telegram
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,40 +17,145 @@ import (
 	"sync"
 	"time"
 
+	"calendar-assistant/pkg/cache"
 	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/documents"
+	"calendar-assistant/pkg/download"
+	"calendar-assistant/pkg/geocode"
+	"calendar-assistant/pkg/i18n"
+	"calendar-assistant/pkg/imaging"
+	"calendar-assistant/pkg/language"
+	"calendar-assistant/pkg/media"
 	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/scheduler"
+	"calendar-assistant/pkg/secrets"
+	"calendar-assistant/pkg/storage"
+	"calendar-assistant/pkg/timezone"
+	"calendar-assistant/pkg/weather"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/sync/errgroup"
 )
 
-// UserPreferences stores user-specific settings
-type UserPreferences struct {
-	Timezone string // IANA timezone name (e.g., "Europe/London", "America/New_York")
-}
-
 // Bot represents a Telegram bot
 type Bot struct {
-	bot             *tgbotapi.BotAPI
-	openaiClient    *openai.Client
-	userPreferences map[string]*UserPreferences // Map of userID -> preferences
-	prefMutex       sync.RWMutex                // Mutex to protect the preferences map
+	bot               *tgbotapi.BotAPI
+	topics            *topicClient // Keeps replies in the forum topic of the message they reply to
+	cfg               *config.Config
+	openaiClient      *openai.Client
+	store             *storage.Store
+	cipher            *secrets.Cipher // Encrypts users' own API keys, nil if bring-your-own-key is disabled
+	catalog           *i18n.Catalog   // Translations of the bot's messages
+	router            *router
+	callbacks         *callbackRouter
+	answeredCallbacks sync.Map // Map of callback query ID -> whether it was answered while being handled
+	timezones         *timezone.Resolver
+	imageCache        *cache.TTL[openai.Event] // Map of extraction key of an image -> extracted event
+	textCache         *cache.TTL[openai.Event] // Map of extraction key of a normalized text -> extracted event
+	downloader        *download.Client
+	lifecycle         *lifecycle                    // Running handlers, for graceful shutdown
+	queue             *userQueue                    // Runs each user's handlers in order
+	readBacks         *cache.TTL[extractedEvent]    // Map of user ID -> event waiting for confirmation in read-back mode
+	batches           *cache.TTL[*batchSession]     // Map of user ID -> batch in progress
+	plans             *cache.TTL[*dayPlan]          // Map of user ID -> proposed plan that can still be edited
+	broadcasts        *cache.TTL[*pendingBroadcast] // Map of admin user ID -> previewed broadcast
+	inlineQueries     *cache.TTL[string]            // Map of user ID -> ID of their latest inline query
+	timezoneSearches  *cache.TTL[int64]             // Map of user ID -> chat where they're searching for their city
+	previews          *cache.TTL[*eventPreview]     // Map of chat ID:preview message ID -> event waiting for confirmation
+	slotOffers        *cache.TTL[*slotOffer]        // Map of chat ID:offer message ID -> event waiting for a free time to be picked
+	onboarding        *cache.TTL[int]               // Map of user ID -> step of the guided setup they're at
+	allowedUsers      map[int64]bool                // Users allowed to use a private bot, empty if it's public
+	limiter           *rateLimiter                  // Limits how many events each user can request
+	slots             *extractionSlots              // Limits how many extractions run at the same time
+	admins            map[int64]bool                // Users who may use the admin commands
+	scheduler         *scheduler.Scheduler          // Runs the bot's background jobs, set by RegisterJobs
+	geocoder          geocode.Geocoder              // Looks up the coordinates of event locations, nil if disabled
+	weather           *weather.Client               // Forecasts the weather of outdoor events
 }
 
 // NewBot creates a new Telegram bot
-func NewBot(token string, openaiClient *openai.Client) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+func NewBot(cfg *config.Config, openaiClient *openai.Client, store *storage.Store) (*Bot, error) {
+	// Replies in forum topics have to name the topic, which the library doesn't know about, and
+	// requests Telegram rate limits are sent again after the wait it asks for
+	topics := newTopicClient(newFloodClient(&http.Client{}))
+	bot, err := tgbotapi.NewBotAPIWithClient(cfg.TelegramBotToken, tgbotapi.APIEndpoint, topics)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
 
-	// Enable debugging
-	bot.Debug = true
+	// The library logs every request and response, including the texts users send, when debugging
+	bot.Debug = cfg.TelegramDebug
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
+	catalog, err := i18n.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load translations: %w", err)
+	}
+
 	b := &Bot{
-		bot:             bot,
-		openaiClient:    openaiClient,
-		userPreferences: make(map[string]*UserPreferences),
+		bot:              bot,
+		topics:           topics,
+		cfg:              cfg,
+		catalog:          catalog,
+		openaiClient:     openaiClient,
+		store:            store,
+		timezones:        timezone.NewResolver(cfg.DefaultTimezone),
+		imageCache:       cache.NewTTL[openai.Event](cfg.ImageCacheTTL),
+		textCache:        cache.NewTTL[openai.Event](cfg.TextCacheTTL),
+		downloader:       download.NewClient(cfg.DownloadTimeout, int64(cfg.DownloadMaxSize), cfg.DownloadRetries),
+		weather:          weather.New(cfg.WeatherURL),
+		lifecycle:        newLifecycle(),
+		queue:            newUserQueue(),
+		readBacks:        cache.NewTTL[extractedEvent](readBackTTL),
+		batches:          cache.NewTTL[*batchSession](batchTTL),
+		plans:            cache.NewTTL[*dayPlan](planTTL),
+		broadcasts:       cache.NewTTL[*pendingBroadcast](broadcastTTL),
+		inlineQueries:    cache.NewTTL[string](time.Minute),
+		timezoneSearches: cache.NewTTL[int64](timezoneSearchTTL),
+		previews:         cache.NewTTL[*eventPreview](previewTTL),
+		slotOffers:       cache.NewTTL[*slotOffer](freeSlotTTL),
+		onboarding:       cache.NewTTL[int](onboardingTTL),
+		allowedUsers:     make(map[int64]bool),
+		limiter:          newRateLimiter(cfg.RateLimitPerMinute),
+		slots:            newExtractionSlots(cfg.MaxConcurrentExtractions),
+		admins:           make(map[int64]bool),
+	}
+	for _, userID := range cfg.AdminUserIDs {
+		b.admins[userID] = true
+	}
+	if len(b.admins) == 0 {
+		log.Println("Warning: ADMIN_USER_IDS is not set, so admin commands are disabled")
+	}
+	for _, userID := range cfg.AllowedUserIDs {
+		b.allowedUsers[userID] = true
+	}
+	if len(b.allowedUsers) > 0 {
+		log.Printf("Running in private mode for %d allowed users", len(b.allowedUsers))
+	}
+	b.router = b.newCommandRouter()
+	b.callbacks = b.newCallbackRouter()
+	openaiClient.SetUsageRecorder(b.recordTokens)
+
+	log.Printf("Loaded preferences for %d users", len(store.Users()))
+
+	// Enable bring-your-own-key mode if an encryption key is configured
+	if cfg.EncryptionKey != "" {
+		cipher, err := secrets.NewCipher(cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		b.cipher = cipher
+		openaiClient.SetKeyProvider(b.userAPIKey)
+	}
+
+	// Geocode event locations if a provider is configured
+	if cfg.Geocoder != "" {
+		geocoder, err := geocode.New(cfg.Geocoder, cfg.GeocoderAPIKey, cfg.GeocoderURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create geocoder: %w", err)
+		}
+		b.geocoder = geocoder
 	}
 
 	// Set up command autocompletions
@@ -58,83 +166,126 @@ func NewBot(token string, openaiClient *openai.Client) (*Bot, error) {
 	return b, nil
 }
 
-// setupCommands sets up command autocompletions for the bot
+// userCommands are the commands shown in the autocompletions of every chat
+var userCommands = []string{"start", "help", "timezone", "clear", "apikey", "event", "today", "agenda", "export", "digest", "reminder", "travel", "weather", "schedule", "scheduled", "unschedule", "delete", "undo", "batch", "done", "plan", "accessibility", "preview", "readback", "whatsnew", "qr", "followup", "premium", "feedback"}
+
+// groupAdminCommands are additionally shown to group admins
+var groupAdminCommands = []string{"grouprole", "groupallow", "groupdisallow", "chatsettings"}
+
+// botCommands returns commands with their descriptions in a language
+func (b *Bot) botCommands(lang string, names []string) []tgbotapi.BotCommand {
+	commands := make([]tgbotapi.BotCommand, 0, len(names))
+	for _, name := range names {
+		commands = append(commands, tgbotapi.BotCommand{
+			Command:     name,
+			Description: b.catalog.T(lang, "command."+name),
+		})
+	}
+	return commands
+}
+
+// setupCommands sets up command autocompletions for the bot. Users whose Telegram app is in a
+// language with translated commands see them in that language, everyone else in the
+// deployment's language.
 func (b *Bot) setupCommands() error {
-	// Regular user commands
-	commands := []tgbotapi.BotCommand{
-		{
-			Command:     "start",
-			Description: "Start the bot",
-		},
-		{
-			Command:     "help",
-			Description: "Show help information",
-		},
-		{
-			Command:     "timezone",
-			Description: "View or set your timezone (e.g., /timezone Europe/London or /timezone GMT+3)",
-		},
-		{
-			Command:     "clear",
-			Description: "Clear your conversation history",
-		},
-		{
-			Command:     "refresh_commands",
-			Description: "Admin only: Refresh the bot's command list",
-		},
-	}
-
-	// Set regular commands for all users
-	config := tgbotapi.NewSetMyCommands(commands...)
-	_, err := b.bot.Request(config)
-	if err != nil {
-		return fmt.Errorf("failed to set regular commands: %w", err)
+	lang := b.defaultLanguage()
+	if err := b.setCommands(lang, ""); err != nil {
+		return err
+	}
+	for _, code := range b.catalog.Languages() {
+		if !b.catalog.Translates(code, "command.start") {
+			continue // Only some messages are translated, e.g. captions
+		}
+		if err := b.setCommands(code, code); err != nil {
+			return err
+		}
 	}
 
-	// TODO: In the future, implement admin-specific commands using scopes
-	// Example:
-	// adminCommands := append(commands, tgbotapi.BotCommand{Command: "refresh_commands", Description: "Refresh the bot's command list"})
-	// adminScope := tgbotapi.NewBotCommandScopeChat(adminChatID)
-	// adminConfig := tgbotapi.NewSetMyCommandsWithScope(adminScope, adminCommands...)
+	// Admins additionally see the admin commands in their private chats
+	b.setupAdminCommands(b.botCommands(lang, userCommands))
 
 	log.Println("Successfully set up command autocompletions")
 	return nil
 }
 
-// getUserPreferences gets or creates user preferences
-func (b *Bot) getUserPreferences(userID string) *UserPreferences {
-	b.prefMutex.RLock()
-	prefs, exists := b.userPreferences[userID]
-	b.prefMutex.RUnlock()
+// setCommands sets the commands of all chats in a language, for the users whose app is in the
+// language code, or for everyone else if it's empty
+func (b *Bot) setCommands(lang, languageCode string) error {
+	commands := b.botCommands(lang, userCommands)
+	config := tgbotapi.NewSetMyCommands(commands...)
+	config.LanguageCode = languageCode
+	if _, err := b.bot.Request(config); err != nil {
+		return fmt.Errorf("failed to set regular commands for language %q: %w", languageCode, err)
+	}
+
+	// Group admins additionally see the permission commands
+	groupCommands := append(commands, b.botCommands(lang, groupAdminCommands)...)
+	groupAdminConfig := tgbotapi.NewSetMyCommandsWithScope(tgbotapi.NewBotCommandScopeAllChatAdministrators(), groupCommands...)
+	groupAdminConfig.LanguageCode = languageCode
+	if _, err := b.bot.Request(groupAdminConfig); err != nil {
+		return fmt.Errorf("failed to set group admin commands for language %q: %w", languageCode, err)
+	}
+	return nil
+}
+
+// defaultPreferences returns the preferences of a new user; the timezone stays empty until
+// the user sets one
+func (b *Bot) defaultPreferences() storage.UserPreferences {
+	return storage.UserPreferences{
+		Language: b.cfg.DefaultLanguage,
+	}
+}
 
-	if !exists {
-		// Create default preferences
-		prefs = &UserPreferences{
-			Timezone: "UTC", // Default to UTC
-		}
-		b.prefMutex.Lock()
-		b.userPreferences[userID] = prefs
-		b.prefMutex.Unlock()
+// getUserPreferences returns a copy of the stored preferences of a user, or the defaults.
+// The store is shared by all bots, so it's read each time rather than cached.
+func (b *Bot) getUserPreferences(userID string) *storage.UserPreferences {
+	prefs, ok := b.store.User(userID)
+	if !ok {
+		prefs = b.defaultPreferences()
 	}
+	return &prefs
+}
 
-	return prefs
+// userTimezone returns the timezone to use for a user, falling back to the deployment default
+func (b *Bot) userTimezone(prefs *storage.UserPreferences) string {
+	if prefs.Timezone != "" {
+		return prefs.Timezone
+	}
+	if b.cfg.DefaultTimezone != "" {
+		return b.cfg.DefaultTimezone
+	}
+	return "UTC"
+}
+
+// needsTimezone reports whether the user has to set a timezone before creating events,
+// which is only the case when the operator hasn't configured a default
+func (b *Bot) needsTimezone(prefs *storage.UserPreferences) bool {
+	return prefs.Timezone == "" && b.cfg.DefaultTimezone == ""
 }
 
 // setUserTimezone sets the timezone for a user
 func (b *Bot) setUserTimezone(userID string, timezone string) {
-	prefs := b.getUserPreferences(userID)
-
-	b.prefMutex.Lock()
-	prefs.Timezone = timezone
-	b.prefMutex.Unlock()
-
+	b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+		prefs.Timezone = timezone
+	})
 	log.Printf("Set timezone for user %s to %s", userID, timezone)
 }
 
-// Start starts the bot
+// updateUserPreferences changes the preferences of a user and saves them
+func (b *Bot) updateUserPreferences(userID string, update func(prefs *storage.UserPreferences)) {
+	if err := b.store.UpdateUser(userID, b.defaultPreferences(), update); err != nil {
+		log.Printf("Error saving preferences for user %s: %v", userID, err)
+	}
+}
+
+// updateOffsetMaxAge is how recent the last processed update must be to resume polling after it
+const updateOffsetMaxAge = 6 * 24 * time.Hour
+
+// Start starts the bot, receiving updates by long polling
 func (b *Bot) Start() error {
 	log.Println("Setting up update configuration...")
-	u := tgbotapi.NewUpdate(0)
+	// Resume after the last processed update, so updates aren't delivered again after a restart
+	u := tgbotapi.NewUpdate(b.store.UpdateOffset(b.cfg.BotName, updateOffsetMaxAge))
 	u.Timeout = 60
 
 	log.Println("Getting updates channel...")
@@ -142,142 +293,394 @@ func (b *Bot) Start() error {
 	log.Println("Update channel established, waiting for messages...")
 
 	for update := range updates {
-		log.Printf("Received update: %+v", update)
-		if update.Message == nil {
-			log.Println("Update contains no message, skipping")
-			continue
-		}
-
-		log.Printf("Processing message: %s from user: %s", update.Message.Text, update.Message.From.UserName)
-		go b.handleMessage(update.Message)
+		b.handleUpdate(update)
 	}
 
 	return nil
 }
 
+// handleUpdate dispatches an update, however it was received, to its handler
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	log.Printf("Received %s", describeUpdate(update))
+	if b.Stopping() {
+		log.Println("Shutting down, skipping update")
+		return
+	}
+
+	// Telegram delivers updates again when it isn't sure they arrived, e.g. after a restart
+	if b.store.UpdateProcessed(b.cfg.BotName, update.UpdateID) {
+		log.Printf("Update %d was already processed, skipping", update.UpdateID)
+		return
+	}
+
+	// Updates dropped by a shutdown aren't marked, so they're processed after the restart
+	if b.dispatchUpdate(update) {
+		b.store.MarkUpdate(b.cfg.BotName, update.UpdateID)
+	}
+}
+
+// dispatchUpdate starts the handler of an update, reporting false if it was dropped
+func (b *Bot) dispatchUpdate(update tgbotapi.Update) bool {
+	if update.CallbackQuery != nil {
+		return b.goHandler(update.CallbackQuery.From.ID, func(ctx context.Context) { b.handleCallbackQuery(ctx, update.CallbackQuery) })
+	}
+
+	if update.InlineQuery != nil {
+		b.debounceInlineQuery(update.InlineQuery)
+		return true
+	}
+
+	if update.PreCheckoutQuery != nil {
+		return b.goHandler(update.PreCheckoutQuery.From.ID, func(context.Context) { b.handlePreCheckoutQuery(update.PreCheckoutQuery) })
+	}
+
+	if update.MyChatMember != nil {
+		return b.goHandler(update.MyChatMember.From.ID, func(context.Context) { b.handleMyChatMember(update.MyChatMember) })
+	}
+
+	if update.Message == nil {
+		log.Println("Update contains no message, skipping")
+		return true
+	}
+
+	log.Printf("Processing message %d from user: %s", update.Message.MessageID, update.Message.From.UserName)
+	return b.goHandler(update.Message.From.ID, func(ctx context.Context) { b.handleMessage(ctx, update.Message) })
+}
+
+// newCommandRouter registers the bot's command handlers
+func (b *Bot) newCommandRouter() *router {
+	r := newRouter()
+	r.use(b.groupChatMiddleware)
+	r.use(b.accessMiddleware)
+	r.use(b.banMiddleware)
+	r.use(b.adminMiddleware)
+	r.use(b.groupPermissionMiddleware)
+
+	r.handle("start", "", b.handleStart)
+	r.handle("clear", "", b.handleClear)
+	r.handle("timezone", "", b.handleTimezone)
+	r.handle("help", "", func(ctx context.Context, message *tgbotapi.Message) {
+		b.handleHelp(message)
+	})
+	r.handle("apikey", "", b.handleAPIKey)
+	r.handle("feedback", "", b.handleFeedback)
+	r.handle("qr", "", b.handleQRCode)
+	r.handle("followup", "", b.handleFollowUp)
+	r.handle("premium", "", b.handlePremium)
+	r.handle("today", "", b.handleToday)
+	r.handle("agenda", "", b.handleAgenda)
+	r.handle("export", "", b.handleExport)
+	r.handle("digest", "", b.handleDigest)
+	r.handle("reminder", "", b.handleReminder)
+	r.handle("travel", "", b.handleTravel)
+	r.handle("weather", "", b.handleWeather)
+
+	// Admin commands
+	r.handleAdmin("admin", b.handleAdminHelp)
+	r.handleAdmin("refresh_commands", b.handleRefreshCommands)
+	r.handleAdmin("export_users", b.handleExportUsers)
+	r.handleAdmin("ban", b.handleBan)
+	r.handleAdmin("unban", b.handleUnban)
+	r.handleAdmin("accuracy", b.handleAccuracy)
+	r.handleAdmin("stats", b.handleStats)
+	r.handleAdmin("broadcast", b.handleBroadcast)
+
+	r.handle("event", ActionCreate, b.handleEventCommand)
+	r.handle("schedule", "", b.handleSchedule)
+	r.handle("scheduled", "", b.handleScheduled)
+	r.handle("unschedule", "", b.handleUnschedule)
+	r.handle("delete", "", b.handleDelete)
+	r.handle("undo", "", b.handleUndo)
+	r.handle("accessibility", "", b.handleAccessibility)
+	r.handle("readback", "", b.handleReadBack)
+	r.handle("preview", "", b.handlePreview)
+	r.handle("whatsnew", "", b.handleWhatsNew)
+	r.handle("batch", ActionCreate, b.handleBatch)
+	r.handle("done", ActionCreate, b.handleBatchDone)
+	r.handle("plan", ActionCreate, b.handlePlan)
+	r.handle("grouprole", ActionManage, b.handleGroupRole)
+	r.handle("groupallow", ActionManage, b.handleGroupAllow)
+	r.handle("groupdisallow", ActionManage, b.handleGroupDisallow)
+	r.handle("chatsettings", ActionManage, b.handleChatSettings)
+
+	// Anything that isn't a known command is treated as an event description
+	r.handleDefault(ActionCreate, b.handleEvent)
+
+	return r
+}
+
 // handleMessage handles a message from a user
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
-	ctx := context.Background()
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	log.Printf("Handling message in chat ID: %d, message ID: %d", message.Chat.ID, message.MessageID)
+
+	if message.From == nil {
+		log.Println("Message has no sender, skipping")
+		return
+	}
+
+	// Payments are recorded whatever else applies to the user, as they've been charged already
+	if message.SuccessfulPayment != nil {
+		b.handleSuccessfulPayment(message)
+		return
+	}
+
+	if message.IsCommand() {
+		log.Printf("Received command: %s", message.Command())
+	}
+	b.router.dispatch(ctx, message)
+}
+
+// handleStart greets the user and asks for their timezone if it isn't set
+func (b *Bot) handleStart(ctx context.Context, message *tgbotapi.Message) {
 	chatID := message.Chat.ID
 	userID := fmt.Sprintf("%d", message.From.ID) // Use the Telegram user ID as the unique identifier
 	messageID := message.MessageID               // Store the original message ID for replies
-	log.Printf("Handling message in chat ID: %d from user ID: %s, message ID: %d", chatID, userID, messageID)
 
-	// Handle commands
-	if message.IsCommand() {
-		log.Printf("Received command: %s", message.Command())
-		switch message.Command() {
-		case "start":
-			welcomeText := "Welcome to Calendar Assistant! I can help you create calendar events from text or images.\n\n📱 iPhone users: For easier setup, use this shortcut to automatically add .ics files to your calendar:\nhttps://www.icloud.com/shortcuts/db9d3a471c414a1abd2ba7b960395bee"
-			msg := tgbotapi.NewMessage(chatID, welcomeText)
-			msg.ReplyToMessageID = messageID
-			if _, err := b.bot.Send(msg); err != nil {
-				log.Printf("Error sending welcome message: %v", err)
-			}
+	// Links to events shared through inline mode open the bot with their token
+	if token, ok := strings.CutPrefix(message.CommandArguments(), sharedEventStartPrefix); ok {
+		b.sendSharedEvent(message, token)
+		return
+	}
 
-			// Check if user already has a timezone set
-			prefs := b.getUserPreferences(userID)
-			if prefs.Timezone == "UTC" {
-				// Ask user to set their timezone
-				timezoneRequestMsg := tgbotapi.NewMessage(chatID, "To provide accurate calendar events, I need to know your timezone. Please set it using the /timezone command followed by your timezone.\n\nExamples:\n/timezone Europe/London\n/timezone America/New_York\n/timezone Asia/Tokyo\n/timezone GMT+3\n/timezone GMT-5:30")
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "start.welcome"))
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending welcome message: %v", err)
+	}
 
-				// Add a custom keyboard with common timezones
-				keyboard := b.createTimezoneKeyboard()
-				timezoneRequestMsg.ReplyMarkup = keyboard
+	// Private chats get the guided setup, one step at a time
+	if message.Chat.IsPrivate() {
+		b.startOnboarding(message)
+		return
+	}
 
-				if _, err := b.bot.Send(timezoneRequestMsg); err != nil {
-					log.Printf("Error sending timezone request message: %v", err)
-				}
-			} else {
-				// User already has a timezone set, just send the help message
-				b.handleHelp(chatID, messageID)
-			}
-			return
-		case "clear":
-			// Clear the thread for this user
-			if err := b.openaiClient.ClearThreadForUser(ctx, userID); err != nil {
-				log.Printf("Error clearing thread for user %s: %v", userID, err)
-				b.sendErrorMessage(chatID, fmt.Errorf("failed to clear thread: %w", err), messageID)
-				return
-			}
-			msg := tgbotapi.NewMessage(chatID, "Your conversation history has been cleared.")
-			msg.ReplyToMessageID = messageID // Reply to the original message
-			if _, err := b.bot.Send(msg); err != nil {
-				log.Printf("Error sending clear confirmation: %v", err)
-			}
-			return
-		case "timezone":
-			// Set the user's timezone
-			args := message.CommandArguments()
-			if args == "" {
-				// If no timezone provided, show the current timezone
-				prefs := b.getUserPreferences(userID)
-				msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Your current timezone is set to: %s\n\nTo change it, use /timezone followed by an IANA timezone name or GMT offset, for example:\n/timezone Europe/London\n/timezone America/New_York\n/timezone Asia/Tokyo\n/timezone GMT+3\n/timezone GMT-5:30", b.formatTimezoneForDisplay(prefs.Timezone)))
-				msg.ReplyToMessageID = messageID
-
-				// Add a custom keyboard with common timezones
-				keyboard := b.createTimezoneKeyboard()
-				msg.ReplyMarkup = keyboard
-
-				if _, err := b.bot.Send(msg); err != nil {
-					log.Printf("Error sending timezone info: %v", err)
-				}
-				return
-			}
+	// Check if user already has a timezone set
+	prefs := b.getUserPreferences(userID)
+	if b.needsTimezone(prefs) {
+		// Ask user to set their timezone
+		text := b.t(message.From, "timezone.request", b.t(message.From, "timezone.examples"))
+		guess, suggestions := b.startTimezoneSuggestions(message)
+		if guess != "" {
+			text = guess + "\n\n" + text
+		}
 
-			// Validate and set the timezone
-			timezone, err := b.parseTimezone(args)
-			if err != nil {
-				msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid timezone: %s\n\nPlease use a valid IANA timezone name or GMT offset, for example:\n/timezone Europe/London\n/timezone America/New_York\n/timezone Asia/Tokyo\n/timezone GMT+3\n/timezone GMT-5:30", args))
-				msg.ReplyToMessageID = messageID
-				if _, err := b.bot.Send(msg); err != nil {
-					log.Printf("Error sending timezone error: %v", err)
-				}
-				return
-			}
+		timezoneRequestMsg := tgbotapi.NewMessage(chatID, text)
+		timezoneRequestMsg.ReplyMarkup = b.timezonePicker(message.From, suggestions...)
 
-			// Set the timezone
-			b.setUserTimezone(userID, timezone)
-			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Your timezone has been set to: %s", b.formatTimezoneForDisplay(timezone)))
-			msg.ReplyToMessageID = messageID
+		if _, err := b.bot.Send(timezoneRequestMsg); err != nil {
+			log.Printf("Error sending timezone request message: %v", err)
+		}
+	} else {
+		// User already has a timezone set, just send the help message
+		b.handleHelp(message)
+	}
+}
 
-			// Remove the custom keyboard
-			msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+// handleClear clears the user's conversation history
+func (b *Bot) handleClear(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID) // Use the Telegram user ID as the unique identifier
+	messageID := message.MessageID               // Store the original message ID for replies
 
-			if _, err := b.bot.Send(msg); err != nil {
-				log.Printf("Error sending timezone confirmation: %v", err)
-			}
-			return
-		case "help":
-			b.handleHelp(chatID, messageID)
-			return
-		case "refresh_commands":
-			// Only allow admin to refresh commands
-			if b.isAdmin(userID) {
-				if err := b.setupCommands(); err != nil {
-					b.sendErrorMessage(chatID, fmt.Errorf("failed to refresh commands: %w", err), messageID)
-					return
-				}
-				msg := tgbotapi.NewMessage(chatID, "Bot commands have been refreshed successfully.")
-				msg.ReplyToMessageID = messageID
-				if _, err := b.bot.Send(msg); err != nil {
-					log.Printf("Error sending refresh confirmation: %v", err)
-				}
-			} else {
-				b.sendErrorMessage(chatID, fmt.Errorf("you are not authorized to use this command"), messageID)
-			}
+	// Clear the thread for this user
+	if err := b.openaiClient.ClearThreadForUser(ctx, userID); err != nil {
+		log.Printf("Error clearing thread for user %s: %v", userID, err)
+		b.sendError(message, "clear.failed", err)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "clear.done"))
+	msg.ReplyToMessageID = messageID // Reply to the original message
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending clear confirmation: %v", err)
+	}
+}
+
+// handleTimezone shows or sets the user's timezone
+func (b *Bot) handleTimezone(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID) // Use the Telegram user ID as the unique identifier
+	messageID := message.MessageID               // Store the original message ID for replies
+
+	// Set the user's timezone
+	args := message.CommandArguments()
+	if args == "" {
+		// If no timezone provided, show the current timezone
+		prefs := b.getUserPreferences(userID)
+		msg := tgbotapi.NewMessage(chatID, b.t(message.From, "timezone.current", b.formatTimezoneForDisplay(b.userTimezone(prefs)), b.t(message.From, "timezone.examples")))
+		msg.ReplyToMessageID = messageID
+
+		// Add a custom keyboard with suggested and common timezones
+		keyboard := b.suggestedTimezonePicker(message)
+		msg.ReplyMarkup = keyboard
+
+		if _, err := b.bot.Send(msg); err != nil {
+			log.Printf("Error sending timezone info: %v", err)
+		}
+		return
+	}
+
+	// Validate and set the timezone
+	timezone, err := b.parseTimezone(args)
+	if err != nil && len(searchTimezones(args)) > 0 {
+		// A city name rather than a timezone
+		b.sendTimezoneMatches(message, args)
+		return
+	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, b.t(message.From, "timezone.invalid", args, b.t(message.From, "timezone.examples")))
+		msg.ReplyToMessageID = messageID
+		if _, err := b.bot.Send(msg); err != nil {
+			log.Printf("Error sending timezone error: %v", err)
+		}
+		return
+	}
+
+	// Set the timezone
+	b.setUserTimezone(userID, timezone)
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "timezone.set", b.formatTimezoneForDisplay(timezone)))
+	msg.ReplyToMessageID = messageID
+
+	// Remove the keyboard of earlier versions, which suggested timezones as replies
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending timezone confirmation: %v", err)
+	}
+}
+
+// handleRefreshCommands re-registers the bot's command list
+func (b *Bot) handleRefreshCommands(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID // Store the original message ID for replies
+
+	if err := b.setupCommands(); err != nil {
+		b.sendError(message, "admin.refresh_failed", err)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "admin.refreshed"))
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending refresh confirmation: %v", err)
+	}
+}
+
+// handleExportUsers sends the admin a JSON snapshot of all user preferences, which can be
+// imported into another deployment with IMPORT_USERS_PATH
+func (b *Bot) handleExportUsers(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	// The snapshot has every user's ID and settings, so it only goes to an admin listed in
+	// ADMIN_USER_IDS, in a private chat, even if the command is routed here some other way
+	if !b.isAdmin(fmt.Sprintf("%d", message.From.ID)) {
+		log.Printf("Denied user export to non-admin %d", message.From.ID)
+		return
+	}
+	if !message.Chat.IsPrivate() {
+		b.sendError(message, "admin.export_private_only", nil)
+		return
+	}
+
+	snapshot, err := b.store.ExportUsers()
+	if err != nil {
+		log.Printf("Error exporting users: %v", err)
+		b.sendError(message, "admin.export_failed", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "users.json", Bytes: snapshot})
+	doc.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending user export: %v", err)
+	}
+}
+
+// eventOptions controls how an event is extracted
+type eventOptions struct {
+	refresh  bool                  // Extract again, keeping the result for this user rather than everyone
+	extract  openai.ExtractOptions // Passed through to the OpenAI client
+	replaces *storage.HistoryEntry // The entry of the event the extraction corrects, nil for a new event
+}
+
+// extractedEvent is an event extracted from a message, before its ICS file is sent
+type extractedEvent struct {
+	message         *tgbotapi.Message // The message the event was extracted from
+	event           *openai.Event
+	inputType       string
+	rawInput        string
+	retry           bool                  // Whether the user asked to extract the event again
+	replaces        *storage.HistoryEntry // The entry of the event it corrects, nil for a new event
+	missingTimezone bool
+}
+
+// handleEvent extracts an event from a text, photo or document and sends back an ICS file
+func (b *Bot) handleEvent(ctx context.Context, message *tgbotapi.Message) {
+	// A city name may answer the timezone picker's search
+	if b.handleTimezoneSearch(message) {
+		return
+	}
+	// A yes or no may answer an event that's waiting for confirmation
+	if b.handleReadBackReply(message) {
+		return
+	}
+	// Replies to a proposed plan edit it
+	if b.handlePlanEdit(message) {
+		return
+	}
+	// Replies to an event preview correct it
+	if b.handlePreviewReply(ctx, message) {
+		return
+	}
+	// Places, polls, stickers and the like aren't events on their own
+	message, handled := b.handleOtherContent(ctx, message)
+	if handled {
+		return
+	}
+	// During a batch, messages are only collected until /done
+	if b.addToBatch(message) {
+		return
+	}
+	// A text reply to an earlier message corrects or adds to it, e.g. "make that 2 hours later"
+	if message.Text != "" {
+		if input, opts, ok := b.replyContextInput(message, message.Text); ok {
+			b.processEvent(ctx, input, opts)
 			return
 		}
 	}
+	b.processEvent(ctx, message, eventOptions{})
+}
+
+// processEvent extracts an event from a message with the given options and sends back an ICS file
+func (b *Bot) processEvent(ctx context.Context, message *tgbotapi.Message, opts eventOptions) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID) // Use the Telegram user ID as the unique identifier
+	messageID := message.MessageID               // Store the original message ID for replies
+
+	// A shared contact is the user answering the timezone prompt, not an event
+	if message.Contact != nil {
+		b.handleSharedContact(message)
+		return
+	}
+	// So is a shared location, unless it's a venue
+	if message.Location != nil && message.Venue == nil {
+		b.handleSharedLocation(message)
+		return
+	}
 
 	// Check if user has set a timezone
-	prefs := b.getUserPreferences(userID)
-	if prefs.Timezone == "UTC" && !message.IsCommand() {
+	prefs := b.eventPreferences(userID, chatID)
+	missingTimezone := b.needsTimezone(prefs)
+	if missingTimezone && !b.cfg.AllowEventsWithoutTimezone && !message.IsCommand() && !message.Chat.IsPrivate() {
+		// A timezone keyboard would pop up for everyone in a group, so just point the user to it
+		b.sendText(chatID, b.t(message.From, "timezone.required_group", b.bot.Self.UserName), messageID)
+		return
+	}
+	if missingTimezone && !b.cfg.AllowEventsWithoutTimezone && !message.IsCommand() {
 		// User hasn't set a timezone and is trying to create an event
-		timezoneRequestMsg := tgbotapi.NewMessage(chatID, "Before I can process your event, I need to know your timezone. Please set it using the /timezone command followed by your timezone.\n\nExamples:\n/timezone Europe/London\n/timezone America/New_York\n/timezone Asia/Tokyo\n/timezone GMT+3\n/timezone GMT-5:30")
+		timezoneRequestMsg := tgbotapi.NewMessage(chatID, b.t(message.From, "timezone.required", b.t(message.From, "timezone.examples")))
 
-		// Add a custom keyboard with common timezones
-		keyboard := b.createTimezoneKeyboard()
+		// Add a custom keyboard with suggested and common timezones
+		keyboard := b.suggestedTimezonePicker(message)
 		timezoneRequestMsg.ReplyMarkup = keyboard
 		timezoneRequestMsg.ReplyToMessageID = messageID
 
@@ -287,23 +690,48 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 		return
 	}
 
-	// Send a "processing" message
-	processingMsg := tgbotapi.NewMessage(chatID, "Processing your request...")
-	processingMsg.ReplyToMessageID = messageID // Reply to the original message
-	sentMsg, err := b.bot.Send(processingMsg)
+	if !b.allowRequest(message) {
+		return
+	}
+	release, err := b.extractionSlot(ctx, userID)
 	if err != nil {
-		log.Printf("Error sending processing message: %v", err)
-	} else {
-		log.Printf("Sent processing message with ID: %d", sentMsg.MessageID)
+		log.Printf("Error waiting for an extraction slot: %v", err)
+		return
 	}
+	defer release()
+
+	// Let the prompt use the user's own date and language
+	opts.extract.Timezone = b.userTimezone(prefs)
+	opts.extract.Language = prefs.Language
+	opts.extract.Describe = prefs.Accessibility
+	opts.extract.Context = withContext(opts.extract.Context, captionContext(message.Caption))
+	opts.extract.Context = withContext(opts.extract.Context, b.forwardContext(message, opts.extract.Timezone))
+
+	// Acknowledge the message right away and show that the bot is working on it until there's
+	// an answer. The reaction is removed again if no event comes of it.
+	b.setReaction(chatID, messageID, reactionSeen)
+	found := false
+	defer func() {
+		if !found {
+			b.setReaction(chatID, messageID, "")
+		}
+	}()
+	stopTyping := b.startChatAction(chatID, tgbotapi.ChatTyping)
+	defer stopTyping()
+
+	// Long image extractions also show their progress
+	var progress *progressStatus
+	defer func() { progress.finish() }()
 
 	var event *openai.Event
 	var extractErr error
+	var inputType, rawInput string
 
 	// Handle text message
 	if message.Text != "" {
-		log.Printf("Processing text message: %s", message.Text)
-		event, extractErr = b.openaiClient.ExtractEventFromText(ctx, userID, message.Text)
+		inputType, rawInput = storage.InputText, message.Text
+		log.Printf("Processing text message: %s", redactSecrets(message.Text))
+		event, extractErr = b.extractEventFromText(ctx, userID, message.Text, opts)
 		if extractErr != nil {
 			log.Printf("Error extracting event from text: %v", extractErr)
 		} else {
@@ -317,27 +745,45 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 		// Get the largest photo
 		photo := message.Photo[len(message.Photo)-1]
 		log.Printf("Using largest photo with file ID: %s", photo.FileID)
+		inputType, rawInput = storage.InputPhoto, photo.FileID
+		progress = b.startProgress(message.From, chatID, messageID, stageDownloading)
 
 		// Get file URL
 		fileURL, err := b.bot.GetFileDirectURL(photo.FileID)
 		if err != nil {
 			log.Printf("Error getting photo URL: %v", err)
-			b.sendErrorMessage(chatID, fmt.Errorf("failed to get photo URL: %w", err), messageID)
+			b.sendError(message, "error.photo_url", err)
 			return
 		}
 		log.Printf("Got file URL: %s", fileURL)
 
-		// Download the photo
-		imageData, err := b.downloadFile(fileURL)
-		if err != nil {
-			log.Printf("Error downloading photo: %v", err)
-			b.sendErrorMessage(chatID, fmt.Errorf("failed to download photo: %w", err), messageID)
-			return
-		}
-		log.Printf("Downloaded photo, size: %d bytes", len(imageData))
+		if b.shouldStream(photo.FileSize) {
+			// Large photos go straight from the download into the upload
+			progress.set(stageAnalyzing)
+			event, extractErr = b.streamEventFromImage(ctx, userID, fileURL, opts)
+			if errors.Is(extractErr, imaging.ErrUnsupportedImage) {
+				b.sendError(message, "error.photo_format", nil)
+				return
+			}
+		} else {
+			// Download the photo
+			imageData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+			if err != nil {
+				log.Printf("Error downloading photo: %v", err)
+				b.sendError(message, "error.photo_download", err)
+				return
+			}
+			log.Printf("Downloaded photo, size: %d bytes", len(imageData))
+			if _, err := imaging.Detect(imageData); err != nil {
+				log.Printf("Photo is not a supported image: %v", err)
+				b.sendError(message, "error.photo_format", nil)
+				return
+			}
 
-		// Extract event from image
-		event, extractErr = b.openaiClient.ExtractEventFromImage(ctx, userID, imageData)
+			// Extract event from image
+			progress.set(stageAnalyzing)
+			event, extractErr = b.extractEventFromImage(ctx, userID, imageData, opts)
+		}
 		if extractErr != nil {
 			log.Printf("Error extracting event from image: %v", extractErr)
 		} else {
@@ -345,94 +791,359 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 		}
 	}
 
-	// Handle document (for screenshots sent as files)
+	// Handle document (for screenshots and text documents sent as files)
 	if message.Document != nil {
 		log.Printf("Processing document with MIME type: %s", message.Document.MimeType)
+		mimeType := b.documentMIME(ctx, message.Document)
 		// Check if it's an image
-		if isImageMIME(message.Document.MimeType) {
+		if b.isImageMIME(mimeType) {
 			log.Printf("Document is an image, processing...")
+			inputType, rawInput = storage.InputDocument, message.Document.FileID
+			progress = b.startProgress(message.From, chatID, messageID, stageDownloading)
 			// Get file URL
 			fileURL, err := b.bot.GetFileDirectURL(message.Document.FileID)
 			if err != nil {
 				log.Printf("Error getting document URL: %v", err)
-				b.sendErrorMessage(chatID, fmt.Errorf("failed to get document URL: %w", err), messageID)
+				b.sendError(message, "error.document_url", err)
 				return
 			}
 			log.Printf("Got document URL: %s", fileURL)
 
-			// Download the document
-			imageData, err := b.downloadFile(fileURL)
+			if b.shouldStream(message.Document.FileSize) {
+				// Large files go straight from the download into the upload
+				progress.set(stageAnalyzing)
+				event, extractErr = b.streamEventFromImage(ctx, userID, fileURL, opts)
+				if errors.Is(extractErr, imaging.ErrUnsupportedImage) {
+					b.sendError(message, "error.image_format", nil, b.acceptedImageTypeNames())
+					return
+				}
+			} else {
+				// Download the document
+				imageData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+				if err != nil {
+					log.Printf("Error downloading document: %v", err)
+					b.sendError(message, "error.document_download", err)
+					return
+				}
+				log.Printf("Downloaded document, size: %d bytes", len(imageData))
+
+				// The MIME type comes from the sender's client, so check the actual content too
+				if format, err := imaging.Detect(imageData); err != nil || !b.isImageMIME(format.MIME) {
+					detected := imaging.Sniff(imageData)
+					log.Printf("Document is not a supported image, detected %s", detected)
+					b.sendErrorMessage(chatID, b.unsupportedFileError(detected), messageID)
+					return
+				}
+
+				// Extract event from image
+				progress.set(stageAnalyzing)
+				event, extractErr = b.extractEventFromImage(ctx, userID, imageData, opts)
+			}
+			if extractErr != nil {
+				log.Printf("Error extracting event from document: %v", extractErr)
+			} else {
+				log.Printf("Successfully extracted event from document: %+v", event)
+			}
+		} else if documents.IsEmail(mimeType, message.Document.FileName) {
+			// Forwarded emails: use an embedded invitation as is, otherwise extract from the text
+			log.Printf("Document is an email, processing...")
+			inputType, rawInput = storage.InputDocument, message.Document.FileID
+			fileURL, err := b.bot.GetFileDirectURL(message.Document.FileID)
+			if err != nil {
+				log.Printf("Error getting document URL: %v", err)
+				b.sendError(message, "error.document_url", err)
+				return
+			}
+
+			emailData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
 			if err != nil {
 				log.Printf("Error downloading document: %v", err)
-				b.sendErrorMessage(chatID, fmt.Errorf("failed to download document: %w", err), messageID)
+				b.sendError(message, "error.document_download", err)
 				return
 			}
-			log.Printf("Downloaded document, size: %d bytes", len(imageData))
+			log.Printf("Downloaded email, size: %d bytes", len(emailData))
 
-			// Extract event from image
-			event, extractErr = b.openaiClient.ExtractEventFromImage(ctx, userID, imageData)
+			email, err := documents.ParseEmail(emailData)
+			if err != nil {
+				log.Printf("Error parsing email: %v", err)
+				b.sendError(message, "error.email_read", err)
+				return
+			}
+			log.Printf("Parsed email %q with %d embedded invitations", email.Subject, len(email.Calendars))
+
+			if len(email.Calendars) > 0 {
+				loc, _ := b.timezones.Resolve(b.userTimezone(prefs))
+				event, extractErr = calendar.EventFromICS(email.Calendars[0], loc)
+			} else {
+				event, extractErr = b.extractEventFromText(ctx, userID, email.Text(), opts)
+			}
 			if extractErr != nil {
-				log.Printf("Error extracting event from document: %v", extractErr)
+				log.Printf("Error extracting event from email: %v", extractErr)
 			} else {
-				log.Printf("Successfully extracted event from document: %+v", event)
+				log.Printf("Successfully extracted event from email: %+v", event)
+			}
+		} else if documents.IsCalendar(mimeType, message.Document.FileName) {
+			// Calendar files already hold their events, so they're only converted to the user's timezone
+			log.Printf("Document is a calendar file, processing...")
+			inputType, rawInput = storage.InputDocument, message.Document.FileID
+			fileURL, err := b.bot.GetFileDirectURL(message.Document.FileID)
+			if err != nil {
+				log.Printf("Error getting document URL: %v", err)
+				b.sendError(message, "error.document_url", err)
+				return
+			}
+
+			calendarData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+			if err != nil {
+				log.Printf("Error downloading document: %v", err)
+				b.sendError(message, "error.document_download", err)
+				return
+			}
+			log.Printf("Downloaded calendar file, size: %d bytes", len(calendarData))
+
+			loc, _ := b.timezones.Resolve(b.userTimezone(prefs))
+			events, err := calendar.EventsFromICS(calendarData, loc)
+			if err != nil {
+				log.Printf("Error reading calendar file: %v", err)
+				b.sendError(message, "error.calendar_read", err)
+				return
+			}
+			log.Printf("Read %d events from calendar file", len(events))
+
+			// A single event is checked like an extracted one, several are sent back in one file
+			if len(events) > 1 {
+				found = true
+				b.deliverImportedEvents(message, events, prefs, loc)
+				return
+			}
+			event = events[0]
+		} else if documents.IsTextDocument(mimeType, message.Document.FileName) {
+			// Agendas and invitations sent as .txt, .docx or .pdf files are handled like text messages
+			if documents.IsPDF(mimeType, message.Document.FileName) && !b.allowPremiumFeature(message, "pdf") {
+				return
+			}
+			log.Printf("Document is a text document, processing...")
+			inputType, rawInput = storage.InputDocument, message.Document.FileID
+			fileURL, err := b.bot.GetFileDirectURL(message.Document.FileID)
+			if err != nil {
+				log.Printf("Error getting document URL: %v", err)
+				b.sendError(message, "error.document_url", err)
+				return
+			}
+
+			documentData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+			if err != nil {
+				log.Printf("Error downloading document: %v", err)
+				b.sendError(message, "error.document_download", err)
+				return
+			}
+			log.Printf("Downloaded document, size: %d bytes", len(documentData))
+
+			text, err := documents.ExtractText(documentData, mimeType, message.Document.FileName)
+			if err != nil {
+				log.Printf("Error reading document text: %v", err)
+				b.sendError(message, "error.document_read", err)
+				return
+			}
+			if text == "" {
+				b.sendError(message, "error.document_empty", nil)
+				return
+			}
+			log.Printf("Extracted %d characters of text from document", len(text))
+
+			event, extractErr = b.extractEventFromText(ctx, userID, text, opts)
+			if extractErr != nil {
+				log.Printf("Error extracting event from document text: %v", extractErr)
+			} else {
+				log.Printf("Successfully extracted event from document text: %+v", event)
 			}
 		} else {
-			log.Printf("Unsupported document type: %s", message.Document.MimeType)
-			b.sendErrorMessage(chatID, fmt.Errorf("unsupported document type: %s", message.Document.MimeType), messageID)
+			log.Printf("Unsupported document type: %s", mimeType)
+			b.sendErrorMessage(chatID, b.unsupportedFileError(mimeType), messageID)
+			return
+		}
+	}
+
+	// Handle voice messages and audio files by transcribing them first
+	if message.Voice != nil || message.Audio != nil {
+		if !b.allowPremiumFeature(message, "voice") {
+			return
+		}
+		fileID, filename, contentType := voiceFile(message)
+		log.Printf("Processing audio message %s (%s)", filename, contentType)
+		inputType, rawInput = storage.InputVoice, fileID
+
+		fileURL, err := b.bot.GetFileDirectURL(fileID)
+		if err != nil {
+			log.Printf("Error getting audio URL: %v", err)
+			b.sendError(message, "error.audio_url", err)
+			return
+		}
+
+		audioData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+		if err != nil {
+			log.Printf("Error downloading audio: %v", err)
+			b.sendError(message, "error.audio_download", err)
 			return
 		}
+		log.Printf("Downloaded audio, size: %d bytes", len(audioData))
+
+		transcript, err := b.openaiClient.TranscribeAudio(ctx, userID, audioData, filename, contentType)
+		if err != nil {
+			log.Printf("Error transcribing audio: %v", err)
+			b.sendError(message, "error.audio_transcribe", err)
+			return
+		}
+		if strings.TrimSpace(transcript) == "" {
+			b.sendError(message, "error.audio_silent", nil)
+			return
+		}
+
+		// From here on a transcript is handled exactly like a text message
+		event, extractErr = b.extractEventFromText(ctx, userID, transcript, opts)
+		if extractErr != nil {
+			log.Printf("Error extracting event from transcript: %v", extractErr)
+		} else {
+			log.Printf("Successfully extracted event from transcript: %+v", event)
+		}
+	}
+
+	// Handle videos and video notes (e.g. screen recordings of a poster) via key frames and the audio track
+	if message.Video != nil || message.VideoNote != nil {
+		fileID, duration := videoFile(message)
+		log.Printf("Processing video message, duration: %ds", duration)
+		inputType, rawInput = storage.InputVideo, fileID
+
+		fileURL, err := b.bot.GetFileDirectURL(fileID)
+		if err != nil {
+			log.Printf("Error getting video URL: %v", err)
+			b.sendError(message, "error.video_url", err)
+			return
+		}
+
+		videoData, err := b.downloadWhilePreparing(ctx, userID, fileURL)
+		if err != nil {
+			log.Printf("Error downloading video: %v", err)
+			b.sendError(message, "error.video_download", err)
+			return
+		}
+		log.Printf("Downloaded video, size: %d bytes", len(videoData))
+
+		event, extractErr = b.extractEventFromVideo(ctx, userID, videoData, duration, opts)
+		if errors.Is(extractErr, media.ErrFFmpegNotFound) {
+			b.sendError(message, "error.video_unsupported", nil)
+			return
+		}
+		if extractErr != nil {
+			log.Printf("Error extracting event from video: %v", extractErr)
+		} else {
+			log.Printf("Successfully extracted event from video: %+v", event)
+		}
 	}
 
 	// Handle extraction error
 	if extractErr != nil {
 		log.Printf("Extraction error: %v", extractErr)
-		b.sendErrorMessage(chatID, fmt.Errorf("failed to extract event: %w", extractErr), messageID)
+		b.sendError(message, "error.extract", extractErr)
 		return
 	}
 
 	// If no event was extracted
 	if event == nil {
 		log.Println("No event information found")
-		b.sendErrorMessage(chatID, fmt.Errorf("no event information found"), messageID)
+		b.sendError(message, "error.no_event", nil)
 		return
 	}
 
-	// Get user preferences for timezone
-	prefs = b.getUserPreferences(userID)
-	log.Printf("Using timezone %s for user %s", prefs.Timezone, userID)
+	found = true
+	extracted := extractedEvent{
+		message:         message,
+		event:           event,
+		inputType:       inputType,
+		rawInput:        rawInput,
+		retry:           opts.refresh,
+		replaces:        opts.replaces,
+		missingTimezone: missingTimezone,
+	}
+
+	// A period without a time first needs one of the user's free times picked
+	if event.Flexible {
+		progress.finish()
+		b.offerFreeSlots(userID, extracted)
+		return
+	}
+
+	// In read-back mode nothing is created until the user confirms what was understood
+	if prefs.ReadBack {
+		progress.finish()
+		b.requestReadBackConfirmation(userID, extracted)
+		return
+	}
 
-	// Validate the timezone (but we don't need the location object)
-	_, err = time.LoadLocation(prefs.Timezone)
+	if prefs.SkipPreview {
+		progress.set(stageGenerating)
+		b.deliverEvent(userID, extracted)
+		return
+	}
+	progress.finish()
+	b.sendPreview(userID, extracted)
+}
+
+// icsOptions returns the options of a calendar file made for a user, with a single event or
+// a feed of several
+func (b *Bot) icsOptions(userID string, reminderMinutes int, feed bool) calendar.ICSOptions {
+	opts := calendar.ICSOptions{
+		UserID:          userID,
+		ReminderMinutes: reminderMinutes,
+		Method:          b.cfg.ICSMethod,
+		ProductID:       b.cfg.ICSProductID,
+		CalendarName:    b.cfg.ICSCalendarName,
+	}
+	if feed {
+		opts.Method = b.cfg.ICSFeedMethod
+		opts.CalendarName = b.cfg.ICSFeedCalendarName
+	}
+	return opts
+}
+
+// deliverEvent records an extracted event in the history and sends its ICS file in reply to
+// the message it came from
+func (b *Bot) deliverEvent(userID string, extracted extractedEvent) {
+	message, event := extracted.message, extracted.event
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	// Look up the location first, so the history keeps the coordinates too
+	b.geocodeEvents(event)
+	b.versionEvent(userID, extracted.replaces, event)
+
+	// Get user preferences for timezone
+	prefs := b.eventPreferences(userID, chatID)
+	loc, err := b.timezones.Resolve(b.userTimezone(prefs))
 	if err != nil {
-		log.Printf("Error loading timezone %s: %v, falling back to UTC", prefs.Timezone, err)
-		prefs.Timezone = "UTC"
+		// Tell the user rather than silently creating the event in another timezone
+		b.sendText(chatID, b.t(message.From, "timezone.fallback", err, b.formatTimezoneForDisplay(loc.String())), messageID)
 	}
+	timezone := loc.String()
+	log.Printf("Using timezone %s for user %s", timezone, userID)
 
 	// We keep the original times from GPT for display purposes
 	// The ICS generation will handle the timezone adjustment
 	log.Printf("Original UTC start time: %s", event.StartTime.Format(time.RFC3339))
 	log.Printf("Original UTC end time: %s", event.EndTime.Format(time.RFC3339))
 
-	// Determine if it's an all-day event
-	eventType := "Timed event"
-	timeFormat := "2006-01-02 15:04"
-
-	// Check if it's an all-day event based on the original UTC time
-	isAllDay := event.StartTime.Hour() == 0 && event.StartTime.Minute() == 0 && event.StartTime.Second() == 0
-
+	isAllDay := calendar.IsAllDay(event)
 	if isAllDay {
-		eventType = "All-day event"
-		timeFormat = "2006-01-02"
-		// For all-day events, we want to show the date without time
 		log.Println("All-day event detected, using date-only format")
 	}
 
 	// Generate ICS file
 	log.Println("Generating ICS file...")
-	icsData, err := calendar.GenerateICS(event, prefs.Timezone)
+	events := b.withWeather(message.From, prefs, []*openai.Event{event}, loc)
+	icsData, err := calendar.GenerateICS(b.withTravel(message.From, prefs, events), loc, b.icsOptions(userID, prefs.ReminderMinutes, false))
 	if err != nil {
 		log.Printf("Error generating ICS file: %v", err)
-		b.sendErrorMessage(chatID, fmt.Errorf("failed to generate ICS file: %w", err), messageID)
+		b.sendError(message, "error.ics_generate", err)
 		return
 	}
 	log.Printf("Generated ICS file, size: %d bytes", len(icsData))
@@ -444,7 +1155,7 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 
 	if err := os.WriteFile(tempFile, icsData, 0644); err != nil {
 		log.Printf("Error saving ICS file: %v", err)
-		b.sendErrorMessage(chatID, fmt.Errorf("failed to save ICS file: %w", err), messageID)
+		b.sendError(message, "error.ics_save", err)
 		return
 	}
 	defer os.Remove(tempFile)
@@ -453,90 +1164,345 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 	log.Println("Sending ICS file...")
 	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(tempFile))
 
-	// Format the caption with the original times but user's timezone label
+	// Format the caption with the original times but user's timezone label, in the event's language
 	// This ensures what the user sees in the message matches what they'll see in their calendar
-	var caption string
-	if isAllDay {
-		caption = fmt.Sprintf("%s: %s\nDate: %s\nLocation: %s\nTimezone: %s\n\n📱 iPhone users: Use this shortcut for easy calendar import:\nhttps://www.icloud.com/shortcuts/db9d3a471c414a1abd2ba7b960395bee",
-			eventType,
-			event.Title,
-			event.StartTime.Format("2006-01-02"),
-			event.Location,
-			b.formatTimezoneForDisplay(prefs.Timezone))
-	} else {
-		caption = fmt.Sprintf("%s: %s\nStart: %s %s\nEnd: %s %s\nLocation: %s\nTimezone: %s\n\n📱 iPhone users: Use this shortcut for easy calendar import:\nhttps://www.icloud.com/shortcuts/db9d3a471c414a1abd2ba7b960395bee",
-			eventType,
-			event.Title,
-			event.StartTime.Format(timeFormat),
-			b.formatTimezoneForDisplay(prefs.Timezone),
-			event.EndTime.Format(timeFormat),
-			b.formatTimezoneForDisplay(prefs.Timezone),
-			event.Location,
-			b.formatTimezoneForDisplay(prefs.Timezone))
-	}
+	caption := b.eventCaption(event, isAllDay, b.formatTimezoneForDisplay(timezone), prefs.Language)
 
 	doc.Caption = caption
+	doc.ParseMode = tgbotapi.ModeMarkdownV2
 	doc.ReplyToMessageID = messageID // Reply to the original message
+	doc.ReplyMarkup = b.eventFileKeyboard(message.From, event, loc)
 
-	// Delete the processing message
-	log.Printf("Deleting processing message with ID: %d", sentMsg.MessageID)
-	deleteMsg := tgbotapi.NewDeleteMessage(chatID, sentMsg.MessageID)
-	if _, err := b.bot.Request(deleteMsg); err != nil {
-		log.Printf("Error deleting processing message: %v", err)
-	}
-
-	if _, err := b.bot.Send(doc); err != nil {
+	b.sendChatAction(chatID, tgbotapi.ChatUploadDocument)
+	sent, err := b.bot.Send(doc)
+	if err != nil {
 		log.Printf("Error sending ICS file: %v", err)
-		b.sendErrorMessage(chatID, fmt.Errorf("failed to send ICS file: %w", err), messageID)
+		b.sendError(message, "error.ics_send", err)
 		return
 	}
 	log.Println("ICS file sent successfully")
+
+	// Record the event in the history once the user has it. Corrections of the file find the
+	// event it sent by its message.
+	entry, err := b.store.AddHistory(storage.HistoryEntry{
+		Bot:       b.cfg.BotName,
+		UserID:    userID,
+		ChatID:    chatID,
+		MessageID: sent.MessageID,
+		InputType: extracted.inputType,
+		RawInput:  extracted.rawInput,
+		Event:     event,
+		Retry:     extracted.retry,
+	})
+	recorded := err == nil
+	if err != nil {
+		log.Printf("Error saving history entry: %v", err)
+	}
+	b.setReaction(chatID, messageID, reactionDone)
+
+	// In accessibility mode, also tell the user what the image itself shows
+	if prefs.Accessibility && event.ContentDescription != "" {
+		b.sendContentDescription(message, event.ContentDescription)
+	}
+
+	// A QR code lets others add the event by scanning it
+	if prefs.QRCode {
+		b.sendEventQRCode(message, event, loc)
+	}
+
+	// Remind the user before the event, and ask how it went once it's over. Both refer to the
+	// history entry, so they're left out if it couldn't be saved.
+	if recorded {
+		b.scheduleReminder(entry, event, loc, prefs.ReminderMinutes)
+		if prefs.FollowUp {
+			b.scheduleFollowUp(entry, event, loc)
+		}
+	}
+
+	// The event was created in UTC, so nudge the user to set their timezone
+	if extracted.missingTimezone {
+		b.sendMissingTimezoneWarning(message, isAllDay)
+	}
+}
+
+// sendMissingTimezoneWarning tells a user without a timezone that their event used UTC,
+// with a one-tap keyboard to set it
+func (b *Bot) sendMissingTimezoneWarning(message *tgbotapi.Message, isAllDay bool) {
+	text := b.t(message.From, "timezone.missing_warning")
+	if isAllDay {
+		text = b.t(message.From, "timezone.missing_warning_all_day")
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = b.suggestedTimezonePicker(message)
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending missing timezone warning: %v", err)
+	}
 }
 
 // sendErrorMessage sends an error message to the user
 func (b *Bot) sendErrorMessage(chatID int64, err error, messageID int) {
 	log.Printf("Sending error message to chat ID %d: %v", chatID, err)
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Error: %v", err))
+	msg := tgbotapi.NewMessage(chatID, b.catalog.T(b.defaultLanguage(), "error.message", err))
 	msg.ReplyToMessageID = messageID // Reply to the original message
 	if _, err := b.bot.Send(msg); err != nil {
 		log.Printf("Error sending error message: %v", err)
 	}
 }
 
-// downloadFile downloads a file from a URL
-func (b *Bot) downloadFile(url string) ([]byte, error) {
+// downloadFile downloads a file from a URL, giving up when ctx is cancelled
+func (b *Bot) downloadFile(ctx context.Context, url string) ([]byte, error) {
 	log.Printf("Downloading file from URL: %s", url)
-	resp, err := http.Get(url)
+	data, err := b.downloader.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	log.Printf("Downloaded %d bytes", len(data))
+	return data, nil
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
+// downloadWhilePreparing downloads a file while the user's OpenAI thread is set up in parallel,
+// so the extraction can start as soon as the download finishes
+func (b *Bot) downloadWhilePreparing(ctx context.Context, userID, fileURL string) ([]byte, error) {
+	var data []byte
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		data, err = b.downloadFile(gctx, fileURL)
+		return err
+	})
+	g.Go(func() error {
+		// Not fatal: the extraction sets up the thread again and reports any error itself
+		if err := b.openaiClient.PrepareThread(gctx, userID); err != nil {
+			log.Printf("Error preparing thread for user %s: %v", userID, err)
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
-	log.Printf("Downloaded %d bytes", len(data))
 	return data, nil
 }
 
-// isImageMIME checks if a MIME type is an image
-func isImageMIME(mimeType string) bool {
-	imageMIMEs := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-		"image/gif":  true,
-		"image/webp": true,
-		"image/bmp":  true,
+// shouldStream reports whether a file of the given size is streamed into the upload instead of
+// being downloaded and preprocessed first
+func (b *Bot) shouldStream(fileSize int) bool {
+	return fileSize >= b.cfg.StreamThreshold
+}
+
+// streamEventFromImage extracts an event from a large image by streaming the download straight
+// into the upload. Such images skip preprocessing, but their result is still cached.
+func (b *Bot) streamEventFromImage(ctx context.Context, userID, fileURL string, opts eventOptions) (*openai.Event, error) {
+	log.Printf("Streaming image from URL: %s", fileURL)
+	body, err := b.downloader.Open(ctx, fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer body.Close()
+
+	hasher := sha256.New()
+	start := time.Now()
+	event, err := b.openaiClient.ExtractEventFromImageReader(ctx, userID, io.TeeReader(body, hasher), opts.extract)
+	b.recordExtraction(userID, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// The upload has read the whole image by now, so the hash matches a downloaded copy's
+	key := b.extractionCacheKey(userID, "image:"+hex.EncodeToString(hasher.Sum(nil)), opts)
+	if opts.refresh {
+		key = userCacheKey(userID, key)
+	}
+	b.imageCache.Set(key, *event)
+	return event, nil
+}
+
+// extractionCacheKey returns the cache key of an extraction from content, which is shared by
+// the users whose extraction of it would be the same: on the same day in the same timezone,
+// with the same language, context, options and model. Users with their own API key only share
+// with themselves, as their runs are billed to them.
+func (b *Bot) extractionCacheKey(userID, content string, opts eventOptions) string {
+	// Relative dates like "tomorrow" depend on the user's day
+	loc, _ := b.timezones.Resolve(opts.extract.Timezone)
+	owner := ""
+	if b.userAPIKey(userID) != "" {
+		owner = userID
+	}
+	parts := []string{
+		time.Now().In(loc).Format("2006-01-02"), opts.extract.Timezone, opts.extract.Language,
+		strconv.FormatBool(opts.extract.Describe), strconv.FormatBool(opts.extract.Summarize),
+		opts.extract.Model, owner, opts.extract.Context, content,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// userCacheKey returns the key of a user's own result for a shared cache key, which a
+// re-extraction stores so it doesn't replace the result of everyone else
+func userCacheKey(userID, key string) string {
+	return userID + ":" + key
+}
+
+// cachedExtraction returns the extraction cached under key, or runs load to produce it. The
+// user's own result from a re-extraction comes first. A refresh always runs load and keeps
+// its result for the user alone. Shared loads don't stop when the user who started them
+// leaves, as others may be waiting for them, but do when the bot shuts down.
+func (b *Bot) cachedExtraction(ctx context.Context, c *cache.TTL[openai.Event], userID, key string, refresh bool, load func(ctx context.Context) (*openai.Event, error)) (*openai.Event, bool, error) {
+	own := userCacheKey(userID, key)
+	if refresh {
+		event, err := load(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		c.Set(own, *event)
+		return event, false, nil
+	}
+	if event, ok := c.Get(own); ok {
+		return &event, true, nil
+	}
+
+	event, cached, err := c.GetOrLoad(key, func() (openai.Event, error) {
+		event, err := load(b.lifecycle.ctx)
+		if err != nil {
+			return openai.Event{}, err
+		}
+		return *event, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &event, cached, nil
+}
+
+// captionContext returns the caption of a photo or file as context for its extraction, as it
+// often corrects or adds to what's in the image (e.g. "it's actually on the 14th")
+func captionContext(caption string) string {
+	caption = strings.TrimSpace(caption)
+	if caption == "" {
+		return ""
+	}
+	return "The sender's caption, which takes precedence over the attachment where they disagree:\n" + caption
+}
+
+// extractEventFromImage extracts an event from an image, reusing the result for images
+// that were already processed recently
+func (b *Bot) extractEventFromImage(ctx context.Context, userID string, imageData []byte, opts eventOptions) (*openai.Event, error) {
+	sum := sha256.Sum256(imageData)
+	key := b.extractionCacheKey(userID, "image:"+hex.EncodeToString(sum[:]), opts)
+
+	event, cached, err := b.cachedExtraction(ctx, b.imageCache, userID, key, opts.refresh, func(ctx context.Context) (*openai.Event, error) {
+		start := time.Now()
+		event, err := b.openaiClient.ExtractEventFromImage(ctx, userID, b.prepareImage(imageData), opts.extract)
+		b.recordExtraction(userID, start, err)
+		return event, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		log.Printf("Using cached extraction for image %s", key)
 	}
+	return event, nil
+}
 
-	return imageMIMEs[mimeType]
+// extractEventFromText extracts an event from text, sharing the result between users who
+// send the same text on the same day in the same timezone and language (e.g. an announcement
+// forwarded by a whole group)
+func (b *Bot) extractEventFromText(ctx context.Context, userID string, text string, opts eventOptions) (*openai.Event, error) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	key := b.extractionCacheKey(userID, "text:"+normalized, opts)
+
+	// Tell the assistant which language to keep the event in
+	opts.extract.InputLanguage = language.Detect(text)
+
+	event, cached, err := b.cachedExtraction(ctx, b.textCache, userID, key, opts.refresh, func(ctx context.Context) (*openai.Event, error) {
+		start := time.Now()
+		event, err := b.openaiClient.ExtractEventFromText(ctx, userID, text, opts.extract)
+		b.recordExtraction(userID, start, err)
+		return event, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		log.Printf("Using cached extraction for text %s", key)
+	}
+	return event, nil
+}
+
+// prepareImage downscales and compresses an image before it's sent to the vision API,
+// falling back to the original data if the image can't be processed
+func (b *Bot) prepareImage(data []byte) []byte {
+	prepared, err := imaging.Prepare(data, b.cfg.ImageMaxDimension, b.cfg.ImageJPEGQuality)
+	if err != nil {
+		log.Printf("Error preparing image, uploading original: %v", err)
+		return data
+	}
+	return prepared
+}
+
+// extractEventFromVideo extracts an event from key frames of a video, using the transcript
+// of its audio track (if any) as additional context
+func (b *Bot) extractEventFromVideo(ctx context.Context, userID string, videoData []byte, duration int, opts eventOptions) (*openai.Event, error) {
+	frames, err := media.ExtractFrames(ctx, videoData, duration, b.cfg.VideoFrameCount)
+	if err != nil {
+		return nil, err
+	}
+	for i, frame := range frames {
+		frames[i] = b.prepareImage(frame)
+	}
+
+	// The audio is a bonus: a silent screen recording still has its frames
+	audioData, err := media.ExtractAudio(ctx, videoData)
+	if err != nil {
+		log.Printf("No audio extracted from video: %v", err)
+	} else {
+		transcript, err := b.openaiClient.TranscribeAudio(ctx, userID, audioData, "audio.wav", "audio/wav")
+		if err != nil {
+			log.Printf("Error transcribing video audio: %v", err)
+		} else if transcript = strings.TrimSpace(transcript); transcript != "" {
+			opts.extract.Context = withContext(opts.extract.Context, "Transcript of the video's audio:\n"+transcript)
+		}
+	}
+
+	start := time.Now()
+	event, err := b.openaiClient.ExtractEventFromImages(ctx, userID, frames, opts.extract)
+	b.recordExtraction(userID, start, err)
+	return event, err
+}
+
+// videoFile returns the file ID and duration in seconds of a video or video note
+func videoFile(message *tgbotapi.Message) (string, int) {
+	if message.Video != nil {
+		return message.Video.FileID, message.Video.Duration
+	}
+	return message.VideoNote.FileID, message.VideoNote.Duration
+}
+
+// voiceFile returns the file ID, a filename with the right extension and the MIME type of
+// a voice message or audio file
+func voiceFile(message *tgbotapi.Message) (string, string, string) {
+	if message.Voice != nil {
+		// Telegram voice messages are always OGG/Opus
+		return message.Voice.FileID, "voice.ogg", "audio/ogg"
+	}
+
+	filename := message.Audio.FileName
+	if filename == "" {
+		filename = "audio.mp3"
+	}
+	contentType := message.Audio.MimeType
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	return message.Audio.FileID, filename, contentType
 }
 
 // parseTimezone handles both IANA timezone names and GMT offsets
 func (b *Bot) parseTimezone(timezoneStr string) (string, error) {
 	// First, check if it's a valid IANA timezone
-	_, err := time.LoadLocation(timezoneStr)
+	_, err := b.timezones.Load(timezoneStr)
 	if err == nil {
 		return timezoneStr, nil
 	}
@@ -617,58 +1583,29 @@ func (b *Bot) formatTimezoneForDisplay(timezone string) string {
 }
 
 // handleHelp sends a help message to the user
-func (b *Bot) handleHelp(chatID int64, messageID int) {
+func (b *Bot) handleHelp(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
 	// Get the user's current timezone
-	userID := fmt.Sprintf("%d", chatID) // Use the chat ID as the user ID for simplicity
-	prefs := b.getUserPreferences(userID)
-	timezoneInfo := fmt.Sprintf("Your current timezone is set to: %s", b.formatTimezoneForDisplay(prefs.Timezone))
+	prefs := b.getUserPreferences(fmt.Sprintf("%d", message.From.ID))
+	timezoneInfo := b.t(message.From, "help.timezone", b.formatTimezoneForDisplay(b.userTimezone(prefs)))
 
-	if prefs.Timezone == "UTC" {
-		timezoneInfo += " (default)\n⚠️ It's important to set your correct timezone for accurate calendar events!"
+	if prefs.Timezone == "" {
+		timezoneInfo += b.t(message.From, "help.timezone_default")
+		if b.needsTimezone(prefs) {
+			timezoneInfo += b.t(message.From, "help.timezone_warning")
+		}
 	}
 
-	helpText := fmt.Sprintf(`Calendar Assistant Bot Help:
-
-%s
-
-Send me a photo of an event announcement or a text description of an event, and I'll create a calendar file (.ics) that you can import into your calendar app.
-
-Commands:
-/start - Start the bot
-/help - Show this help message
-/timezone - View or set your timezone
-  Examples:
-    /timezone - Show your current timezone
-    /timezone Europe/London - Set timezone to London
-    /timezone America/New_York - Set timezone to New York
-    /timezone GMT+3 - Set timezone to GMT+3
-    /timezone GMT-5:30 - Set timezone to GMT-5:30
-/clear - Clear your conversation history
-
-Tip: You can see all available commands by typing "/" in the chat - Telegram will show command autocompletions.
-
-When you send me an event, I'll extract:
-- Event title
-- Description
-- Location
-- Start time
-- End time
-
-The calendar file will be created in your preferred timezone. If no timezone is set, UTC will be used.
-
-To import the .ics file:
-- On iOS: Open the file to add it to your Calendar
-  📱 For easier iPhone setup: Use this shortcut to automatically add .ics files to your calendar:
-  https://www.icloud.com/shortcuts/db9d3a471c414a1abd2ba7b960395bee
-- On Android: Open the file with your calendar app
-- On desktop: Double-click the file or import it through your calendar application`, timezoneInfo)
+	helpText := b.t(message.From, "help.text", timezoneInfo, b.bot.Self.UserName)
 
 	msg := tgbotapi.NewMessage(chatID, helpText)
 	msg.ReplyToMessageID = messageID
 
 	// If timezone is not set, add the timezone keyboard
-	if prefs.Timezone == "UTC" {
-		msg.ReplyMarkup = b.createTimezoneKeyboard()
+	if b.needsTimezone(prefs) {
+		msg.ReplyMarkup = b.timezonePicker(message.From)
 	}
 
 	if _, err := b.bot.Send(msg); err != nil {
@@ -676,40 +1613,8 @@ To import the .ics file:
 	}
 }
 
-// isAdmin checks if a user is an admin
+// isAdmin checks if a user is one of the admins configured with ADMIN_USER_IDS
 func (b *Bot) isAdmin(userID string) bool {
-	// For now, let's consider all users as admins for the refresh_commands command
-	// In a production environment, you would want to check against a list of admin user IDs
-	return true
-}
-
-// createTimezoneKeyboard creates a keyboard with common timezone options
-func (b *Bot) createTimezoneKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	keyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("/timezone GMT+0"),
-			tgbotapi.NewKeyboardButton("/timezone GMT+1"),
-			tgbotapi.NewKeyboardButton("/timezone GMT+2"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("/timezone GMT+3"),
-			tgbotapi.NewKeyboardButton("/timezone GMT+4"),
-			tgbotapi.NewKeyboardButton("/timezone GMT+5"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("/timezone GMT-5"),
-			tgbotapi.NewKeyboardButton("/timezone GMT-8"),
-			tgbotapi.NewKeyboardButton("/timezone GMT-10"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("/timezone Europe/London"),
-			tgbotapi.NewKeyboardButton("/timezone America/New_York"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("/timezone Asia/Tokyo"),
-			tgbotapi.NewKeyboardButton("/timezone Australia/Sydney"),
-		),
-	)
-	keyboard.OneTimeKeyboard = true
-	return keyboard
+	id, err := strconv.ParseInt(userID, 10, 64)
+	return err == nil && b.admins[id]
 }
@@ -0,0 +1,235 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// broadcastTTL is how long a previewed broadcast can be sent
+const broadcastTTL = 30 * time.Minute
+
+// Callback data of the broadcast preview buttons: "broadcast:<send|cancel>:<preview message ID>"
+const (
+	callbackBroadcast = "broadcast"
+	broadcastSend     = "send"
+	broadcastCancel   = "cancel"
+)
+
+// pendingBroadcast is an announcement an admin previewed but hasn't sent yet
+type pendingBroadcast struct {
+	chatID    int64
+	messageID int // The preview message, which the buttons belong to
+	text      string
+}
+
+// broadcastMessage is a message of a broadcast to a user's private chat
+type broadcastMessage struct {
+	userID string
+	text   string
+}
+
+// broadcastResult counts the outcome of a broadcast
+type broadcastResult struct {
+	sent    int
+	blocked int // Users who blocked the bot or deleted their account
+	failed  int
+}
+
+// broadcast sends messages at most cfg.BroadcastRate per second, waiting whenever Telegram asks
+// to slow down, until ctx is cancelled or the bot stops. finished is called for every user who
+// got their message or can never get it because they blocked the bot, so callers can tell who
+// is left for another attempt.
+func (b *Bot) broadcast(ctx context.Context, messages []broadcastMessage, finished func(userID string)) broadcastResult {
+	ticker := time.NewTicker(time.Second / time.Duration(b.cfg.BroadcastRate))
+	defer ticker.Stop()
+
+	var result broadcastResult
+	for _, msg := range messages {
+		select {
+		case <-ctx.Done():
+			return result
+		case <-ticker.C:
+		}
+		if b.Stopping() {
+			return result
+		}
+
+		chatID, err := strconv.ParseInt(msg.userID, 10, 64)
+		if err != nil {
+			log.Printf("Skipping broadcast to invalid user ID %q", msg.userID)
+			result.failed++
+			continue
+		}
+
+		err = b.sendBroadcast(ctx, chatID, msg.text)
+		switch {
+		case err == nil:
+			result.sent++
+		case isBlockedError(err):
+			result.blocked++
+		default:
+			log.Printf("Error broadcasting to user %s: %v", msg.userID, err)
+			result.failed++
+			continue
+		}
+		if finished != nil {
+			finished(msg.userID)
+		}
+	}
+	return result
+}
+
+// sendBroadcast sends one message of a broadcast. Short waits Telegram asks for are handled by
+// the bot's HTTP client; after a longer one, the message is tried again once.
+func (b *Bot) sendBroadcast(ctx context.Context, chatID int64, text string) error {
+	_, err := b.bot.Send(tgbotapi.NewMessage(chatID, text))
+
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		return err
+	}
+
+	log.Printf("Broadcast is rate limited, waiting %ds", apiErr.RetryAfter)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(apiErr.RetryAfter) * time.Second):
+	}
+	_, err = b.bot.Send(tgbotapi.NewMessage(chatID, text))
+	return err
+}
+
+// canReceiveBroadcast reports whether a user may get broadcasts, leaving out users who may not
+// use the bot right now
+func (b *Bot) canReceiveBroadcast(userID string) bool {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil || !b.isAllowedUser(id) {
+		return false
+	}
+	_, banned := b.store.ActiveBan(userID)
+	return !banned
+}
+
+// isBlockedError reports whether a message failed because the user blocked the bot or
+// deleted their account, so there's no point in trying again
+func isBlockedError(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+// handleBroadcast previews an announcement to all known users, which is only sent once the
+// admin confirms it. The text follows the command or is the message the command replies to.
+func (b *Bot) handleBroadcast(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	adminID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+
+	text := strings.TrimSpace(message.CommandArguments())
+	if reply := message.ReplyToMessage; text == "" && reply != nil {
+		text = strings.TrimSpace(reply.Text)
+	}
+	if text == "" {
+		b.sendText(chatID, b.t(message.From, "broadcast.usage"), messageID)
+		return
+	}
+
+	recipients := len(b.broadcastRecipients())
+	duration := (time.Duration(recipients) * time.Second / time.Duration(b.cfg.BroadcastRate)).Round(time.Second)
+	preview := b.t(message.From, "broadcast.preview", recipients, duration, text)
+
+	msg := tgbotapi.NewMessage(chatID, preview)
+	msg.ReplyToMessageID = messageID
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending broadcast preview: %v", err)
+		return
+	}
+
+	// The buttons name the preview's own message, so an older preview can't be sent by mistake
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, sent.MessageID, b.broadcastKeyboard(message.From, sent.MessageID, recipients))
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error adding broadcast buttons: %v", err)
+	}
+
+	b.broadcasts.Set(adminID, &pendingBroadcast{chatID: chatID, messageID: sent.MessageID, text: text})
+}
+
+// broadcastKeyboard creates the buttons under a broadcast preview
+func (b *Bot) broadcastKeyboard(user *tgbotapi.User, messageID, recipients int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "broadcast.button.send", recipients), callbackData(callbackBroadcast, broadcastSend, strconv.Itoa(messageID))),
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "broadcast.button.cancel"), callbackData(callbackBroadcast, broadcastCancel, strconv.Itoa(messageID))),
+	))
+}
+
+// handleBroadcastAnswer handles a press on one of the broadcast preview buttons. The broadcast
+// runs in the background, as it can take minutes, and the admin gets a report when it's done.
+func (b *Bot) handleBroadcastAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	adminID := fmt.Sprintf("%d", query.From.ID)
+	if len(args) != 2 {
+		return
+	}
+	messageID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	pending, ok := b.broadcasts.Get(adminID)
+	if !ok || pending.messageID != messageID {
+		b.answerCallback(query, b.t(query.From, "broadcast.expired"))
+		return
+	}
+	b.broadcasts.Delete(adminID)
+	b.answerCallback(query, "")
+
+	// Remove the buttons so the announcement can't be sent twice
+	edit := tgbotapi.NewEditMessageReplyMarkup(pending.chatID, pending.messageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error removing broadcast buttons: %v", err)
+	}
+
+	if args[0] != broadcastSend {
+		b.sendText(pending.chatID, b.t(query.From, "broadcast.cancelled"), pending.messageID)
+		return
+	}
+
+	var messages []broadcastMessage
+	for _, userID := range b.broadcastRecipients() {
+		messages = append(messages, broadcastMessage{userID: userID, text: pending.text})
+	}
+	log.Printf("Admin %s started a broadcast to %d users", adminID, len(messages))
+	b.sendText(pending.chatID, b.t(query.From, "broadcast.sending", len(messages)), pending.messageID)
+
+	go func() {
+		result := b.broadcast(context.Background(), messages, nil)
+		log.Printf("Broadcast of admin %s finished: %d sent, %d blocked the bot, %d failed", adminID, result.sent, result.blocked, result.failed)
+
+		report := b.t(query.From, "broadcast.finished", result.sent, result.blocked, result.failed)
+		if left := len(messages) - result.sent - result.blocked - result.failed; left > 0 {
+			report = b.t(query.From, "broadcast.stopped", result.sent, result.blocked, result.failed, left)
+		}
+		b.sendText(pending.chatID, report, pending.messageID)
+	}()
+}
+
+// broadcastRecipients returns the known users who may get broadcasts, in a stable order
+func (b *Bot) broadcastRecipients() []string {
+	var recipients []string
+	for userID := range b.store.Users() {
+		if b.canReceiveBroadcast(userID) {
+			recipients = append(recipients, userID)
+		}
+	}
+	sort.Strings(recipients)
+	return recipients
+}
@@ -0,0 +1,27 @@
+package telegram
+
+import (
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// eventFileKeyboard creates the buttons of an event file: links that add the event to web
+// calendars without handling the file, and the re-extract buttons
+func (b *Bot) eventFileKeyboard(user *tgbotapi.User, event *openai.Event, loc *time.Location) tgbotapi.InlineKeyboardMarkup {
+	keyboard := b.reextractKeyboard(user)
+	links := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(b.t(user, "links.google"), calendar.GoogleCalendarURL(event, loc)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(b.t(user, "links.outlook"), calendar.OutlookCalendarURL(event, loc, calendar.OutlookPersonal)),
+			tgbotapi.NewInlineKeyboardButtonURL(b.t(user, "links.office"), calendar.OutlookCalendarURL(event, loc, calendar.OutlookOffice)),
+		),
+	}
+	keyboard.InlineKeyboard = append(links, keyboard.InlineKeyboard...)
+	return keyboard
+}
@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxCallbackDataLength is the most callback data Telegram accepts for a button, in bytes
+const maxCallbackDataLength = 64
+
+// callbackSeparator separates the name and arguments in callback data
+const callbackSeparator = ":"
+
+// callbackHandler handles a press on an inline button, with the arguments of its callback data
+type callbackHandler func(ctx context.Context, query *tgbotapi.CallbackQuery, args []string)
+
+// callbackRoute describes a registered button handler and who may press the button
+type callbackRoute struct {
+	name      string
+	adminOnly bool // Only the bot's admins may press the button
+	handler   callbackHandler
+}
+
+// callbackRouter dispatches button presses to handlers by the name their callback data starts with
+type callbackRouter struct {
+	routes map[string]callbackRoute
+}
+
+// newCallbackRouter creates an empty callback router
+func newCallbackRouter() *callbackRouter {
+	return &callbackRouter{
+		routes: make(map[string]callbackRoute),
+	}
+}
+
+// handle registers a handler for the buttons with a name
+func (r *callbackRouter) handle(name string, handler callbackHandler) {
+	r.routes[name] = callbackRoute{name: name, handler: handler}
+}
+
+// handleAdmin registers a handler for buttons only the bot's admins may press
+func (r *callbackRouter) handleAdmin(name string, handler callbackHandler) {
+	r.routes[name] = callbackRoute{name: name, adminOnly: true, handler: handler}
+}
+
+// route finds the route and arguments for callback data, reporting whether one is registered
+func (r *callbackRouter) route(data string) (callbackRoute, []string, bool) {
+	name, args := decodeCallbackData(data)
+	rt, ok := r.routes[name]
+	return rt, args, ok
+}
+
+// callbackData encodes the name of a button handler and its arguments as callback data,
+// e.g. "plan:create:42". Arguments must not contain the separator.
+func callbackData(name string, args ...string) string {
+	data := strings.Join(append([]string{name}, args...), callbackSeparator)
+	if len(data) > maxCallbackDataLength {
+		log.Printf("Warning: callback data %q is longer than %d bytes, Telegram will reject the button", data, maxCallbackDataLength)
+	}
+	return data
+}
+
+// decodeCallbackData splits callback data into the handler name and its arguments
+func decodeCallbackData(data string) (string, []string) {
+	parts := strings.Split(data, callbackSeparator)
+	return parts[0], parts[1:]
+}
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data sent by the inline buttons under an ICS file
+const (
+	callbackReextract       = "reextract"
+	callbackReextractStrong = "reextract_strong"
+)
+
+// reextractKeyboard creates the inline buttons that let the user retry an extraction
+func (b *Bot) reextractKeyboard(user *tgbotapi.User) tgbotapi.InlineKeyboardMarkup {
+	row := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "reextract.button"), callbackReextract),
+	)
+	if b.cfg.OpenAIStrongModel != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.t(user, "reextract.button_strong"), callbackReextractStrong))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// newCallbackRouter registers the handlers of the bot's inline buttons
+func (b *Bot) newCallbackRouter() *callbackRouter {
+	r := newCallbackRouter()
+	r.handle(callbackReextract, b.handleReextract)
+	r.handle(callbackReextractStrong, b.handleReextract)
+	r.handle(callbackFollowUp, b.handleFollowUpAnswer)
+	r.handle(callbackReadBack, b.handleReadBackAnswer)
+	r.handle(callbackPlan, b.handlePlanAnswer)
+	r.handle(callbackPreview, b.handlePreviewAnswer)
+	r.handle(callbackFreeSlot, b.handleFreeSlotAnswer)
+	r.handle(callbackTimezone, b.handleTimezoneAnswer)
+	r.handle(callbackOnboarding, b.handleOnboardingAnswer)
+	r.handleAdmin(callbackBroadcast, b.handleBroadcastAnswer)
+	return r
+}
+
+// handleCallbackQuery handles a press on one of the bot's inline buttons. Telegram shows a
+// spinner on the button until the query is answered, so it's answered without a notification
+// if the handler doesn't answer it itself.
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	log.Printf("Received callback %q from user %d", query.Data, query.From.ID)
+
+	b.answeredCallbacks.Store(query.ID, false)
+	defer func() {
+		if answered, ok := b.answeredCallbacks.LoadAndDelete(query.ID); ok && !answered.(bool) {
+			b.answerCallback(query, "")
+		}
+	}()
+
+	if !b.isAllowedUser(query.From.ID) {
+		b.answerCallback(query, b.t(query.From, "callback.private"))
+		return
+	}
+	if ban, banned := b.store.ActiveBan(fmt.Sprintf("%d", query.From.ID)); banned {
+		b.answerCallback(query, b.banNotice(query.From, ban))
+		return
+	}
+
+	rt, args, ok := b.callbacks.route(query.Data)
+	if !ok {
+		log.Printf("Unknown callback data: %s", query.Data)
+		return
+	}
+	if rt.adminOnly && !b.isAdmin(fmt.Sprintf("%d", query.From.ID)) {
+		log.Printf("Denied admin button %s to user %d", rt.name, query.From.ID)
+		b.answerCallback(query, b.t(query.From, "callback.admin_only"))
+		return
+	}
+	rt.handler(ctx, query, args)
+}
+
+// handleReextract runs the extraction for the message an ICS file replied to again. The new
+// result is cached for the message's sender alone, so others who sent the same content keep
+// theirs.
+func (b *Bot) handleReextract(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if query.Message == nil || query.Message.ReplyToMessage == nil || query.Message.ReplyToMessage.From == nil {
+		b.answerCallback(query, b.t(query.From, "reextract.original_gone"))
+		return
+	}
+	original := query.Message.ReplyToMessage
+	if original.IsCommand() {
+		// Telegram doesn't include what a replied-to /event command replied to itself
+		b.answerCallback(query, b.t(query.From, "reextract.command"))
+		return
+	}
+
+	// Only the sender, or someone allowed to edit events in a group, may re-extract
+	if original.From.ID != query.From.ID &&
+		(original.Chat.IsPrivate() || !b.canPerformGroupAction(original.Chat.ID, query.From.ID, ActionEdit)) {
+		b.answerCallback(query, b.t(query.From, "reextract.not_own"))
+		return
+	}
+
+	// The new file updates the event of the one the button is under
+	opts := eventOptions{refresh: true, replaces: b.replacedEntry(query.Message)}
+	if query.Data == callbackReextractStrong {
+		opts.extract = openai.ExtractOptions{Model: b.cfg.OpenAIStrongModel}
+	}
+
+	b.answerCallback(query, b.t(query.From, "reextract.started"))
+	b.processEvent(ctx, original, opts)
+}
+
+// answerCallback acknowledges a callback query, optionally showing a short notification
+func (b *Bot) answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	b.answeredCallbacks.Store(query.ID, true)
+	if _, err := b.bot.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/language"
+	"calendar-assistant/pkg/openai"
+)
+
+// iPhoneShortcutURL is an iOS shortcut that imports an ICS file into the Calendar app
+const iPhoneShortcutURL = "https://www.icloud.com/shortcuts/db9d3a471c414a1abd2ba7b960395bee"
+
+// eventCaption formats the caption of an event file as MarkdownV2, in the language of the
+// event, falling back to the user's language and then the deployment's
+func (b *Bot) eventCaption(event *openai.Event, isAllDay bool, timezone, userLanguage string) string {
+	lang := language.Detect(event.Title + "\n" + event.Description)
+	if !b.catalog.Has(lang) {
+		lang = userLanguage
+	}
+	if !b.catalog.Has(lang) {
+		lang = b.defaultLanguage()
+	}
+	label := func(key string) string {
+		return b.catalog.T(lang, "caption."+key)
+	}
+
+	// Times are monospaced so they stand out, and the location opens a map
+	var sb strings.Builder
+	if isAllDay {
+		fmt.Fprintf(&sb, "%s: %s\n", escapeMarkdown(label("all_day_event")), markdownBold(event.Title))
+		date := markdownCode(event.StartTime.Format("2006-01-02"))
+		if calendar.IsMultiDay(event) {
+			date += " – " + markdownCode(calendar.LastDay(event).Format("2006-01-02"))
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", escapeMarkdown(label("date")), date)
+	} else {
+		timeFormat := "2006-01-02 15:04"
+		fmt.Fprintf(&sb, "%s: %s\n", escapeMarkdown(label("timed_event")), markdownBold(event.Title))
+		fmt.Fprintf(&sb, "%s: %s %s\n", escapeMarkdown(label("start")), markdownCode(event.StartTime.Format(timeFormat)), escapeMarkdown(timezone))
+		fmt.Fprintf(&sb, "%s: %s %s\n", escapeMarkdown(label("end")), markdownCode(event.EndTime.Format(timeFormat)), escapeMarkdown(timezone))
+	}
+	if event.Location != "" {
+		fmt.Fprintf(&sb, "%s: %s\n", escapeMarkdown(label("location")), markdownLocation(event.Location))
+	}
+	if calendar.HasPlace(event) {
+		fmt.Fprintf(&sb, "%s: %s · %s\n", escapeMarkdown(label("map")),
+			markdownLink("Google Maps", calendar.GoogleMapsURL(event)), markdownLink("Apple Maps", calendar.AppleMapsURL(event)))
+	}
+	if event.URL != "" {
+		fmt.Fprintf(&sb, "%s: %s\n", escapeMarkdown(label("link")), markdownLink(event.URL, event.URL))
+	}
+	fmt.Fprintf(&sb, "%s: %s\n\n", escapeMarkdown(label("timezone")), escapeMarkdown(timezone))
+	fmt.Fprintf(&sb, "%s\n%s", escapeMarkdown(label("iphone_hint")), escapeMarkdown(iPhoneShortcutURL))
+	return sb.String()
+}
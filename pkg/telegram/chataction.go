@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatActionInterval is how often a chat action is repeated, as Telegram shows one for about
+// five seconds
+const chatActionInterval = 4 * time.Second
+
+// startChatAction shows a chat action such as "typing..." until the returned function is called
+func (b *Bot) startChatAction(chatID int64, action string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(chatActionInterval)
+		defer ticker.Stop()
+		for {
+			b.sendChatAction(chatID, action)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// sendChatAction shows a chat action once
+func (b *Bot) sendChatAction(chatID int64, action string) {
+	if _, err := b.bot.Request(tgbotapi.NewChatAction(chatID, action)); err != nil {
+		log.Printf("Error sending chat action %s: %v", action, err)
+	}
+}
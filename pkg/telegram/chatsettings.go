@@ -0,0 +1,112 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"calendar-assistant/pkg/language"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// languageCodePattern matches ISO 639 language codes such as "en" or "de"
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// eventPreferences returns a copy of a user's preferences for creating events in a chat, with
+// the defaults of a group chat filling in what the user hasn't set themselves
+func (b *Bot) eventPreferences(userID string, chatID int64) *storage.UserPreferences {
+	effective := b.getUserPreferences(userID)
+
+	if chat, ok := b.store.ChatSettings(chatID); ok {
+		if effective.Timezone == "" {
+			effective.Timezone = chat.Timezone
+		}
+		// The chat's language takes precedence over the default new users get
+		if chat.Language != "" && !b.languageChosen(effective) {
+			effective.Language = chat.Language
+		}
+	}
+	return effective
+}
+
+// handleChatSettings shows or changes the default timezone and language of a group chat, used
+// for members who haven't set their own so a shared chat produces consistent events
+func (b *Bot) handleChatSettings(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	if message.Chat.IsPrivate() {
+		b.sendError(message, "chatsettings.groups_only", nil)
+		return
+	}
+
+	settings, _ := b.store.ChatSettings(chatID)
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		b.sendText(chatID, b.chatSettingsText(message.From, settings)+"\n\n"+b.t(message.From, "chatsettings.help"), messageID)
+		return
+	}
+	if len(args) != 2 {
+		b.sendError(message, "chatsettings.usage", nil)
+		return
+	}
+
+	value := args[1]
+	clear := strings.EqualFold(value, "off")
+	switch strings.ToLower(args[0]) {
+	case "timezone":
+		if clear {
+			settings.Timezone = ""
+			break
+		}
+		timezone, err := b.parseTimezone(value)
+		if err != nil {
+			b.sendError(message, "chatsettings.invalid_timezone", nil, value)
+			return
+		}
+		settings.Timezone = timezone
+	case "language":
+		if clear {
+			settings.Language = ""
+			break
+		}
+		code := strings.ToLower(value)
+		if !languageCodePattern.MatchString(code) {
+			b.sendError(message, "chatsettings.invalid_language", nil, value)
+			return
+		}
+		settings.Language = code
+	default:
+		b.sendError(message, "chatsettings.unknown_setting", nil, args[0])
+		return
+	}
+
+	if err := b.store.SaveChatSettings(chatID, settings); err != nil {
+		log.Printf("Error saving settings of chat %d: %v", chatID, err)
+		b.sendError(message, "chatsettings.save_failed", err)
+		return
+	}
+	log.Printf("Set settings of chat %d to %+v", chatID, settings)
+
+	b.sendText(chatID, b.chatSettingsText(message.From, settings), messageID)
+}
+
+// chatSettingsText describes the defaults of a group chat
+func (b *Bot) chatSettingsText(user *tgbotapi.User, settings storage.ChatSettings) string {
+	timezone := b.t(user, "chatsettings.not_set")
+	if settings.Timezone != "" {
+		timezone = b.formatTimezoneForDisplay(settings.Timezone)
+	}
+	lang := b.t(user, "chatsettings.not_set")
+	if settings.Language != "" {
+		lang = settings.Language
+		if name := language.Name(settings.Language); name != "" {
+			lang = fmt.Sprintf("%s (%s)", name, settings.Language)
+		}
+	}
+	return b.t(user, "chatsettings.text", timezone, lang)
+}
@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+)
+
+// maxConflicts is how many clashing events a warning lists
+const maxConflicts = 3
+
+// conflicts returns the stored events of a user that overlap an event, in order of their start.
+// All-day and cancelled events don't block time, so they neither clash nor are clashed with,
+// and an earlier version of the same event isn't a clash either.
+func (b *Bot) conflicts(userID string, event *openai.Event) []*openai.Event {
+	if !blocksTime(event) {
+		return nil
+	}
+
+	key := eventKey(event)
+	var clashes []*openai.Event
+	for _, stored := range b.storedEvents(userID) {
+		if !blocksTime(stored) || eventKey(stored) == key {
+			continue
+		}
+		// Both are wall-clock times in the user's timezone, so they compare as they are
+		if overlapsAny([]*openai.Event{stored}, event.StartTime, event.EndTime) {
+			clashes = append(clashes, stored)
+		}
+	}
+	return clashes
+}
+
+// blocksTime reports whether an event keeps its time busy. Cancelled and free events don't, and
+// all-day events only do when marked busy
+func blocksTime(event *openai.Event) bool {
+	if event.Status == openai.StatusCancelled || event.Transparency == openai.TransparencyFree {
+		return false
+	}
+	return event.Transparency == openai.TransparencyBusy || !calendar.IsAllDay(event)
+}
+
+// conflictWarning describes the events an event clashes with, e.g. This clashes with
+// "Dentist" 15:00–16:00, or "" if there are none
+func conflictWarning(event *openai.Event, clashes []*openai.Event) string {
+	if len(clashes) == 0 {
+		return ""
+	}
+
+	var names []string
+	for i, clash := range clashes {
+		if i == maxConflicts {
+			names = append(names, fmt.Sprintf("%d more", len(clashes)-maxConflicts))
+			break
+		}
+		names = append(names, fmt.Sprintf("%q %s", clash.Title, conflictTime(event, clash)))
+	}
+	return "⚠️ This clashes with " + strings.Join(names, ", ")
+}
+
+// conflictTime formats the time of a clashing event, with its date if it's another day than
+// the event's
+func conflictTime(event, clash *openai.Event) string {
+	start, end := clash.StartTime.Format("15:04"), clash.EndTime.Format("15:04")
+	if clash.StartTime.Format("2006-01-02") != event.StartTime.Format("2006-01-02") {
+		start = clash.StartTime.Format("Mon 2 Jan 15:04")
+	}
+	if clash.EndTime.Format("2006-01-02") != clash.StartTime.Format("2006-01-02") {
+		end = clash.EndTime.Format("Mon 2 Jan 15:04")
+	}
+	return start + "–" + end
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleDelete sends a file that removes the events of one of the bot's event files from the
+// calendar, in reply to the file
+func (b *Bot) handleDelete(ctx context.Context, message *tgbotapi.Message) {
+	file := message.ReplyToMessage
+	if file == nil || file.From == nil || file.From.ID != b.bot.Self.ID || file.Document == nil {
+		b.sendError(message, "delete.usage", nil)
+		return
+	}
+
+	entries := b.store.HistoryByMessage(b.cfg.BotName, message.Chat.ID, file.MessageID)
+	if len(entries) == 0 {
+		b.sendError(message, "delete.not_found", nil)
+		return
+	}
+	b.cancelEvents(message, entries)
+}
+
+// handleUndo sends a file that removes the events of the last event file the user got in the
+// chat from the calendar
+func (b *Bot) handleUndo(ctx context.Context, message *tgbotapi.Message) {
+	history := b.store.History(fmt.Sprintf("%d", message.From.ID))
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.ChatID != message.Chat.ID || entry.MessageID == 0 || entry.Event == nil || entry.Event.Status == openai.StatusCancelled {
+			continue
+		}
+		b.cancelEvents(message, b.store.HistoryByMessage(b.cfg.BotName, entry.ChatID, entry.MessageID))
+		return
+	}
+	b.sendError(message, "delete.not_found", nil)
+}
+
+// cancelEvents sends a cancellation of the latest versions of the events of history entries,
+// marks them as cancelled and drops their reminders and other scheduled messages
+func (b *Bot) cancelEvents(message *tgbotapi.Message, entries []storage.HistoryEntry) {
+	chatID := message.Chat.ID
+	owner := entries[0].UserID
+
+	// Only the owner, or someone allowed to cancel events in a group, may remove them
+	if owner != fmt.Sprintf("%d", message.From.ID) &&
+		(message.Chat.IsPrivate() || !b.canPerformGroupAction(chatID, message.From.ID, ActionCancel)) {
+		b.sendError(message, "delete.not_allowed", nil)
+		return
+	}
+
+	var events []*openai.Event
+	uids := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Event == nil || uids[entry.Event.UID] {
+			continue
+		}
+		event := b.latestVersion(owner, entry.Event.UID)
+		if event == nil || event.Status == openai.StatusCancelled {
+			continue
+		}
+		uids[event.UID] = true
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		b.sendError(message, "delete.already", nil)
+		return
+	}
+
+	prefs := b.eventPreferences(owner, chatID)
+	loc, _ := b.timezones.Resolve(b.userTimezone(prefs))
+
+	// The travel time blocked before the events goes with them
+	icsData, err := calendar.GenerateCancellation(b.withTravel(message.From, prefs, events), loc, b.icsOptions(owner, 0, false))
+	if err != nil {
+		log.Printf("Error generating cancellation ICS file: %v", err)
+		b.sendError(message, "error.ics_generate", err)
+		return
+	}
+
+	caption := b.t(message.From, "delete.caption", events[0].Title)
+	if len(events) > 1 {
+		caption = b.t(message.From, "delete.caption_many", len(events))
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("cancel_%d.ics", message.MessageID),
+		Bytes: icsData,
+	})
+	doc.Caption = caption
+	doc.ReplyToMessageID = message.MessageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending cancellation ICS file: %v", err)
+		b.sendError(message, "error.ics_send", err)
+		return
+	}
+	log.Printf("Sent cancellation of %d events of user %s", len(events), owner)
+
+	// Only a cancellation the user got counts, so a failed one can be tried again
+	for uid := range uids {
+		if _, err := b.store.CancelEvent(owner, uid); err != nil {
+			log.Printf("Error cancelling event %s of user %s: %v", uid, owner, err)
+		}
+	}
+	b.unscheduleEvents(owner, uids)
+}
+
+// latestVersion returns the latest version of a user's event with a UID, or nil if there's none
+func (b *Bot) latestVersion(userID, uid string) *openai.Event {
+	history := b.store.History(userID)
+	for i := len(history) - 1; i >= 0; i-- {
+		if event := history[i].Event; event != nil && event.UID == uid {
+			return event
+		}
+	}
+	return nil
+}
+
+// unscheduleEvents drops the reminders, follow-ups and file re-sends of a user's events, so
+// nothing brings a cancelled event back
+func (b *Bot) unscheduleEvents(userID string, uids map[string]bool) {
+	entries := make(map[int64]bool)
+	for _, entry := range b.store.History(userID) {
+		if entry.Event != nil && uids[entry.Event.UID] {
+			entries[entry.ID] = true
+		}
+	}
+
+	for _, scheduled := range b.store.Scheduled(userID) {
+		if !entries[scheduled.HistoryID] {
+			continue
+		}
+		if _, err := b.store.DeleteScheduled(scheduled.ID); err != nil {
+			log.Printf("Error deleting scheduled message #%d: %v", scheduled.ID, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// digestJob is the scheduler job that sends a user's daily digest, keyed by user ID
+const digestJob = "digest"
+
+// defaultDigestTime is when the digest is sent if the user doesn't pick a time
+const defaultDigestTime = "08:00"
+
+// digestPayload is what the digest job needs to know about the user
+type digestPayload struct {
+	LanguageCode string `json:"language_code,omitempty"` // Language of the user's Telegram app
+}
+
+// handleDigest shows, turns on or off the daily digest of the user's events, e.g. /digest 7:30
+// or /digest off
+func (b *Bot) handleDigest(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	args := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if args == "" {
+		prefs := b.getUserPreferences(userID)
+		digestTime := prefs.DigestTime
+
+		if digestTime == "" {
+			b.sendText(message.Chat.ID, b.t(message.From, "digest.status_off"), message.MessageID)
+		} else {
+			b.sendText(message.Chat.ID, b.t(message.From, "digest.status_on", digestTime), message.MessageID)
+		}
+		return
+	}
+
+	if args == "off" {
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.DigestTime = ""
+		})
+		if _, err := b.scheduler.Cancel(b.jobKind(digestJob), userID); err != nil {
+			log.Printf("Error cancelling digest of user %s: %v", userID, err)
+		}
+		log.Printf("Turned off the digest of user %s", userID)
+		b.sendText(message.Chat.ID, b.t(message.From, "digest.turned_off"), message.MessageID)
+		return
+	}
+
+	digestTime := defaultDigestTime
+	if args != "on" {
+		var ok bool
+		if digestTime, ok = parseDigestTime(args); !ok {
+			b.sendError(message, "digest.usage", nil)
+			return
+		}
+	}
+
+	b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+		prefs.DigestTime = digestTime
+	})
+	if err := b.scheduleDigest(message.From); err != nil {
+		log.Printf("Error scheduling digest of user %s: %v", userID, err)
+		b.sendError(message, "digest.failed", err)
+		return
+	}
+	log.Printf("Turned on the digest of user %s at %s", userID, digestTime)
+	b.sendText(message.Chat.ID, b.t(message.From, "digest.turned_on", digestTime), message.MessageID)
+}
+
+// parseDigestTime parses the local time of the digest, like "7", "7:30" or "07:30", into HH:MM
+func parseDigestTime(text string) (string, bool) {
+	hour, minute, found := strings.Cut(text, ":")
+	if !found {
+		minute = "0"
+	}
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return "", false
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil || m < 0 || m > 59 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", h, m), true
+}
+
+// scheduleDigest schedules the user's next digest at their digest time in their timezone,
+// replacing the one scheduled before
+func (b *Bot) scheduleDigest(user *tgbotapi.User) error {
+	userID := fmt.Sprintf("%d", user.ID)
+	prefs := b.getUserPreferences(userID)
+	digestTime := prefs.DigestTime
+	if digestTime == "" {
+		return nil
+	}
+
+	at, err := time.Parse("15:04", digestTime)
+	if err != nil {
+		return fmt.Errorf("invalid digest time %q: %w", digestTime, err)
+	}
+	now := b.userNow(user)
+	runAt := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !runAt.After(now) {
+		runAt = runAt.AddDate(0, 0, 1)
+	}
+
+	_, err = b.scheduler.Schedule(b.jobKind(digestJob), userID, digestPayload{LanguageCode: user.LanguageCode}, runAt)
+	return err
+}
+
+// runDigest sends a user their events of today and schedules the digest of tomorrow. Days
+// without events are skipped quietly.
+func (b *Bot) runDigest(ctx context.Context, job storage.Job) error {
+	id, err := strconv.ParseInt(job.Key, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q: %w", job.Key, err)
+	}
+	var payload digestPayload
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid digest payload: %w", err)
+		}
+	}
+	user := &tgbotapi.User{ID: id, LanguageCode: payload.LanguageCode}
+
+	events := b.eventsOn(job.Key, b.userNow(user).Format("2006-01-02"))
+	if len(events) > 0 {
+		text := b.agendaText(user, b.t(user, "digest.header", len(events)), events)
+		msg := tgbotapi.NewMessage(id, text)
+		msg.ParseMode = tgbotapi.ModeMarkdownV2
+		if _, err := b.bot.Send(msg); err != nil {
+			if isBlockedError(err) {
+				log.Printf("User %s blocked the bot, turning off their digest", job.Key)
+				b.updateUserPreferences(job.Key, func(prefs *storage.UserPreferences) {
+					prefs.DigestTime = ""
+				})
+				return nil
+			}
+			return fmt.Errorf("failed to send digest: %w", err)
+		}
+		log.Printf("Sent digest of %d events to user %s", len(events), job.Key)
+	}
+
+	return b.scheduleDigest(user)
+}
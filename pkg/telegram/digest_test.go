@@ -0,0 +1,29 @@
+package telegram
+
+import "testing"
+
+func TestParseDigestTime(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"7", "07:00", true},
+		{"7:30", "07:30", true},
+		{"07:30", "07:30", true},
+		{"0:00", "00:00", true},
+		{"23:59", "23:59", true},
+		{"24", "", false},
+		{"7:60", "", false},
+		{"-1", "", false},
+		{"7:", "", false},
+		{"7am", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDigestTime(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseDigestTime(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
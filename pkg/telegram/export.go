@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Formats of /export
+const (
+	exportICS    = "ics"
+	exportCSV    = "csv"
+	exportGoogle = "google"
+)
+
+// handleExport sends the user's events in one file, for moving them to another calendar or
+// tool: an ICS file, a generic CSV file or a CSV file for Google Calendar's import
+func (b *Bot) handleExport(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+
+	format := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if format == "" {
+		format = exportICS
+	}
+	if format != exportICS && format != exportCSV && format != exportGoogle {
+		b.sendError(message, "export.usage", nil)
+		return
+	}
+
+	var events []*openai.Event
+	for _, event := range b.storedEvents(userID) {
+		if event.Status != openai.StatusCancelled {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		b.sendText(message.Chat.ID, b.t(message.From, "export.empty"), message.MessageID)
+		return
+	}
+
+	prefs := b.eventPreferences(userID, message.Chat.ID)
+	loc, _ := b.timezones.Resolve(b.userTimezone(prefs))
+
+	var data []byte
+	var err error
+	name := "events.ics"
+	switch format {
+	case exportICS:
+		data, err = calendar.GenerateICS(events, loc, b.icsOptions(userID, prefs.ReminderMinutes, true))
+	case exportCSV:
+		data, err = calendar.GenerateCSV(events, loc, userID)
+		name = "events.csv"
+	case exportGoogle:
+		data, err = calendar.GenerateGoogleCSV(events)
+		name = "google_calendar.csv"
+	}
+	if err != nil {
+		log.Printf("Error exporting events of user %s as %s: %v", userID, format, err)
+		b.sendError(message, "export.failed", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = b.t(message.From, "export.caption."+format, len(events), b.formatTimezoneForDisplay(loc.String()))
+	doc.ReplyToMessageID = message.MessageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending export: %v", err)
+		b.sendError(message, "export.failed", err)
+		return
+	}
+	log.Printf("Exported %d events of user %s as %s", len(events), userID, format)
+}
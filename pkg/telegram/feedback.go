@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleFeedback stores the feedback after /feedback and forwards it to the operators. Replying
+// to one of the bot's messages, e.g. a wrong event file, sends that message along with it.
+func (b *Bot) handleFeedback(ctx context.Context, message *tgbotapi.Message) {
+	text := strings.TrimSpace(message.CommandArguments())
+	if text == "" {
+		b.sendError(message, "feedback.usage", nil)
+		return
+	}
+
+	feedback := storage.Feedback{
+		UserID: fmt.Sprintf("%d", message.From.ID),
+		ChatID: message.Chat.ID,
+		Text:   text,
+	}
+	original := message.ReplyToMessage
+	if original != nil && original.From != nil && original.From.ID == b.bot.Self.ID {
+		feedback.Context = strings.TrimSpace(original.Text + "\n" + original.Caption)
+		feedback.HistoryID = b.feedbackHistoryID(feedback.UserID, feedback.Context)
+	} else {
+		original = nil
+	}
+
+	feedback, err := b.store.AddFeedback(feedback)
+	if err != nil {
+		log.Printf("Error storing feedback of user %s: %v", feedback.UserID, err)
+		b.sendError(message, "feedback.failed", nil)
+		return
+	}
+	log.Printf("Stored feedback %d of user %s", feedback.ID, feedback.UserID)
+
+	b.forwardFeedback(message, feedback, original)
+	b.sendText(message.Chat.ID, b.t(message.From, "feedback.thanks"), message.MessageID)
+}
+
+// feedbackHistoryID finds the extraction a bot message shows, by the title of the user's most
+// recent event that it mentions, or returns 0 if there is none
+func (b *Bot) feedbackHistoryID(userID, text string) int64 {
+	history := b.store.History(userID)
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.Event != nil && entry.Event.Title != "" && strings.Contains(text, entry.Event.Title) {
+			return entry.ID
+		}
+	}
+	return 0
+}
+
+// forwardFeedback sends feedback to the feedback chat, or to every admin if there is none,
+// followed by the bot message it replies to
+func (b *Bot) forwardFeedback(message *tgbotapi.Message, feedback storage.Feedback, original *tgbotapi.Message) {
+	recipients := []int64{b.cfg.FeedbackChatID}
+	if b.cfg.FeedbackChatID == 0 {
+		recipients = b.cfg.AdminUserIDs
+	}
+	if len(recipients) == 0 {
+		log.Println("Neither FEEDBACK_CHAT_ID nor ADMIN_USER_IDS is set, so feedback is only stored")
+		return
+	}
+
+	sender := fmt.Sprintf("user %d", message.From.ID)
+	if message.From.UserName != "" {
+		sender = fmt.Sprintf("@%s (%d)", message.From.UserName, message.From.ID)
+	}
+	text := fmt.Sprintf("Feedback #%d from %s:\n\n%s", feedback.ID, sender, feedback.Text)
+	if feedback.HistoryID != 0 {
+		text += fmt.Sprintf("\n\nAbout history entry %d", feedback.HistoryID)
+	}
+
+	for _, chatID := range recipients {
+		msg := tgbotapi.NewMessage(chatID, text)
+		sent, err := b.bot.Send(msg)
+		if err != nil {
+			log.Printf("Error forwarding feedback %d to chat %d: %v", feedback.ID, chatID, err)
+			continue
+		}
+		if original == nil {
+			continue
+		}
+		forward := tgbotapi.NewForward(chatID, original.Chat.ID, original.MessageID)
+		if _, err := b.bot.Send(forward); err != nil {
+			log.Printf("Error forwarding the context of feedback %d to chat %d: %v", feedback.ID, chatID, err)
+			b.sendText(chatID, "Context:\n\n"+feedback.Context, sent.MessageID)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/imaging"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sniffLength is how many bytes are downloaded to detect a file's type from its magic bytes
+const sniffLength = 512
+
+// unsupportedTypeGuidance tells users what to do with files of types the bot can't read
+var unsupportedTypeGuidance = map[string]string{
+	"image/heic":    "HEIC photos aren't supported. Send the picture as a photo instead of a file so Telegram converts it, or convert it to JPEG first.",
+	"image/heif":    "HEIF photos aren't supported. Send the picture as a photo instead of a file so Telegram converts it, or convert it to JPEG first.",
+	"image/avif":    "AVIF images aren't supported. Send the picture as a photo instead of a file, or convert it to JPEG or PNG first.",
+	"image/tiff":    "TIFF images aren't supported. Please convert the image to JPEG or PNG, or send it as a photo.",
+	"image/bmp":     "BMP images aren't supported. Please convert the image to JPEG or PNG, or send it as a photo.",
+	"image/svg+xml": "SVG images aren't supported. Please send a screenshot of the image instead.",
+}
+
+// isImageMIME checks if a MIME type is one of the accepted image types
+func (b *Bot) isImageMIME(mimeType string) bool {
+	return containsString(b.cfg.SupportedImageTypes, mimeType)
+}
+
+// documentMIME returns the MIME type of a document, detecting it from the file's first bytes
+// when Telegram doesn't know it (clients send no or a generic type for unknown extensions)
+func (b *Bot) documentMIME(ctx context.Context, document *tgbotapi.Document) string {
+	mimeType := normalizeMIME(document.MimeType)
+	if mimeType != "" && mimeType != "application/octet-stream" {
+		return mimeType
+	}
+
+	fileURL, err := b.bot.GetFileDirectURL(document.FileID)
+	if err != nil {
+		log.Printf("Error getting document URL for type detection: %v", err)
+		return mimeType
+	}
+	body, err := b.downloader.Open(ctx, fileURL)
+	if err != nil {
+		log.Printf("Error downloading document for type detection: %v", err)
+		return mimeType
+	}
+	defer body.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		log.Printf("Error reading document for type detection: %v", err)
+		return mimeType
+	}
+
+	detected := imaging.Sniff(head[:n])
+	log.Printf("Detected document type %s (Telegram reported %q)", detected, document.MimeType)
+	return detected
+}
+
+// unsupportedFileError explains why a file of the given type can't be used and what to do instead
+func (b *Bot) unsupportedFileError(mimeType string) error {
+	if guidance, ok := unsupportedTypeGuidance[mimeType]; ok {
+		return fmt.Errorf("%s", guidance)
+	}
+	if imaging.IsSupported(mimeType) {
+		// A format the vision API accepts, but this deployment has turned off
+		return fmt.Errorf("%s images aren't accepted here. Please convert the image to %s, or send it as a photo",
+			imageTypeName(mimeType), b.acceptedImageTypeNames())
+	}
+	if strings.HasPrefix(mimeType, "video/") {
+		return fmt.Errorf("please send videos as a video rather than a file")
+	}
+	return fmt.Errorf("unsupported file type %s. Send me a photo or screenshot, a .txt, .docx, .pdf, .eml or .ics file, or a video", mimeType)
+}
+
+// acceptedImageTypeNames lists the accepted image formats for messages, e.g. "JPEG or PNG"
+func (b *Bot) acceptedImageTypeNames() string {
+	var names []string
+	for _, mimeType := range b.cfg.SupportedImageTypes {
+		names = append(names, imageTypeName(mimeType))
+	}
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// imageTypeName returns the short name of an image MIME type, e.g. "JPEG" for image/jpeg
+func imageTypeName(mimeType string) string {
+	return strings.ToUpper(strings.TrimPrefix(mimeType, "image/"))
+}
+
+// normalizeMIME lowercases a MIME type and drops its parameters
+func normalizeMIME(mimeType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+}
@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// floodRetries is how often a request is sent again after Telegram asks the bot to slow down
+	floodRetries = 3
+	// floodMaxWait is the longest wait a request is retried after; longer ones fail right away
+	floodMaxWait = time.Minute
+)
+
+// floodClient retries requests that fail with "Too Many Requests: retry after N", waiting as
+// long as Telegram asks, so replies such as the user's ICS file aren't dropped when the bot is
+// sending too fast
+type floodClient struct {
+	next tgbotapi.HTTPClient
+}
+
+// newFloodClient wraps the HTTP client the bot sends its requests with
+func newFloodClient(next tgbotapi.HTTPClient) *floodClient {
+	return &floodClient{next: next}
+}
+
+// Do sends a request to the Bot API, retrying it while Telegram limits the bot
+func (c *floodClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	if method == "getUpdates" || req.Body == nil {
+		// Polling retries by itself, and requests without a body can't be replayed here
+		return c.next.Do(req)
+	}
+
+	// Keep the body, so the request can be sent again
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+
+		resp, err := c.next.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= floodRetries {
+			return resp, err
+		}
+
+		wait, err := retryAfter(resp)
+		if err != nil || wait <= 0 || wait > floodMaxWait {
+			return resp, nil // The library reports the error
+		}
+		log.Printf("Telegram limits %s, retrying in %s (attempt %d of %d)", method, wait, attempt+1, floodRetries)
+
+		select {
+		case <-req.Context().Done():
+			return resp, nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryAfter reads how long Telegram asks to wait from a rate limited response. The body is
+// consumed and put back, so the response can still be returned.
+func retryAfter(resp *http.Response) (time.Duration, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Parameters *tgbotapi.ResponseParameters `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Parameters == nil {
+		return 0, err
+	}
+	return time.Duration(result.Parameters.RetryAfter) * time.Second, nil
+}
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTransport answers Bot API requests with canned responses, recording the requests
+type fakeTransport struct {
+	responses []fakeResponse // Answers in order; the last one repeats
+	requests  []*http.Request
+	bodies    []string
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	f.requests = append(f.requests, req)
+	f.bodies = append(f.bodies, string(body))
+
+	answer := f.responses[min(len(f.requests), len(f.responses))-1]
+	return &http.Response{
+		StatusCode: answer.status,
+		Body:       io.NopCloser(strings.NewReader(answer.body)),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+	}, nil
+}
+
+// botRequest builds a form-encoded request of a Bot API method
+func botRequest(ctx context.Context, method, body string) *http.Request {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.telegram.org/botTOKEN/"+method, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+const (
+	okResponse        = `{"ok":true,"result":true}`
+	floodResponse     = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`
+	longFloodResponse = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 120","parameters":{"retry_after":120}}`
+)
+
+func TestFloodClient(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		method     string
+		responses  []fakeResponse
+		wantStatus int
+		wantCalls  int
+	}{
+		{"success", context.Background(), "sendMessage", []fakeResponse{{200, okResponse}}, 200, 1},
+		{"retried after the wait", context.Background(), "sendMessage", []fakeResponse{{429, floodResponse}, {200, okResponse}}, 200, 2},
+		{"wait too long", context.Background(), "sendMessage", []fakeResponse{{429, longFloodResponse}}, 429, 1},
+		{"no wait given", context.Background(), "sendMessage", []fakeResponse{{429, `{"ok":false}`}}, 429, 1},
+		{"polling retries by itself", context.Background(), "getUpdates", []fakeResponse{{429, floodResponse}}, 429, 1},
+		{"cancelled while waiting", cancelled, "sendMessage", []fakeResponse{{429, floodResponse}}, 429, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &fakeTransport{responses: tt.responses}
+			client := newFloodClient(transport)
+
+			resp, err := client.Do(botRequest(tt.ctx, tt.method, "chat_id=1&text=hello"))
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || len(transport.requests) != tt.wantCalls {
+				t.Errorf("Do() = %d after %d requests, want %d after %d", resp.StatusCode, len(transport.requests), tt.wantStatus, tt.wantCalls)
+			}
+			// The library still reads the error from the body of a response that isn't retried
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.responses[len(tt.responses)-1].body {
+				t.Errorf("response body = %q, want %q", body, tt.responses[len(tt.responses)-1].body)
+			}
+			for i, body := range transport.bodies {
+				if tt.method != "getUpdates" && body != "chat_id=1&text=hello" {
+					t.Errorf("request %d had the body %q, want the original", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want time.Duration
+	}{
+		{"retry after", floodResponse, time.Second},
+		{"long retry after", longFloodResponse, 2 * time.Minute},
+		{"no parameters", `{"ok":false,"error_code":429}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Body: io.NopCloser(strings.NewReader(tt.body))}
+			got, err := retryAfter(resp)
+			if err != nil || got != tt.want {
+				t.Errorf("retryAfter() = %v, %v, want %v, nil", got, err, tt.want)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.body {
+				t.Errorf("body after retryAfter() = %q, want it unchanged", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// callbackFollowUp names the follow-up buttons, whose callback data is "followup:<historyID>:<answer>"
+const callbackFollowUp = "followup"
+
+// followUpAnswers are the buttons of the follow-up, in display order
+var followUpAnswers = []struct {
+	accuracy string
+	label    string // Catalog key of the button's label
+}{
+	{storage.AccuracyCorrect, "followup.answer.correct"},
+	{storage.AccuracyWrongTime, "followup.answer.wrong_time"},
+	{storage.AccuracyWrongLocation, "followup.answer.wrong_location"},
+	{storage.AccuracyWrongDetails, "followup.answer.wrong_details"},
+}
+
+// handleFollowUp shows or changes whether a user is asked how accurate each event was once
+// it's over
+func (b *Bot) handleFollowUp(ctx context.Context, message *tgbotapi.Message) {
+	b.handleToggle(message, toggleSetting{
+		key:  "followup",
+		name: "follow-ups",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.FollowUp },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.FollowUp = enabled },
+	})
+}
+
+// scheduleFollowUp schedules a message to the user's private chat asking whether an event was
+// accurate, shortly after it ends, if they asked for follow-ups with /followup
+func (b *Bot) scheduleFollowUp(entry storage.HistoryEntry, event *openai.Event, loc *time.Location) {
+	if b.cfg.FollowUpDelay <= 0 || entry.ID == 0 {
+		return
+	}
+	userID, err := strconv.ParseInt(entry.UserID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	// Event times are wall-clock times in the user's timezone
+	end := event.EndTime
+	if end.IsZero() || end.Before(event.StartTime) {
+		end = event.StartTime
+	}
+	endsAt := time.Date(end.Year(), end.Month(), end.Day(), end.Hour(), end.Minute(), end.Second(), 0, loc)
+	if calendar.IsAllDay(event) {
+		// All-day events end at the end of their last day
+		last := calendar.LastDay(event)
+		endsAt = time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	sendAt := endsAt.Add(b.cfg.FollowUpDelay)
+	if sendAt.Before(time.Now()) {
+		return // The event is already over, e.g. an old announcement
+	}
+
+	scheduled, err := b.store.AddScheduled(storage.ScheduledMessage{
+		Bot:       b.cfg.BotName,
+		UserID:    entry.UserID,
+		ChatID:    userID, // Asked privately, not in the group the event came from
+		Kind:      storage.ScheduledFollowUp,
+		Text:      event.Title,
+		HistoryID: entry.ID,
+		Automatic: true,
+		SendAt:    sendAt,
+	})
+	if err != nil {
+		log.Printf("Error scheduling follow-up for history entry %d: %v", entry.ID, err)
+		return
+	}
+	log.Printf("Scheduled follow-up #%d for history entry %d at %s", scheduled.ID, entry.ID, sendAt.Format(time.RFC3339))
+}
+
+// followUpMessage builds the follow-up question with its answer buttons
+func (b *Bot) followUpMessage(user *tgbotapi.User, msg storage.ScheduledMessage) tgbotapi.MessageConfig {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(followUpAnswers); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, answer := range followUpAnswers[i:min(i+2, len(followUpAnswers))] {
+			data := callbackData(callbackFollowUp, strconv.FormatInt(msg.HistoryID, 10), answer.accuracy)
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.t(user, answer.label), data))
+		}
+		rows = append(rows, row)
+	}
+
+	reply := tgbotapi.NewMessage(msg.ChatID, b.t(user, "followup.question", msg.Text))
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return reply
+}
+
+// handleFollowUpAnswer records the answer to a follow-up
+func (b *Bot) handleFollowUpAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) != 2 {
+		return
+	}
+	historyID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	label := ""
+	for _, answer := range followUpAnswers {
+		if answer.accuracy == args[1] {
+			label = b.t(query.From, answer.label)
+		}
+	}
+	if label == "" {
+		return
+	}
+
+	found, err := b.store.SetHistoryAccuracy(historyID, fmt.Sprintf("%d", query.From.ID), args[1])
+	if err != nil {
+		log.Printf("Error saving follow-up answer for history entry %d: %v", historyID, err)
+		b.answerCallback(query, b.t(query.From, "followup.save_failed"))
+		return
+	}
+	if !found {
+		b.answerCallback(query, b.t(query.From, "followup.not_found"))
+		return
+	}
+	log.Printf("User %d rated history entry %d as %s", query.From.ID, historyID, args[1])
+
+	b.answerCallback(query, b.t(query.From, "followup.thanks"))
+
+	// Replace the buttons with the chosen answer so it can't be answered twice
+	if query.Message != nil {
+		edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID,
+			query.Message.Text+"\n\n"+b.t(query.From, "followup.answered", label))
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error updating follow-up message: %v", err)
+		}
+	}
+}
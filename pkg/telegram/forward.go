@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// forwardContext describes when and where a forwarded message was originally posted, so that
+// phrases like "this Saturday" in an old forward resolve against the post date rather than
+// today. It's empty for messages that weren't forwarded.
+func (b *Bot) forwardContext(message *tgbotapi.Message, timezone string) string {
+	if message.ForwardDate == 0 {
+		return ""
+	}
+
+	loc, _ := b.timezones.Resolve(timezone)
+	posted := time.Unix(int64(message.ForwardDate), 0).In(loc)
+
+	var source string
+	switch {
+	case message.ForwardFromChat != nil && message.ForwardFromChat.Title != "":
+		source = fmt.Sprintf(" in %q", message.ForwardFromChat.Title)
+	case message.ForwardFrom != nil:
+		source = " by " + strings.TrimSpace(message.ForwardFrom.FirstName+" "+message.ForwardFrom.LastName)
+	case message.ForwardSenderName != "":
+		source = " by " + message.ForwardSenderName
+	}
+
+	return fmt.Sprintf("This message was forwarded. It was originally posted%s on %s, so resolve relative dates like \"tomorrow\" or \"this Saturday\" from that date, not from today.",
+		source, posted.Format("Monday, 2 January 2006 15:04"))
+}
+
+// withContext appends more context for an extraction to what's already there
+func withContext(existing, more string) string {
+	if more == "" {
+		return existing
+	}
+	return strings.TrimSpace(existing + "\n\n" + more)
+}
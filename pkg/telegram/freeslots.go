@@ -0,0 +1,267 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"calendar-assistant/pkg/openai"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Free times offered for an event whose source names a period but no time
+const (
+	maxFreeSlots = 3                // Times offered, each on another day
+	freeSlotStep = 30 * time.Minute // Offered times start on the hour or half hour
+	freeSlotTTL  = previewTTL       // How long one of the times can be picked
+)
+
+// Default working hours, within which free times are found if WORKING_HOURS can't be read
+const (
+	defaultWorkStart = 9 * time.Hour
+	defaultWorkEnd   = 18 * time.Hour
+)
+
+// Callback data of the free time buttons: "slot:pick:<offer message ID>:<index>" or
+// "slot:cancel:<offer message ID>"
+const (
+	callbackFreeSlot = "slot"
+	freeSlotPick     = "pick"
+	freeSlotCancel   = "cancel"
+)
+
+// timeSlot is a free time, as wall-clock times in the user's timezone like event times
+type timeSlot struct {
+	start time.Time
+	end   time.Time
+}
+
+// slotOffer is an event waiting for the user to pick one of the free times offered for it
+type slotOffer struct {
+	extracted extractedEvent
+	slots     []timeSlot
+}
+
+// offerFreeSlots offers free times in the user's stored events for an event that names a
+// period but no time, as buttons that continue with the event at the time picked
+func (b *Bot) offerFreeSlots(userID string, extracted extractedEvent) {
+	message, event := extracted.message, extracted.event
+	chatID := message.Chat.ID
+
+	slots := b.freeSlots(userID, event, wallClockNow(b.userNow(message.From)))
+	if len(slots) == 0 {
+		b.sendText(chatID, b.t(message.From, "slots.none", event.Title), message.MessageID)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "slots.offer", event.Title))
+	msg.ReplyToMessageID = message.MessageID
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending free times: %v", err)
+		return
+	}
+
+	// The buttons name the offer's own message, like preview buttons
+	id := strconv.Itoa(sent.MessageID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, slot := range slots {
+		label := slot.start.Format("Mon 2 Jan, 15:04") + "–" + slot.end.Format("15:04")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackFreeSlot, freeSlotPick, id, strconv.Itoa(i))),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(message.From, "slots.cancel"), callbackData(callbackFreeSlot, freeSlotCancel, id)),
+	))
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, sent.MessageID, tgbotapi.NewInlineKeyboardMarkup(rows...))
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error adding free time buttons: %v", err)
+	}
+
+	b.slotOffers.Set(previewKey(chatID, sent.MessageID), &slotOffer{extracted: extracted, slots: slots})
+	log.Printf("Offered %d free times for %q to user %s", len(slots), event.Title, userID)
+}
+
+// handleFreeSlotAnswer handles a press on one of the free time buttons
+func (b *Bot) handleFreeSlotAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) < 2 || query.Message == nil {
+		return
+	}
+	messageID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	key := previewKey(query.Message.Chat.ID, messageID)
+	offer, ok := b.slotOffers.Get(key)
+	if !ok {
+		b.answerCallback(query, b.t(query.From, "slots.expired"))
+		return
+	}
+	original := offer.extracted.message
+	if original.From.ID != query.From.ID {
+		b.answerCallback(query, b.t(query.From, "slots.not_yours"))
+		return
+	}
+
+	var slot timeSlot
+	if args[0] == freeSlotPick {
+		i, err := strconv.Atoi(args[len(args)-1])
+		if err != nil || len(args) != 3 || i < 0 || i >= len(offer.slots) {
+			return
+		}
+		slot = offer.slots[i]
+	}
+	b.slotOffers.Delete(key)
+	b.answerCallback(query, "")
+
+	// Remove the buttons so another time can't be picked as well
+	edit := tgbotapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, messageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error removing free time buttons: %v", err)
+	}
+
+	if args[0] != freeSlotPick {
+		b.sendText(original.Chat.ID, b.t(query.From, "slots.cancelled"), original.MessageID)
+		return
+	}
+
+	// From here on the event is handled like one with the time in the source
+	userID := fmt.Sprintf("%d", original.From.ID)
+	extracted := offer.extracted
+	extracted.event = scheduledAt(extracted.event, slot)
+	log.Printf("User %s picked %s for %q", userID, slot.start.Format(time.RFC3339), extracted.event.Title)
+
+	prefs := b.eventPreferences(userID, original.Chat.ID)
+	switch {
+	case prefs.ReadBack:
+		b.requestReadBackConfirmation(userID, extracted)
+	case prefs.SkipPreview:
+		b.deliverEvent(userID, extracted)
+	default:
+		b.sendPreview(userID, extracted)
+	}
+}
+
+// pinToFreeSlot gives a flexible event its first free time, or the start of the working
+// hours of its period if there's none, where there's no one to offer times to
+func (b *Bot) pinToFreeSlot(user *tgbotapi.User, event *openai.Event) {
+	if !event.Flexible {
+		return
+	}
+	slot := timeSlot{start: event.StartTime, end: event.StartTime.Add(b.flexibleDuration(event))}
+	if slots := b.freeSlots(fmt.Sprintf("%d", user.ID), event, wallClockNow(b.userNow(user))); len(slots) > 0 {
+		slot = slots[0]
+	}
+	*event = *scheduledAt(event, slot)
+}
+
+// scheduledAt returns a flexible event at a time in its period
+func scheduledAt(event *openai.Event, slot timeSlot) *openai.Event {
+	scheduled := *event
+	scheduled.StartTime, scheduled.EndTime = slot.start, slot.end
+	scheduled.Flexible = false
+	scheduled.Duration = 0
+	return &scheduled
+}
+
+// freeSlots finds up to maxFreeSlots free times for a flexible event in the working hours of
+// its period, each on another day and not before now. Working days come first; weekends are
+// only used if they're all the period has room in.
+func (b *Bot) freeSlots(userID string, event *openai.Event, now time.Time) []timeSlot {
+	var busy []*openai.Event
+	for _, stored := range b.storedEvents(userID) {
+		if blocksTime(stored) {
+			busy = append(busy, stored)
+		}
+	}
+	duration := b.flexibleDuration(event)
+	workStart, workEnd := workingHours(b.cfg.WorkingHours)
+
+	for _, weekends := range []bool{false, true} {
+		var slots []timeSlot
+		day := time.Date(event.StartTime.Year(), event.StartTime.Month(), event.StartTime.Day(), 0, 0, 0, 0, time.UTC)
+		for ; day.Before(event.EndTime) && len(slots) < maxFreeSlots; day = day.AddDate(0, 0, 1) {
+			if !weekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+				continue
+			}
+
+			from, to := day.Add(workStart), day.Add(workEnd)
+			for _, limit := range []time.Time{event.StartTime, now} {
+				if limit.After(from) {
+					from = limit
+				}
+			}
+			if event.EndTime.Before(to) {
+				to = event.EndTime
+			}
+
+			for start := roundUp(from, freeSlotStep); !start.Add(duration).After(to); start = start.Add(freeSlotStep) {
+				if !overlapsAny(busy, start, start.Add(duration)) {
+					slots = append(slots, timeSlot{start: start, end: start.Add(duration)})
+					break
+				}
+			}
+		}
+		if len(slots) > 0 {
+			return slots
+		}
+	}
+	return nil
+}
+
+// flexibleDuration returns how long a flexible event takes, the default event duration if
+// the source doesn't say
+func (b *Bot) flexibleDuration(event *openai.Event) time.Duration {
+	if event.Duration > 0 {
+		return time.Duration(event.Duration) * time.Minute
+	}
+	if b.cfg.DefaultEventDuration > 0 {
+		return b.cfg.DefaultEventDuration
+	}
+	return time.Hour
+}
+
+// workingHours parses working hours like 09:00-18:00 into the times of day they start and end
+func workingHours(text string) (time.Duration, time.Duration) {
+	m := timeRangePattern.FindStringSubmatch(text)
+	if m == nil {
+		return defaultWorkStart, defaultWorkEnd
+	}
+	start, startErr := planTime(time.Time{}, m[1], m[2])
+	end, endErr := planTime(time.Time{}, m[3], m[4])
+	if startErr != nil || endErr != nil || !end.After(start) {
+		return defaultWorkStart, defaultWorkEnd
+	}
+	midnight := time.Time{}
+	return start.Sub(midnight), end.Sub(midnight)
+}
+
+// overlapsAny reports whether any of events overlaps the time from start to end
+func overlapsAny(events []*openai.Event, start, end time.Time) bool {
+	for _, event := range events {
+		if event.StartTime.Before(end) && start.Before(event.EndTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// roundUp rounds a time up to a multiple of step
+func roundUp(t time.Time, step time.Duration) time.Time {
+	if rounded := t.Truncate(step); rounded.Before(t) {
+		return rounded.Add(step)
+	}
+	return t
+}
+
+// wallClockNow returns the date and time of day of now in the user's timezone as UTC, the
+// form event times are stored in
+func wallClockNow(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, time.UTC)
+}
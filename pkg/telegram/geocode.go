@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/geocode"
+	"calendar-assistant/pkg/openai"
+)
+
+// geocodeTimeout limits how long the lookups of one calendar file may hold it up
+const geocodeTimeout = 15 * time.Second
+
+// geocodeEvents adds the coordinates of their locations to events and normalizes the
+// addresses, so calendar apps can show a map pin and travel times. Events keep their
+// location as it is if geocoding is disabled or finds nothing.
+func (b *Bot) geocodeEvents(events ...*openai.Event) {
+	if b.geocoder == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+
+	for _, event := range events {
+		if event.Geo != nil || !calendar.HasPlace(event) {
+			continue
+		}
+		location := strings.TrimSpace(event.Location)
+
+		place, err := b.geocoder.Geocode(ctx, location)
+		if err != nil {
+			if !errors.Is(err, geocode.ErrNotFound) {
+				log.Printf("Error geocoding location %q: %v", location, err)
+			}
+			continue
+		}
+
+		event.Geo = &openai.Geo{Lat: place.Lat, Lon: place.Lon}
+		event.Location = normalizedLocation(location, place.Address)
+	}
+}
+
+// normalizedLocation returns the address found for a location, keeping the location's own
+// wording in front if the address doesn't contain it, e.g. the name of a venue
+func normalizedLocation(location, address string) string {
+	switch {
+	case address == "":
+		return location
+	case strings.Contains(strings.ToLower(address), strings.ToLower(location)):
+		return address
+	default:
+		return location + ", " + address
+	}
+}
@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// groupChatMiddleware keeps the bot quiet in group chats unless it's addressed: a message is
+// only handled as an event when it mentions the bot or replies to one of its messages, and
+// commands meant for other bots are ignored. The mention is removed before extraction.
+func (b *Bot) groupChatMiddleware(r route, next commandHandler) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message) {
+		if message.Chat.IsPrivate() || message.Chat.IsChannel() {
+			next(ctx, message)
+			return
+		}
+
+		if message.IsCommand() {
+			// "/help@other_bot" is for another bot in the same group
+			if _, addressee, ok := strings.Cut(message.CommandWithAt(), "@"); ok && !strings.EqualFold(addressee, b.bot.Self.UserName) {
+				return
+			}
+			if r.name != "default" {
+				next(ctx, message)
+				return
+			}
+		}
+
+		if !b.isAddressed(message) && !b.awaitingMessage(message) {
+			return
+		}
+		log.Printf("Bot addressed in group chat %d by user %d", message.Chat.ID, message.From.ID)
+
+		b.stripMention(message)
+		if message.Text == "" && message.Caption == "" && message.Photo == nil && message.Document == nil &&
+			message.Voice == nil && message.Audio == nil && message.Video == nil && message.VideoNote == nil {
+			b.sendText(message.Chat.ID, b.t(message.From, "group.mention_hint"), message.MessageID)
+			return
+		}
+		next(ctx, message)
+	}
+}
+
+// isAddressed reports whether a group message mentions the bot or replies to one of its messages
+func (b *Bot) isAddressed(message *tgbotapi.Message) bool {
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == b.bot.Self.ID {
+		return true
+	}
+
+	text, entities := message.Text, message.Entities
+	if text == "" {
+		text, entities = message.Caption, message.CaptionEntities
+	}
+	for _, entity := range entities {
+		switch entity.Type {
+		case "mention":
+			// Offsets count UTF-16 code units
+			units := utf16.Encode([]rune(text))
+			if entity.Offset+entity.Length <= len(units) &&
+				strings.EqualFold(string(utf16.Decode(units[entity.Offset:entity.Offset+entity.Length])), "@"+b.bot.Self.UserName) {
+				return true
+			}
+		case "text_mention":
+			if entity.User != nil && entity.User.ID == b.bot.Self.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// awaitingMessage reports whether the bot is waiting for more messages from the sender in this
+// chat, e.g. posts for a batch or a yes or no to an event read back, which needn't mention it
+func (b *Bot) awaitingMessage(message *tgbotapi.Message) bool {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	if session, ok := b.batches.Get(userID); ok && session.chatID == message.Chat.ID {
+		return true
+	}
+	if pending, ok := b.readBacks.Get(userID); ok && pending.message.Chat.ID == message.Chat.ID {
+		return true
+	}
+	if chatID, ok := b.timezoneSearches.Get(userID); ok && chatID == message.Chat.ID {
+		return true
+	}
+	return false
+}
+
+// stripMention removes mentions of the bot from a message's text or caption, so they don't end
+// up in the event
+func (b *Bot) stripMention(message *tgbotapi.Message) {
+	mention := regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(b.bot.Self.UserName) + `\b`)
+	message.Text, message.Entities = removeMatches(message.Text, message.Entities, mention)
+	message.Caption, message.CaptionEntities = removeMatches(message.Caption, message.CaptionEntities, mention)
+}
+
+// removeMatches removes the matches of pattern from text, trimming the spaces around what's
+// left, and moves the entities along so they still cover the same text. Entities that only
+// covered removed text are dropped.
+func removeMatches(text string, entities []tgbotapi.MessageEntity, pattern *regexp.Regexp) (string, []tgbotapi.MessageEntity) {
+	matches := pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 && strings.TrimSpace(text) == text {
+		return text, entities
+	}
+
+	// Offsets count UTF-16 code units, so the removal is done on those
+	units := utf16.Encode([]rune(text))
+	removed := make([]bool, len(units))
+	for _, match := range matches {
+		start, end := utf16Len(text[:match[0]]), utf16Len(text[:match[1]])
+		for i := start; i < end; i++ {
+			removed[i] = true
+		}
+	}
+	for i := 0; i < len(units) && (removed[i] || unicode.IsSpace(rune(units[i]))); i++ {
+		removed[i] = true
+	}
+	for i := len(units) - 1; i >= 0 && (removed[i] || unicode.IsSpace(rune(units[i]))); i-- {
+		removed[i] = true
+	}
+
+	// kept[i] is where the unit at i ends up, counting the units kept before it
+	kept := make([]int, len(units)+1)
+	var result []uint16
+	for i, unit := range units {
+		kept[i] = len(result)
+		if !removed[i] {
+			result = append(result, unit)
+		}
+	}
+	kept[len(units)] = len(result)
+
+	var moved []tgbotapi.MessageEntity
+	for _, entity := range entities {
+		start := kept[min(max(entity.Offset, 0), len(units))]
+		end := kept[min(max(entity.Offset+entity.Length, 0), len(units))]
+		if end <= start {
+			continue
+		}
+		entity.Offset, entity.Length = start, end-start
+		moved = append(moved, entity)
+	}
+	return string(utf16.Decode(result)), moved
+}
+
+// utf16Len returns the length of text in UTF-16 code units
+func utf16Len(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
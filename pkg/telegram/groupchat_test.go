@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"regexp"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRemoveMatches(t *testing.T) {
+	mention := regexp.MustCompile(`(?i)@calendar_bot\b`)
+	entity := func(kind string, offset, length int) tgbotapi.MessageEntity {
+		return tgbotapi.MessageEntity{Type: kind, Offset: offset, Length: length}
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		entities     []tgbotapi.MessageEntity
+		wantText     string
+		wantEntities []tgbotapi.MessageEntity
+	}{
+		{
+			"mention first",
+			"@calendar_bot meet at https://example.com",
+			[]tgbotapi.MessageEntity{entity("mention", 0, 13), entity("url", 22, 19)},
+			"meet at https://example.com",
+			[]tgbotapi.MessageEntity{entity("url", 8, 19)},
+		},
+		{
+			"mention in the middle",
+			"Lunch @Calendar_Bot on Friday",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 5), entity("mention", 6, 13), entity("italic", 23, 6)},
+			"Lunch  on Friday",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 5), entity("italic", 10, 6)},
+		},
+		{
+			"entity around the mention",
+			"Party @calendar_bot tonight",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 27)},
+			"Party  tonight",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 14)},
+		},
+		{
+			// The emoji takes two UTF-16 code units
+			"characters outside the BMP",
+			"🎉 @calendar_bot party at https://example.com",
+			[]tgbotapi.MessageEntity{entity("mention", 3, 13), entity("url", 26, 19)},
+			"🎉  party at https://example.com",
+			[]tgbotapi.MessageEntity{entity("url", 13, 19)},
+		},
+		{
+			"only the mention",
+			"  @calendar_bot ",
+			[]tgbotapi.MessageEntity{entity("mention", 2, 13)},
+			"",
+			nil,
+		},
+		{
+			"no mention",
+			"Dinner at 8",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 6)},
+			"Dinner at 8",
+			[]tgbotapi.MessageEntity{entity("bold", 0, 6)},
+		},
+		{
+			"another bot",
+			"@calendar_bot_two dinner",
+			[]tgbotapi.MessageEntity{entity("mention", 0, 17)},
+			"@calendar_bot_two dinner",
+			[]tgbotapi.MessageEntity{entity("mention", 0, 17)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities := removeMatches(tt.text, tt.entities, mention)
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if len(entities) != len(tt.wantEntities) {
+				t.Fatalf("entities = %+v, want %+v", entities, tt.wantEntities)
+			}
+			for i := range entities {
+				if entities[i] != tt.wantEntities[i] {
+					t.Errorf("entity %d = %+v, want %+v", i, entities[i], tt.wantEntities[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// GroupAction is an operation on shared events that can be restricted in a group
+type GroupAction string
+
+const (
+	ActionCreate GroupAction = "create"
+	ActionEdit   GroupAction = "edit"
+	ActionCancel GroupAction = "cancel"
+
+	// ActionManage covers changing group settings and is always limited to group admins
+	ActionManage GroupAction = "manage"
+)
+
+// configurableActions lists the actions whose role can be changed with /grouprole
+var configurableActions = []GroupAction{ActionCreate, ActionEdit, ActionCancel}
+
+// GroupRole defines who may perform an action in a group
+type GroupRole string
+
+const (
+	RoleAnyone    GroupRole = "anyone"
+	RoleAdmins    GroupRole = "admins"
+	RoleAllowlist GroupRole = "allowlist"
+)
+
+// groupRole returns the role required for an action in a group
+func (b *Bot) groupRole(chatID int64, action GroupAction) GroupRole {
+	if action == ActionManage {
+		return RoleAdmins
+	}
+
+	// Everyone may work with events until an admin restricts it
+	if role, ok := b.store.GroupPermissions(chatID).Roles[string(action)]; ok {
+		return GroupRole(role)
+	}
+	return RoleAnyone
+}
+
+// isGroupAdmin checks whether a user is an administrator or the creator of a chat
+func (b *Bot) isGroupAdmin(chatID, userID int64) bool {
+	member, err := b.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		log.Printf("Error getting chat member %d in chat %d: %v", userID, chatID, err)
+		return false
+	}
+
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// canPerformGroupAction checks whether a user may perform an action in a group
+func (b *Bot) canPerformGroupAction(chatID, userID int64, action GroupAction) bool {
+	switch b.groupRole(chatID, action) {
+	case RoleAnyone:
+		return true
+	case RoleAllowlist:
+		if slices.Contains(b.store.GroupPermissions(chatID).Allowlist, userID) {
+			return true
+		}
+		// Admins are always allowed, even if they're not on the allowlist
+		return b.isGroupAdmin(chatID, userID)
+	default:
+		return b.isGroupAdmin(chatID, userID)
+	}
+}
+
+// groupPermissionMiddleware enforces group roles for routes that touch shared events
+func (b *Bot) groupPermissionMiddleware(r route, next commandHandler) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message) {
+		if r.action == "" || message.Chat.IsPrivate() || message.Chat.IsChannel() {
+			next(ctx, message)
+			return
+		}
+
+		if message.From == nil || !b.canPerformGroupAction(message.Chat.ID, message.From.ID, r.action) {
+			log.Printf("Denied %s (%s) in chat %d", r.name, r.action, message.Chat.ID)
+			text := b.t(message.From, "group.manage_denied")
+			if r.action != ActionManage {
+				text = b.t(message.From, "group.action_denied", b.t(message.From, "group.action."+string(r.action)))
+			}
+			msg := tgbotapi.NewMessage(message.Chat.ID, text)
+			msg.ReplyToMessageID = message.MessageID
+			if _, err := b.bot.Send(msg); err != nil {
+				log.Printf("Error sending permission denied message: %v", err)
+			}
+			return
+		}
+
+		next(ctx, message)
+	}
+}
+
+// handleGroupRole shows or changes the roles for group actions
+func (b *Bot) handleGroupRole(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	if message.Chat.IsPrivate() {
+		b.sendError(message, "group.groups_only", nil)
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		b.sendGroupRoles(message.From, chatID, messageID)
+		return
+	}
+
+	if len(args) != 2 {
+		b.sendError(message, "group.role_usage", nil)
+		return
+	}
+
+	action, ok := parseGroupAction(args[0])
+	if !ok {
+		b.sendError(message, "group.unknown_action", nil, args[0])
+		return
+	}
+	role, ok := parseGroupRole(args[1])
+	if !ok {
+		b.sendError(message, "group.unknown_role", nil, args[1])
+		return
+	}
+
+	perms := b.store.GroupPermissions(chatID)
+	perms.Roles[string(action)] = string(role)
+	if err := b.store.SaveGroupPermissions(chatID, perms); err != nil {
+		log.Printf("Error saving group permissions of chat %d: %v", chatID, err)
+		b.sendError(message, "group.role_save_failed", err)
+		return
+	}
+	log.Printf("Set role for %s in chat %d to %s", action, chatID, role)
+
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, "group.role_set",
+		b.t(message.From, "group.action."+string(action)), b.describeGroupRole(message.From, role)))
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending group role confirmation: %v", err)
+	}
+}
+
+// handleGroupAllow adds a user to the group allowlist
+func (b *Bot) handleGroupAllow(ctx context.Context, message *tgbotapi.Message) {
+	b.updateGroupAllowlist(message, true)
+}
+
+// handleGroupDisallow removes a user from the group allowlist
+func (b *Bot) handleGroupDisallow(ctx context.Context, message *tgbotapi.Message) {
+	b.updateGroupAllowlist(message, false)
+}
+
+// updateGroupAllowlist adds or removes the target user of a message from the allowlist
+func (b *Bot) updateGroupAllowlist(message *tgbotapi.Message, allow bool) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	if message.Chat.IsPrivate() {
+		b.sendError(message, "group.groups_only", nil)
+		return
+	}
+
+	targetID, err := allowlistTarget(message)
+	if errors.Is(err, errNoAllowlistTarget) {
+		b.sendError(message, "group.allowlist_usage", nil)
+		return
+	}
+	if err != nil {
+		b.sendError(message, "group.invalid_user_id", nil, strings.TrimSpace(message.CommandArguments()))
+		return
+	}
+
+	perms := b.store.GroupPermissions(chatID)
+	perms.Allowlist = slices.DeleteFunc(perms.Allowlist, func(userID int64) bool { return userID == targetID })
+	if allow {
+		perms.Allowlist = append(perms.Allowlist, targetID)
+	}
+	if err := b.store.SaveGroupPermissions(chatID, perms); err != nil {
+		log.Printf("Error saving group permissions of chat %d: %v", chatID, err)
+		b.sendError(message, "group.allowlist_save_failed", err)
+		return
+	}
+	log.Printf("Chat %d: set allowlisting of user %d to %t", chatID, targetID, allow)
+
+	key := "group.disallowed"
+	if allow {
+		key = "group.allowed"
+	}
+	msg := tgbotapi.NewMessage(chatID, b.t(message.From, key, targetID))
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending allowlist confirmation: %v", err)
+	}
+}
+
+// errNoAllowlistTarget means an allowlist command neither replies to a member nor names one
+var errNoAllowlistTarget = errors.New("no member to allowlist")
+
+// allowlistTarget finds the user an allowlist command refers to, either by reply or by ID
+func allowlistTarget(message *tgbotapi.Message) (int64, error) {
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil {
+		return message.ReplyToMessage.From.ID, nil
+	}
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		return 0, errNoAllowlistTarget
+	}
+
+	userID, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user ID: %s", args)
+	}
+	return userID, nil
+}
+
+// sendGroupRoles sends the current roles and allowlist of a group
+func (b *Bot) sendGroupRoles(user *tgbotapi.User, chatID int64, messageID int) {
+	var sb strings.Builder
+	sb.WriteString(b.t(user, "group.roles_header") + "\n")
+	for _, action := range configurableActions {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", b.t(user, "group.action."+string(action)), b.describeGroupRole(user, b.groupRole(chatID, action))))
+	}
+
+	allowed := b.store.GroupPermissions(chatID).Allowlist
+	slices.Sort(allowed)
+
+	if len(allowed) > 0 {
+		sb.WriteString("\n" + b.t(user, "group.allowlist_header") + "\n")
+		for _, userID := range allowed {
+			sb.WriteString(fmt.Sprintf("- %d\n", userID))
+		}
+	}
+
+	sb.WriteString("\n" + b.t(user, "group.roles_help"))
+
+	msg := tgbotapi.NewMessage(chatID, sb.String())
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending group roles: %v", err)
+	}
+}
+
+// parseGroupAction parses a configurable group action name, reporting whether it is one
+func parseGroupAction(s string) (GroupAction, bool) {
+	for _, action := range configurableActions {
+		if strings.EqualFold(s, string(action)) {
+			return action, true
+		}
+	}
+	return "", false
+}
+
+// parseGroupRole parses a group role name, reporting whether it is one
+func parseGroupRole(s string) (GroupRole, bool) {
+	switch role := GroupRole(strings.ToLower(s)); role {
+	case RoleAnyone, RoleAdmins, RoleAllowlist:
+		return role, true
+	}
+	return "", false
+}
+
+// describeGroupRole formats a role for display to the user
+func (b *Bot) describeGroupRole(user *tgbotapi.User, role GroupRole) string {
+	switch role {
+	case RoleAdmins, RoleAllowlist:
+		return b.t(user, "group.role."+string(role))
+	default:
+		return b.t(user, "group.role.anyone")
+	}
+}
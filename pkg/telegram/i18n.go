@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+
+	"calendar-assistant/pkg/i18n"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultLanguage returns the language of the deployment, if there's a catalog for it
+func (b *Bot) defaultLanguage() string {
+	if b.catalog.Has(b.cfg.DefaultLanguage) {
+		return b.cfg.DefaultLanguage
+	}
+	return i18n.DefaultLanguage
+}
+
+// userLanguage returns the language to talk to a user in: the language they chose, otherwise
+// their Telegram app's, otherwise the deployment's
+func (b *Bot) userLanguage(user *tgbotapi.User) string {
+	if user == nil {
+		return b.defaultLanguage()
+	}
+
+	prefs := b.getUserPreferences(fmt.Sprintf("%d", user.ID))
+	if b.languageChosen(prefs) && b.catalog.Has(prefs.Language) {
+		return prefs.Language
+	}
+	if client := i18n.Base(user.LanguageCode); b.catalog.Has(client) {
+		return client
+	}
+	return b.defaultLanguage()
+}
+
+// languageChosen reports whether a user picked their language, including the deployment's.
+// Choices made before that was recorded only stand out when they aren't the default.
+func (b *Bot) languageChosen(prefs *storage.UserPreferences) bool {
+	return prefs.LanguageChosen || (prefs.Language != "" && prefs.Language != b.cfg.DefaultLanguage)
+}
+
+// t translates a message for a user
+func (b *Bot) t(user *tgbotapi.User, key string, args ...any) string {
+	return b.catalog.T(b.userLanguage(user), key, args...)
+}
+
+// sendError replies to a message with a translated error, followed by its cause if there's one
+func (b *Bot) sendError(message *tgbotapi.Message, key string, cause error, args ...any) {
+	b.sendUserError(message.From, message.Chat.ID, message.MessageID, key, cause, args...)
+}
+
+// sendUserError sends a translated error to a user in a chat, in reply to a message unless
+// messageID is 0, followed by its cause if there's one
+func (b *Bot) sendUserError(user *tgbotapi.User, chatID int64, messageID int, key string, cause error, args ...any) {
+	text := b.t(user, key, args...)
+	if cause != nil {
+		text = fmt.Sprintf("%s: %v", text, cause)
+	}
+	log.Printf("Sending error message to chat ID %d: %s", chatID, text)
+	b.sendText(chatID, b.t(user, "error.message", text), messageID)
+}
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"path/filepath"
+	"testing"
+
+	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/i18n"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestUserLanguage(t *testing.T) {
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		prefs      *storage.UserPreferences // Stored preferences, nil for a new user
+		clientLang string
+		want       string
+	}{
+		{"new user", nil, "ru", "ru"},
+		{"new user with an unsupported app language", nil, "de-DE", "en"},
+		{"chose the default language", &storage.UserPreferences{Language: "en", LanguageChosen: true}, "ru", "en"},
+		{"chose another language", &storage.UserPreferences{Language: "ru", LanguageChosen: true}, "en", "ru"},
+		{"chose another language before it was recorded", &storage.UserPreferences{Language: "ru"}, "en", "ru"},
+		{"got the default language", &storage.UserPreferences{Language: "en"}, "ru", "ru"},
+		{"chose a language that's no longer supported", &storage.UserPreferences{Language: "uk", LanguageChosen: true}, "ru", "ru"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.Open(filepath.Join(t.TempDir(), "store.json"))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if tt.prefs != nil {
+				if err := store.SaveUser("1", *tt.prefs); err != nil {
+					t.Fatalf("SaveUser() error = %v", err)
+				}
+			}
+			b := &Bot{cfg: &config.Config{DefaultLanguage: "en"}, catalog: catalog, store: store}
+
+			if got := b.userLanguage(&tgbotapi.User{ID: 1, LanguageCode: tt.clientLang}); got != tt.want {
+				t.Errorf("userLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// deliverImportedEvents records the events of a calendar file the user sent in the history and
+// sends them back as one file in the user's timezone, with the bot's UIDs and reminders
+func (b *Bot) deliverImportedEvents(message *tgbotapi.Message, events []*openai.Event, prefs *storage.UserPreferences, loc *time.Location) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+
+	b.geocodeEvents(events...)
+	var entries []storage.HistoryEntry
+	for _, event := range events {
+		b.versionEvent(userID, nil, event)
+		entry, err := b.store.AddHistory(storage.HistoryEntry{
+			Bot:       b.cfg.BotName,
+			UserID:    userID,
+			ChatID:    chatID,
+			InputType: storage.InputDocument,
+			RawInput:  message.Document.FileID,
+			Event:     event,
+		})
+		if err != nil {
+			log.Printf("Error saving history entry: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	events = b.withWeather(message.From, prefs, events, loc)
+	icsData, err := calendar.GenerateICS(b.withTravel(message.From, prefs, events), loc, b.icsOptions(userID, prefs.ReminderMinutes, true))
+	if err != nil {
+		log.Printf("Error generating ICS file: %v", err)
+		b.sendError(message, "error.ics_generate", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("events_%d.ics", messageID),
+		Bytes: icsData,
+	})
+	doc.Caption = b.t(message.From, "import.caption", len(events), b.formatTimezoneForDisplay(loc.String()))
+	doc.ReplyToMessageID = messageID
+	sent, err := b.bot.Send(doc)
+	if err != nil {
+		log.Printf("Error sending ICS file: %v", err)
+		b.sendError(message, "error.ics_send", err)
+		return
+	}
+	// /delete in reply to the file removes all of its events
+	for _, entry := range entries {
+		if _, err := b.store.SetHistoryMessage(entry.ID, sent.MessageID); err != nil {
+			log.Printf("Error saving the message of history entry %d: %v", entry.ID, err)
+		}
+	}
+}
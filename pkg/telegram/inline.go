@@ -0,0 +1,200 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inlineDebounce is how long an inline query waits for the user to stop typing before the
+// event is extracted, as Telegram sends a new query for almost every keystroke
+const inlineDebounce = 800 * time.Millisecond
+
+// inlineMinLength is the shortest inline query worth extracting an event from
+const inlineMinLength = 8
+
+// sharedEventStartPrefix starts the /start parameter of the links to shared events: "ics_<token>"
+const sharedEventStartPrefix = "ics_"
+
+// debounceInlineQuery handles an inline query once the user has stopped typing for
+// inlineDebounce, dropping the queries superseded meanwhile. The wait happens outside the
+// user's queue, so their other updates, such as button presses, aren't held up by it.
+func (b *Bot) debounceInlineQuery(query *tgbotapi.InlineQuery) {
+	userID := fmt.Sprintf("%d", query.From.ID)
+	b.inlineQueries.Set(userID, query.ID)
+	time.AfterFunc(inlineDebounce, func() {
+		if latest, ok := b.inlineQueries.Get(userID); ok && latest != query.ID {
+			return
+		}
+		b.goHandler(query.From.ID, func(ctx context.Context) { b.handleInlineQuery(ctx, query) })
+	})
+}
+
+// handleInlineQuery extracts an event from an inline query such as "@bot dinner tomorrow 7pm"
+// and offers its summary as a result. The message sent has a button that opens the bot and
+// sends the calendar file, for the sender and anyone else in the chat.
+func (b *Bot) handleInlineQuery(ctx context.Context, query *tgbotapi.InlineQuery) {
+	userID := fmt.Sprintf("%d", query.From.ID)
+	text := strings.TrimSpace(query.Query)
+
+	if !b.isAllowedUser(query.From.ID) {
+		b.answerInlineQuery(query, nil, b.t(query.From, "inline.private"), "start")
+		return
+	}
+	if _, banned := b.store.ActiveBan(userID); banned {
+		b.answerInlineQuery(query, nil, "", "")
+		return
+	}
+	if len([]rune(text)) < inlineMinLength {
+		b.answerInlineQuery(query, nil, "", "")
+		return
+	}
+
+	// The user may have typed on while the query waited behind their other updates
+	if latest, ok := b.inlineQueries.Get(userID); ok && latest != query.ID {
+		return
+	}
+
+	prefs := b.eventPreferences(userID, 0) // Inline queries don't tell which chat they're from
+	if b.needsTimezone(prefs) && !b.cfg.AllowEventsWithoutTimezone {
+		b.answerInlineQuery(query, nil, b.t(query.From, "inline.timezone"), "timezone")
+		return
+	}
+	// Inline queries cost the same as messages, so they count towards the rate limit and the
+	// daily quota and wait for an extraction slot. The reason for a refusal is too long for
+	// the button above the results, which only says to come back later.
+	if !b.allowUserRequest(query.From, func(string) {}) {
+		b.answerInlineQuery(query, nil, b.t(query.From, "inline.limit"), "start")
+		return
+	}
+	release, err := b.extractionSlot(ctx, userID)
+	if err != nil {
+		log.Printf("Error waiting for an extraction slot: %v", err)
+		b.answerInlineQuery(query, nil, "", "")
+		return
+	}
+	defer release()
+
+	opts := eventOptions{}
+	opts.extract.Timezone = b.userTimezone(prefs)
+	opts.extract.Language = prefs.Language
+	event, err := b.extractEventFromText(ctx, userID, text, opts)
+	if err != nil {
+		log.Printf("Error extracting event from inline query of user %s: %v", userID, err)
+		b.answerInlineQuery(query, nil, "I couldn't find an event, try describing it differently", "start")
+		return
+	}
+
+	b.pinToFreeSlot(query.From, event)
+
+	loc, _ := b.timezones.Resolve(b.userTimezone(prefs))
+	token, err := newShareToken()
+	if err != nil {
+		log.Printf("Error creating share token: %v", err)
+		b.answerInlineQuery(query, nil, "", "")
+		return
+	}
+	if err := b.store.AddSharedEvent(storage.SharedEvent{
+		Token:    token,
+		UserID:   userID,
+		Timezone: loc.String(),
+		Event:    event,
+	}); err != nil {
+		log.Printf("Error saving shared event: %v", err)
+		b.answerInlineQuery(query, nil, "", "")
+		return
+	}
+
+	isAllDay := calendar.IsAllDay(event)
+	summary := b.eventCaption(event, isAllDay, b.formatTimezoneForDisplay(loc.String()), prefs.Language)
+
+	article := tgbotapi.NewInlineQueryResultArticleMarkdownV2(token, event.Title, summary)
+	article.Description = event.StartTime.Format("Mon 2 Jan 15:04")
+	if event.Location != "" {
+		article.Description += ", " + event.Location
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonURL("📅 Add to calendar", fmt.Sprintf("https://t.me/%s?start=%s%s", b.bot.Self.UserName, sharedEventStartPrefix, token)),
+	))
+	article.ReplyMarkup = &keyboard
+
+	b.answerInlineQuery(query, []interface{}{article}, "", "")
+}
+
+// answerInlineQuery answers an inline query with results, and optionally a button above them
+// that opens a private chat with the bot with /start <startParameter>
+func (b *Bot) answerInlineQuery(query *tgbotapi.InlineQuery, results []interface{}, buttonText, startParameter string) {
+	if results == nil {
+		results = []interface{}{}
+	}
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID:     query.ID,
+		Results:           results,
+		IsPersonal:        true, // Results depend on the user's timezone
+		SwitchPMText:      buttonText,
+		SwitchPMParameter: startParameter,
+	}
+	if _, err := b.bot.Request(answer); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
+
+// sendSharedEvent sends the calendar file of an event shared through inline mode, for a
+// link such as https://t.me/<bot>?start=ics_<token>
+func (b *Bot) sendSharedEvent(message *tgbotapi.Message, token string) {
+	chatID := message.Chat.ID
+	messageID := message.MessageID
+
+	shared, ok := b.store.SharedEvent(token)
+	if !ok {
+		b.sendText(chatID, b.t(message.From, "shared.not_found"), messageID)
+		return
+	}
+
+	// The times are in the creator's timezone, whoever opens the link, and only reminders the
+	// event asks for itself are added
+	loc, _ := b.timezones.Resolve(shared.Timezone)
+	event := *shared.Event // A copy, as geocoding changes the event
+	b.geocodeEvents(&event)
+	icsData, err := calendar.GenerateICS([]*openai.Event{&event}, loc, b.icsOptions(shared.UserID, 0, false))
+	if err != nil {
+		log.Printf("Error generating ICS file: %v", err)
+		b.sendError(message, "error.ics_generate", err)
+		return
+	}
+
+	isAllDay := calendar.IsAllDay(&event)
+	prefs := b.getUserPreferences(fmt.Sprintf("%d", message.From.ID))
+	lang := prefs.Language
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("event_%s.ics", token),
+		Bytes: icsData,
+	})
+	doc.Caption = b.eventCaption(&event, isAllDay, b.formatTimezoneForDisplay(loc.String()), lang)
+	doc.ParseMode = tgbotapi.ModeMarkdownV2
+	doc.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending shared ICS file: %v", err)
+		b.sendError(message, "error.ics_send", err)
+	}
+}
+
+// newShareToken creates a random token for the link to a shared event
+func newShareToken() (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
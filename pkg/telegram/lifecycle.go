@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// lifecycle tracks the handlers that are running so shutdown can wait for them
+type lifecycle struct {
+	mu       sync.Mutex // Orders starting a handler against shutdown starting to wait for them
+	handlers sync.WaitGroup
+	inFlight atomic.Int64
+	stopping atomic.Bool
+	ctx      context.Context // Handlers run with it, and it's cancelled if shutdown gives up on them
+	cancel   context.CancelFunc
+}
+
+// newLifecycle returns a lifecycle whose handlers run until shutdown cancels them
+func newLifecycle() *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{ctx: ctx, cancel: cancel}
+}
+
+// goHandler queues a handler behind the user's earlier ones, tracking it until it returns, and
+// reports whether it did. Updates that arrive once shutdown has started are dropped, and
+// Telegram delivers them again later.
+func (b *Bot) goHandler(userID int64, handler func(ctx context.Context)) bool {
+	b.lifecycle.mu.Lock()
+	if b.Stopping() {
+		b.lifecycle.mu.Unlock()
+		log.Println("Shutting down, skipping update")
+		return false
+	}
+	b.lifecycle.handlers.Add(1)
+	b.lifecycle.mu.Unlock()
+
+	b.lifecycle.inFlight.Add(1)
+	b.queue.enqueue(fmt.Sprintf("%d", userID), func() {
+		defer b.lifecycle.handlers.Done()
+		defer b.lifecycle.inFlight.Add(-1)
+		handler(b.lifecycle.ctx)
+	})
+	return true
+}
+
+// Stopping reports whether the bot is shutting down and no longer accepts updates
+func (b *Bot) Stopping() bool {
+	return b.lifecycle.stopping.Load()
+}
+
+// Shutdown stops receiving updates and waits for the running handlers to finish, so no
+// extraction is cut off halfway. It gives up when ctx is done.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	// Once stopping is set under the lock, no handler is added while waiting for them
+	b.lifecycle.mu.Lock()
+	stopped := b.lifecycle.stopping.Swap(true)
+	b.lifecycle.mu.Unlock()
+	if stopped {
+		return nil
+	}
+
+	log.Println("Stopping receiving updates...")
+	b.bot.StopReceivingUpdates()
+
+	log.Printf("Waiting for %d running handlers to finish...", b.lifecycle.inFlight.Load())
+	done := make(chan struct{})
+	go func() {
+		b.lifecycle.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("All handlers finished")
+		return nil
+	case <-ctx.Done():
+		// Cut off the handlers still running, e.g. waiting for OpenAI
+		b.lifecycle.cancel()
+		return fmt.Errorf("gave up waiting for %d running handlers: %w", b.lifecycle.inFlight.Load(), ctx.Err())
+	}
+}
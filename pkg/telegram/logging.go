@@ -0,0 +1,47 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	// apiKeyCommandPattern matches /apikey with its arguments, which may be a user's OpenAI key
+	apiKeyCommandPattern = regexp.MustCompile(`(?is)^(\s*/apikey(?:@\w+)?)\s.*$`)
+	// secretKeyPattern matches OpenAI keys pasted anywhere else in a message
+	secretKeyPattern = regexp.MustCompile(`sk-[A-Za-z0-9_-]{8,}`)
+)
+
+// redactSecrets replaces the secrets a message may carry, so the text can be logged
+func redactSecrets(text string) string {
+	text = apiKeyCommandPattern.ReplaceAllString(text, "$1 [redacted]")
+	return secretKeyPattern.ReplaceAllString(text, "sk-[redacted]")
+}
+
+// describeUpdate summarises an update for the log, without the secrets its text may carry
+func describeUpdate(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		text := update.Message.Text
+		if text == "" {
+			text = update.Message.Caption
+		}
+		return fmt.Sprintf("update %d: message %d in chat %d from user %d: %q", update.UpdateID,
+			update.Message.MessageID, update.Message.Chat.ID, update.Message.From.ID, redactSecrets(text))
+	case update.CallbackQuery != nil:
+		return fmt.Sprintf("update %d: callback %q from user %d", update.UpdateID,
+			update.CallbackQuery.Data, update.CallbackQuery.From.ID)
+	case update.InlineQuery != nil:
+		return fmt.Sprintf("update %d: inline query %q from user %d", update.UpdateID,
+			redactSecrets(update.InlineQuery.Query), update.InlineQuery.From.ID)
+	case update.PreCheckoutQuery != nil:
+		return fmt.Sprintf("update %d: pre-checkout query from user %d", update.UpdateID, update.PreCheckoutQuery.From.ID)
+	case update.MyChatMember != nil:
+		return fmt.Sprintf("update %d: membership change in chat %d from user %d", update.UpdateID,
+			update.MyChatMember.Chat.ID, update.MyChatMember.From.ID)
+	default:
+		return fmt.Sprintf("update %d", update.UpdateID)
+	}
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testAPIKey = "sk-proj-abcdefghijklmnopqrstuvwxyz0123456789"
+
+func TestRedactSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"own key", "/apikey " + testAPIKey, "/apikey [redacted]"},
+		{"key for another user", "/apikey 12345 " + testAPIKey, "/apikey [redacted]"},
+		{"addressed to the bot", "/apikey@CalendarBot " + testAPIKey, "/apikey@CalendarBot [redacted]"},
+		{"key on the next line", "/apikey\n" + testAPIKey, "/apikey [redacted]"},
+		{"status", "/apikey", "/apikey"},
+		{"key pasted into a message", "my key is " + testAPIKey + ", thanks", "my key is sk-[redacted], thanks"},
+		{"ordinary message", "Lunch with Anna tomorrow at 1pm", "Lunch with Anna tomorrow at 1pm"},
+		{"other command", "/apikeys are great", "/apikeys are great"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactSecrets(tt.text); got != tt.want {
+				t.Errorf("redactSecrets(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateDoesNotLogAPIKey(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	// A stopping bot logs the update and skips it, without needing Telegram or the store
+	b := &Bot{lifecycle: newLifecycle()}
+	b.lifecycle.stopping.Store(true)
+
+	updates := []tgbotapi.Update{
+		{UpdateID: 1, Message: &tgbotapi.Message{
+			MessageID: 10,
+			Text:      "/apikey " + testAPIKey,
+			Chat:      &tgbotapi.Chat{ID: 42, Type: "private"},
+			From:      &tgbotapi.User{ID: 42},
+		}},
+		{UpdateID: 2, Message: &tgbotapi.Message{
+			MessageID: 11,
+			Caption:   "key: " + testAPIKey,
+			Chat:      &tgbotapi.Chat{ID: 42, Type: "private"},
+			From:      &tgbotapi.User{ID: 42},
+		}},
+		{UpdateID: 3, InlineQuery: &tgbotapi.InlineQuery{
+			Query: testAPIKey,
+			From:  &tgbotapi.User{ID: 42},
+		}},
+	}
+	for _, update := range updates {
+		b.handleUpdate(update)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, testAPIKey) || strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("log output contains the API key:\n%s", out)
+	}
+	if !strings.Contains(out, "update 1") {
+		t.Errorf("log output doesn't mention the update:\n%s", out)
+	}
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// markdownEscaper escapes the characters that are special anywhere in MarkdownV2 text
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+	"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+// markdownCodeEscaper escapes the characters that are special inside MarkdownV2 code
+var markdownCodeEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// markdownURLEscaper escapes the characters that are special inside a MarkdownV2 link target
+var markdownURLEscaper = strings.NewReplacer("\\", "\\\\", ")", "\\)")
+
+// escapeMarkdown escapes text for MarkdownV2, so it's shown as is
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// markdownBold formats text as bold MarkdownV2
+func markdownBold(text string) string {
+	return "*" + escapeMarkdown(text) + "*"
+}
+
+// markdownCode formats text as monospaced MarkdownV2
+func markdownCode(text string) string {
+	return "`" + markdownCodeEscaper.Replace(text) + "`"
+}
+
+// markdownLink formats a MarkdownV2 link with text pointing to target
+func markdownLink(text, target string) string {
+	return "[" + escapeMarkdown(text) + "](" + markdownURLEscaper.Replace(target) + ")"
+}
+
+// mapsURL returns a link that searches a location on a map
+func mapsURL(location string) string {
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(location)
+}
+
+// markdownLocation formats a location as a link to it on a map
+func markdownLocation(location string) string {
+	return markdownLink(location, mapsURL(location))
+}
+
+// sendMarkdown sends a MarkdownV2 message, as a reply if messageID isn't 0
+func (b *Bot) sendMarkdown(chatID int64, text string, messageID int) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyToMessageID = messageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
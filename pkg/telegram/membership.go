@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"calendar-assistant/pkg/storage"
+)
+
+// purgeJob deletes the data of a chat that blocked or removed the bot, once the grace period is over
+const purgeJob = "purge_inactive"
+
+// handleMyChatMember tracks the bot being blocked or removed from a chat, and being let back in
+func (b *Bot) handleMyChatMember(update *tgbotapi.ChatMemberUpdated) {
+	switch update.NewChatMember.Status {
+	case "kicked", "left":
+		b.deactivateChat(update)
+	case "member", "administrator", "creator", "restricted":
+		if update.OldChatMember.Status == "kicked" || update.OldChatMember.Status == "left" {
+			b.reactivateChat(update)
+		}
+	}
+}
+
+// deactivateChat stops everything the bot would send into a chat that blocked or removed it,
+// and schedules the deletion of its data if enabled
+func (b *Bot) deactivateChat(update *tgbotapi.ChatMemberUpdated) {
+	chatID := update.Chat.ID
+	private := update.Chat.IsPrivate()
+	log.Printf("Bot was blocked or removed in chat %d by user %d", chatID, update.From.ID)
+
+	err := b.store.SetInactive(storage.InactiveChat{
+		Bot:     b.cfg.BotName,
+		ChatID:  chatID,
+		Private: private,
+		Since:   time.Unix(int64(update.Date), 0),
+	})
+	if err != nil {
+		log.Printf("Error marking chat %d inactive: %v", chatID, err)
+	}
+
+	deleted, err := b.store.DeleteScheduledInChat(b.cfg.BotName, chatID)
+	if err != nil {
+		log.Printf("Error deleting scheduled messages of chat %d: %v", chatID, err)
+	} else if deleted > 0 {
+		log.Printf("Deleted %d scheduled messages of chat %d", deleted, chatID)
+	}
+
+	if b.scheduler == nil {
+		return
+	}
+	key := fmt.Sprintf("%d", chatID)
+	if private {
+		if _, err := b.scheduler.Cancel(b.jobKind(digestJob), key); err != nil {
+			log.Printf("Error cancelling the digest of user %s: %v", key, err)
+		}
+	}
+	if b.cfg.InactivePurgeAfter > 0 {
+		if _, err := b.scheduler.Schedule(b.jobKind(purgeJob), key, nil, time.Now().Add(b.cfg.InactivePurgeAfter)); err != nil {
+			log.Printf("Error scheduling the purge of chat %s: %v", key, err)
+		}
+	}
+}
+
+// reactivateChat cancels a pending purge of a chat that let the bot back in and resumes the
+// user's digest
+func (b *Bot) reactivateChat(update *tgbotapi.ChatMemberUpdated) {
+	chatID := update.Chat.ID
+	log.Printf("Bot was unblocked or added back in chat %d by user %d", chatID, update.From.ID)
+
+	if _, err := b.store.SetActive(b.cfg.BotName, chatID); err != nil {
+		log.Printf("Error marking chat %d active: %v", chatID, err)
+	}
+
+	if b.scheduler == nil {
+		return
+	}
+	key := fmt.Sprintf("%d", chatID)
+	if _, err := b.scheduler.Cancel(b.jobKind(purgeJob), key); err != nil {
+		log.Printf("Error cancelling the purge of chat %s: %v", key, err)
+	}
+	if update.Chat.IsPrivate() {
+		if err := b.scheduleDigest(&update.From); err != nil {
+			log.Printf("Error scheduling the digest of user %s: %v", key, err)
+		}
+	}
+}
+
+// runPurge deletes the data of a chat that is still inactive at the end of the grace period.
+// Users and groups are shared by the bots, so while a chat still uses another bot only what this
+// bot keeps for it goes.
+func (b *Bot) runPurge(ctx context.Context, job storage.Job) error {
+	chatID, err := strconv.ParseInt(job.Key, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %q: %w", job.Key, err)
+	}
+	chat, ok := b.store.Inactive(b.cfg.BotName, chatID)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case !b.inactiveOnAllBots(chatID):
+		if chat.Private {
+			if err := b.store.PurgeBotUser(b.cfg.BotName, job.Key); err != nil {
+				return fmt.Errorf("failed to purge user %s: %w", job.Key, err)
+			}
+		}
+		// The chat stays inactive, so the purge of the last bot it leaves finds it inactive on all
+		log.Printf("Purged the data of inactive chat %d, which still uses other bots", chatID)
+		return nil
+	case chat.Private:
+		userID := job.Key
+		if err := b.openaiClient.ClearThreadForUser(ctx, userID); err != nil {
+			log.Printf("Error clearing thread for user %s: %v", userID, err)
+		}
+		if err := b.store.PurgeUser(userID); err != nil {
+			return fmt.Errorf("failed to purge user %s: %w", userID, err)
+		}
+		log.Printf("Purged the data of inactive chat %d", chatID)
+	default:
+		if err := b.store.SaveChatSettings(chatID, storage.ChatSettings{}); err != nil {
+			return fmt.Errorf("failed to purge chat %d: %w", chatID, err)
+		}
+		if err := b.store.SaveGroupPermissions(chatID, storage.GroupPermissions{}); err != nil {
+			return fmt.Errorf("failed to purge chat %d: %w", chatID, err)
+		}
+		log.Printf("Purged the data of inactive chat %d", chatID)
+	}
+
+	_, err = b.store.SetActive(b.cfg.BotName, chatID)
+	return err
+}
+
+// inactiveOnAllBots reports whether a chat blocked or removed every bot served by the process
+func (b *Bot) inactiveOnAllBots(chatID int64) bool {
+	for _, name := range b.cfg.BotNames {
+		if _, ok := b.store.Inactive(name, chatID); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"calendar-assistant/pkg/language"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAccuracy shows admins how accurate extractions are per input type and language, so
+// they know where prompt or model improvements pay off most
+func (b *Bot) handleAccuracy(ctx context.Context, message *tgbotapi.Message) {
+	msg := tgbotapi.NewMessage(message.Chat.ID, accuracyReport(b.store.AccuracyByInputType(), b.store.AccuracyByLanguage()))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.bot.Send(msg); err != nil {
+		b.sendErrorMessage(message.Chat.ID, fmt.Errorf("failed to send metrics: %w", err), message.MessageID)
+	}
+}
+
+// accuracyReport formats the accuracy metrics as tables, as HTML
+func accuracyReport(byInputType, byLanguage map[string]storage.AccuracyStats) string {
+	var sb strings.Builder
+	sb.WriteString("<b>Extraction accuracy</b>\n")
+	sb.WriteString("Redo: share of events extracted again. Accurate/Wrong: share of follow-up answers.\n")
+
+	sb.WriteString("\n<b>By input type</b>\n")
+	sb.WriteString(accuracyTable(byInputType, func(key string) string { return key }))
+
+	sb.WriteString("\n<b>By language</b>\n")
+	sb.WriteString(accuracyTable(byLanguage, func(key string) string {
+		if name := language.Name(key); name != "" {
+			return name
+		}
+		return "unknown"
+	}))
+
+	return sb.String()
+}
+
+// accuracyTable formats one breakdown of the accuracy metrics, largest groups first
+func accuracyTable(stats map[string]storage.AccuracyStats, label func(string) string) string {
+	if len(stats) == 0 {
+		return "No extractions yet.\n"
+	}
+
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if stats[keys[i]].Extractions != stats[keys[j]].Extractions {
+			return stats[keys[i]].Extractions > stats[keys[j]].Extractions
+		}
+		return keys[i] < keys[j]
+	})
+
+	var table strings.Builder
+	fmt.Fprintf(&table, "%-10s %6s %5s %6s %8s %5s\n", "", "Events", "Redo", "Rated", "Accurate", "Wrong")
+	for _, key := range keys {
+		s := stats[key]
+		fmt.Fprintf(&table, "%-10.10s %6d %4.0f%% %6d %7.0f%% %4.0f%%\n",
+			label(key), s.Extractions, 100*s.RetryRate(), s.Rated, 100*s.AccurateRate(), 100*s.WrongRate())
+	}
+	return "<pre>" + html.EscapeString(table.String()) + "</pre>\n"
+}
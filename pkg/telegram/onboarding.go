@@ -0,0 +1,217 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// onboardingTTL is how long the guided setup waits for the user to take the next step
+const onboardingTTL = 24 * time.Hour
+
+// Callback data of the guided setup: "onboard:lang:<code>", "onboard:remind:<minutes>",
+// "onboard:sample" or "onboard:skip:<step>"
+const (
+	callbackOnboarding = "onboard"
+	onboardLanguage    = "lang"
+	onboardReminder    = "remind"
+	onboardSample      = "sample"
+	onboardSkip        = "skip"
+)
+
+// Steps of the guided setup, in order
+const (
+	onboardingTimezone = iota + 1
+	onboardingLanguage
+	onboardingReminder
+	onboardingTry
+	onboardingSteps = onboardingTry
+)
+
+// reminderChoices are the default reminders offered, in minutes before an event; 0 is none
+var reminderChoices = []int{0, 10, 30, 60, 24 * 60}
+
+// startOnboarding greets a user and walks them through the setup: timezone, language, default
+// reminder, and a first event to try
+func (b *Bot) startOnboarding(message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	log.Printf("Starting the guided setup for user %s", userID)
+	b.onboarding.Set(userID, onboardingTimezone)
+	b.sendOnboardingStep(message.From, message.Chat.ID, onboardingTimezone, message)
+}
+
+// continueOnboarding moves on to the next step once the user answered the step they're at,
+// if they're in the guided setup
+func (b *Bot) continueOnboarding(user *tgbotapi.User, chatID int64, answered int) {
+	userID := fmt.Sprintf("%d", user.ID)
+	step, ok := b.onboarding.Get(userID)
+	if !ok || step != answered {
+		return
+	}
+
+	if step == onboardingSteps {
+		b.onboarding.Delete(userID)
+		log.Printf("User %s finished the guided setup", userID)
+		b.sendText(chatID, b.t(user, "onboarding.done"), 0)
+		return
+	}
+	b.onboarding.Set(userID, step+1)
+	b.sendOnboardingStep(user, chatID, step+1, nil)
+}
+
+// sendOnboardingStep sends the question of a step of the guided setup with its buttons. start is
+// the /start message, which the timezone step guesses the timezone from.
+func (b *Bot) sendOnboardingStep(user *tgbotapi.User, chatID int64, step int, start *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", user.ID)
+	text := b.t(user, "onboarding.step", step, onboardingSteps) + "\n\n"
+	var keyboard tgbotapi.InlineKeyboardMarkup
+
+	switch step {
+	case onboardingTimezone:
+		if start == nil {
+			start = &tgbotapi.Message{From: user, Chat: &tgbotapi.Chat{ID: chatID}}
+		}
+		guess, suggestions := b.startTimezoneSuggestions(start)
+		if guess != "" {
+			text += guess + "\n\n"
+		}
+		text += b.t(user, "onboarding.timezone")
+		keyboard = b.timezonePicker(user, suggestions...)
+
+		// Users who already have a timezone may keep it
+		prefs := b.getUserPreferences(userID)
+		if !b.needsTimezone(prefs) {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(b.t(user, "onboarding.keep_timezone", b.userTimezone(prefs)),
+					callbackData(callbackOnboarding, onboardSkip, strconv.Itoa(step))),
+			))
+		}
+
+	case onboardingLanguage:
+		text += b.t(user, "onboarding.language")
+		var row []tgbotapi.InlineKeyboardButton
+		for _, lang := range b.catalog.Languages() {
+			if !b.catalog.Translates(lang, "language.name") {
+				continue
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.catalog.T(lang, "language.name"),
+				callbackData(callbackOnboarding, onboardLanguage, lang)))
+		}
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(row)
+
+	case onboardingReminder:
+		text += b.t(user, "onboarding.reminder")
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for i, minutes := range reminderChoices {
+			button := tgbotapi.NewInlineKeyboardButtonData(b.reminderLabel(user, minutes),
+				callbackData(callbackOnboarding, onboardReminder, strconv.Itoa(minutes)))
+			if i%2 == 0 {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+			} else {
+				rows[len(rows)-1] = append(rows[len(rows)-1], button)
+			}
+		}
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	case onboardingTry:
+		text += b.t(user, "onboarding.try")
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "onboarding.sample_button"), callbackData(callbackOnboarding, onboardSample)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "onboarding.skip_button"), callbackData(callbackOnboarding, onboardSkip, strconv.Itoa(step))),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending step %d of the guided setup: %v", step, err)
+	}
+}
+
+// reminderLabel describes a default reminder, in minutes before an event
+func (b *Bot) reminderLabel(user *tgbotapi.User, minutes int) string {
+	switch {
+	case minutes == 0:
+		return b.t(user, "reminder.none")
+	case minutes%60 == 0:
+		return b.t(user, "reminder.hours", minutes/60)
+	default:
+		return b.t(user, "reminder.minutes", minutes)
+	}
+}
+
+// handleOnboardingAnswer handles the buttons of the guided setup
+func (b *Bot) handleOnboardingAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) == 0 || query.Message == nil {
+		return
+	}
+	chatID := query.Message.Chat.ID
+	userID := fmt.Sprintf("%d", query.From.ID)
+
+	switch args[0] {
+	case onboardLanguage:
+		if len(args) != 2 || !b.catalog.Has(args[1]) {
+			return
+		}
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.Language = args[1]
+			prefs.LanguageChosen = true
+		})
+		log.Printf("Set language of user %s to %s", userID, args[1])
+		b.answerOnboardingStep(query, b.t(query.From, "onboarding.language_set", b.catalog.T(args[1], "language.name")))
+		b.continueOnboarding(query.From, chatID, onboardingLanguage)
+
+	case onboardReminder:
+		if len(args) != 2 {
+			return
+		}
+		minutes, err := strconv.Atoi(args[1])
+		if err != nil || minutes < 0 {
+			return
+		}
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.ReminderMinutes = minutes
+		})
+		log.Printf("Set default reminder of user %s to %d minutes", userID, minutes)
+		b.answerOnboardingStep(query, b.t(query.From, "onboarding.reminder_set", b.reminderLabel(query.From, minutes)))
+		b.continueOnboarding(query.From, chatID, onboardingReminder)
+
+	case onboardSample:
+		sampleText := b.t(query.From, "onboarding.sample_event")
+		b.answerOnboardingStep(query, b.t(query.From, "onboarding.sample_sent", sampleText))
+
+		// The sample is extracted like any message the user sends
+		sample := *query.Message
+		sample.From = query.From
+		sample.Text, sample.Entities, sample.ReplyMarkup = sampleText, nil, nil
+		b.processEvent(ctx, &sample, eventOptions{})
+		b.continueOnboarding(query.From, chatID, onboardingTry)
+
+	case onboardSkip:
+		if len(args) != 2 {
+			return
+		}
+		step, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+		b.answerOnboardingStep(query, query.Message.Text)
+		b.continueOnboarding(query.From, chatID, step)
+	}
+}
+
+// answerOnboardingStep answers a button of the guided setup, replacing the question with text
+// so its buttons can't be pressed again
+func (b *Bot) answerOnboardingStep(query *tgbotapi.CallbackQuery, text string) {
+	b.answerCallback(query, "")
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	if _, err := b.bot.Send(edit); err != nil && !isMessageNotModified(err) {
+		log.Printf("Error updating the guided setup: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// planTTL is how long a proposed plan can be edited before it's discarded
+const planTTL = 2 * time.Hour
+
+// Callback data of the plan buttons: "plan:<create|discard>:<plan message ID>"
+const (
+	callbackPlan = "plan"
+	planCreate   = "create"
+	planDiscard  = "discard"
+)
+
+// hoursPattern matches a line of available hours such as "9-12, 13:30-17:00"
+var hoursPattern = regexp.MustCompile(`^\s*\d{1,2}(:\d{2})?\s*-\s*\d{1,2}(:\d{2})?(\s*,\s*\d{1,2}(:\d{2})?\s*-\s*\d{1,2}(:\d{2})?)*\s*$`)
+
+// timeRangePattern matches the new time of an edited block, e.g. "14:00-15:30"
+var timeRangePattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})\s*-\s*(\d{1,2}):(\d{2})$`)
+
+// dayPlan is a proposed schedule of focus events the user can still edit. A user's handlers
+// run one at a time, so a plan is never changed concurrently.
+type dayPlan struct {
+	chatID    int64
+	messageID int // The message showing the plan, which edits reply to
+	todo      string
+	events    []*openai.Event
+}
+
+// handlePlan proposes timeboxed focus events for a to-do list. The list follows the command
+// on the next lines, or is the message the command replies to; the first line may give the
+// available hours.
+func (b *Bot) handlePlan(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	messageID := message.MessageID
+	usage := b.t(message.From, "plan.usage")
+
+	hours, todo := parsePlanRequest(message.CommandArguments())
+	if message.ReplyToMessage != nil && message.ReplyToMessage.Text != "" {
+		hours = strings.TrimSpace(message.CommandArguments())
+		todo = message.ReplyToMessage.Text
+	}
+	if strings.TrimSpace(todo) == "" {
+		b.sendText(chatID, usage, messageID)
+		return
+	}
+
+	prefs := b.eventPreferences(userID, chatID)
+	if b.needsTimezone(prefs) && !b.cfg.AllowEventsWithoutTimezone {
+		b.sendText(chatID, b.t(message.From, "plan.timezone_required"), messageID)
+		return
+	}
+
+	if !b.allowRequest(message) {
+		return
+	}
+
+	stopTyping := b.startChatAction(chatID, tgbotapi.ChatTyping)
+
+	opts := openai.ExtractOptions{
+		Timezone: b.userTimezone(prefs),
+		Language: prefs.Language,
+	}
+	events, err := b.openaiClient.PlanDay(ctx, userID, todo, hours, opts)
+	stopTyping()
+	if err != nil {
+		log.Printf("Error planning day for user %s: %v", userID, err)
+		b.sendError(message, "plan.failed", err)
+		return
+	}
+
+	plan := &dayPlan{chatID: chatID, todo: todo, events: events}
+	msg := tgbotapi.NewMessage(chatID, b.planText(message.From, plan))
+	msg.ReplyToMessageID = messageID
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending plan: %v", err)
+		return
+	}
+
+	// The buttons name the plan's own message, so older plans can't be confirmed by mistake
+	plan.messageID = sent.MessageID
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, sent.MessageID, b.planKeyboard(message.From, sent.MessageID))
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error adding plan buttons: %v", err)
+	}
+
+	b.plans.Set(userID, plan)
+	log.Printf("Proposed a plan with %d focus events to user %s", len(events), userID)
+}
+
+// parsePlanRequest splits the arguments of /plan into the available hours, if the first line
+// gives them, and the to-do list
+func parsePlanRequest(args string) (string, string) {
+	lines := strings.SplitN(strings.TrimSpace(args), "\n", 2)
+	if hoursPattern.MatchString(lines[0]) {
+		todo := ""
+		if len(lines) == 2 {
+			todo = lines[1]
+		}
+		return strings.TrimSpace(lines[0]), todo
+	}
+	return "", strings.TrimSpace(args)
+}
+
+// planKeyboard creates the buttons under a proposed plan
+func (b *Bot) planKeyboard(user *tgbotapi.User, messageID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "plan.button.create"), callbackData(callbackPlan, planCreate, strconv.Itoa(messageID))),
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "plan.button.discard"), callbackData(callbackPlan, planDiscard, strconv.Itoa(messageID))),
+	))
+}
+
+// planText lists the focus events of a plan with instructions for editing them
+func (b *Bot) planText(user *tgbotapi.User, plan *dayPlan) string {
+	var sb strings.Builder
+	sb.WriteString(b.t(user, "plan.header") + "\n")
+	for i, event := range plan.events {
+		fmt.Fprintf(&sb, "\n%d. %s-%s %s", i+1, event.StartTime.Format("15:04"), event.EndTime.Format("15:04"), event.Title)
+	}
+	sb.WriteString("\n\n" + b.t(user, "plan.instructions"))
+	return sb.String()
+}
+
+// handlePlanEdit applies an edit replied to a proposed plan, reporting whether the message was one
+func (b *Bot) handlePlanEdit(message *tgbotapi.Message) bool {
+	if message.Text == "" || message.From == nil || message.ReplyToMessage == nil {
+		return false
+	}
+
+	userID := fmt.Sprintf("%d", message.From.ID)
+	plan, ok := b.plans.Get(userID)
+	if !ok || plan.chatID != message.Chat.ID || plan.messageID != message.ReplyToMessage.MessageID {
+		return false
+	}
+
+	if err := plan.apply(message.Text); err != nil {
+		b.sendError(message, err.key, nil, err.args...)
+		return true
+	}
+	b.plans.Set(userID, plan) // Keeps the plan for another planTTL
+
+	edit := tgbotapi.NewEditMessageText(plan.chatID, plan.messageID, b.planText(message.From, plan))
+	keyboard := b.planKeyboard(message.From, plan.messageID)
+	edit.ReplyMarkup = &keyboard
+	if _, err := b.bot.Send(edit); err != nil {
+		log.Printf("Error updating plan: %v", err)
+	}
+	return true
+}
+
+// planEditError is why an edit of a plan can't be applied, as a catalog key and its arguments
+type planEditError struct {
+	key  string
+	args []any
+}
+
+// planRemoveWords are the words that remove a block of a plan, in the languages the bot knows
+var planRemoveWords = []string{"remove", "delete", "удалить", "убрать"}
+
+// apply changes a block of the plan as described by an edit such as "2 14:00-15:30"
+func (plan *dayPlan) apply(edit string) *planEditError {
+	fields := strings.SplitN(strings.TrimSpace(edit), " ", 2)
+	n, err := strconv.Atoi(strings.TrimSuffix(fields[0], "."))
+	if err != nil || len(fields) < 2 {
+		return &planEditError{key: "plan.edit_usage"}
+	}
+	if n < 1 || n > len(plan.events) {
+		return &planEditError{key: "plan.no_block", args: []any{n}}
+	}
+	change := strings.TrimSpace(fields[1])
+	event := plan.events[n-1]
+
+	if slices.ContainsFunc(planRemoveWords, func(word string) bool { return strings.EqualFold(change, word) }) {
+		if len(plan.events) == 1 {
+			return &planEditError{key: "plan.last_block"}
+		}
+		plan.events = append(plan.events[:n-1], plan.events[n:]...)
+		return nil
+	}
+
+	if m := timeRangePattern.FindStringSubmatch(change); m != nil {
+		start, startErr := planTime(event.StartTime, m[1], m[2])
+		end, endErr := planTime(event.StartTime, m[3], m[4])
+		if startErr != nil || endErr != nil || !end.After(start) {
+			return &planEditError{key: "plan.invalid_range", args: []any{change}}
+		}
+		event.StartTime, event.EndTime = start, end
+		event.AllDay = false
+		return nil
+	}
+
+	event.Title = change
+	return nil
+}
+
+// planTime returns the given time of day on the date of day
+func planTime(day time.Time, hour, minute string) (time.Time, error) {
+	h, err := strconv.Atoi(hour)
+	if err != nil || h > 23 {
+		return time.Time{}, fmt.Errorf("invalid hour %s", hour)
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil || m > 59 {
+		return time.Time{}, fmt.Errorf("invalid minute %s", minute)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location()), nil
+}
+
+// handlePlanAnswer handles a press on one of the plan buttons
+func (b *Bot) handlePlanAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) != 2 {
+		return
+	}
+	messageID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	userID := fmt.Sprintf("%d", query.From.ID)
+	plan, ok := b.plans.Get(userID)
+	if !ok || plan.messageID != messageID {
+		b.answerCallback(query, b.t(query.From, "plan.expired"))
+		return
+	}
+	b.plans.Delete(userID)
+	b.answerCallback(query, "")
+
+	// Remove the buttons so the plan can't be used twice
+	edit := tgbotapi.NewEditMessageReplyMarkup(plan.chatID, plan.messageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error removing plan buttons: %v", err)
+	}
+
+	if args[0] != planCreate {
+		b.sendText(plan.chatID, b.t(query.From, "plan.discarded"), plan.messageID)
+		return
+	}
+
+	prefs := b.eventPreferences(userID, plan.chatID)
+	loc, err := b.timezones.Resolve(b.userTimezone(prefs))
+	if err != nil {
+		b.sendText(plan.chatID, b.t(query.From, "batch.timezone_fallback", err, b.formatTimezoneForDisplay(loc.String())), plan.messageID)
+	}
+
+	b.geocodeEvents(plan.events...)
+	icsData, err := calendar.GenerateICS(plan.events, loc, b.icsOptions(userID, prefs.ReminderMinutes, true))
+	if err != nil {
+		log.Printf("Error generating ICS file: %v", err)
+		b.sendUserError(query.From, plan.chatID, plan.messageID, "error.ics_generate", err)
+		return
+	}
+
+	for _, event := range plan.events {
+		if _, err := b.store.AddHistory(storage.HistoryEntry{
+			Bot:       b.cfg.BotName,
+			UserID:    userID,
+			ChatID:    plan.chatID,
+			InputType: storage.InputPlan,
+			RawInput:  plan.todo,
+			Event:     event,
+		}); err != nil {
+			log.Printf("Error saving history entry: %v", err)
+		}
+	}
+
+	doc := tgbotapi.NewDocument(plan.chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("plan_%d.ics", plan.messageID),
+		Bytes: icsData,
+	})
+	doc.Caption = b.t(query.From, "plan.caption", len(plan.events))
+	doc.ReplyToMessageID = plan.messageID
+	if _, err := b.bot.Send(doc); err != nil {
+		log.Printf("Error sending ICS file: %v", err)
+		b.sendUserError(query.From, plan.chatID, plan.messageID, "error.ics_send", err)
+	}
+}
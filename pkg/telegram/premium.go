@@ -0,0 +1,161 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// premiumCurrency is the currency of Telegram Stars
+const premiumCurrency = "XTR"
+
+// premiumPayloadPrefix starts the payload of premium invoices, followed by the buyer's user ID
+const premiumPayloadPrefix = "premium:"
+
+// premiumEnabled reports whether users can buy the premium tier
+func (b *Bot) premiumEnabled() bool {
+	return b.cfg.PremiumPriceStars > 0
+}
+
+// isPremium reports whether a user has the premium tier
+func (b *Bot) isPremium(userID string) bool {
+	if !b.premiumEnabled() {
+		return false
+	}
+	_, ok := b.store.ActivePremium(userID)
+	return ok
+}
+
+// handlePremium shows the user's premium tier and sends an invoice to buy or extend it
+func (b *Bot) handlePremium(ctx context.Context, message *tgbotapi.Message) {
+	if !b.premiumEnabled() {
+		b.sendText(message.Chat.ID, b.t(message.From, "premium.disabled"), message.MessageID)
+		return
+	}
+	if !message.Chat.IsPrivate() {
+		b.sendText(message.Chat.ID, b.t(message.From, "premium.private_only", b.bot.Self.UserName), message.MessageID)
+		return
+	}
+
+	userID := fmt.Sprintf("%d", message.From.ID)
+	if entitlement, ok := b.store.ActivePremium(userID); ok {
+		b.sendText(message.Chat.ID, b.t(message.From, "premium.active", entitlement.ExpiresAt.Format("2006-01-02")), message.MessageID)
+	}
+
+	days := int(b.cfg.PremiumDuration.Hours() / 24)
+	invoice := tgbotapi.NewInvoice(message.Chat.ID,
+		b.t(message.From, "premium.title"),
+		b.t(message.From, "premium.description", days, b.quotaLabel(message.From, b.cfg.PremiumDailyQuota), b.quotaLabel(message.From, b.cfg.FreeDailyQuota)),
+		premiumPayloadPrefix+userID, "", "", premiumCurrency,
+		[]tgbotapi.LabeledPrice{{Label: b.t(message.From, "premium.title"), Amount: b.cfg.PremiumPriceStars}})
+	invoice.SuggestedTipAmounts = []int{} // Stars don't support tips
+	if _, err := b.bot.Send(invoice); err != nil {
+		log.Printf("Error sending premium invoice to user %s: %v", userID, err)
+		b.sendError(message, "premium.invoice_failed", err)
+	}
+}
+
+// quotaLabel describes a daily quota, e.g. "20 events a day"
+func (b *Bot) quotaLabel(user *tgbotapi.User, quota int) string {
+	if quota == 0 {
+		return b.t(user, "premium.quota_unlimited")
+	}
+	return b.t(user, "premium.quota", quota)
+}
+
+// handlePreCheckoutQuery confirms a premium purchase before Telegram charges the user, as long
+// as the invoice is still valid
+func (b *Bot) handlePreCheckoutQuery(query *tgbotapi.PreCheckoutQuery) {
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: query.ID, OK: true}
+	switch {
+	case !b.premiumEnabled():
+		answer.OK, answer.ErrorMessage = false, b.t(query.From, "premium.disabled")
+	case query.InvoicePayload != fmt.Sprintf("%s%d", premiumPayloadPrefix, query.From.ID):
+		answer.OK, answer.ErrorMessage = false, b.t(query.From, "premium.invalid_invoice")
+	case query.Currency != premiumCurrency || query.TotalAmount != b.cfg.PremiumPriceStars:
+		// The price changed since the invoice was sent
+		answer.OK, answer.ErrorMessage = false, b.t(query.From, "premium.invalid_invoice")
+	}
+
+	if !answer.OK {
+		log.Printf("Declining payment of user %d: %s", query.From.ID, answer.ErrorMessage)
+	}
+	if _, err := b.bot.Request(answer); err != nil {
+		log.Printf("Error answering pre-checkout query: %v", err)
+	}
+}
+
+// handleSuccessfulPayment grants the premium tier once Telegram confirms a payment
+func (b *Bot) handleSuccessfulPayment(message *tgbotapi.Message) {
+	payment := message.SuccessfulPayment
+	userID := fmt.Sprintf("%d", message.From.ID)
+	if !strings.HasPrefix(payment.InvoicePayload, premiumPayloadPrefix) {
+		log.Printf("Ignoring payment of user %s with unknown payload %q", userID, payment.InvoicePayload)
+		return
+	}
+
+	entitlement, err := b.store.AddPremium(storage.Payment{
+		UserID:   userID,
+		ChargeID: payment.TelegramPaymentChargeID,
+		Stars:    payment.TotalAmount,
+	}, b.cfg.PremiumDuration)
+	if err != nil {
+		// The payment went through, so the operators have to sort this out
+		log.Printf("Error granting premium to user %s for payment %s: %v", userID, payment.TelegramPaymentChargeID, err)
+		b.sendError(message, "premium.grant_failed", nil)
+		return
+	}
+
+	log.Printf("User %s paid %d Stars for premium until %s", userID, payment.TotalAmount, entitlement.ExpiresAt.Format("2006-01-02"))
+	b.sendText(message.Chat.ID, b.t(message.From, "premium.thanks", entitlement.ExpiresAt.Format("2006-01-02")), message.MessageID)
+}
+
+// allowUserQuota checks the user's daily quota before a request that costs API budget, telling
+// them through notify when they've used it up. Users with their own API key have no quota.
+func (b *Bot) allowUserQuota(user *tgbotapi.User, notify func(text string)) bool {
+	userID := fmt.Sprintf("%d", user.ID)
+	premium := b.isPremium(userID)
+	quota := b.cfg.FreeDailyQuota
+	if premium {
+		quota = b.cfg.PremiumDailyQuota
+	}
+	if quota == 0 || b.store.APIKey(userID) != "" {
+		return true
+	}
+
+	if used := b.store.ExtractionsToday(userID); used < quota {
+		return true
+	}
+
+	log.Printf("User %s used up their daily quota of %d", userID, quota)
+	if premium || !b.premiumEnabled() {
+		notify(b.t(user, "premium.quota_reached", quota))
+	} else {
+		notify(b.t(user, "premium.quota_reached_upgrade", quota))
+	}
+	return false
+}
+
+// allowPremiumFeature checks whether the user may use a premium feature, such as voice input,
+// telling them how to get it if they can't. Everyone may use them while premium is disabled.
+func (b *Bot) allowPremiumFeature(message *tgbotapi.Message, feature string) bool {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	if !b.premiumEnabled() || b.isAdmin(userID) || b.isPremium(userID) {
+		return true
+	}
+
+	log.Printf("User %s tried the premium feature %s", userID, feature)
+	b.sendText(message.Chat.ID, b.t(message.From, "premium.required", b.t(message.From, "premium.feature_"+feature)), message.MessageID)
+	return false
+}
+
+// extractionSlot waits for a free extraction slot, letting premium users go first, and returns
+// the function that gives it back
+func (b *Bot) extractionSlot(ctx context.Context, userID string) (func(), error) {
+	return b.slots.acquire(ctx, b.isPremium(userID) || b.isAdmin(userID))
+}
@@ -0,0 +1,566 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// previewTTL is how long an event preview can be confirmed or edited
+const previewTTL = 6 * time.Hour
+
+// Callback data of the preview buttons: "preview:<action>:<preview message ID>", followed by
+// the field to edit and, for a shift, the minutes to move it by
+const (
+	callbackPreview = "preview"
+	previewConfirm  = "confirm"
+	previewEdit     = "edit"
+	previewCancel   = "cancel"
+	previewField    = "field"
+	previewShift    = "shift"
+	previewBack     = "back"
+	previewStatus   = "status"
+	previewBusy     = "busy"
+	previewPriority = "priority"
+)
+
+// Fields of a preview that can be edited on their own
+const (
+	fieldTitle    = "title"
+	fieldStart    = "start"
+	fieldEnd      = "end"
+	fieldLocation = "location"
+)
+
+// previewStatuses are the statuses the status button of a preview cycles through
+var previewStatuses = []string{openai.StatusConfirmed, openai.StatusTentative, openai.StatusCancelled}
+
+// previewPriorities are the priorities the priority button of a preview cycles through
+var previewPriorities = []int{0, openai.PriorityHigh, openai.PriorityMedium, openai.PriorityLow}
+
+// previewShifts are the quick adjustments offered for the start and end, in minutes
+var previewShifts = []int{-60, -30, 30, 60}
+
+// eventPreview is an extracted event shown to the user before its file is created. A user's
+// handlers run one at a time, but anyone allowed to edit events in a group may change a preview,
+// so previews are replaced in the cache rather than changed in place.
+type eventPreview struct {
+	extracted extractedEvent
+	chatID    int64
+	messageID int    // The preview message, which the buttons and corrections belong to
+	field     string // The field a reply sets, empty if a reply is a free-form correction
+}
+
+// previewKey returns the cache key of the preview shown in a message
+func previewKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+// handlePreview shows or changes whether a user sees a preview of each event before its
+// file is created
+func (b *Bot) handlePreview(ctx context.Context, message *tgbotapi.Message) {
+	b.handleToggle(message, toggleSetting{
+		key:  "preview",
+		name: "event previews",
+		get:  func(prefs *storage.UserPreferences) bool { return !prefs.SkipPreview },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.SkipPreview = !enabled },
+	})
+}
+
+// sendPreview shows an extracted event with buttons to confirm, edit or cancel it
+func (b *Bot) sendPreview(userID string, extracted extractedEvent) {
+	message := extracted.message
+	chatID := message.Chat.ID
+
+	msg := tgbotapi.NewMessage(chatID, b.previewText(userID, extracted))
+	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyToMessageID = message.MessageID
+	sent, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending event preview: %v", err)
+		b.sendError(message, "preview.send_failed", err)
+		return
+	}
+
+	// The buttons name the preview's own message, so each preview is confirmed on its own
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, sent.MessageID, b.previewKeyboard(message.From, sent.MessageID, extracted.event))
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error adding preview buttons: %v", err)
+	}
+
+	b.previews.Set(previewKey(chatID, sent.MessageID), &eventPreview{
+		extracted: extracted,
+		chatID:    chatID,
+		messageID: sent.MessageID,
+	})
+}
+
+// previewKeyboard creates the buttons under an event preview with the event's status, free/busy
+// and priority, in the language of the user who sent the event
+func (b *Bot) previewKeyboard(user *tgbotapi.User, messageID int, event *openai.Event) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(messageID)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.confirm"), callbackData(callbackPreview, previewConfirm, id)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.edit"), callbackData(callbackPreview, previewEdit, id)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.cancel"), callbackData(callbackPreview, previewCancel, id)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.title"), callbackData(callbackPreview, previewField, id, fieldTitle)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.start"), callbackData(callbackPreview, previewField, id, fieldStart)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.end"), callbackData(callbackPreview, previewField, id, fieldEnd)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.location"), callbackData(callbackPreview, previewField, id, fieldLocation)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.status", b.t(user, statusKey(event.Status))), callbackData(callbackPreview, previewStatus, id)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, busyKey(event)), callbackData(callbackPreview, previewBusy, id)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.priority", b.t(user, priorityKey(event.Priority))), callbackData(callbackPreview, previewPriority, id)),
+		),
+	)
+}
+
+// previewTimeKeyboard creates the quick adjustments of the start or end of a preview
+func (b *Bot) previewTimeKeyboard(user *tgbotapi.User, messageID int, field string) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(messageID)
+	var shifts []tgbotapi.InlineKeyboardButton
+	for _, minutes := range previewShifts {
+		label := fmt.Sprintf("%+dm", minutes)
+		if minutes%60 == 0 {
+			label = fmt.Sprintf("%+dh", minutes/60)
+		}
+		shifts = append(shifts, tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackPreview, previewShift, id, field, strconv.Itoa(minutes))))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		shifts,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.t(user, "preview.button.back"), callbackData(callbackPreview, previewBack, id))),
+	)
+}
+
+// previewButtons returns the buttons a preview shows in its current state
+func (b *Bot) previewButtons(p *eventPreview) tgbotapi.InlineKeyboardMarkup {
+	user := p.extracted.message.From
+	if p.field == fieldStart || p.field == fieldEnd {
+		return b.previewTimeKeyboard(user, p.messageID, p.field)
+	}
+	return b.previewKeyboard(user, p.messageID, p.extracted.event)
+}
+
+// statusKey returns the catalog key of the name of an event's status; events without one are
+// confirmed
+func statusKey(status string) string {
+	switch status {
+	case openai.StatusTentative:
+		return "preview.status.tentative"
+	case openai.StatusCancelled:
+		return "preview.status.cancelled"
+	}
+	return "preview.status.confirmed"
+}
+
+// nextStatus returns the status after the current one in the preview's cycle
+func nextStatus(status string) string {
+	for i, s := range previewStatuses {
+		if s == status {
+			return previewStatuses[(i+1)%len(previewStatuses)]
+		}
+	}
+	return openai.StatusTentative // No status counts as confirmed
+}
+
+// showsBusy reports whether an event blocks its time in a calendar; without a free/busy of its
+// own, timed events do and all-day events don't
+func showsBusy(event *openai.Event) bool {
+	if event.Transparency != "" {
+		return event.Transparency == openai.TransparencyBusy
+	}
+	return !calendar.IsAllDay(event)
+}
+
+// busyKey returns the catalog key of the name of how an event shows in a calendar
+func busyKey(event *openai.Event) string {
+	if showsBusy(event) {
+		return "preview.busy"
+	}
+	return "preview.free"
+}
+
+// priorityKey returns the catalog key of the name of an event's priority, grouping ICS
+// priorities as calendars do
+func priorityKey(priority int) string {
+	switch {
+	case priority <= 0:
+		return "preview.priority.none"
+	case priority < openai.PriorityMedium:
+		return "preview.priority.high"
+	case priority == openai.PriorityMedium:
+		return "preview.priority.medium"
+	}
+	return "preview.priority.low"
+}
+
+// nextPriority returns the priority after the current one in the preview's cycle
+func nextPriority(priority int) int {
+	for i, p := range previewPriorities {
+		if p == priority {
+			return previewPriorities[(i+1)%len(previewPriorities)]
+		}
+	}
+	return 0 // Other ICS priorities go back to none
+}
+
+// previewText lists the fields of an extracted event, as MarkdownV2, in the language of the user
+// who sent it
+func (b *Bot) previewText(userID string, extracted extractedEvent) string {
+	event := extracted.event
+	user := extracted.message.From
+	prefs := b.eventPreferences(userID, extracted.message.Chat.ID)
+	timezone := b.formatTimezoneForDisplay(b.userTimezone(prefs))
+	label := func(key string) string {
+		return markdownBold(b.t(user, key))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(label("preview.header") + "\n\n")
+	fmt.Fprintf(&sb, "%s %s\n", label("preview.field.title"), markdownBold(event.Title))
+	if calendar.IsMultiDay(event) {
+		fmt.Fprintf(&sb, "%s %s – %s %s\n", label("preview.field.dates"), markdownCode(event.StartTime.Format("Mon 2 Jan 2006")), markdownCode(calendar.LastDay(event).Format("Mon 2 Jan 2006")), escapeMarkdown(b.t(user, "preview.all_day")))
+	} else if calendar.IsAllDay(event) {
+		fmt.Fprintf(&sb, "%s %s %s\n", label("preview.field.date"), markdownCode(event.StartTime.Format("Mon 2 Jan 2006")), escapeMarkdown(b.t(user, "preview.all_day")))
+	} else {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.start"), markdownCode(event.StartTime.Format("Mon 2 Jan 2006, 15:04")))
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.end"), markdownCode(event.EndTime.Format("Mon 2 Jan 2006, 15:04")))
+	}
+	if event.Status == openai.StatusTentative || event.Status == openai.StatusCancelled {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.status"), escapeMarkdown(b.t(user, statusKey(event.Status))))
+	}
+	if event.Transparency != "" {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.shows_as"), escapeMarkdown(b.t(user, busyKey(event))))
+	}
+	if event.Priority > 0 {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.priority"), escapeMarkdown(b.t(user, priorityKey(event.Priority))))
+	}
+	if len(event.Categories) > 0 {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.category"), escapeMarkdown(strings.Join(event.Categories, ", ")))
+	}
+	if event.Recurrence != nil {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.repeats"), escapeMarkdown(calendar.DescribeRecurrence(event.Recurrence)))
+	}
+	if event.Location != "" {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.location"), markdownLocation(event.Location))
+	}
+	if event.URL != "" {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.link"), markdownLink(event.URL, event.URL))
+	}
+	if event.Description != "" {
+		fmt.Fprintf(&sb, "%s %s\n", label("preview.field.description"), escapeMarkdown(event.Description))
+	}
+	fmt.Fprintf(&sb, "%s %s", label("preview.field.timezone"), escapeMarkdown(timezone))
+	if warning := conflictWarning(event, b.conflicts(userID, event)); warning != "" {
+		sb.WriteString("\n\n" + escapeMarkdown(warning))
+	}
+	return sb.String()
+}
+
+// handlePreviewAnswer handles a press on one of the preview buttons
+func (b *Bot) handlePreviewAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) < 2 || query.Message == nil {
+		return
+	}
+	messageID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	key := previewKey(query.Message.Chat.ID, messageID)
+	preview, ok := b.previews.Get(key)
+	if !ok {
+		b.answerCallback(query, b.t(query.From, "preview.expired"))
+		return
+	}
+	if !b.canChangePreview(preview, query.From.ID) {
+		b.answerCallback(query, b.t(query.From, "preview.not_allowed"))
+		return
+	}
+	userID := fmt.Sprintf("%d", preview.extracted.message.From.ID)
+
+	switch args[0] {
+	case previewConfirm:
+		// Only the press that removes the preview delivers it, so a double tap sends one file
+		if preview, ok = b.previews.Take(key); !ok {
+			b.answerCallback(query, b.t(query.From, "preview.expired"))
+			return
+		}
+		b.answerCallback(query, "")
+		b.removePreviewButtons(preview)
+		log.Printf("User %d confirmed the preview of message %d", query.From.ID, preview.extracted.message.MessageID)
+		b.deliverEvent(userID, preview.extracted)
+
+	case previewCancel:
+		if preview, ok = b.previews.Take(key); !ok {
+			b.answerCallback(query, b.t(query.From, "preview.expired"))
+			return
+		}
+		b.answerCallback(query, "")
+		b.removePreviewButtons(preview)
+		b.setReaction(preview.chatID, preview.extracted.message.MessageID, "")
+		b.sendText(preview.chatID, b.t(query.From, "preview.discarded"), preview.messageID)
+
+	case previewEdit:
+		b.answerCallback(query, "")
+		b.updatePreview(userID, &eventPreview{extracted: preview.extracted, chatID: preview.chatID, messageID: preview.messageID})
+		b.sendText(preview.chatID, b.t(query.From, "preview.edit_hint"), preview.messageID)
+
+	case previewField:
+		if len(args) != 3 {
+			return
+		}
+		field := args[2]
+		b.updatePreview(userID, &eventPreview{extracted: preview.extracted, chatID: preview.chatID, messageID: preview.messageID, field: field})
+		b.answerCallback(query, b.t(query.From, "preview.field_hint."+field))
+
+	case previewShift:
+		if len(args) != 4 {
+			return
+		}
+		minutes, err := strconv.Atoi(args[3])
+		if err != nil {
+			return
+		}
+		event, err := shiftEventTime(preview.extracted.event, args[2], time.Duration(minutes)*time.Minute)
+		if errors.Is(err, errEndsBeforeStart) {
+			b.answerCallback(query, b.t(query.From, "preview.ends_before_start"))
+			return
+		}
+		if err != nil {
+			b.answerCallback(query, "")
+			return
+		}
+		b.answerCallback(query, "")
+		extracted := preview.extracted
+		extracted.event = event
+		b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID, field: args[2]})
+
+	case previewStatus:
+		b.answerCallback(query, "")
+		extracted := preview.extracted
+		event := *extracted.event
+		event.Status = nextStatus(event.Status)
+		extracted.event = &event
+		b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID})
+
+	case previewBusy:
+		b.answerCallback(query, "")
+		extracted := preview.extracted
+		event := *extracted.event
+		if showsBusy(&event) {
+			event.Transparency = openai.TransparencyFree
+		} else {
+			event.Transparency = openai.TransparencyBusy
+		}
+		extracted.event = &event
+		b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID})
+
+	case previewPriority:
+		b.answerCallback(query, "")
+		extracted := preview.extracted
+		event := *extracted.event
+		event.Priority = nextPriority(event.Priority)
+		extracted.event = &event
+		b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID})
+
+	case previewBack:
+		b.answerCallback(query, "")
+		b.updatePreview(userID, &eventPreview{extracted: preview.extracted, chatID: preview.chatID, messageID: preview.messageID})
+	}
+}
+
+// updatePreview stores a changed preview and shows it with the buttons of its state
+func (b *Bot) updatePreview(userID string, preview *eventPreview) {
+	b.previews.Set(previewKey(preview.chatID, preview.messageID), preview)
+
+	edit := tgbotapi.NewEditMessageText(preview.chatID, preview.messageID, b.previewText(userID, preview.extracted))
+	edit.ParseMode = tgbotapi.ModeMarkdownV2
+	keyboard := b.previewButtons(preview)
+	edit.ReplyMarkup = &keyboard
+	if _, err := b.bot.Send(edit); err != nil && !isMessageNotModified(err) {
+		log.Printf("Error updating event preview: %v", err)
+	}
+}
+
+// isMessageNotModified reports whether an edit failed only because nothing changed
+func isMessageNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
+
+// errEndsBeforeStart is returned for a change that would end an event before it starts
+var errEndsBeforeStart = errors.New("the event can't end before it starts")
+
+// shiftEventTime moves the start of an event, keeping its length, or its end
+func shiftEventTime(event *openai.Event, field string, by time.Duration) (*openai.Event, error) {
+	shifted := *event
+	switch field {
+	case fieldStart:
+		shifted.StartTime = event.StartTime.Add(by)
+		shifted.EndTime = event.EndTime.Add(by)
+	case fieldEnd:
+		shifted.EndTime = event.EndTime.Add(by)
+		if !shifted.EndTime.After(shifted.StartTime) {
+			return nil, errEndsBeforeStart
+		}
+	default:
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	// Moving by part of a day gives the event a time of day
+	if by%(24*time.Hour) != 0 {
+		shifted.AllDay = false
+	}
+	return &shifted, nil
+}
+
+// setEventField sets a field of an event to a value typed by the user. Times are given as
+// 15:04, keeping the date, or as 2006-01-02 15:04; it reports false for other times so they
+// can be understood as a correction instead.
+func setEventField(event *openai.Event, field, value string) (*openai.Event, bool) {
+	changed := *event
+	switch field {
+	case fieldTitle:
+		changed.Title = value
+	case fieldLocation:
+		changed.Location = value
+		changed.Geo = nil // The coordinates were of the old location
+	case fieldStart, fieldEnd:
+		current := event.StartTime
+		if field == fieldEnd {
+			current = event.EndTime
+		}
+		t, ok := parseEventTime(value, current)
+		if !ok {
+			return nil, false
+		}
+		changed.AllDay = false // A typed time gives the event a time of day
+		if field == fieldStart {
+			changed.EndTime = t.Add(event.EndTime.Sub(event.StartTime))
+			changed.StartTime = t
+		} else {
+			if !t.After(event.StartTime) {
+				return nil, false
+			}
+			changed.EndTime = t
+		}
+	default:
+		return nil, false
+	}
+	return &changed, true
+}
+
+// parseEventTime parses a time typed by the user in the timezone of the time it replaces
+func parseEventTime(value string, current time.Time) (time.Time, bool) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, current.Location()); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("15:04", value); err == nil {
+		return time.Date(current.Year(), current.Month(), current.Day(), t.Hour(), t.Minute(), 0, 0, current.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// canChangePreview reports whether a user may confirm, edit or cancel a preview: its sender,
+// or in a group anyone allowed to edit events
+func (b *Bot) canChangePreview(preview *eventPreview, userID int64) bool {
+	original := preview.extracted.message
+	if original.From.ID == userID {
+		return true
+	}
+	return !original.Chat.IsPrivate() && b.canPerformGroupAction(original.Chat.ID, userID, ActionEdit)
+}
+
+// removePreviewButtons removes the buttons of a preview once it's been answered
+func (b *Bot) removePreviewButtons(preview *eventPreview) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(preview.chatID, preview.messageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.bot.Request(edit); err != nil {
+		log.Printf("Error removing preview buttons: %v", err)
+	}
+}
+
+// handlePreviewReply applies a correction replied to a preview, reporting whether the message
+// was one. The event is extracted again from what was understood so far and the correction.
+func (b *Bot) handlePreviewReply(ctx context.Context, message *tgbotapi.Message) bool {
+	reply := message.ReplyToMessage
+	if message.Text == "" || message.From == nil || reply == nil || reply.From == nil || reply.From.ID != b.bot.Self.ID {
+		return false
+	}
+
+	key := previewKey(message.Chat.ID, reply.MessageID)
+	preview, ok := b.previews.Get(key)
+	if !ok || !b.canChangePreview(preview, message.From.ID) {
+		return false
+	}
+
+	userID := fmt.Sprintf("%d", preview.extracted.message.From.ID)
+	value := strings.TrimSpace(message.Text)
+
+	// A new title, location or time is set as typed, anything else is understood as a correction
+	if preview.field != "" {
+		if event, ok := setEventField(preview.extracted.event, preview.field, value); ok {
+			extracted := preview.extracted
+			extracted.event = event
+			b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID})
+			return true
+		}
+		value = fmt.Sprintf("The %s is %s", preview.field, value)
+	}
+
+	if !b.allowRequest(message) {
+		return true
+	}
+
+	corrected, err := b.correctEvent(ctx, userID, preview.extracted, value)
+	if err != nil {
+		log.Printf("Error correcting event of message %d: %v", preview.extracted.message.MessageID, err)
+		b.sendError(message, "preview.correction_failed", err)
+		return true
+	}
+
+	extracted := preview.extracted
+	extracted.event = corrected
+	b.updatePreview(userID, &eventPreview{extracted: extracted, chatID: preview.chatID, messageID: preview.messageID})
+	return true
+}
+
+// correctEvent extracts an event again from what was understood so far and a correction
+func (b *Bot) correctEvent(ctx context.Context, userID string, extracted extractedEvent, correction string) (*openai.Event, error) {
+	prefs := b.eventPreferences(userID, extracted.message.Chat.ID)
+	opts := eventOptions{refresh: true} // The cached result would ignore the correction
+	opts.extract.Timezone = b.userTimezone(prefs)
+	opts.extract.Language = prefs.Language
+
+	// The whole event goes along, so the fields the correction doesn't mention, such as the
+	// recurrence or reminders, come back unchanged
+	event := extracted.event
+	text := fmt.Sprintf("Correction: %s", correction)
+	opts.extract.Context = "Apply the correction to this event and keep everything else as it is:\n" + openai.EventJSON(event)
+
+	corrected, err := b.extractEventFromText(ctx, userID, text, opts)
+	if err != nil {
+		return nil, err
+	}
+	// What wasn't extracted doesn't change with a correction, apart from the coordinates of
+	// a new location
+	corrected.ContentDescription = event.ContentDescription
+	corrected.UID, corrected.Sequence = event.UID, event.Sequence
+	if corrected.Location == event.Location {
+		corrected.Geo = event.Geo
+	}
+	return corrected, nil
+}
@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// progressDelay is how long an extraction runs before a status message shows its progress;
+// quicker ones only show a chat action
+const progressDelay = 10 * time.Second
+
+// Stages of an image extraction shown in its status message, as the keys of their texts
+const (
+	stageDownloading = "progress.downloading"
+	stageAnalyzing   = "progress.analyzing"
+	stageGenerating  = "progress.generating"
+)
+
+// progressStatus shows the stage of a long extraction in a status message, which is only sent
+// once the extraction has taken longer than progressDelay. A nil progressStatus does nothing.
+type progressStatus struct {
+	b         *Bot
+	user      *tgbotapi.User // The user the status message is translated for
+	chatID    int64
+	replyTo   int
+	mu        sync.Mutex
+	stage     string
+	messageID int  // The status message, 0 until it's been sent
+	finished  bool // Whether the extraction is over, so no status message should be sent
+	timer     *time.Timer
+}
+
+// startProgress starts timing an extraction at its first stage
+func (b *Bot) startProgress(user *tgbotapi.User, chatID int64, replyTo int, stage string) *progressStatus {
+	p := &progressStatus{b: b, user: user, chatID: chatID, replyTo: replyTo, stage: stage}
+	p.timer = time.AfterFunc(progressDelay, p.show)
+	return p
+}
+
+// show sends the status message with the current stage
+func (p *progressStatus) show() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(p.chatID, p.b.t(p.user, p.stage))
+	msg.ReplyToMessageID = p.replyTo
+	sent, err := p.b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending progress message: %v", err)
+		return
+	}
+	p.messageID = sent.MessageID
+}
+
+// set moves the extraction to another stage, updating the status message if it's been sent
+func (p *progressStatus) set(stage string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished || p.stage == stage {
+		return
+	}
+	p.stage = stage
+
+	if p.messageID != 0 {
+		if _, err := p.b.bot.Send(tgbotapi.NewEditMessageText(p.chatID, p.messageID, p.b.t(p.user, stage))); err != nil {
+			log.Printf("Error updating progress message: %v", err)
+		}
+	}
+}
+
+// finish ends the extraction, removing the status message if it's been sent
+func (p *progressStatus) finish() {
+	if p == nil {
+		return
+	}
+	p.timer.Stop()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.finished = true
+
+	if p.messageID != 0 {
+		p.b.deleteMessage(p.chatID, p.messageID)
+	}
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleQRCode shows or changes whether a user also gets a QR code of each event, for sharing
+// it on a screen or letting others at a meeting scan it
+func (b *Bot) handleQRCode(ctx context.Context, message *tgbotapi.Message) {
+	b.handleToggle(message, toggleSetting{
+		key:  "qr",
+		name: "QR codes",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.QRCode },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.QRCode = enabled },
+	})
+}
+
+// sendEventQRCode sends a QR code that adds an event to the calendar of whoever scans it
+func (b *Bot) sendEventQRCode(message *tgbotapi.Message, event *openai.Event, loc *time.Location) {
+	png, err := calendar.QRCode(event, loc)
+	if err != nil {
+		log.Printf("Error creating QR code: %v", err)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(message.Chat.ID, tgbotapi.FileBytes{Name: "event.png", Bytes: png})
+	photo.Caption = b.t(message.From, "qr.caption", event.Title)
+	photo.ReplyToMessageID = message.MessageID
+	if _, err := b.bot.Send(photo); err != nil {
+		log.Printf("Error sending QR code: %v", err)
+	}
+}
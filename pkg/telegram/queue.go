@@ -0,0 +1,48 @@
+package telegram
+
+import "sync"
+
+// userQueue runs each user's handlers one at a time and in order, so concurrent messages from
+// the same user don't start conflicting runs on their OpenAI thread. Different users still
+// run in parallel.
+type userQueue struct {
+	mu      sync.Mutex
+	pending map[string][]func() // Handlers waiting per user; a key exists while the user's worker runs
+}
+
+// newUserQueue creates an empty queue
+func newUserQueue() *userQueue {
+	return &userQueue{pending: make(map[string][]func())}
+}
+
+// enqueue runs a handler after the user's earlier handlers have finished, starting a worker
+// for the user if none is running
+func (q *userQueue) enqueue(userID string, handler func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if waiting, running := q.pending[userID]; running {
+		q.pending[userID] = append(waiting, handler)
+		return
+	}
+
+	q.pending[userID] = nil
+	go q.work(userID, handler)
+}
+
+// work runs a user's handlers until none are left
+func (q *userQueue) work(userID string, handler func()) {
+	for handler != nil {
+		handler()
+
+		q.mu.Lock()
+		if waiting := q.pending[userID]; len(waiting) > 0 {
+			handler = waiting[0]
+			q.pending[userID] = waiting[1:]
+		} else {
+			delete(q.pending, userID)
+			handler = nil
+		}
+		q.mu.Unlock()
+	}
+}
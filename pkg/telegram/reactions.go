@@ -0,0 +1,41 @@
+package telegram
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Reactions that acknowledge a message without sending another one. ✅ isn't among the emoji
+// bots may react with, so 👌 marks a message whose event file was sent.
+const (
+	reactionSeen = "👀"
+	reactionDone = "👌"
+)
+
+// reactionType is a reaction as the Bot API expects it
+type reactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+// setReaction reacts to a message with an emoji, or removes the bot's reaction if emoji is
+// empty. The library predates setMessageReaction, so the request is made directly. Reactions
+// are only feedback, so failures, e.g. in chats that don't allow them, are just logged.
+func (b *Bot) setReaction(chatID int64, messageID int, emoji string) {
+	reactions := []reactionType{}
+	if emoji != "" {
+		reactions = append(reactions, reactionType{Type: "emoji", Emoji: emoji})
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	if err := params.AddInterface("reaction", reactions); err != nil {
+		log.Printf("Error encoding reaction: %v", err)
+		return
+	}
+	if _, err := b.bot.MakeRequest("setMessageReaction", params); err != nil {
+		log.Printf("Error reacting to message %d: %v", messageID, err)
+	}
+}
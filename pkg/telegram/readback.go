@@ -0,0 +1,180 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// readBackTTL is how long an event waits for the user to confirm it in read-back mode
+const readBackTTL = 30 * time.Minute
+
+// Callback data of the read-back buttons: "readback:<yes|no>:<original message ID>"
+const (
+	callbackReadBack = "readback"
+	readBackYes      = "yes"
+	readBackNo       = "no"
+)
+
+// handleReadBack shows or changes whether a user has to confirm each event before its
+// file is created
+func (b *Bot) handleReadBack(ctx context.Context, message *tgbotapi.Message) {
+	b.handleToggle(message, toggleSetting{
+		key:  "readback",
+		name: "read-back mode",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.ReadBack },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.ReadBack = enabled },
+	})
+}
+
+// requestReadBackConfirmation reads an extracted event back to the user and keeps it until
+// they confirm or reject it. A newer event replaces one that's still waiting.
+func (b *Bot) requestReadBackConfirmation(userID string, pending extractedEvent) {
+	message := pending.message
+	b.readBacks.Set(userID, pending)
+
+	prefs := b.eventPreferences(userID, message.Chat.ID)
+	timezone := b.formatTimezoneForDisplay(b.userTimezone(prefs))
+
+	warning := conflictWarning(pending.event, b.conflicts(userID, pending.event))
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.readBackText(message.From, pending.event, timezone, warning))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(message.From, "readback.button.yes"), readBackCallbackData(readBackYes, message.MessageID)),
+		tgbotapi.NewInlineKeyboardButtonData(b.t(message.From, "readback.button.no"), readBackCallbackData(readBackNo, message.MessageID)),
+	))
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending read-back message: %v", err)
+	}
+}
+
+// readBackCallbackData builds the callback data of a read-back button
+func readBackCallbackData(answer string, messageID int) string {
+	return callbackData(callbackReadBack, answer, strconv.Itoa(messageID))
+}
+
+// readBackText describes an event in full sentences, so mistakes stand out when reading it,
+// followed by a warning about clashing events if there is one
+func (b *Bot) readBackText(user *tgbotapi.User, event *openai.Event, timezone, warning string) string {
+	const day = "Monday, 2 January 2006"
+
+	var text strings.Builder
+	text.WriteString(b.t(user, "readback.header") + "\n\n")
+
+	isAllDay := calendar.IsAllDay(event)
+	switch {
+	case isAllDay && calendar.IsMultiDay(event):
+		text.WriteString(b.t(user, "readback.all_day_range", event.Title, event.StartTime.Format(day), calendar.LastDay(event).Format(day)))
+	case isAllDay:
+		text.WriteString(b.t(user, "readback.all_day", event.Title, event.StartTime.Format(day)))
+	case event.EndTime.Format("2006-01-02") == event.StartTime.Format("2006-01-02"):
+		text.WriteString(b.t(user, "readback.same_day", event.Title, event.StartTime.Format(day),
+			event.StartTime.Format("15:04"), event.EndTime.Format("15:04"), timezone))
+	default:
+		text.WriteString(b.t(user, "readback.range", event.Title, event.StartTime.Format(day), event.StartTime.Format("15:04"),
+			event.EndTime.Format(day), event.EndTime.Format("15:04"), timezone))
+	}
+
+	if event.Location != "" {
+		text.WriteString(b.t(user, "readback.location", event.Location))
+	}
+	text.WriteString(".")
+
+	if event.Description != "" {
+		text.WriteString("\n\n" + b.t(user, "readback.details", event.Description))
+	}
+
+	if warning != "" {
+		fmt.Fprintf(&text, "\n\n%s", warning)
+	}
+
+	text.WriteString("\n\n" + b.t(user, "readback.question"))
+	return text.String()
+}
+
+// readBackAnswers are the typed answers to a read-back, in the languages the bot knows
+var readBackAnswers = map[string]string{
+	"yes": readBackYes, "y": readBackYes, "yeah": readBackYes, "yep": readBackYes, "ok": readBackYes,
+	"да": readBackYes, "д": readBackYes, "ага": readBackYes, "верно": readBackYes,
+	"no": readBackNo, "n": readBackNo, "nope": readBackNo,
+	"нет": readBackNo, "н": readBackNo, "неверно": readBackNo,
+}
+
+// handleReadBackReply handles a typed yes or no to a pending read-back, reporting whether
+// the message was such an answer
+func (b *Bot) handleReadBackReply(message *tgbotapi.Message) bool {
+	if message.Text == "" || message.From == nil {
+		return false
+	}
+
+	answer, ok := readBackAnswers[strings.ToLower(strings.Trim(strings.TrimSpace(message.Text), ".!"))]
+	if !ok {
+		return false
+	}
+
+	userID := fmt.Sprintf("%d", message.From.ID)
+	pending, ok := b.readBacks.Get(userID)
+	if !ok || pending.message.Chat.ID != message.Chat.ID {
+		return false
+	}
+
+	b.answerReadBack(userID, pending, answer)
+	return true
+}
+
+// handleReadBackAnswer handles a press on one of the read-back buttons
+func (b *Bot) handleReadBackAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) != 2 {
+		return
+	}
+	messageID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	// Only the user who sent the event has it waiting, and only the latest one counts
+	userID := fmt.Sprintf("%d", query.From.ID)
+	pending, ok := b.readBacks.Get(userID)
+	if !ok || pending.message.MessageID != messageID {
+		b.answerCallback(query, b.t(query.From, "readback.expired"))
+		return
+	}
+
+	b.answerCallback(query, "")
+
+	// Remove the buttons so the event can't be confirmed twice
+	if query.Message != nil {
+		edit := tgbotapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+		if _, err := b.bot.Request(edit); err != nil {
+			log.Printf("Error removing read-back buttons: %v", err)
+		}
+	}
+
+	b.answerReadBack(userID, pending, args[0])
+}
+
+// answerReadBack creates the file for a confirmed event, or discards a rejected one
+func (b *Bot) answerReadBack(userID string, pending extractedEvent, answer string) {
+	b.readBacks.Delete(userID)
+	message := pending.message
+
+	if answer != readBackYes {
+		log.Printf("User %s rejected the read-back of message %d", userID, message.MessageID)
+		b.setReaction(message.Chat.ID, message.MessageID, "")
+		b.sendText(message.Chat.ID, b.t(message.From, "readback.discarded"), message.MessageID)
+		return
+	}
+
+	log.Printf("User %s confirmed the read-back of message %d", userID, message.MessageID)
+	b.deliverEvent(userID, pending)
+}
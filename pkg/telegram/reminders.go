@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxReminderMinutes is the earliest a reminder can be set before an event
+const maxReminderMinutes = 7 * 24 * 60
+
+// allDayReminderHour is the hour of the day all-day events are reminded of
+const allDayReminderHour = 9
+
+// handleReminder shows or changes how long before each event the user gets a reminder,
+// e.g. /reminder 30m, /reminder 1h or /reminder off
+func (b *Bot) handleReminder(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	args := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if args == "" {
+		prefs := b.getUserPreferences(userID)
+		minutes := prefs.ReminderMinutes
+
+		b.sendText(message.Chat.ID, b.t(message.From, "reminder.status", b.reminderLabel(message.From, minutes)), message.MessageID)
+		return
+	}
+
+	minutes, ok := parseReminderMinutes(args)
+	if !ok {
+		b.sendError(message, "reminder.usage", nil)
+		return
+	}
+
+	b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+		prefs.ReminderMinutes = minutes
+	})
+	log.Printf("Set default reminder of user %s to %d minutes", userID, minutes)
+	b.sendText(message.Chat.ID, b.t(message.From, "reminder.set", b.reminderLabel(message.From, minutes)), message.MessageID)
+}
+
+// parseReminderMinutes parses how long before an event to remind, like "30m", "2h", "1d" or
+// "off", into minutes
+func parseReminderMinutes(text string) (int, bool) {
+	switch text {
+	case "off", "none", "0":
+		return 0, true
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(text); err != nil {
+			return 0, false
+		}
+	}
+
+	minutes := int(d / time.Minute)
+	if minutes <= 0 || minutes > maxReminderMinutes {
+		return 0, false
+	}
+	return minutes, true
+}
+
+// scheduleReminder schedules a reminder before an event, as long before it as the user asked
+// for. Events extracted again aren't reminded of twice.
+func (b *Bot) scheduleReminder(entry storage.HistoryEntry, event *openai.Event, loc *time.Location, minutes int) {
+	if minutes <= 0 || entry.ID == 0 {
+		return
+	}
+
+	// Event times are wall-clock times in the user's timezone
+	start := event.StartTime
+	startsAt := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+	sendAt := startsAt.Add(-time.Duration(minutes) * time.Minute)
+	if calendar.IsAllDay(event) {
+		// All-day events are reminded of in the morning, of the day itself or the days before
+		sendAt = startsAt.AddDate(0, 0, -minutes/(24*60)).Add(allDayReminderHour * time.Hour)
+	}
+	if sendAt.Before(time.Now()) {
+		return // Too late for a reminder, e.g. an event that's about to start
+	}
+
+	text := event.Title + "\n" + startsAt.Format("Mon, 2 Jan 15:04")
+	if calendar.IsAllDay(event) {
+		text = event.Title + "\n" + startsAt.Format("Mon, 2 Jan")
+	}
+	if event.Location != "" {
+		text += "\n" + event.Location
+	}
+
+	for _, scheduled := range b.store.Scheduled(entry.UserID) {
+		if scheduled.Kind == storage.ScheduledReminder && scheduled.Text == text && scheduled.SendAt.Equal(sendAt) {
+			return
+		}
+	}
+
+	scheduled, err := b.store.AddScheduled(storage.ScheduledMessage{
+		Bot:       b.cfg.BotName,
+		UserID:    entry.UserID,
+		ChatID:    entry.ChatID,
+		Kind:      storage.ScheduledReminder,
+		Text:      text,
+		HistoryID: entry.ID,
+		Automatic: true,
+		SendAt:    sendAt,
+	})
+	if err != nil {
+		log.Printf("Error scheduling reminder for history entry %d: %v", entry.ID, err)
+		return
+	}
+	log.Printf("Scheduled reminder #%d for history entry %d at %s", scheduled.ID, entry.ID, sendAt.Format(time.RFC3339))
+}
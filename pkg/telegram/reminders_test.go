@@ -0,0 +1,33 @@
+package telegram
+
+import "testing"
+
+func TestParseReminderMinutes(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   int
+		wantOK bool
+	}{
+		{"off", 0, true},
+		{"none", 0, true},
+		{"0", 0, true},
+		{"30m", 30, true},
+		{"2h", 120, true},
+		{"1h30m", 90, true},
+		{"1d", 24 * 60, true},
+		{"7d", maxReminderMinutes, true},
+		{"8d", 0, false},
+		{"30s", 0, false},
+		{"-5m", 0, false},
+		{"30", 0, false},
+		{"soon", 0, false},
+		{"d", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseReminderMinutes(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseReminderMinutes(%q) = %d, %v, want %d, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleEventCommand extracts an event from the message /event replies to, e.g. a friend's
+// message in a group, or from the text after the command
+func (b *Bot) handleEventCommand(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.TrimSpace(message.CommandArguments())
+
+	if message.ReplyToMessage != nil {
+		input, opts, ok := b.replyContextInput(message, args)
+		if !ok {
+			b.sendError(message, "event_command.no_content", nil)
+			return
+		}
+		b.processEvent(ctx, input, opts)
+		return
+	}
+
+	if args == "" {
+		b.sendError(message, "event_command.usage", nil)
+		return
+	}
+	input := *message
+	input.Text = args
+	input.Entities = nil
+	b.processEvent(ctx, &input, eventOptions{})
+}
+
+// replyContextInput returns what to extract an event from when a message replies to another,
+// e.g. "make that 2 hours later": the replied-to message's text or media, sent by the replying
+// user, with the reply as context. A reply to one of the bot's event files uses the file's
+// caption and updates its event. It reports false if the replied-to message can't be used.
+func (b *Bot) replyContextInput(message *tgbotapi.Message, reply string) (*tgbotapi.Message, eventOptions, bool) {
+	original := message.ReplyToMessage
+	if original == nil || original.From == nil {
+		return nil, eventOptions{}, false
+	}
+
+	input := *original
+	if original.From.ID == b.bot.Self.ID {
+		// Only the bot's event files describe an event; its other messages are prompts
+		if original.Document == nil || !strings.HasSuffix(original.Document.FileName, ".ics") || original.Caption == "" {
+			return nil, eventOptions{}, false
+		}
+		input.Text, input.Entities = original.Caption, original.CaptionEntities
+		input.Document, input.Caption, input.CaptionEntities = nil, "", nil
+	}
+	if input.Text == "" && input.Caption == "" && input.Photo == nil && input.Document == nil &&
+		input.Voice == nil && input.Audio == nil && input.Video == nil && input.VideoNote == nil {
+		return nil, eventOptions{}, false
+	}
+
+	// The event is the replying user's, answered in reply to their own message
+	input.MessageID = message.MessageID
+	input.From = message.From
+	input.Chat = message.Chat
+	input.Date = message.Date
+	input.ReplyToMessage = nil
+
+	opts := eventOptions{replaces: b.replacedEntry(original)}
+	if reply != "" {
+		opts.extract.Context = "The sender replied to this message with the following, which corrects or adds to it:\n" + reply
+	}
+	return &input, opts, true
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandHandler handles a single incoming message
+type commandHandler func(ctx context.Context, message *tgbotapi.Message)
+
+// route describes a registered handler and the permissions it requires
+type route struct {
+	name      string
+	action    GroupAction // Empty if the route doesn't touch shared events
+	adminOnly bool        // Only the bot's admins may use the route
+	handler   commandHandler
+}
+
+// middleware wraps a route's handler with additional checks
+type middleware func(r route, next commandHandler) commandHandler
+
+// router dispatches messages to command handlers
+type router struct {
+	routes      map[string]route
+	fallback    route
+	middlewares []middleware
+}
+
+// newRouter creates an empty router
+func newRouter() *router {
+	return &router{
+		routes: make(map[string]route),
+	}
+}
+
+// handle registers a handler for a command
+func (r *router) handle(command string, action GroupAction, handler commandHandler) {
+	r.routes[command] = route{name: command, action: action, handler: handler}
+}
+
+// handleAdmin registers a handler for a command only the bot's admins may use
+func (r *router) handleAdmin(command string, handler commandHandler) {
+	r.routes[command] = route{name: command, adminOnly: true, handler: handler}
+}
+
+// handleDefault registers the handler for messages that don't match a command
+func (r *router) handleDefault(action GroupAction, handler commandHandler) {
+	r.fallback = route{name: "default", action: action, handler: handler}
+}
+
+// use appends a middleware to the chain; middlewares run in registration order
+func (r *router) use(mw middleware) {
+	r.middlewares = append(r.middlewares, mw)
+}
+
+// dispatch routes a message through the middleware chain to its handler
+func (r *router) dispatch(ctx context.Context, message *tgbotapi.Message) {
+	rt := r.fallback
+	if message.IsCommand() {
+		if registered, ok := r.routes[message.Command()]; ok {
+			rt = registered
+		}
+	}
+
+	if rt.handler == nil {
+		return
+	}
+
+	handler := rt.handler
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](rt, handler)
+	}
+	handler(ctx, message)
+}
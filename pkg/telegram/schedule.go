@@ -0,0 +1,313 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/scheduler"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxScheduledPerUser limits how many pending messages a user can schedule themselves
+const maxScheduledPerUser = 20
+
+// scheduledGiveUpAfter is how long delivery of a scheduled message is retried before it's dropped
+const scheduledGiveUpAfter = 24 * time.Hour
+
+// Times used for the named parts of the day in /schedule
+var dayPartTimes = map[string]string{
+	"morning":   "09:00",
+	"noon":      "12:00",
+	"afternoon": "14:00",
+	"evening":   "19:00",
+	"night":     "21:00",
+}
+
+// handleSchedule schedules a reply-to event file, or a reminder about it, for later delivery
+func (b *Bot) handleSchedule(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	usage := b.t(message.From, "schedule.usage")
+
+	original := message.ReplyToMessage
+	if original == nil || original.From == nil || original.From.ID != b.bot.Self.ID ||
+		original.Document == nil || !strings.EqualFold(filepath.Ext(original.Document.FileName), ".ics") {
+		b.sendText(chatID, usage, message.MessageID)
+		return
+	}
+
+	args := strings.Fields(strings.ToLower(message.CommandArguments()))
+	kind := storage.ScheduledFile
+	if len(args) > 0 && args[0] == storage.ScheduledReminder {
+		kind = storage.ScheduledReminder
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		b.sendText(chatID, usage, message.MessageID)
+		return
+	}
+
+	if len(b.userScheduled(userID)) >= maxScheduledPerUser {
+		b.sendError(message, "schedule.limit", nil, maxScheduledPerUser)
+		return
+	}
+
+	loc, err := b.timezones.Resolve(b.userTimezone(b.getUserPreferences(userID)))
+	if err != nil {
+		log.Printf("Using fallback timezone for scheduling: %v", err)
+	}
+	when := strings.Join(args, " ")
+	sendAt, err := parseScheduleTime(when, time.Now().In(loc))
+	switch {
+	case errors.Is(err, errScheduleTimePassed):
+		b.sendError(message, "schedule.time_passed", nil)
+		return
+	case err != nil:
+		b.sendError(message, "schedule.time_invalid", nil, when)
+		return
+	}
+
+	// The file's event lets /delete drop the message along with the event
+	var historyID int64
+	if entries := b.store.HistoryByMessage(b.cfg.BotName, chatID, original.MessageID); len(entries) > 0 {
+		historyID = entries[0].ID
+	}
+
+	scheduled, err := b.store.AddScheduled(storage.ScheduledMessage{
+		Bot:       b.cfg.BotName,
+		UserID:    userID,
+		ChatID:    chatID,
+		Kind:      kind,
+		FileID:    original.Document.FileID,
+		Text:      original.Caption,
+		HistoryID: historyID,
+		SendAt:    sendAt,
+	})
+	if err != nil {
+		log.Printf("Error saving scheduled message: %v", err)
+		b.sendError(message, "schedule.save_failed", err)
+		return
+	}
+	log.Printf("Scheduled %s #%d for user %s at %s", kind, scheduled.ID, userID, sendAt.Format(time.RFC3339))
+
+	key := "schedule.scheduled_file"
+	if kind == storage.ScheduledReminder {
+		key = "schedule.scheduled_reminder"
+	}
+	b.sendText(chatID, b.t(message.From, key, sendAt.Format("Mon, 2 Jan 15:04"), b.formatTimezoneForDisplay(loc.String())), message.MessageID)
+}
+
+// handleScheduled lists the user's pending scheduled messages
+func (b *Bot) handleScheduled(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	scheduled := b.userScheduled(userID)
+	if len(scheduled) == 0 {
+		b.sendText(message.Chat.ID, b.t(message.From, "scheduled.empty"), message.MessageID)
+		return
+	}
+
+	loc, _ := b.timezones.Resolve(b.userTimezone(b.getUserPreferences(userID)))
+
+	var sb strings.Builder
+	sb.WriteString(b.t(message.From, "scheduled.header") + "\n")
+	for _, msg := range scheduled {
+		summary := strings.SplitN(msg.Text, "\n", 2)[0]
+		sb.WriteString(fmt.Sprintf("\n#%d · %s · %s\n%s\n", msg.ID, msg.Kind, msg.SendAt.In(loc).Format("Mon, 2 Jan 15:04"), summary))
+	}
+	sb.WriteString("\n" + b.t(message.From, "scheduled.footer"))
+
+	b.sendText(message.Chat.ID, sb.String(), message.MessageID)
+}
+
+// userScheduled returns the pending messages the user scheduled with /schedule, leaving out the
+// reminders and follow-ups the bot schedules for their events
+func (b *Bot) userScheduled(userID string) []storage.ScheduledMessage {
+	var scheduled []storage.ScheduledMessage
+	for _, msg := range b.store.Scheduled(userID) {
+		if msg.UserCreated() {
+			scheduled = append(scheduled, msg)
+		}
+	}
+	return scheduled
+}
+
+// handleUnschedule cancels one of the user's scheduled messages
+func (b *Bot) handleUnschedule(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(message.CommandArguments()), "#"), 10, 64)
+	if err != nil {
+		b.sendText(message.Chat.ID, b.t(message.From, "unschedule.usage"), message.MessageID)
+		return
+	}
+
+	// Users can only cancel their own messages, the bot's go with their events
+	owned := false
+	for _, msg := range b.userScheduled(userID) {
+		if msg.ID == id {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		b.sendError(message, "unschedule.not_found", nil, id)
+		return
+	}
+
+	if _, err := b.store.DeleteScheduled(id); err != nil {
+		log.Printf("Error deleting scheduled message %d: %v", id, err)
+		b.sendError(message, "unschedule.failed", err)
+		return
+	}
+	b.sendText(message.Chat.ID, b.t(message.From, "unschedule.cancelled", id), message.MessageID)
+}
+
+// scheduledMessagesJob is the scheduler job that delivers due scheduled messages
+const scheduledMessagesJob = "scheduled_messages"
+
+// RegisterJobs registers the bot's background jobs with the scheduler
+func (b *Bot) RegisterJobs(s *scheduler.Scheduler) error {
+	b.scheduler = s
+	s.Handle(b.jobKind(digestJob), b.runDigest)
+	s.Handle(b.jobKind(purgeJob), b.runPurge)
+	return s.Every(b.jobKind(scheduledMessagesJob), b.cfg.SchedulerInterval, func(ctx context.Context) error {
+		b.deliverDueMessages()
+		return nil
+	})
+}
+
+// jobKind returns the scheduler kind of one of the bot's jobs; each bot in the process runs
+// its own
+func (b *Bot) jobKind(kind string) string {
+	if b.cfg.BotName == "" {
+		return kind
+	}
+	return b.cfg.BotName + "/" + kind
+}
+
+// deliverDueMessages sends all scheduled messages that are due. Messages are only removed once
+// sent, so anything due while the bot was down is delivered after a restart.
+func (b *Bot) deliverDueMessages() {
+	now := time.Now()
+	for _, msg := range b.store.DueScheduled(b.cfg.BotName, now) {
+		if err := b.deliverScheduled(msg); err != nil {
+			log.Printf("Error delivering scheduled message %d: %v", msg.ID, err)
+			if now.Sub(msg.SendAt) < scheduledGiveUpAfter {
+				continue // Try again on the next tick
+			}
+			log.Printf("Giving up on scheduled message %d", msg.ID)
+		}
+
+		if _, err := b.store.DeleteScheduled(msg.ID); err != nil {
+			log.Printf("Error deleting scheduled message %d: %v", msg.ID, err)
+		}
+	}
+}
+
+// deliverScheduled sends a single scheduled message
+func (b *Bot) deliverScheduled(msg storage.ScheduledMessage) error {
+	// Only the user's chosen language is known here, not their Telegram app's
+	var user *tgbotapi.User
+	if id, err := strconv.ParseInt(msg.UserID, 10, 64); err == nil {
+		user = &tgbotapi.User{ID: id}
+	}
+
+	var chattable tgbotapi.Chattable
+	switch msg.Kind {
+	case storage.ScheduledReminder:
+		chattable = tgbotapi.NewMessage(msg.ChatID, b.t(user, "schedule.delivered_reminder")+"\n\n"+msg.Text)
+	case storage.ScheduledFollowUp:
+		chattable = b.followUpMessage(user, msg)
+	default:
+		doc := tgbotapi.NewDocument(msg.ChatID, tgbotapi.FileID(msg.FileID))
+		doc.Caption = b.t(user, "schedule.delivered_file") + "\n\n" + msg.Text
+		chattable = doc
+	}
+
+	if _, err := b.bot.Send(chattable); err != nil {
+		return err
+	}
+	log.Printf("Delivered scheduled %s #%d to chat %d", msg.Kind, msg.ID, msg.ChatID)
+	return nil
+}
+
+var (
+	errScheduleTimeInvalid = errors.New("invalid schedule time")
+	errScheduleTimePassed  = errors.New("schedule time has already passed")
+)
+
+// parseScheduleTime parses when to send a scheduled message, relative to now in the user's timezone.
+// It understands "in 2h", "[today|tomorrow|YYYY-MM-DD] [at] [HH:MM|morning|afternoon|evening]".
+func parseScheduleTime(text string, now time.Time) (time.Time, error) {
+	text = strings.TrimSpace(strings.ToLower(text))
+	invalid := fmt.Errorf("%w: %q", errScheduleTimeInvalid, text)
+
+	// Relative durations
+	if rest, ok := strings.CutPrefix(text, "in "); ok {
+		d, err := time.ParseDuration(strings.ReplaceAll(rest, " ", ""))
+		if err != nil || d <= 0 {
+			return time.Time{}, invalid
+		}
+		return now.Add(d), nil
+	}
+
+	var fields []string
+	for _, field := range strings.Fields(text) {
+		if field != "at" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, invalid
+	}
+
+	day := now
+	explicitDay := true
+	switch fields[0] {
+	case "today":
+	case "tomorrow":
+		day = now.AddDate(0, 0, 1)
+	default:
+		date, err := time.ParseInLocation("2006-01-02", fields[0], now.Location())
+		if err != nil {
+			explicitDay = false
+			break
+		}
+		day = date
+	}
+	if explicitDay {
+		fields = fields[1:]
+	}
+
+	clock := "09:00"
+	if len(fields) > 1 {
+		return time.Time{}, invalid
+	}
+	if len(fields) == 1 {
+		clock = fields[0]
+		if named, ok := dayPartTimes[clock]; ok {
+			clock = named
+		}
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, invalid
+	}
+
+	sendAt := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !explicitDay && !sendAt.After(now) {
+		// A bare time that has already passed today means tomorrow
+		sendAt = sendAt.AddDate(0, 0, 1)
+	}
+	if !sendAt.After(now) {
+		return time.Time{}, errScheduleTimePassed
+	}
+	return sendAt, nil
+}
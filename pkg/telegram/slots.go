@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+)
+
+// extractionSlots limits how many extractions run at the same time. When all slots are taken,
+// requests wait in line, and premium users' requests are let in before everyone else's.
+type extractionSlots struct {
+	limit   int // Extractions running at the same time, 0 if unlimited
+	mu      sync.Mutex
+	running int
+	premium []chan struct{} // Premium requests waiting for a slot, in order
+	free    []chan struct{} // Other requests waiting for a slot, in order
+}
+
+// newExtractionSlots creates slots for limit extractions at the same time
+func newExtractionSlots(limit int) *extractionSlots {
+	return &extractionSlots{limit: limit}
+}
+
+// acquire waits for a free slot and returns the function that gives it back. Premium requests
+// skip ahead of other waiting requests.
+func (s *extractionSlots) acquire(ctx context.Context, premium bool) (func(), error) {
+	if s.limit <= 0 {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	if s.running < s.limit {
+		s.running++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	ready := make(chan struct{})
+	if premium {
+		s.premium = append(s.premium, ready)
+	} else {
+		s.free = append(s.free, ready)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if removeWaiting(&s.premium, ready) || removeWaiting(&s.free, ready) {
+			return nil, ctx.Err()
+		}
+		// The slot was handed over just now, so pass it on
+		s.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+// release gives a slot back, handing it to the next waiting request if there is one
+func (s *extractionSlots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked gives a slot back; the caller must hold the lock
+func (s *extractionSlots) releaseLocked() {
+	for _, queue := range []*[]chan struct{}{&s.premium, &s.free} {
+		if len(*queue) > 0 {
+			next := (*queue)[0]
+			*queue = (*queue)[1:]
+			close(next) // The slot stays taken, now by the next request
+			return
+		}
+	}
+	s.running--
+}
+
+// removeWaiting removes a waiting request from a queue, reporting whether it was still in it
+func removeWaiting(queue *[]chan struct{}, ready chan struct{}) bool {
+	for i, waiting := range *queue {
+		if waiting == ready {
+			*queue = append((*queue)[:i], (*queue)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleStats shows admins how much the bot is used, how well it performs and how many
+// OpenAI tokens it spends
+func (b *Bot) handleStats(ctx context.Context, message *tgbotapi.Message) {
+	now := time.Now()
+	periods := []statsPeriod{
+		{"Today", b.store.UsageSince(now)},
+		{"7 days", b.store.UsageSince(now.AddDate(0, 0, -6))},
+		{"30 days", b.store.UsageSince(now.AddDate(0, 0, -29))},
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, statsReport(len(b.store.Users()), periods))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.bot.Send(msg); err != nil {
+		b.sendErrorMessage(message.Chat.ID, fmt.Errorf("failed to send stats: %w", err), message.MessageID)
+	}
+}
+
+// statsPeriod is the usage of one row of /stats
+type statsPeriod struct {
+	label string
+	usage storage.UsageStats
+}
+
+// statsReport formats the usage of each period as a table, as HTML
+func statsReport(knownUsers int, periods []statsPeriod) string {
+	var table strings.Builder
+	fmt.Fprintf(&table, "%-8s %5s %6s %6s %7s %9s\n", "", "Users", "Events", "Errors", "Latency", "Tokens")
+	for _, p := range periods {
+		u := p.usage
+		fmt.Fprintf(&table, "%-8s %5d %6d %5.0f%% %6.1fs %9d\n",
+			p.label, u.ActiveUsers, u.Extractions, 100*u.ErrorRate(), u.AverageLatency().Seconds(), u.PromptTokens+u.CompletionTokens)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Usage</b>\n")
+	fmt.Fprintf(&sb, "%d users have set preferences. Days are in UTC.\n", knownUsers)
+	fmt.Fprintf(&sb, "<pre>%s</pre>\n", html.EscapeString(table.String()))
+	sb.WriteString("Users: active users. Events: extractions sent to OpenAI, not counting cached ones. Latency: average per extraction. Tokens: billed to the operator's key.")
+	return sb.String()
+}
+
+// recordExtraction counts an extraction sent to OpenAI, which started at start, in the usage stats
+func (b *Bot) recordExtraction(userID string, start time.Time, err error) {
+	if err := b.store.RecordExtraction(userID, time.Since(start), err != nil); err != nil {
+		log.Printf("Error recording extraction: %v", err)
+	}
+}
+
+// recordTokens counts the tokens of an OpenAI run in the usage stats
+func (b *Bot) recordTokens(userID string, promptTokens, completionTokens int64) {
+	if err := b.store.RecordTokens(userID, promptTokens, completionTokens); err != nil {
+		log.Printf("Error recording token usage: %v", err)
+	}
+}
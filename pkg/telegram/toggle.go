@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// toggleSetting is an on/off user setting, shown with /<command> and changed with
+// /<command> on or /<command> off
+type toggleSetting struct {
+	key      string // Prefix of its catalog keys: <key>.status_on, .status_off, .usage, .turned_on and .turned_off
+	name     string // What the setting turns on, for the log
+	turnedOn string // Catalog key of the reply to turning it on, if not <key>.turned_on
+	get      func(prefs *storage.UserPreferences) bool
+	set      func(prefs *storage.UserPreferences, enabled bool)
+	extra    func(user *tgbotapi.User) string // Optional text added to the status and to the reply to turning it on
+}
+
+// handleToggle shows or changes a user's on/off setting in reply to its command
+func (b *Bot) handleToggle(message *tgbotapi.Message, setting toggleSetting) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		prefs := b.getUserPreferences(userID)
+		enabled = setting.get(prefs)
+
+		key := setting.key + ".status_off"
+		if enabled {
+			key = setting.key + ".status_on"
+		}
+		b.sendText(message.Chat.ID, b.withToggleExtra(message.From, setting, b.t(message.From, key)), message.MessageID)
+		return
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		b.sendError(message, setting.key+".usage", nil)
+		return
+	}
+
+	b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+		setting.set(prefs, enabled)
+	})
+	log.Printf("Set %s for user %s to %t", setting.name, userID, enabled)
+
+	if !enabled {
+		b.sendText(message.Chat.ID, b.t(message.From, setting.key+".turned_off"), message.MessageID)
+		return
+	}
+	key := setting.turnedOn
+	if key == "" {
+		key = setting.key + ".turned_on"
+	}
+	b.sendText(message.Chat.ID, b.withToggleExtra(message.From, setting, b.t(message.From, key)), message.MessageID)
+}
+
+// withToggleExtra adds a setting's extra text, if it has any, to a reply
+func (b *Bot) withToggleExtra(user *tgbotapi.User, setting toggleSetting, text string) string {
+	if setting.extra == nil {
+		return text
+	}
+	if extra := setting.extra(user); extra != "" {
+		return text + "\n\n" + extra
+	}
+	return text
+}
@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/cache"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// topicMemory is how long the topic of a message in a forum is remembered, for replies to it
+const topicMemory = 7 * 24 * time.Hour
+
+// topicMessage is the part of a message that tells which forum topic it's in. The library
+// predates forum topics, so this is read from the raw updates.
+type topicMessage struct {
+	MessageID      int  `json:"message_id"`
+	ThreadID       int  `json:"message_thread_id"`
+	IsTopicMessage bool `json:"is_topic_message"`
+	Chat           struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+// topicUpdate is the part of an update with the messages that can be in a forum topic
+type topicUpdate struct {
+	Message       *topicMessage `json:"message"`
+	EditedMessage *topicMessage `json:"edited_message"`
+	CallbackQuery *struct {
+		Message *topicMessage `json:"message"`
+	} `json:"callback_query"`
+}
+
+// topicClient keeps replies in forum topics. It remembers the topic of each message the bot
+// receives or sends in a forum, and posts every message that replies to one of them into the
+// same topic; without message_thread_id, Telegram would post it into the General topic.
+type topicClient struct {
+	next   tgbotapi.HTTPClient
+	topics *cache.TTL[int] // Map of chat ID:message ID -> topic of the message
+}
+
+// newTopicClient wraps the HTTP client the bot sends its requests with
+func newTopicClient(next tgbotapi.HTTPClient) *topicClient {
+	return &topicClient{next: next, topics: cache.NewTTL[int](topicMemory)}
+}
+
+// Do sends a request to the Bot API, adding the topic of the message it replies to
+func (c *topicClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	if strings.HasPrefix(method, "send") || method == "copyMessage" {
+		if err := c.addTopic(req); err != nil {
+			log.Printf("Error adding topic to %s: %v", method, err)
+		}
+	}
+
+	resp, err := c.next.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	// Learn the topics of the messages received and sent
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, nil // The library reports the broken body
+	}
+	if method == "getUpdates" {
+		var result struct {
+			Result []json.RawMessage `json:"result"`
+		}
+		if json.Unmarshal(body, &result) == nil {
+			for _, update := range result.Result {
+				c.recordUpdate(update)
+			}
+		}
+	} else if strings.HasPrefix(method, "send") {
+		var result struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if json.Unmarshal(body, &result) == nil {
+			c.recordMessage(result.Result)
+		}
+	}
+	return resp, nil
+}
+
+// recordUpdate remembers the topics of the messages in an update
+func (c *topicClient) recordUpdate(data []byte) {
+	var update topicUpdate
+	if err := json.Unmarshal(data, &update); err != nil {
+		return
+	}
+	c.remember(update.Message)
+	c.remember(update.EditedMessage)
+	if update.CallbackQuery != nil {
+		c.remember(update.CallbackQuery.Message)
+	}
+}
+
+// recordMessage remembers the topic of a message the bot sent
+func (c *topicClient) recordMessage(data []byte) {
+	var message topicMessage
+	if err := json.Unmarshal(data, &message); err == nil {
+		c.remember(&message)
+	}
+}
+
+// remember stores the topic of a message if it's in one
+func (c *topicClient) remember(message *topicMessage) {
+	if message == nil || !message.IsTopicMessage || message.ThreadID == 0 {
+		return
+	}
+	c.topics.Set(fmt.Sprintf("%d:%d", message.Chat.ID, message.MessageID), message.ThreadID)
+}
+
+// addTopic sets message_thread_id on a request that replies to a message in a topic. The Bot
+// API reads parameters from the query string as well as the body, so it's added to the URL
+// and the body is left as the library built it.
+func (c *topicClient) addTopic(req *http.Request) error {
+	query := req.URL.Query()
+	if req.Body == nil || query.Has("message_thread_id") {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	if err != nil {
+		return err
+	}
+
+	params, err := requestParams(req.Header.Get("Content-Type"), body)
+	if err != nil || params.Has("message_thread_id") {
+		return err
+	}
+	replyTo, _ := strconv.Atoi(params.Get("reply_to_message_id"))
+	if replyTo == 0 {
+		return nil
+	}
+	topic, ok := c.topics.Get(params.Get("chat_id") + ":" + strconv.Itoa(replyTo))
+	if !ok {
+		return nil
+	}
+
+	query.Set("message_thread_id", strconv.Itoa(topic))
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// requestParams returns the form fields of a request body, leaving out uploaded files
+func requestParams(contentType string, body []byte) (url.Values, error) {
+	mediaType, mediaParams, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/form-data" {
+		return url.ParseQuery(string(body))
+	}
+
+	params := url.Values{}
+	reader := multipart.NewReader(bytes.NewReader(body), mediaParams["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return params, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		params.Add(part.FormName(), string(value))
+	}
+}
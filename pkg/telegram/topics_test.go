@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+// multipartRequest builds a multipart request of a Bot API method with a file, like uploads
+func multipartRequest(method string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	file, _ := w.CreateFormFile("document", "event.ics")
+	file.Write([]byte("BEGIN:VCALENDAR"))
+	w.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.telegram.org/botTOKEN/"+method, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestTopicClient(t *testing.T) {
+	const updates = `{"ok":true,"result":[
+		{"update_id":1,"message":{"message_id":10,"message_thread_id":7,"is_topic_message":true,"chat":{"id":-100}}},
+		{"update_id":2,"message":{"message_id":11,"chat":{"id":-100}}},
+		{"update_id":3,"callback_query":{"id":"q","message":{"message_id":12,"message_thread_id":8,"is_topic_message":true,"chat":{"id":-100}}}}
+	]}`
+	const sent = `{"ok":true,"result":{"message_id":20,"message_thread_id":9,"is_topic_message":true,"chat":{"id":-100}}}`
+
+	tests := []struct {
+		name      string
+		req       *http.Request
+		wantTopic string
+	}{
+		{"reply to a topic message", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi&reply_to_message_id=10"), "7"},
+		{"edit, which stays in its topic", botRequest(context.Background(), "editMessageText", "chat_id=-100&message_id=12&text=hi"), ""},
+		{"reply to a message outside topics", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi&reply_to_message_id=11"), ""},
+		{"reply to an unknown message", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi&reply_to_message_id=99"), ""},
+		{"reply in another chat", botRequest(context.Background(), "sendMessage", "chat_id=-200&text=hi&reply_to_message_id=10"), ""},
+		{"not a reply", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi"), ""},
+		{"topic already set", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi&reply_to_message_id=10&message_thread_id=3"), ""},
+		{"upload replying to a topic message", multipartRequest("sendDocument", map[string]string{"chat_id": "-100", "reply_to_message_id": "12"}), "8"},
+		{"reply to the bot's own message", botRequest(context.Background(), "sendMessage", "chat_id=-100&text=hi&reply_to_message_id=20"), "9"},
+	}
+
+	transport := &fakeTransport{responses: []fakeResponse{{200, updates}, {200, sent}, {200, okResponse}}}
+	client := newTopicClient(transport)
+	for _, req := range []*http.Request{
+		botRequest(context.Background(), "getUpdates", "offset=0"),
+		botRequest(context.Background(), "sendMessage", "chat_id=-100&text=first"),
+	} {
+		if _, err := client.Do(req); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := requestParams(tt.req.Header.Get("Content-Type"), mustReadBody(t, tt.req))
+			if err != nil {
+				t.Fatalf("requestParams() error = %v", err)
+			}
+
+			if _, err := client.Do(tt.req); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			last := transport.requests[len(transport.requests)-1]
+			if got := last.URL.Query().Get("message_thread_id"); got != tt.wantTopic {
+				t.Errorf("message_thread_id = %q, want %q", got, tt.wantTopic)
+			}
+			// The body is passed on as the library built it
+			got, err := requestParams(last.Header.Get("Content-Type"), []byte(transport.bodies[len(transport.bodies)-1]))
+			if err != nil || got.Encode() != want.Encode() {
+				t.Errorf("body params = %v, %v, want %v", got, err, want)
+			}
+		})
+	}
+}
+
+// mustReadBody reads a request's body and puts it back, so the request can still be sent
+func mustReadBody(t *testing.T, req *http.Request) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(req.Body); err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	req.Body.Close()
+	data := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data
+}
@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/geocode"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxTravelBuffer is the longest fixed travel time that can be set
+const maxTravelBuffer = 4 * time.Hour
+
+// handleTravel shows or changes the travel time blocked before events, either a fixed buffer
+// or estimated from the user's home, e.g. /travel 30m, /travel home Alexanderplatz 1, Berlin
+// or /travel off
+func (b *Bot) handleTravel(ctx context.Context, message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	args := strings.TrimSpace(message.CommandArguments())
+	command, rest, _ := strings.Cut(args, " ")
+	command = strings.ToLower(command)
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case command == "":
+		prefs := b.getUserPreferences(userID)
+		label := b.travelLabel(message.From, prefs)
+
+		b.sendText(message.Chat.ID, b.t(message.From, "travel.status", label), message.MessageID)
+		return
+
+	case command == "off":
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.TravelMinutes = 0
+			prefs.HomeLocation = ""
+			prefs.Home = nil
+		})
+
+	case command == "home" && strings.ToLower(rest) == "off":
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.HomeLocation = ""
+			prefs.Home = nil
+		})
+
+	case command == "home" && rest != "":
+		if b.geocoder == nil {
+			b.sendError(message, "travel.no_geocoder", nil)
+			return
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, geocodeTimeout)
+		place, err := b.geocoder.Geocode(lookupCtx, rest)
+		cancel()
+		if errors.Is(err, geocode.ErrNotFound) {
+			b.sendError(message, "travel.home_not_found", nil, rest)
+			return
+		}
+		if err != nil {
+			log.Printf("Error geocoding home of user %s: %v", userID, err)
+			b.sendError(message, "travel.home_not_found", err, rest)
+			return
+		}
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.HomeLocation = normalizedLocation(rest, place.Address)
+			prefs.Home = &openai.Geo{Lat: place.Lat, Lon: place.Lon}
+		})
+
+	default:
+		d, err := time.ParseDuration(strings.ToLower(args))
+		if err != nil || d < time.Minute || d > maxTravelBuffer {
+			b.sendError(message, "travel.usage", nil)
+			return
+		}
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.TravelMinutes = int(d / time.Minute)
+		})
+	}
+
+	prefs := b.getUserPreferences(userID)
+	label := b.travelLabel(message.From, prefs)
+
+	log.Printf("Set travel time of user %s to %s", userID, label)
+	b.sendText(message.Chat.ID, b.t(message.From, "travel.set", label), message.MessageID)
+}
+
+// travelLabel describes the travel time of a user's preferences, which the caller has to hold
+// the preferences lock for
+func (b *Bot) travelLabel(user *tgbotapi.User, prefs *storage.UserPreferences) string {
+	switch {
+	case prefs.Home != nil && prefs.TravelMinutes > 0:
+		return b.t(user, "travel.home_or_minutes", prefs.HomeLocation, prefs.TravelMinutes)
+	case prefs.Home != nil:
+		return b.t(user, "travel.home", prefs.HomeLocation)
+	case prefs.TravelMinutes > 0:
+		return b.t(user, "travel.minutes", prefs.TravelMinutes)
+	default:
+		return b.t(user, "travel.off")
+	}
+}
+
+// withTravel returns events with a "Travel to" event before each one that takes place
+// somewhere, if the user asked for travel time. The time is estimated from the user's home
+// when both places are known, and otherwise the user's fixed travel time.
+func (b *Bot) withTravel(user *tgbotapi.User, prefs *storage.UserPreferences, events []*openai.Event) []*openai.Event {
+	if prefs.Home == nil && prefs.TravelMinutes == 0 {
+		return events
+	}
+
+	withTravel := make([]*openai.Event, 0, 2*len(events))
+	for _, event := range events {
+		if minutes := travelMinutes(prefs, event); minutes > 0 {
+			withTravel = append(withTravel, calendar.TravelEvent(event, b.t(user, "travel.title", event.Title), minutes))
+		}
+		withTravel = append(withTravel, event)
+	}
+	return withTravel
+}
+
+// travelMinutes returns how long getting to an event takes, or 0 if there's no travel to
+// block: for all-day and online events, and events at or near home
+func travelMinutes(prefs *storage.UserPreferences, event *openai.Event) int {
+	if calendar.IsAllDay(event) || !calendar.HasPlace(event) {
+		return 0
+	}
+	if prefs.Home != nil && event.Geo != nil {
+		if minutes, ok := calendar.EstimateTravelMinutes(prefs.Home, event.Geo); ok {
+			return minutes
+		}
+	}
+	return prefs.TravelMinutes
+}
@@ -0,0 +1,223 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-assistant/pkg/timezone"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data of the timezone picker: "tz:regions", "tz:region:<region index>:<page>",
+// "tz:set:<timezone>" or "tz:search"
+const (
+	callbackTimezone = "tz"
+	tzRegions        = "regions"
+	tzRegion         = "region"
+	tzSet            = "set"
+	tzSearch         = "search"
+)
+
+// tzPageSize is how many cities of a region the picker shows at once
+const tzPageSize = 8
+
+// timezoneSearchTTL is how long the picker waits for a city name after "Search by city"
+const timezoneSearchTTL = 10 * time.Minute
+
+// timezonePicker creates the inline keyboard to pick a timezone: one-tap suggestions, the
+// regions to page through, and a search by city name
+func (b *Bot) timezonePicker(user *tgbotapi.User, suggestions ...string) tgbotapi.InlineKeyboardMarkup {
+	rows := timezoneButtonRows(suggestions)
+
+	var row []tgbotapi.InlineKeyboardButton
+	for i, region := range timezone.Regions {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.t(user, "tzpicker.region_name."+region.Name),
+			callbackData(callbackTimezone, tzRegion, strconv.Itoa(i), "0")))
+		if len(row) == 2 || i == len(timezone.Regions)-1 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(user, "tzpicker.search_button"), callbackData(callbackTimezone, tzSearch)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// suggestedTimezonePicker creates the timezone picker with suggestions for the sender of a message
+func (b *Bot) suggestedTimezonePicker(message *tgbotapi.Message) tgbotapi.InlineKeyboardMarkup {
+	return b.timezonePicker(message.From, b.suggestTimezones(message)...)
+}
+
+// timezoneButtonRows creates buttons that set the given timezones, two per row
+func timezoneButtonRows(zones []string) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(zones); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(timezoneButton(zones[i]))
+		if i+1 < len(zones) {
+			row = append(row, timezoneButton(zones[i+1]))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// timezoneButton creates a button that sets a timezone, labelled with its city
+func timezoneButton(zone string) tgbotapi.InlineKeyboardButton {
+	label := zone
+	if strings.Contains(zone, "/") {
+		label = timezone.City(zone)
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackTimezone, tzSet, zone))
+}
+
+// regionPage creates the text and buttons of a page of a region's cities
+func (b *Bot) regionPage(user *tgbotapi.User, index, page int) (string, tgbotapi.InlineKeyboardMarkup) {
+	region := timezone.Regions[index]
+	pages := (len(region.Zones) + tzPageSize - 1) / tzPageSize
+	page = max(0, min(page, pages-1))
+
+	zones := region.Zones[page*tzPageSize : min((page+1)*tzPageSize, len(region.Zones))]
+	rows := timezoneButtonRows(zones)
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️", callbackData(callbackTimezone, tzRegion, strconv.Itoa(index), strconv.Itoa(page-1))))
+	}
+	nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(b.t(user, "tzpicker.regions_button"), callbackData(callbackTimezone, tzRegions)))
+	if page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("▶️", callbackData(callbackTimezone, tzRegion, strconv.Itoa(index), strconv.Itoa(page+1))))
+	}
+	rows = append(rows, nav)
+
+	text := b.t(user, "tzpicker.region", b.t(user, "tzpicker.region_name."+region.Name), page+1, pages)
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleTimezoneAnswer handles a press on one of the timezone picker's buttons. Each user who
+// presses a button picks their own timezone, so the picker works in groups too.
+func (b *Bot) handleTimezoneAnswer(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) {
+	if len(args) == 0 || query.Message == nil {
+		return
+	}
+	chatID, messageID := query.Message.Chat.ID, query.Message.MessageID
+	userID := fmt.Sprintf("%d", query.From.ID)
+
+	switch args[0] {
+	case tzRegions:
+		b.answerCallback(query, "")
+		b.editPicker(chatID, messageID, b.t(query.From, "tzpicker.regions"), b.timezonePicker(query.From))
+
+	case tzRegion:
+		if len(args) != 3 {
+			return
+		}
+		index, err := strconv.Atoi(args[1])
+		if err != nil || index < 0 || index >= len(timezone.Regions) {
+			return
+		}
+		page, err := strconv.Atoi(args[2])
+		if err != nil {
+			return
+		}
+		b.answerCallback(query, "")
+		text, keyboard := b.regionPage(query.From, index, page)
+		b.editPicker(chatID, messageID, text, keyboard)
+
+	case tzSet:
+		if len(args) != 2 {
+			return
+		}
+		tz, err := b.parseTimezone(args[1])
+		if err != nil {
+			b.answerCallback(query, err.Error())
+			return
+		}
+		b.setUserTimezone(userID, tz)
+		b.timezoneSearches.Delete(userID)
+		b.answerCallback(query, "")
+
+		text := b.t(query.From, "timezone.set", b.formatTimezoneForDisplay(tz))
+		if !query.Message.Chat.IsPrivate() {
+			// Others may still pick their own timezone with the same buttons
+			b.sendText(chatID, text, messageID)
+			return
+		}
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error confirming timezone: %v", err)
+		}
+		b.continueOnboarding(query.From, chatID, onboardingTimezone)
+
+	case tzSearch:
+		b.timezoneSearches.Set(userID, chatID)
+		b.answerCallback(query, "")
+		b.sendText(chatID, b.t(query.From, "tzpicker.search_prompt"), messageID)
+	}
+}
+
+// editPicker shows another step of the timezone picker in its message
+func (b *Bot) editPicker(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	if _, err := b.bot.Send(edit); err != nil && !isMessageNotModified(err) {
+		log.Printf("Error updating timezone picker: %v", err)
+	}
+}
+
+// handleTimezoneSearch answers a city name sent after "Search by city" with the timezones that
+// match it, reporting whether the message was one
+func (b *Bot) handleTimezoneSearch(message *tgbotapi.Message) bool {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	chatID, ok := b.timezoneSearches.Get(userID)
+	if !ok || chatID != message.Chat.ID || message.Text == "" || message.IsCommand() {
+		return false
+	}
+	b.timezoneSearches.Delete(userID)
+	b.sendTimezoneMatches(message, message.Text)
+	return true
+}
+
+// sendTimezoneMatches offers the timezones of the cities matching a name, or the regions if
+// none do
+func (b *Bot) sendTimezoneMatches(message *tgbotapi.Message, name string) {
+	matches := searchTimezones(name)
+	log.Printf("Timezones matching %q: %v", name, matches)
+
+	var msg tgbotapi.MessageConfig
+	if len(matches) == 0 {
+		msg = tgbotapi.NewMessage(message.Chat.ID, b.t(message.From, "tzpicker.no_matches", name))
+		msg.ReplyMarkup = b.timezonePicker(message.From)
+	} else {
+		msg = tgbotapi.NewMessage(message.Chat.ID, b.t(message.From, "tzpicker.matches"))
+		rows := timezoneButtonRows(matches)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(message.From, "tzpicker.regions_button"), callbackData(callbackTimezone, tzRegions)),
+		))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	msg.ReplyToMessageID = message.MessageID
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending timezone matches: %v", err)
+	}
+}
+
+// searchTimezones finds the timezones of the cities best matching a name, including the
+// known city names in other languages
+func searchTimezones(name string) []string {
+	var matches []string
+	if tz, ok := cityTimezones[strings.ToLower(strings.TrimSpace(name))]; ok {
+		matches = append(matches, tz)
+	}
+	for _, tz := range timezone.Search(name, maxTimezoneSuggestions) {
+		if len(matches) < maxTimezoneSuggestions && !containsString(matches, tz) {
+			matches = append(matches, tz)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,412 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"calendar-assistant/pkg/timezone"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxTimezoneSuggestions limits how many suggested timezones are offered at once
+const maxTimezoneSuggestions = 4
+
+// languageTimezones maps Telegram language codes to the timezones their speakers most likely use
+var languageTimezones = map[string][]string{
+	"ar":    {"Asia/Riyadh", "Africa/Cairo", "Asia/Dubai"},
+	"az":    {"Asia/Baku"},
+	"be":    {"Europe/Minsk"},
+	"cs":    {"Europe/Prague"},
+	"de":    {"Europe/Berlin", "Europe/Vienna", "Europe/Zurich"},
+	"el":    {"Europe/Athens"},
+	"en":    {"Europe/London", "America/New_York", "America/Los_Angeles"},
+	"en-gb": {"Europe/London"},
+	"en-us": {"America/New_York", "America/Chicago", "America/Los_Angeles"},
+	"es":    {"Europe/Madrid", "America/Mexico_City", "America/Bogota"},
+	"fa":    {"Asia/Tehran"},
+	"fi":    {"Europe/Helsinki"},
+	"fr":    {"Europe/Paris", "Europe/Brussels", "America/Montreal"},
+	"he":    {"Asia/Jerusalem"},
+	"hi":    {"Asia/Kolkata"},
+	"hu":    {"Europe/Budapest"},
+	"hy":    {"Asia/Yerevan"},
+	"id":    {"Asia/Jakarta"},
+	"it":    {"Europe/Rome"},
+	"ja":    {"Asia/Tokyo"},
+	"ka":    {"Asia/Tbilisi"},
+	"kk":    {"Asia/Almaty"},
+	"ko":    {"Asia/Seoul"},
+	"nl":    {"Europe/Amsterdam", "Europe/Brussels"},
+	"pl":    {"Europe/Warsaw"},
+	"pt":    {"Europe/Lisbon", "America/Sao_Paulo"},
+	"pt-br": {"America/Sao_Paulo"},
+	"ro":    {"Europe/Bucharest"},
+	"ru":    {"Europe/Moscow", "Asia/Yekaterinburg", "Asia/Novosibirsk"},
+	"sr":    {"Europe/Belgrade"},
+	"sv":    {"Europe/Stockholm"},
+	"th":    {"Asia/Bangkok"},
+	"tr":    {"Europe/Istanbul"},
+	"uk":    {"Europe/Kyiv"},
+	"uz":    {"Asia/Tashkent"},
+	"vi":    {"Asia/Ho_Chi_Minh"},
+	"zh":    {"Asia/Shanghai", "Asia/Hong_Kong", "Asia/Taipei"},
+}
+
+// languageCountries maps Telegram language codes to the country their speakers most likely live
+// in, for languages spoken mainly in one country
+var languageCountries = map[string]string{
+	"az":    "AZ",
+	"be":    "BY",
+	"cs":    "CZ",
+	"de":    "DE",
+	"el":    "GR",
+	"en-gb": "GB",
+	"en-us": "US",
+	"fa":    "IR",
+	"fi":    "FI",
+	"fr":    "FR",
+	"he":    "IL",
+	"hi":    "IN",
+	"hu":    "HU",
+	"hy":    "AM",
+	"id":    "ID",
+	"it":    "IT",
+	"ja":    "JP",
+	"ka":    "GE",
+	"kk":    "KZ",
+	"ko":    "KR",
+	"nl":    "NL",
+	"pl":    "PL",
+	"pt":    "PT",
+	"pt-br": "BR",
+	"ro":    "RO",
+	"ru":    "RU",
+	"sr":    "RS",
+	"sv":    "SE",
+	"th":    "TH",
+	"tr":    "TR",
+	"uk":    "UA",
+	"uz":    "UZ",
+	"vi":    "VN",
+	"zh":    "CN",
+}
+
+// callingCodeTimezones maps international calling codes to likely timezones
+var callingCodeTimezones = map[string][]string{
+	"1":   {"America/New_York", "America/Chicago", "America/Los_Angeles"},
+	"7":   {"Europe/Moscow", "Asia/Almaty", "Asia/Yekaterinburg"},
+	"20":  {"Africa/Cairo"},
+	"30":  {"Europe/Athens"},
+	"31":  {"Europe/Amsterdam"},
+	"33":  {"Europe/Paris"},
+	"34":  {"Europe/Madrid"},
+	"36":  {"Europe/Budapest"},
+	"39":  {"Europe/Rome"},
+	"40":  {"Europe/Bucharest"},
+	"44":  {"Europe/London"},
+	"46":  {"Europe/Stockholm"},
+	"48":  {"Europe/Warsaw"},
+	"49":  {"Europe/Berlin"},
+	"52":  {"America/Mexico_City"},
+	"55":  {"America/Sao_Paulo"},
+	"61":  {"Australia/Sydney", "Australia/Perth"},
+	"62":  {"Asia/Jakarta"},
+	"65":  {"Asia/Singapore"},
+	"66":  {"Asia/Bangkok"},
+	"81":  {"Asia/Tokyo"},
+	"82":  {"Asia/Seoul"},
+	"84":  {"Asia/Ho_Chi_Minh"},
+	"86":  {"Asia/Shanghai"},
+	"90":  {"Europe/Istanbul"},
+	"91":  {"Asia/Kolkata"},
+	"98":  {"Asia/Tehran"},
+	"351": {"Europe/Lisbon"},
+	"358": {"Europe/Helsinki"},
+	"374": {"Asia/Yerevan"},
+	"375": {"Europe/Minsk"},
+	"380": {"Europe/Kyiv"},
+	"381": {"Europe/Belgrade"},
+	"420": {"Europe/Prague"},
+	"966": {"Asia/Riyadh"},
+	"971": {"Asia/Dubai"},
+	"972": {"Asia/Jerusalem"},
+	"994": {"Asia/Baku"},
+	"995": {"Asia/Tbilisi"},
+	"998": {"Asia/Tashkent"},
+}
+
+// cityTimezones maps lowercase city names (English and Russian) to their timezone
+var cityTimezones = map[string]string{
+	"almaty":           "Asia/Almaty",
+	"amsterdam":        "Europe/Amsterdam",
+	"bangkok":          "Asia/Bangkok",
+	"barcelona":        "Europe/Madrid",
+	"belgrade":         "Europe/Belgrade",
+	"berlin":           "Europe/Berlin",
+	"chicago":          "America/Chicago",
+	"dubai":            "Asia/Dubai",
+	"istanbul":         "Europe/Istanbul",
+	"kyiv":             "Europe/Kyiv",
+	"lisbon":           "Europe/Lisbon",
+	"london":           "Europe/London",
+	"los angeles":      "America/Los_Angeles",
+	"madrid":           "Europe/Madrid",
+	"minsk":            "Europe/Minsk",
+	"moscow":           "Europe/Moscow",
+	"new york":         "America/New_York",
+	"novosibirsk":      "Asia/Novosibirsk",
+	"paris":            "Europe/Paris",
+	"prague":           "Europe/Prague",
+	"saint petersburg": "Europe/Moscow",
+	"san francisco":    "America/Los_Angeles",
+	"singapore":        "Asia/Singapore",
+	"st. petersburg":   "Europe/Moscow",
+	"sydney":           "Australia/Sydney",
+	"tashkent":         "Asia/Tashkent",
+	"tbilisi":          "Asia/Tbilisi",
+	"tokyo":            "Asia/Tokyo",
+	"vladivostok":      "Asia/Vladivostok",
+	"warsaw":           "Europe/Warsaw",
+	"yekaterinburg":    "Asia/Yekaterinburg",
+	"yerevan":          "Asia/Yerevan",
+	"алматы":           "Asia/Almaty",
+	"белград":          "Europe/Belgrade",
+	"берлин":           "Europe/Berlin",
+	"владивосток":      "Asia/Vladivostok",
+	"дубай":            "Asia/Dubai",
+	"екатеринбург":     "Asia/Yekaterinburg",
+	"ереван":           "Asia/Yerevan",
+	"казань":           "Europe/Moscow",
+	"киев":             "Europe/Kyiv",
+	"лиссабон":         "Europe/Lisbon",
+	"лондон":           "Europe/London",
+	"минск":            "Europe/Minsk",
+	"москва":           "Europe/Moscow",
+	"москве":           "Europe/Moscow",
+	"новосибирск":      "Asia/Novosibirsk",
+	"париж":            "Europe/Paris",
+	"петербург":        "Europe/Moscow",
+	"спб":              "Europe/Moscow",
+	"стамбул":          "Europe/Istanbul",
+	"тбилиси":          "Asia/Tbilisi",
+	"ташкент":          "Asia/Tashkent",
+}
+
+// suggestTimezones guesses likely timezones for the sender of a message, strongest signal first:
+// cities mentioned in the message or the user's history, a shared phone number, then the
+// Telegram language code
+func (b *Bot) suggestTimezones(message *tgbotapi.Message) []string {
+	var suggestions []string
+	add := func(timezones ...string) {
+		for _, tz := range timezones {
+			if len(suggestions) >= maxTimezoneSuggestions {
+				return
+			}
+			if !containsString(suggestions, tz) {
+				suggestions = append(suggestions, tz)
+			}
+		}
+	}
+
+	// Cities mentioned now or in earlier events
+	texts := []string{message.Text, message.Caption}
+	for _, entry := range b.store.History(fmt.Sprintf("%d", message.From.ID)) {
+		texts = append(texts, entry.RawInput)
+		if entry.Event != nil {
+			texts = append(texts, entry.Event.Location)
+		}
+	}
+	for _, text := range texts {
+		add(timezonesForCities(text)...)
+	}
+
+	// The user's own phone number, if they shared it
+	if message.Contact != nil && message.Contact.UserID == message.From.ID {
+		add(timezonesForPhone(message.Contact.PhoneNumber)...)
+	}
+
+	add(timezonesForLanguage(message.From.LanguageCode)...)
+
+	log.Printf("Suggested timezones for user %d: %v", message.From.ID, suggestions)
+	return suggestions
+}
+
+// startTimezoneSuggestions suggests timezones when a user starts the bot, leading with a one-tap
+// guess from their Telegram language. guess is the sentence asking about it, empty if there's none.
+func (b *Bot) startTimezoneSuggestions(message *tgbotapi.Message) (guess string, suggestions []string) {
+	suggestions = b.suggestTimezones(message)
+	country, tz := b.languageTimezoneGuess(message.From)
+	if tz == "" {
+		return "", suggestions
+	}
+
+	guessed := []string{tz}
+	for _, suggestion := range suggestions {
+		if suggestion != tz && len(guessed) < maxTimezoneSuggestions {
+			guessed = append(guessed, suggestion)
+		}
+	}
+	return b.t(message.From, "timezone.language_guess", b.t(message.From, "country."+country), tz), guessed
+}
+
+// timezonesForCities finds the timezones of known cities mentioned in a text, in the order
+// the cities are mentioned, so the suggestions don't change between runs
+func timezonesForCities(text string) []string {
+	text = strings.ToLower(text)
+	if text == "" {
+		return nil
+	}
+
+	type mention struct {
+		at   int
+		city string
+	}
+	var mentions []mention
+	for city := range cityTimezones {
+		if at := strings.Index(text, city); at >= 0 {
+			mentions = append(mentions, mention{at, city})
+		}
+	}
+	slices.SortFunc(mentions, func(a, b mention) int {
+		if a.at != b.at {
+			return a.at - b.at
+		}
+		return strings.Compare(a.city, b.city)
+	})
+
+	var timezones []string
+	for _, m := range mentions {
+		if tz := cityTimezones[m.city]; !containsString(timezones, tz) {
+			timezones = append(timezones, tz)
+		}
+	}
+	return timezones
+}
+
+// timezonesForPhone looks up timezones by the longest matching international calling code
+func timezonesForPhone(phone string) []string {
+	digits := strings.TrimLeft(strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone), "0")
+
+	for length := 3; length >= 1; length-- {
+		if len(digits) < length {
+			continue
+		}
+		if timezones, ok := callingCodeTimezones[digits[:length]]; ok {
+			return timezones
+		}
+	}
+	return nil
+}
+
+// timezonesForLanguage looks up timezones by language code, preferring regional variants
+func timezonesForLanguage(languageCode string) []string {
+	code := strings.ToLower(languageCode)
+	if timezones, ok := languageTimezones[code]; ok {
+		return timezones
+	}
+	if i := strings.Index(code, "-"); i > 0 {
+		return languageTimezones[code[:i]]
+	}
+	return nil
+}
+
+// countryForLanguage looks up the country of a language code, preferring regional variants
+func countryForLanguage(languageCode string) string {
+	code := strings.ToLower(languageCode)
+	if country, ok := languageCountries[code]; ok {
+		return country
+	}
+	if i := strings.Index(code, "-"); i > 0 {
+		return languageCountries[code[:i]]
+	}
+	return ""
+}
+
+// languageTimezoneGuess guesses a user's country and timezone from their Telegram language code.
+// It returns empty strings when the language isn't tied to one country.
+func (b *Bot) languageTimezoneGuess(user *tgbotapi.User) (country, tz string) {
+	country = countryForLanguage(user.LanguageCode)
+	if country == "" {
+		return "", ""
+	}
+	for _, candidate := range timezonesForLanguage(user.LanguageCode) {
+		if _, err := b.timezones.Load(candidate); err == nil {
+			return country, candidate
+		}
+	}
+	return "", ""
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSharedContact suggests timezones based on the phone number a user shared
+func (b *Bot) handleSharedContact(message *tgbotapi.Message) {
+	if message.Contact.UserID != message.From.ID {
+		b.sendError(message, "contact.not_own", nil)
+		return
+	}
+
+	suggestions := b.suggestTimezones(message)
+	key := "contact.suggestions"
+	if len(suggestions) == 0 {
+		key = "contact.no_suggestions"
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.t(message.From, key))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = b.timezonePicker(message.From, suggestions...)
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending timezone suggestions: %v", err)
+	}
+}
+
+// handleSharedLocation sets the timezone of the place a user shared, offering the neighbouring
+// timezones in case the location is close to a border
+func (b *Bot) handleSharedLocation(message *tgbotapi.Message) {
+	userID := fmt.Sprintf("%d", message.From.ID)
+	candidates := timezone.Nearest(message.Location.Latitude, message.Location.Longitude, maxTimezoneSuggestions)
+
+	var tz string
+	var others []string
+	for _, candidate := range candidates {
+		if _, err := b.timezones.Load(candidate); err != nil {
+			continue // Not in this server's timezone database
+		}
+		if tz == "" {
+			tz = candidate
+		} else {
+			others = append(others, candidate)
+		}
+	}
+	if tz == "" {
+		b.sendError(message, "location.failed", nil)
+		return
+	}
+
+	b.setUserTimezone(userID, tz)
+	log.Printf("Set timezone of user %s from their location (%.2f, %.2f)", userID, message.Location.Latitude, message.Location.Longitude)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.t(message.From, "location.set", b.formatTimezoneForDisplay(tz)))
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = b.timezonePicker(message.From, others...)
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error confirming timezone from location: %v", err)
+	}
+	b.continueOnboarding(message.From, message.Chat.ID, onboardingTimezone)
+}
@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleOtherContent handles the content that isn't an event on its own: a place shared in
+// reply to an event sets its location, a poll is read as the text of an event, and stickers,
+// dice, games and GIFs get an explanation of what the bot accepts. It returns the message to
+// extract an event from, unless it reports that the message was handled already.
+func (b *Bot) handleOtherContent(ctx context.Context, message *tgbotapi.Message) (*tgbotapi.Message, bool) {
+	if place := sharedPlace(message); place != "" && message.ReplyToMessage != nil {
+		if input, opts, ok := b.replyContextInput(message, "The event takes place at "+place); ok {
+			b.processEvent(ctx, input, opts)
+			return nil, true
+		}
+	}
+
+	if message.Poll != nil {
+		input := *message
+		input.Text = pollText(message.Poll)
+		input.Poll = nil
+		return &input, false
+	}
+
+	// GIFs with a caption are read from the caption
+	if message.Animation != nil && message.Caption != "" {
+		input := *message
+		input.Text, input.Entities = message.Caption, message.CaptionEntities
+		input.Animation, input.Caption, input.CaptionEntities = nil, "", nil
+		return &input, false
+	}
+
+	var kind string
+	switch {
+	case message.Sticker != nil:
+		kind = "sticker"
+	case message.Dice != nil:
+		kind = "dice"
+	case message.Game != nil:
+		kind = "game"
+	case message.Animation != nil:
+		kind = "animation"
+	case message.Venue != nil:
+		kind = "venue"
+	default:
+		return message, false
+	}
+
+	log.Printf("Message from user %d is a %s, which has no event", message.From.ID, kind)
+	b.sendText(message.Chat.ID, b.t(message.From, "unsupported."+kind)+"\n\n"+b.t(message.From, "unsupported.accepted"), message.MessageID)
+	return nil, true
+}
+
+// sharedPlace describes the venue or location of a message, or returns "" if it has none
+func sharedPlace(message *tgbotapi.Message) string {
+	switch {
+	case message.Venue != nil:
+		return strings.TrimSpace(message.Venue.Title + ", " + message.Venue.Address)
+	case message.Location != nil:
+		return fmt.Sprintf("%.6f, %.6f", message.Location.Latitude, message.Location.Longitude)
+	}
+	return ""
+}
+
+// pollText turns a poll into text to extract an event from, e.g. a poll about when to meet
+func pollText(poll *tgbotapi.Poll) string {
+	var sb strings.Builder
+	sb.WriteString(poll.Question)
+	for _, option := range poll.Options {
+		sb.WriteString("\n- " + option.Text)
+	}
+	return sb.String()
+}
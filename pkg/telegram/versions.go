@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"log"
+	"strings"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// replacedEntry returns the history entry of the event whose ICS file a message of the bot
+// sent, so a correction of it updates the event instead of adding another, or nil if it sent none
+func (b *Bot) replacedEntry(message *tgbotapi.Message) *storage.HistoryEntry {
+	if message == nil || message.From == nil || message.From.ID != b.bot.Self.ID || message.Document == nil {
+		return nil
+	}
+	// A correction of a file with several events can't tell which one it's about
+	entries := b.store.HistoryByMessage(b.cfg.BotName, message.Chat.ID, message.MessageID)
+	if len(entries) != 1 || entries[0].Event == nil {
+		return nil
+	}
+	return &entries[0]
+}
+
+// versionEvent gives an event about to be sent its UID and sequence: those of the event it
+// corrects, one version later, or its own UID, one version later if it was sent before
+func (b *Bot) versionEvent(userID string, replaces *storage.HistoryEntry, event *openai.Event) {
+	// A group member may correct someone else's event, whose earlier versions are the owner's
+	owners := []string{userID}
+	if replaces != nil {
+		event.UID = replaces.Event.UID
+		if replaces.UserID != userID {
+			owners = append(owners, replaces.UserID)
+		}
+	} else {
+		event.UID = calendar.EventUID(userID, event)
+	}
+
+	event.Sequence = 0
+	for _, owner := range owners {
+		if sequence, ok := b.store.EventSequence(owner, event.UID); ok && sequence+1 > event.Sequence {
+			event.Sequence = sequence + 1
+		}
+	}
+	if event.Sequence > 0 {
+		log.Printf("Updating event %s of user(s) %s to sequence %d", event.UID, strings.Join(owners, ", "), event.Sequence)
+	}
+}
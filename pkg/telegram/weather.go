@@ -0,0 +1,114 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"calendar-assistant/pkg/calendar"
+	"calendar-assistant/pkg/openai"
+	"calendar-assistant/pkg/storage"
+	"calendar-assistant/pkg/weather"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// weatherTimeout limits how long the forecasts of one calendar file may hold it up
+const weatherTimeout = 15 * time.Second
+
+// All-day events get the forecast of the daytime of their first day
+const (
+	allDayWeatherFrom = 9
+	allDayWeatherTo   = 18
+)
+
+// weatherIcons are shown in front of the conditions of a forecast
+var weatherIcons = map[string]string{
+	weather.ConditionClear:        "☀️",
+	weather.ConditionCloudy:       "⛅",
+	weather.ConditionFog:          "🌫",
+	weather.ConditionDrizzle:      "🌦",
+	weather.ConditionRain:         "🌧",
+	weather.ConditionSnow:         "🌨",
+	weather.ConditionThunderstorm: "⛈",
+}
+
+// handleWeather shows or changes whether outdoor events get the weather forecast in their
+// description, with /weather on or /weather off
+func (b *Bot) handleWeather(ctx context.Context, message *tgbotapi.Message) {
+	setting := toggleSetting{
+		key:  "weather",
+		name: "weather forecasts",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.Weather },
+		set:  func(prefs *storage.UserPreferences, enabled bool) { prefs.Weather = enabled },
+	}
+	if b.geocoder == nil {
+		setting.turnedOn = "weather.no_geocoder" // Without coordinates there's no forecast to get
+	}
+	b.handleToggle(message, setting)
+}
+
+// withWeather returns events with the forecast added to the description of the outdoor ones
+// within the forecast window, if the user asked for it. Events without coordinates are left
+// as they are, as are the events passed in.
+func (b *Bot) withWeather(user *tgbotapi.User, prefs *storage.UserPreferences, events []*openai.Event, loc *time.Location) []*openai.Event {
+	if !prefs.Weather {
+		return events
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), weatherTimeout)
+	defer cancel()
+
+	withWeather := make([]*openai.Event, 0, len(events))
+	for _, event := range events {
+		if !event.Outdoor || event.Geo == nil {
+			withWeather = append(withWeather, event)
+			continue
+		}
+
+		// Event times are wall-clock times in the user's timezone
+		start, end := inZone(event.StartTime, loc), inZone(event.EndTime, loc)
+		if calendar.IsAllDay(event) {
+			start = start.Add(allDayWeatherFrom * time.Hour)
+			end = inZone(event.StartTime, loc).Add(allDayWeatherTo * time.Hour)
+		}
+
+		forecast, err := b.weather.Forecast(ctx, event.Geo.Lat, event.Geo.Lon, start, end)
+		if err != nil {
+			if !errors.Is(err, weather.ErrNoForecast) {
+				log.Printf("Error getting the forecast of %q: %v", event.Title, err)
+			}
+			withWeather = append(withWeather, event)
+			continue
+		}
+
+		changed := *event
+		note := b.weatherNote(user, forecast)
+		if changed.Description == "" {
+			changed.Description = note
+		} else {
+			changed.Description += "\n\n" + note
+		}
+		withWeather = append(withWeather, &changed)
+	}
+	return withWeather
+}
+
+// weatherNote describes a forecast in a line, e.g. "Forecast: 🌧 rain, 12–15 °C, 80% chance of
+// precipitation"
+func (b *Bot) weatherNote(user *tgbotapi.User, forecast weather.Forecast) string {
+	low, high := int(math.Round(forecast.MinTemperature)), int(math.Round(forecast.MaxTemperature))
+	temperature := fmt.Sprintf("%d °C", high)
+	if low != high {
+		temperature = fmt.Sprintf("%d–%d °C", low, high)
+	}
+	return b.t(user, "weather.note", weatherIcons[forecast.Condition], b.t(user, "weather."+forecast.Condition), temperature, forecast.PrecipitationChance)
+}
+
+// inZone returns the time in loc with the same date and time of day as a wall-clock time
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+}
@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// webhookSecretHeader carries the secret token Telegram sends with every webhook update
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// StartWebhook registers the webhook with Telegram and serves updates until ctx is cancelled.
+// The server speaks plain HTTP unless a TLS certificate is configured, so it can run behind a
+// TLS-terminating reverse proxy or load balancer; every replica can share the same webhook.
+func (b *Bot) StartWebhook(ctx context.Context) error {
+	webhookURL, err := url.Parse(b.cfg.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	// tgbotapi's WebhookConfig has no secret token, so set the webhook directly
+	params := tgbotapi.Params{}
+	params["url"] = webhookURL.String()
+	params["secret_token"] = b.cfg.WebhookSecret
+	if _, err := b.bot.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	log.Printf("Webhook set to %s", webhookURL.Redacted())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, b.handleWebhook)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              b.cfg.WebhookListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down webhook server: %v", err)
+		}
+	}()
+
+	log.Printf("Listening for webhook updates on %s%s", b.cfg.WebhookListenAddr, path)
+	if b.cfg.WebhookTLSCert != "" {
+		err = server.ListenAndServeTLS(b.cfg.WebhookTLSCert, b.cfg.WebhookTLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// handleWebhook accepts an update from Telegram after checking the secret token
+func (b *Bot) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Telegram retries updates that fail, so a replica that's shutting down leaves them to the others
+	if b.Stopping() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	token := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(b.cfg.WebhookSecret)) != 1 {
+		log.Printf("Rejected webhook request from %s with an invalid secret token", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading webhook update: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	b.topics.recordUpdate(body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	update, err := b.bot.HandleUpdate(r)
+	if err != nil {
+		log.Printf("Error decoding webhook update: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Handlers run in the background, so Telegram gets its answer right away
+	b.handleUpdate(*update)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"calendar-assistant/pkg/config"
+	"calendar-assistant/pkg/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// whatsNewTitles head the release notes in the languages the bot knows
+var whatsNewTitles = map[string]string{
+	"en": "🆕 What's new in version %s",
+	"ru": "🆕 Что нового в версии %s",
+	"uk": "🆕 Що нового у версії %s",
+}
+
+// handleWhatsNew shows the notes of the current release, or changes whether a user gets
+// them when a new version is released
+func (b *Bot) handleWhatsNew(ctx context.Context, message *tgbotapi.Message) {
+	notes := b.cfg.ReleaseNotes
+	setting := toggleSetting{
+		key:  "whatsnew",
+		name: "release announcements",
+		get:  func(prefs *storage.UserPreferences) bool { return prefs.WhatsNew },
+		set: func(prefs *storage.UserPreferences, enabled bool) {
+			prefs.WhatsNew = enabled
+			// The current notes are shown right away, so they aren't announced again
+			if enabled && notes != nil {
+				prefs.SeenReleaseNotes = notes.Version
+			}
+		},
+	}
+	if notes != nil {
+		setting.extra = func(user *tgbotapi.User) string {
+			prefs := b.getUserPreferences(fmt.Sprintf("%d", user.ID))
+			lang := prefs.Language
+			return releaseNotesText(notes, lang, b.cfg.DefaultLanguage)
+		}
+	}
+	b.handleToggle(message, setting)
+}
+
+// AnnounceReleaseNotes sends the current release notes once to every user who opted in and
+// hasn't got them yet. Users who weren't reached are tried again on the next start.
+func (b *Bot) AnnounceReleaseNotes(ctx context.Context) {
+	notes := b.cfg.ReleaseNotes
+	if notes == nil {
+		return
+	}
+
+	var messages []broadcastMessage
+	for userID, prefs := range b.store.Users() {
+		if !prefs.WhatsNew || prefs.SeenReleaseNotes == notes.Version {
+			continue
+		}
+		messages = append(messages, broadcastMessage{
+			userID: userID,
+			text:   releaseNotesText(notes, prefs.Language, b.cfg.DefaultLanguage),
+		})
+	}
+
+	recipients := messages[:0]
+	for _, msg := range messages {
+		if b.canReceiveBroadcast(msg.userID) {
+			recipients = append(recipients, msg)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	log.Printf("Announcing release notes of version %s to %d users", notes.Version, len(recipients))
+	result := b.broadcast(ctx, recipients, func(userID string) {
+		b.updateUserPreferences(userID, func(prefs *storage.UserPreferences) {
+			prefs.SeenReleaseNotes = notes.Version
+		})
+	})
+	log.Printf("Announced release notes of version %s: %d sent, %d blocked the bot, %d failed",
+		notes.Version, result.sent, result.blocked, result.failed)
+}
+
+// releaseNotesText formats the release notes in the user's language, falling back to the
+// default language, English, and then any language the notes have
+func releaseNotesText(notes *config.ReleaseNotes, lang, defaultLang string) string {
+	text, ok := "", false
+	for _, candidate := range []string{lang, defaultLang, "en"} {
+		if text, ok = notes.Notes[candidate]; ok {
+			lang = candidate
+			break
+		}
+	}
+	if !ok {
+		langs := make([]string, 0, len(notes.Notes))
+		for candidate := range notes.Notes {
+			langs = append(langs, candidate)
+		}
+		sort.Strings(langs)
+		lang, text = langs[0], notes.Notes[langs[0]]
+	}
+
+	title, ok := whatsNewTitles[lang]
+	if !ok {
+		title = whatsNewTitles["en"]
+	}
+	return fmt.Sprintf(title, notes.Version) + "\n\n" + strings.TrimSpace(text)
+}
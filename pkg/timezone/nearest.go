@@ -0,0 +1,45 @@
+package timezone
+
+import (
+	"math"
+	"sort"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// Nearest returns the timezones whose principal cities are nearest to a location, nearest
+// first. Timezone borders don't follow distance, so close to a border the nearest city may be
+// in the neighbouring timezone, and callers should let the user correct it.
+func Nearest(lat, lon float64, limit int) []string {
+	type candidate struct {
+		zone     string
+		distance float64
+	}
+	candidates := make([]candidate, 0, len(zoneCoordinates))
+	for zone, coords := range zoneCoordinates {
+		candidates = append(candidates, candidate{zone, distanceKm(lat, lon, coords[0], coords[1])})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	zones := make([]string, len(candidates))
+	for i, c := range candidates {
+		zones[i] = c.zone
+	}
+	return zones
+}
+
+// distanceKm returns the great-circle distance between two points using the haversine formula
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,81 @@
+package timezone
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// LoadError describes a timezone that couldn't be loaded on this server
+type LoadError struct {
+	Name string
+	Err  error
+}
+
+// Error returns a message that can be shown to the user as is
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("I couldn't load the timezone %s on this server", e.Name)
+}
+
+// Unwrap returns the underlying error from time.LoadLocation
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Resolver loads timezones once and caches them, falling back to a default when a
+// timezone can't be loaded
+type Resolver struct {
+	fallback string
+	cache    map[string]*time.Location
+	mu       sync.RWMutex // Mutex to protect the cache
+}
+
+// NewResolver creates a resolver that falls back to the given timezone, or UTC if it's empty
+func NewResolver(fallback string) *Resolver {
+	if fallback == "" {
+		fallback = "UTC"
+	}
+	return &Resolver{
+		fallback: fallback,
+		cache:    make(map[string]*time.Location),
+	}
+}
+
+// Load returns the location for an IANA timezone name
+func (r *Resolver) Load(name string) (*time.Location, error) {
+	r.mu.RLock()
+	loc, ok := r.cache[name]
+	r.mu.RUnlock()
+	if ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, &LoadError{Name: name, Err: err}
+	}
+
+	r.mu.Lock()
+	r.cache[name] = loc
+	r.mu.Unlock()
+
+	return loc, nil
+}
+
+// Resolve returns the location for name, or the fallback location if it can't be loaded.
+// The error is non-nil whenever the fallback was used, so the caller can tell the user.
+func (r *Resolver) Resolve(name string) (*time.Location, error) {
+	loc, err := r.Load(name)
+	if err == nil {
+		return loc, nil
+	}
+	log.Printf("Error loading timezone %s: %v, falling back to %s", name, err, r.fallback)
+
+	fallback, fallbackErr := r.Load(r.fallback)
+	if fallbackErr != nil {
+		log.Printf("Error loading fallback timezone %s: %v, falling back to UTC", r.fallback, fallbackErr)
+		return time.UTC, err
+	}
+	return fallback, err
+}
@@ -0,0 +1,140 @@
+package timezone
+
+import (
+	"sort"
+	"strings"
+)
+
+// Region is a group of timezones offered together when picking a timezone
+type Region struct {
+	Name  string
+	Zones []string
+}
+
+// Regions are the timezones offered when picking one, by region. They cover the larger cities
+// of each region rather than every IANA name.
+var Regions = []Region{
+	{Name: "Europe", Zones: []string{
+		"Europe/Amsterdam", "Europe/Athens", "Europe/Belgrade", "Europe/Berlin", "Europe/Brussels",
+		"Europe/Bucharest", "Europe/Budapest", "Europe/Chisinau", "Europe/Copenhagen", "Europe/Dublin",
+		"Europe/Helsinki", "Europe/Istanbul", "Europe/Kaliningrad", "Europe/Kyiv", "Europe/Lisbon",
+		"Europe/London", "Europe/Madrid", "Europe/Minsk", "Europe/Moscow", "Europe/Oslo",
+		"Europe/Paris", "Europe/Prague", "Europe/Riga", "Europe/Rome", "Europe/Samara",
+		"Europe/Sofia", "Europe/Stockholm", "Europe/Tallinn", "Europe/Vienna", "Europe/Vilnius",
+		"Europe/Warsaw", "Europe/Zurich",
+	}},
+	{Name: "America", Zones: []string{
+		"America/Anchorage", "America/Argentina/Buenos_Aires", "America/Bogota", "America/Caracas", "America/Chicago",
+		"America/Denver", "America/Edmonton", "America/Halifax", "America/Havana", "America/Lima",
+		"America/Los_Angeles", "America/Mexico_City", "America/Montevideo", "America/New_York", "America/Panama",
+		"America/Phoenix", "America/Santiago", "America/Sao_Paulo", "America/St_Johns", "America/Toronto",
+		"America/Vancouver", "America/Winnipeg", "Pacific/Honolulu",
+	}},
+	{Name: "Asia", Zones: []string{
+		"Asia/Almaty", "Asia/Baku", "Asia/Bangkok", "Asia/Dhaka", "Asia/Dubai",
+		"Asia/Ho_Chi_Minh", "Asia/Hong_Kong", "Asia/Irkutsk", "Asia/Jakarta", "Asia/Jerusalem",
+		"Asia/Kabul", "Asia/Karachi", "Asia/Kathmandu", "Asia/Kolkata", "Asia/Krasnoyarsk",
+		"Asia/Kuala_Lumpur", "Asia/Magadan", "Asia/Manila", "Asia/Novosibirsk", "Asia/Omsk",
+		"Asia/Riyadh", "Asia/Seoul", "Asia/Shanghai", "Asia/Singapore", "Asia/Taipei",
+		"Asia/Tashkent", "Asia/Tbilisi", "Asia/Tehran", "Asia/Tokyo", "Asia/Vladivostok",
+		"Asia/Yakutsk", "Asia/Yekaterinburg", "Asia/Yerevan",
+	}},
+	{Name: "Africa", Zones: []string{
+		"Africa/Accra", "Africa/Addis_Ababa", "Africa/Algiers", "Africa/Cairo", "Africa/Casablanca",
+		"Africa/Johannesburg", "Africa/Khartoum", "Africa/Kinshasa", "Africa/Lagos", "Africa/Luanda",
+		"Africa/Nairobi", "Africa/Tunis",
+	}},
+	{Name: "Australia & Pacific", Zones: []string{
+		"Australia/Adelaide", "Australia/Brisbane", "Australia/Darwin", "Australia/Hobart", "Australia/Melbourne",
+		"Australia/Perth", "Australia/Sydney", "Pacific/Auckland", "Pacific/Fiji", "Pacific/Guam",
+		"Pacific/Port_Moresby", "Pacific/Tongatapu",
+	}},
+	{Name: "UTC offsets", Zones: []string{
+		"GMT-12", "GMT-11", "GMT-10", "GMT-9", "GMT-8", "GMT-7", "GMT-6", "GMT-5", "GMT-4", "GMT-3",
+		"GMT-2", "GMT-1", "UTC", "GMT+1", "GMT+2", "GMT+3", "GMT+4", "GMT+5", "GMT+6", "GMT+7",
+		"GMT+8", "GMT+9", "GMT+10", "GMT+11", "GMT+12", "GMT+13", "GMT+14",
+	}},
+}
+
+// City returns the city of an IANA timezone name, e.g. "New York" for "America/New_York"
+func City(zone string) string {
+	return strings.ReplaceAll(zone[strings.LastIndex(zone, "/")+1:], "_", " ")
+}
+
+// Search finds the timezones whose city best matches a name, allowing for typos. Cities that
+// start with the name come first, then cities containing it, then close misspellings.
+func Search(name string, limit int) []string {
+	query := strings.ToLower(strings.TrimSpace(name))
+	if query == "" {
+		return nil
+	}
+
+	type match struct {
+		zone  string
+		score int
+	}
+	var matches []match
+	for _, region := range Regions {
+		for _, zone := range region.Zones {
+			if !strings.Contains(zone, "/") {
+				continue // Offsets have no city
+			}
+			city := strings.ToLower(City(zone))
+			switch {
+			case strings.HasPrefix(city, query):
+				matches = append(matches, match{zone, 0})
+			case len(query) >= 3 && strings.Contains(city, query):
+				matches = append(matches, match{zone, 1})
+			default:
+				// Allow one typo in short names and two in longer ones, e.g. swapped letters
+				maxDistance := 1
+				if len([]rune(query)) >= 5 {
+					maxDistance = 2
+				}
+				if len([]rune(query)) >= 4 {
+					if d := levenshtein(query, city); d <= maxDistance {
+						matches = append(matches, match{zone, 1 + d})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].zone < matches[j].zone
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	zones := make([]string, len(matches))
+	for i, m := range matches {
+		zones[i] = m.zone
+	}
+	return zones
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,140 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// openMeteoURL is the forecast endpoint of the Open-Meteo API, which needs no key
+const openMeteoURL = "https://api.open-meteo.com/v1/forecast"
+
+// ForecastDays is how far ahead Open-Meteo forecasts
+const ForecastDays = 16
+
+// requestTimeout limits a single request for a forecast
+const requestTimeout = 10 * time.Second
+
+// hourFormat is how Open-Meteo writes the hours of a forecast
+const hourFormat = "2006-01-02T15:04"
+
+// ErrNoForecast is returned for times outside the forecast window
+var ErrNoForecast = errors.New("no forecast for this time")
+
+// Conditions a forecast can summarize to, from the WMO weather codes Open-Meteo uses
+const (
+	ConditionClear        = "clear"
+	ConditionCloudy       = "cloudy"
+	ConditionFog          = "fog"
+	ConditionDrizzle      = "drizzle"
+	ConditionRain         = "rain"
+	ConditionSnow         = "snow"
+	ConditionThunderstorm = "thunderstorm"
+)
+
+// Forecast summarizes the weather of a few hours
+type Forecast struct {
+	Condition           string  // One of the Condition constants, the worst of the hours
+	MinTemperature      float64 // In °C
+	MaxTemperature      float64 // In °C
+	PrecipitationChance int     // Highest chance of rain or snow in an hour, in percent
+}
+
+// Client gets forecasts from Open-Meteo
+type Client struct {
+	client  *http.Client
+	baseURL string
+}
+
+// New creates an Open-Meteo client, using the public API if baseURL is empty
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = openMeteoURL
+	}
+	return &Client{client: &http.Client{Timeout: requestTimeout}, baseURL: baseURL}
+}
+
+// Forecast returns the forecast at a place for the hours from start to end
+func (c *Client) Forecast(ctx context.Context, lat, lon float64, start, end time.Time) (Forecast, error) {
+	from := start.UTC().Truncate(time.Hour)
+	to := end.UTC().Add(-time.Nanosecond).Truncate(time.Hour)
+	if to.Before(from) {
+		to = from
+	}
+	if end.Before(time.Now()) || from.After(time.Now().AddDate(0, 0, ForecastDays-1)) {
+		return Forecast{}, ErrNoForecast
+	}
+
+	params := url.Values{}
+	params.Set("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	params.Set("longitude", strconv.FormatFloat(lon, 'f', 4, 64))
+	params.Set("hourly", "temperature_2m,precipitation_probability,weather_code")
+	params.Set("timezone", "GMT")
+	params.Set("start_hour", from.Format(hourFormat))
+	params.Set("end_hour", to.Format(hourFormat))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Forecast{}, fmt.Errorf("failed to query Open-Meteo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Reason string `json:"reason"` // Why a request failed
+		Hourly struct {
+			Temperature   []float64 `json:"temperature_2m"`
+			Precipitation []float64 `json:"precipitation_probability"` // Zero where a model has none
+			WeatherCode   []int     `json:"weather_code"`
+		} `json:"hourly"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Forecast{}, fmt.Errorf("failed to parse Open-Meteo response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Forecast{}, fmt.Errorf("Open-Meteo returned status %d: %s", resp.StatusCode, result.Reason)
+	}
+	if len(result.Hourly.Temperature) == 0 || len(result.Hourly.WeatherCode) == 0 {
+		return Forecast{}, ErrNoForecast
+	}
+
+	forecast := Forecast{
+		Condition:      condition(slices.Max(result.Hourly.WeatherCode)),
+		MinTemperature: slices.Min(result.Hourly.Temperature),
+		MaxTemperature: slices.Max(result.Hourly.Temperature),
+	}
+	if len(result.Hourly.Precipitation) > 0 {
+		forecast.PrecipitationChance = int(slices.Max(result.Hourly.Precipitation))
+	}
+	return forecast, nil
+}
+
+// condition returns the condition of a WMO weather code; higher codes are worse weather, so
+// the highest code of a few hours is the one to prepare for
+func condition(code int) string {
+	switch {
+	case code >= 95:
+		return ConditionThunderstorm
+	case code >= 85 || (code >= 71 && code <= 77):
+		return ConditionSnow
+	case code >= 61:
+		return ConditionRain
+	case code >= 51:
+		return ConditionDrizzle
+	case code >= 45:
+		return ConditionFog
+	case code >= 2:
+		return ConditionCloudy
+	default:
+		return ConditionClear
+	}
+}